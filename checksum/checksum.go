@@ -0,0 +1,191 @@
+// Package checksum writes and checks the checksums.sha256 manifest scummer can keep in
+// each game directory, so that files that went bad on the card or disk they are kept on
+// are found before they ruin a playthrough. The manifest is in the format sha256sum
+// writes, so "sha256sum -c checksums.sha256" checks it too.
+package checksum
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestName is the name of the manifest in a game directory.
+const ManifestName = "checksums.sha256"
+
+// IsManifestFile returns whether a file is left out of the manifest: the manifest
+// itself, and the .scummvm files scummer writes, which are expected to change.
+func IsManifestFile(path string) bool {
+	return strings.EqualFold(filepath.Base(path), ManifestName) || strings.EqualFold(filepath.Ext(path), ".scummvm")
+}
+
+// Manifest hashes every file in a game directory and returns the manifest, one
+// "<sha256>  <path>" line per file, sorted by path.
+func Manifest(directory string) ([]byte, error) {
+	// Look inside a game directory that is a symlink, which WalkDir wouldn't
+	directory, err := filepath.EvalSymlinks(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0)
+	err = filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || IsManifestFile(path) {
+			return nil
+		}
+		sum, err := HashFile(path)
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, sum+"  "+filepath.ToSlash(relativePath)+"\n")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The order the files are listed in depends on the filesystem
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i][sha256.Size*2+2:] < lines[j][sha256.Size*2+2:]
+	})
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// HashFile returns the SHA-256 of a file, in hex.
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ReadManifest reads a manifest, returning the SHA-256 of each file by its path
+// relative to the game directory, with forward slashes. The binary marker sha256sum
+// puts in front of the path ("*") is allowed.
+func ReadManifest(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		if _, err := hex.DecodeString(sum); !ok || err != nil || len(sum) != sha256.Size*2 {
+			return nil, fmt.Errorf("%s:%d: not a sha256sum line", path, lineNumber)
+		}
+		name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+		sums[name] = strings.ToLower(sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// Result is what checking a game directory against its manifest found.
+type Result struct {
+	// Checked is the number of files in the manifest that were hashed.
+	Checked int
+
+	// Corrupted are the files whose contents are not what they were.
+	Corrupted []string
+
+	// Missing are the files in the manifest that are gone.
+	Missing []string
+
+	// Added are the files that aren't in the manifest. They aren't a problem on their
+	// own, since ScummVM may keep files such as saves in the game directory.
+	Added []string
+
+	// Unreadable are the files that couldn't be read, with why.
+	Unreadable []string
+}
+
+// OK returns whether every file in the manifest is still there and as it was.
+func (r Result) OK() bool {
+	return len(r.Corrupted) == 0 && len(r.Missing) == 0 && len(r.Unreadable) == 0
+}
+
+// Check hashes the files of a game directory again and compares them with its
+// manifest. The error wraps fs.ErrNotExist if the directory has no manifest.
+func Check(directory string) (Result, error) {
+	result := Result{}
+	directory, err := filepath.EvalSymlinks(directory)
+	if err != nil {
+		return result, err
+	}
+	sums, err := ReadManifest(filepath.Join(directory, ManifestName))
+	if err != nil {
+		return result, err
+	}
+
+	// Hash the files in the manifest, in order so that the results are too
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sum, err := HashFile(filepath.Join(directory, filepath.FromSlash(name)))
+		switch {
+		case os.IsNotExist(err):
+			result.Missing = append(result.Missing, name)
+		case err != nil:
+			result.Unreadable = append(result.Unreadable, fmt.Sprintf("%s: %s", name, err))
+		case sum != sums[name]:
+			result.Checked++
+			result.Corrupted = append(result.Corrupted, name)
+		default:
+			result.Checked++
+		}
+	}
+
+	// Look for files that were added since
+	err = filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || IsManifestFile(path) {
+			return nil
+		}
+		relativePath, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		if _, ok := sums[filepath.ToSlash(relativePath)]; !ok {
+			result.Added = append(result.Added, filepath.ToSlash(relativePath))
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/furui/scummer/match"
+)
+
+// skipAnswer is the answer that means the directory should be skipped.
+const skipAnswer = "skip"
+
+// scummvmAnswers maps directories to the GameID that was chosen for them, or to
+// skipAnswer. The directories can either be full paths, or just the name of the
+// directory inside the scummvm data file directory. For example:
+//
+// Astro Chicken (Floppy DOS): sci:astrochicken
+// G:\example\SCUMMVM\Interactive Wave: director:iwave
+// Demo Disk: skip
+type scummvmAnswers map[string]string
+
+// readScummvmAnswers loads an answers file. A file that doesn't exist yet is treated as
+// an empty one, so that it can be created by recording answers.
+func readScummvmAnswers(answersFile string) (scummvmAnswers, error) {
+	answers := make(scummvmAnswers)
+
+	answersYAML, err := os.ReadFile(answersFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return answers, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(answersYAML, &answers); err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+// writeScummvmAnswers saves an answers file.
+func writeScummvmAnswers(answersFile string, answers scummvmAnswers) error {
+	answersYAML, err := yaml.Marshal(answers)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(answersFile, answersYAML, 0644)
+}
+
+// Lookup returns the answer for a directory, checking its full path before its name.
+func (answers scummvmAnswers) Lookup(directory string) (string, bool) {
+	if answer, ok := answers[directory]; ok {
+		return strings.TrimSpace(answer), true
+	}
+	if answer, ok := answers[filepath.Base(directory)]; ok {
+		return strings.TrimSpace(answer), true
+	}
+	return "", false
+}
+
+// Record saves the answer for a directory under the directory's name.
+func (answers scummvmAnswers) Record(directory string, answer string) {
+	answers[filepath.Base(directory)] = answer
+}
+
+// answeredCandidateIndex returns the index of the candidate with the answered GameID.
+// The answer may leave out the engine prefix. It returns -1 if none of the candidates
+// have that GameID.
+func answeredCandidateIndex(candidates []match.ScummGameMatch, answer string) int {
+	for i, candidate := range candidates {
+		if candidate.GameID == answer || (!strings.Contains(answer, ":") && match.BareGameID(candidate.GameID) == answer) {
+			return i
+		}
+	}
+	return -1
+}
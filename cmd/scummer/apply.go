@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/output"
+)
+
+// runApply writes the .scummvm files for the results of an earlier scan, usually one
+// that was run with --no-write and then reviewed or edited by hand.
+func runApply(args []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+	writeChecksums := flags.Bool("checksums", false, "also write a checksums.sha256 manifest of each game's files into its directory, for \"scummer check\"")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer apply [flags] [<success.json>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// The results file defaults to the one scan writes
+	successFile := stateFile("success.json")
+	if flags.NArg() > 0 {
+		successFile = flags.Arg(0)
+	}
+
+	// Load the results
+	scummvmOutputSlice, err := output.ReadResults(successFile)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Make sure every entry can be written before writing any of them, since the file
+	// may have been edited by hand
+	for i, scummvmOutput := range scummvmOutputSlice {
+		if strings.TrimSpace(scummvmOutput.GameID) == "" || scummvmOutput.GameID == "unknown" {
+			fmt.Printf("Entry %d (%s) in %s has no GameID\n", i+1, scummvmOutput.Directory, successFile)
+			return
+		}
+		if strings.TrimSpace(scummvmOutput.Directory) == "" {
+			fmt.Printf("Entry %d (%s) in %s has no Directory\n", i+1, scummvmOutput.GameID, successFile)
+			return
+		}
+	}
+
+	// Rename the directories that need it, and save their new names
+	changeJournal := newRunJournal()
+	defer finishRunJournal(changeJournal)
+	renamed, err := output.RenameGameDirectories(scummvmOutputSlice, changeJournal)
+	if renamed {
+		if err := output.WriteResults(successFile, scummvmOutputSlice); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Write the .scummvm files
+	fmt.Println("Writing entries out to .scummvm files...")
+	if err := output.WriteMarkerFiles(scummvmOutputSlice, changeJournal, detect.Retry{}); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Write the checksums of the games' files
+	if *writeChecksums {
+		written := writeChecksumManifests(scummvmOutputSlice, changeJournal)
+		fmt.Printf("Wrote the checksums of %d of the %d game directories\n", written, len(scummvmOutputSlice))
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/furui/scummer/journal"
+)
+
+// The ways --archives can handle the games kept in an archive at the top of the library.
+const (
+	// archivesIgnore leaves the archives alone, as scummer always has.
+	archivesIgnore = "ignore"
+
+	// archivesDetect unpacks each archive into a temporary directory for as long as it
+	// takes scummvm to detect the game, and writes the .scummvm file next to the archive.
+	archivesDetect = "detect"
+
+	// archivesUnpack unpacks each archive into a directory of its own in the library for
+	// good, which is then scanned like any other.
+	archivesUnpack = "unpack"
+
+	// archivesPrompt asks for each archive whether to unpack it, and detects the ones
+	// that aren't unpacked.
+	archivesPrompt = "prompt"
+)
+
+// archiveExtractors unpack the archive formats games can be kept in, by extension. Each
+// unpacks an archive into a destination directory, leaving out the directory the files
+// are in if they are all in one. Zip archives are read by Go itself, and the others are
+// handed to 7-Zip or The Unarchiver.
+var archiveExtractors = map[string]func(archive string, destination string) error{
+	".zip": extractZip,
+	".7z":  extractWithUnarchivers(".7z", sevenZip),
+	".rar": extractWithUnarchivers(".rar", theUnarchiver, sevenZip),
+}
+
+// archiveExtensions returns the extensions of the archive formats, in alphabetical order.
+func archiveExtensions() []string {
+	extensions := make([]string, 0, len(archiveExtractors))
+	for extension := range archiveExtractors {
+		extensions = append(extensions, extension)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// extractArchive unpacks an archive with the extractor for its extension, or the files
+// of a CD image that are needed to play the game.
+func extractArchive(archive string, destination string) error {
+	if isDiscImage(archive) {
+		return extractDiscImageFiles(archive, destination)
+	}
+	extractor, ok := archiveExtractors[strings.ToLower(filepath.Ext(archive))]
+	if !ok {
+		return fmt.Errorf("%s isn't an archive scummer can unpack, must be one of %s", archive, strings.Join(archiveExtensions(), ", "))
+	}
+	return extractor(archive, destination)
+}
+
+// archiveDirectoryName returns the name of the directory an archive is unpacked to,
+// which is the archive's name without its extension.
+func archiveDirectoryName(archive string) string {
+	name := filepath.Base(archive)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// unpackArchive unpacks an archive into a new directory, and records it in the journal.
+// It is unpacked next to the directory first and renamed into place once it is all
+// there, so that an archive that can't be unpacked doesn't leave half a game behind.
+func unpackArchive(archive string, directory string, changeJournal *journal.Journal) error {
+	if _, err := os.Lstat(directory); err == nil {
+		return fmt.Errorf("can't unpack %s into %s: it already exists", archive, directory)
+	}
+
+	unpackingDirectory := filepath.Join(filepath.Dir(directory), ".scummer-unpacking-"+filepath.Base(directory))
+	if err := os.RemoveAll(unpackingDirectory); err != nil {
+		return err
+	}
+	if err := extractArchive(archive, unpackingDirectory); err != nil {
+		os.RemoveAll(unpackingDirectory)
+		return fmt.Errorf("unpacking %s: %w", archive, err)
+	}
+	if err := os.Rename(unpackingDirectory, directory); err != nil {
+		os.RemoveAll(unpackingDirectory)
+		return err
+	}
+	return changeJournal.AddedDirectory(directory)
+}
+
+// archiveStaging is the temporary directory the games left in their archives, and the
+// games that are only a CD image, are unpacked into to be detected. Each archive or image
+// is unpacked just long enough for scummvm to detect the game, into a directory named
+// like the game's directory in the library, so that the candidates are compared with the
+// same name. A nil archiveStaging has no archives, so the scan doesn't have to check
+// whether it has any.
+type archiveStaging struct {
+	directory string
+
+	// archives are the archives, by the directory in the library they would be
+	// unpacked to.
+	archives map[string]string
+
+	// discImages are the CD images, by the game directory they are in.
+	discImages map[string]discImageContents
+}
+
+// newArchiveStaging creates the temporary directory archives are unpacked into.
+func newArchiveStaging() (*archiveStaging, error) {
+	directory, err := os.MkdirTemp("", "scummer-archives-*")
+	if err != nil {
+		return nil, err
+	}
+	return &archiveStaging{directory: directory, archives: make(map[string]string), discImages: make(map[string]discImageContents)}, nil
+}
+
+// add records an archive, to be detected as if it had been unpacked into directory.
+func (s *archiveStaging) add(directory string, archive string) {
+	s.archives[directory] = archive
+}
+
+// addDiscImage records the CD image a game directory has in it, to be detected as if
+// the files that are needed from it were in the directory.
+func (s *archiveStaging) addDiscImage(directory string, contents discImageContents) {
+	s.discImages[directory] = contents
+}
+
+// discImage returns what is in the CD image of a game directory of the library, if it is
+// one of the directories that only have an image in them.
+func (s *archiveStaging) discImage(directory string) (discImageContents, bool) {
+	if s == nil {
+		return discImageContents{}, false
+	}
+	contents, ok := s.discImages[directory]
+	return contents, ok
+}
+
+// source returns the archive or CD image a game directory of the library is unpacked
+// from to be detected, or "" if it is detected where it is.
+func (s *archiveStaging) source(directory string) string {
+	if contents, ok := s.discImage(directory); ok {
+		return contents.image
+	}
+	return s.archive(directory)
+}
+
+// archive returns the archive a game directory of the library would be unpacked from, or
+// "" if it isn't one of the archives.
+func (s *archiveStaging) archive(directory string) string {
+	if s == nil {
+		return ""
+	}
+	return s.archives[directory]
+}
+
+// path returns where a game directory of the library is detected: in the temporary
+// directory for an archive or CD image, and where it is for any other.
+func (s *archiveStaging) path(directory string) string {
+	if s.source(directory) == "" {
+		return directory
+	}
+	return filepath.Join(s.directory, filepath.Base(directory))
+}
+
+// stage unpacks the archive or CD image of a game directory of the library into the
+// temporary directory, if it has one.
+func (s *archiveStaging) stage(directory string) error {
+	archive := s.source(directory)
+	if archive == "" {
+		return nil
+	}
+	if err := extractArchive(archive, s.path(directory)); err != nil {
+		s.unstage(directory)
+		return fmt.Errorf("unpacking %s: %w", archive, err)
+	}
+	return nil
+}
+
+// unstage removes an unpacked archive or CD image again once scummvm is done with it, so
+// that only one game at a time takes up space.
+func (s *archiveStaging) unstage(directory string) error {
+	if s.source(directory) == "" {
+		return nil
+	}
+	return os.RemoveAll(s.path(directory))
+}
+
+// remove deletes the temporary directory.
+func (s *archiveStaging) remove() {
+	if s != nil {
+		os.RemoveAll(s.directory)
+	}
+}
+
+// withTemporaryUnpack unpacks an archive into a temporary directory named like the
+// directory it would be unpacked to, calls use with it, and removes it again.
+func withTemporaryUnpack(archive string, directory string, use func(unpackedDirectory string) error) error {
+	temporaryDirectory, err := os.MkdirTemp("", "scummer-archives-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(temporaryDirectory)
+
+	unpackedDirectory := filepath.Join(temporaryDirectory, filepath.Base(directory))
+	if err := extractArchive(archive, unpackedDirectory); err != nil {
+		return fmt.Errorf("unpacking %s: %w", archive, err)
+	}
+	return use(unpackedDirectory)
+}
+
+// prepareGameArchives finds the archives at the top of the library and gets them ready
+// to be scanned the way mode says: unpacked into the library for good, with the
+// directories they were unpacked to returned to be scanned like the others, or added
+// to the returned archiveStaging to be detected where they are, with the directories
+// they would be unpacked to returned. An archive next to a directory of the same name
+// is left alone, since it is most likely the one it was unpacked from.
+func prepareGameArchives(library string, archives []string, directories []string, mode string, noWrite bool, changeJournal *journal.Journal) ([]string, *archiveStaging, error) {
+	if mode == archivesIgnore || len(archives) == 0 {
+		return nil, nil, nil
+	}
+
+	existingDirectories := make(map[string]bool)
+	for _, directory := range directories {
+		existingDirectories[strings.ToLower(directory)] = true
+	}
+
+	var staging *archiveStaging
+	archiveDirectories := make([]string, 0, len(archives))
+	for _, archive := range archives {
+		archivePath := filepath.Join(library, archive)
+		directoryName := archiveDirectoryName(archive)
+		directory := filepath.Join(library, directoryName)
+		if existingDirectories[strings.ToLower(directoryName)] {
+			fmt.Printf("⏭️  %s: there is already a %s directory\n", archive, directoryName)
+			continue
+		}
+		if _, err := os.Lstat(directory); !errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("⏭️  %s: there is already a %s next to it\n", archive, directoryName)
+			continue
+		}
+		existingDirectories[strings.ToLower(directoryName)] = true
+
+		// Unpack it for good if we were told to, and aren't leaving the library as it is
+		unpack := mode == archivesUnpack
+		if mode == archivesPrompt && isInteractive() {
+			unpack = promptYesNo(fmt.Sprintf("Unpack %s into %s, rather than only detecting the game in it?", archive, directoryName))
+		}
+		if unpack && !noWrite {
+			fmt.Printf("Unpacking %s... ", archive)
+			if err := unpackArchive(archivePath, directory, changeJournal); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Printf("✅\n")
+			archiveDirectories = append(archiveDirectories, directoryName)
+			continue
+		}
+
+		// Otherwise detect it where it is
+		if staging == nil {
+			var err error
+			if staging, err = newArchiveStaging(); err != nil {
+				return nil, nil, err
+			}
+		}
+		staging.add(directory, archivePath)
+		archiveDirectories = append(archiveDirectories, directoryName)
+	}
+	return archiveDirectories, staging, nil
+}
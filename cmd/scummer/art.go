@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+)
+
+// gameArtNames are the names of the cover art files that are looked for in a game's
+// directory, most preferred first.
+var gameArtNames = []string{"cover", "folder", "boxart", "box", "front"}
+
+// findGameArt looks for a PNG cover in a game's directory. The names are matched
+// without regard to case.
+func findGameArt(directory string) (string, bool) {
+	files, err := os.ReadDir(directory)
+	if err != nil {
+		return "", false
+	}
+	for _, artName := range gameArtNames {
+		for _, file := range files {
+			if file.IsDir() || !strings.EqualFold(file.Name(), artName+".png") {
+				continue
+			}
+			return filepath.Join(directory, file.Name()), true
+		}
+	}
+	return "", false
+}
+
+// copyFile copies a file, replacing the destination if it already exists.
+func copyFile(source string, destination string) error {
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destinationFile, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(destinationFile, sourceFile); err != nil {
+		destinationFile.Close()
+		return err
+	}
+	return destinationFile.Close()
+}
+
+// copyGameArt copies the cover art found in each game's directory to where the preset
+// expects it, named after the game's .scummvm file. Art that is already there is left
+// alone. The covers are recorded in the journal. It returns the number of covers that
+// were copied.
+func copyGameArt(scummGameMatches []match.ScummGameMatch, root string, preset scummerPreset, changeJournal *journal.Journal) (int, error) {
+	if preset.Image == "" {
+		return 0, fmt.Errorf("the preset doesn't say where art goes")
+	}
+
+	copied := 0
+	for _, scummGameMatch := range scummGameMatches {
+		artFile, ok := findGameArt(scummGameMatch.Directory)
+		if !ok {
+			continue
+		}
+
+		// Leave art that is already there alone, it may have been scraped
+		destination := filepath.Join(root, filepath.FromSlash(fmt.Sprintf(preset.Image, gameArtName(scummGameMatch))))
+		if _, err := os.Stat(destination); err == nil {
+			continue
+		}
+
+		if err := changeJournal.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+			return copied, err
+		}
+		if err := changeJournal.ChangeFile(destination, func() error { return copyFile(artFile, destination) }); err != nil {
+			return copied, err
+		}
+		copied++
+	}
+	return copied, nil
+}
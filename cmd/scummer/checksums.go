@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/furui/scummer/checksum"
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// writeChecksumManifests writes a checksums.sha256 manifest into each game's directory,
+// replacing the one that is there, and records them in the journal. Every disc of a
+// multi-disc game gets one, since each is a directory of its own, but a game left in its
+// archive doesn't, since it has no directory to put it in. A directory whose
+// manifest can't be written is warned about and the others are carried on with. It
+// returns the number of manifests that were written.
+func writeChecksumManifests(scummGameMatches []match.ScummGameMatch, changeJournal *journal.Journal) int {
+	written := 0
+	for _, scummGameMatch := range scummGameMatches {
+		if scummGameMatch.Archive != "" {
+			continue
+		}
+		fmt.Printf("Writing the checksums of %s... ", scummGameMatch.Directory)
+		manifest, err := checksum.Manifest(scummGameMatch.Directory)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if err := changeJournal.WriteFile(filepath.Join(scummGameMatch.Directory, checksum.ManifestName), manifest, 0644); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Printf("%d files\n", strings.Count(string(manifest), "\n"))
+		written++
+	}
+	return written
+}
+
+// runCheck hashes the files of every game of an earlier scan again and compares them
+// with the checksums.sha256 manifest written into its directory, reporting the files
+// that are corrupted or missing.
+func runCheck(args []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	showAdded := flags.Bool("show-added", false, "list the files that were added to the game directories since their checksums were written, such as saves")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer check [flags] [<success.json>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// The results file defaults to the one scan writes
+	successFile := stateFile("success.json")
+	if flags.NArg() > 0 {
+		successFile = flags.Arg(0)
+	}
+
+	// Load the results of the scan
+	scummvmOutputSlice, err := output.ReadResults(successFile)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Check each game directory that has a manifest
+	checked := 0
+	damaged := 0
+	withoutManifest := 0
+	for _, scummGameMatch := range scummvmOutputSlice {
+		if scummGameMatch.Archive != "" {
+			continue
+		}
+		fmt.Printf("%s... ", scummGameMatch.Directory)
+		result, err := checksum.Check(scummGameMatch.Directory)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("no %s\n", checksum.ManifestName)
+			withoutManifest++
+			continue
+		}
+		if err != nil {
+			fmt.Printf("❌ %s\n", err)
+			damaged++
+			continue
+		}
+		checked++
+
+		// Sum up what is wrong with it
+		problems := make([]string, 0)
+		if len(result.Corrupted) > 0 {
+			problems = append(problems, fmt.Sprintf("%d corrupted: %s", len(result.Corrupted), strings.Join(result.Corrupted, ", ")))
+		}
+		if len(result.Missing) > 0 {
+			problems = append(problems, fmt.Sprintf("%d missing: %s", len(result.Missing), strings.Join(result.Missing, ", ")))
+		}
+		if len(result.Unreadable) > 0 {
+			problems = append(problems, fmt.Sprintf("%d unreadable: %s", len(result.Unreadable), strings.Join(result.Unreadable, ", ")))
+		}
+		if result.OK() {
+			fmt.Printf("✅ %d files\n", result.Checked)
+		} else {
+			fmt.Printf("❌ %s\n", strings.Join(problems, "; "))
+			damaged++
+		}
+		if *showAdded && len(result.Added) > 0 {
+			fmt.Printf("   added since: %s\n", strings.Join(result.Added, ", "))
+		}
+	}
+
+	// Sum up what was found
+	if withoutManifest > 0 {
+		fmt.Printf("\n%d game directories have no %s, run \"scummer scan --checksums\" or \"scummer apply --checksums\" to write them\n", withoutManifest, checksum.ManifestName)
+	}
+	if damaged > 0 {
+		fmt.Printf("\nChecked %d games, %d have files that are damaged or gone\n", checked, damaged)
+		os.Exit(1)
+	}
+	fmt.Printf("\nChecked %d games, every file is as it was\n", checked)
+}
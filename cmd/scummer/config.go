@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/match"
+)
+
+// defaultConfigFile is the config file that is read when --config isn't given, if it
+// exists.
+const defaultConfigFile = "scummer.yaml"
+
+// scummerConfig is the contents of the config file. For example:
+//
+//	ranking:
+//	  similarity: 1
+//	  language: 0.2
+//	  engine: 0.1
+//	  engines: [scumm, sci]
+//	webhooks:
+//	  - url: http://homeassistant.local:8123/api/webhook/scummer
+//	    events: [new-failures]
+//	report_url: http://nas.local:8085/dashboard
+//	schedule:
+//	  rescan: "0 4 * * *"
+//	exporters:
+//	  - name: pegasus
+//	    command: [python3, pegasus.py]
+//	hooks:
+//	  post_write: [rsync, -a, /games/, handheld:/roms/scummvm/]
+//	min_scummvm_version: "2.7"
+type scummerConfig struct {
+	// Ranking, when given, ranks the candidates by a weighted score instead of by
+	// similarity alone.
+	Ranking *match.RankingWeights `yaml:"ranking"`
+
+	// Webhooks are the URLs that are told about a scan once it is over, and ReportURL is
+	// where they can link to for the details.
+	Webhooks  []scummerWebhook `yaml:"webhooks"`
+	ReportURL string           `yaml:"report_url"`
+
+	// Schedule is when "scummer serve" scans the library by itself.
+	Schedule scummerSchedule `yaml:"schedule"`
+
+	// Exporters are external commands that export the results to formats of their own,
+	// after every scan and with "scummer export".
+	Exporters []exporterPlugin `yaml:"exporters"`
+
+	// Hooks are the commands "scummer scan" runs as it goes.
+	Hooks scummerHooks `yaml:"hooks"`
+
+	// MinScummvmVersion is the oldest scummvm "scummer scan" is willing to use, unless
+	// --min-version says otherwise.
+	MinScummvmVersion string `yaml:"min_scummvm_version"`
+}
+
+// readScummerConfig loads the config file. When the file isn't required, a missing
+// file is treated as an empty one.
+func readScummerConfig(configFile string, required bool) (scummerConfig, error) {
+	config := scummerConfig{}
+
+	configYAML, err := os.ReadFile(configFile)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return config, nil
+	} else if err != nil {
+		return config, err
+	}
+
+	if err := yaml.Unmarshal(configYAML, &config); err != nil {
+		return config, err
+	}
+
+	// Make sure the webhooks will actually fire
+	if err := checkScummerWebhooks(config.Webhooks); err != nil {
+		return config, err
+	}
+
+	// Make sure the schedules can be understood
+	if _, err := config.Schedule.scheduledScans(); err != nil {
+		return config, err
+	}
+
+	// Make sure the exporters can be told apart and run
+	if err := checkExporterPlugins(config.Exporters); err != nil {
+		return config, err
+	}
+
+	// Make sure the minimum version is a version
+	if config.MinScummvmVersion != "" {
+		if _, err := detect.ParseMinimumVersion(config.MinScummvmVersion); err != nil {
+			return config, err
+		}
+	}
+
+	// Similarity is what the ranking is mostly about, so it counts fully unless told otherwise
+	if config.Ranking != nil && config.Ranking.Similarity == 0 {
+		config.Ranking.Similarity = 1
+	}
+
+	return config, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+
+	"github.com/furui/scummer/match"
+)
+
+// These are the statuses the csv and tsv formats give the directories, besides the
+// kind of error for the ones that weren't matched.
+const (
+	// statusMatched is a directory that was matched with a game.
+	statusMatched = "matched"
+
+	// statusDuplicate is another copy of a game that was already found.
+	statusDuplicate = "duplicate"
+
+	// statusIncomplete is a game scummvm found, but warned has files missing.
+	statusIncomplete = "incomplete"
+)
+
+// scummGameMatchStatus sums up what happened to a directory in one word.
+func scummGameMatchStatus(scummGameMatch match.ScummGameMatch) string {
+	switch {
+	case scummGameMatch.ErrorKind != "":
+		return scummGameMatch.ErrorKind
+	case scummGameMatch.DuplicateRole == duplicateRoleDuplicate:
+		return statusDuplicate
+	case scummGameMatch.Incomplete:
+		return statusIncomplete
+	default:
+		return statusMatched
+	}
+}
+
+// writeScummGameMatchTable writes every directory, matched or not, as a row of a table
+// with the given separator between the columns.
+func writeScummGameMatchTable(scummGameMatches []match.ScummGameMatch, options exportOptions, defaultOutputFile string, separator rune) error {
+	outputFile := options.OutputFile
+	if outputFile == "" {
+		outputFile = defaultOutputFile
+	}
+
+	var table bytes.Buffer
+	writer := csv.NewWriter(&table)
+	writer.Comma = separator
+	writer.Write([]string{"Directory", "GameID", "Description", "Engine", "Confidence", "Status"})
+
+	// Write the matches, followed by the errors
+	rows := 0
+	for _, scummGameMatch := range append(append([]match.ScummGameMatch{}, scummGameMatches...), options.Errors...) {
+		confidence := ""
+		if scummGameMatch.ErrorKind == "" {
+			confidence = strconv.FormatFloat(scummGameMatch.Confidence, 'f', 2, 64)
+		}
+		writer.Write([]string{
+			scummGameMatch.Directory,
+			scummGameMatch.GameID,
+			scummGameMatch.Description,
+			match.CandidateEngine(scummGameMatch.GameID),
+			confidence,
+			scummGameMatchStatus(scummGameMatch),
+		})
+		rows++
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	// Write the file
+	if err := options.Journal.WriteFile(outputFile, table.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d directories to %s\n", rows, outputFile)
+	return nil
+}
+
+// exportCsv writes the results as comma separated values, for spreadsheets.
+func exportCsv(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	return writeScummGameMatchTable(scummGameMatches, options, "scummer.csv", ',')
+}
+
+// exportTsv writes the results as tab separated values, which spreadsheets open without
+// asking how the columns are separated.
+func exportTsv(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	return writeScummGameMatchTable(scummGameMatches, options, "scummer.tsv", '\t')
+}
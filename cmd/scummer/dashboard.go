@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/furui/scummer"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// dashboardPageTemplate is the page that shows how the library is doing: what the last
+// scan found, what went wrong, and a button to scan each directory again.
+var dashboardPageTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>scummer</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.totals span { display: inline-block; margin: 0 1.5em 1em 0; }
+.totals strong { font-size: larger; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.2em 0.8em 0.2em 0; vertical-align: top; }
+.low { color: #b00; }
+.muted { color: #666; font-size: smaller; }
+form { margin: 0; }
+</style>
+</head>
+<body>
+<h1>{{.Library}}</h1>
+<p><a href="/">Review ambiguous matches</a></p>
+{{if .Message}}<p><strong>{{.Message}}</strong></p>{{end}}
+<p class="muted">Last scan {{.LastScan}}</p>
+<form method="post" action="/scan"><button type="submit">Scan library</button> <label><input type="checkbox" name="write_markers" value="1"> write .scummvm files</label></form>
+<p id="progress" class="muted"></p>
+<p class="totals">
+<span><strong>{{.Summary.Detected}}</strong> games</span>
+<span><strong>{{.Summary.Ambiguous}}</strong> ambiguous</span>
+<span><strong>{{.Summary.Failed}}</strong> failed</span>
+<span><strong>{{.Summary.Skipped}}</strong> skipped</span>
+</p>
+<p class="muted">{{range .Engines}}{{.Engine}} {{.Games}} &nbsp; {{end}}</p>
+
+<h2>Failures</h2>
+{{if .Errors}}
+<table>
+<tr><th>Directory</th><th>Error</th><th></th></tr>
+{{range .Errors}}
+<tr>
+<td>{{.Directory}}</td>
+<td>{{.ErrorKind}}: {{.Description}}{{if .SuspectedGameID}}<br><span class="muted">maybe {{.SuspectedTitle}} ({{.SuspectedGameID}})</span>{{end}}</td>
+<td><form method="post" action="/rescan"><input type="hidden" name="directory" value="{{.Directory}}"><button type="submit">Rescan</button></form></td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>Nothing failed.</p>
+{{end}}
+
+<h2>Games</h2>
+{{if .Games}}
+<table>
+<tr><th>Directory</th><th>Game</th><th>Confidence</th><th></th></tr>
+{{range .Games}}
+<tr>
+<td>{{.Directory}}</td>
+<td>{{if .Title}}{{.Title}}{{else}}{{.Description}}{{end}} <span class="muted">{{.GameID}}</span></td>
+<td{{if lt .Confidence $.Threshold}} class="low"{{end}}>{{printf "%.2f" .Confidence}}</td>
+<td><form method="post" action="/rescan"><input type="hidden" name="directory" value="{{.Directory}}"><button type="submit">Rescan</button></form></td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>No games found yet.</p>
+{{end}}
+<script>
+var progress = document.getElementById("progress");
+var events = new EventSource("/events");
+events.addEventListener("started", function(e) {
+	var event = JSON.parse(e.data);
+	progress.textContent = "Scanning " + event.Library + ": 0 of " + event.Total;
+});
+events.addEventListener("directory", function(e) {
+	var event = JSON.parse(e.data);
+	progress.textContent = "Scanning " + event.Library + ": " + event.Done + " of " + event.Total + ", " + event.Directory + " " + event.Status;
+});
+events.addEventListener("failed", function(e) {
+	progress.textContent = "Scan failed: " + JSON.parse(e.data).Error;
+});
+events.addEventListener("finished", function(e) {
+	window.location.reload();
+});
+</script>
+</body>
+</html>
+`))
+
+// dashboardEngine is the number of games of an engine, as it is shown on the dashboard.
+type dashboardEngine struct {
+	Engine string
+	Games  int
+}
+
+// handleDashboard shows the results of the last scan.
+func (s *reviewServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	scummvmOutputSlice, scummvmOutputErrorSlice, err := s.lockedResults()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Sum up the results, listing the engines with the most games first
+	summary := newScanSummary(scummvmOutputSlice, scummvmOutputErrorSlice, 0, nil)
+	engines := make([]dashboardEngine, 0, len(summary.Engines))
+	for engine, games := range summary.Engines {
+		engines = append(engines, dashboardEngine{Engine: engine, Games: games})
+	}
+	sort.Slice(engines, func(i, j int) bool {
+		if engines[i].Games != engines[j].Games {
+			return engines[i].Games > engines[j].Games
+		}
+		return engines[i].Engine < engines[j].Engine
+	})
+
+	// The last scan is when the results were last written
+	lastScan := "unknown"
+	if info, err := os.Stat(s.successFile); err == nil {
+		lastScan = info.ModTime().Format(time.RFC1123)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardPageTemplate.Execute(w, map[string]interface{}{
+		"Library":   libraryRoot(append(scummvmOutputSlice, scummvmOutputErrorSlice...)),
+		"LastScan":  lastScan,
+		"Summary":   summary,
+		"Engines":   engines,
+		"Games":     scummvmOutputSlice,
+		"Errors":    scummvmOutputErrorSlice,
+		"Threshold": s.threshold,
+		"Message":   r.URL.Query().Get("message"),
+	}); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// handleRescan scans a single directory again and puts the new result in place of the
+// old one.
+func (s *reviewServer) handleRescan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rescanned, err := s.rescan(r, r.PostForm.Get("directory"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	message := fmt.Sprintf("%s is %s", rescanned.Directory, rescanned.GameID)
+	if rescanned.ErrorKind != "" {
+		message = fmt.Sprintf("%s still failed: %s", rescanned.Directory, rescanned.Description)
+	}
+	fmt.Println(message)
+	http.Redirect(w, r, "/dashboard?message="+template.URLQueryEscaper(message), http.StatusSeeOther)
+}
+
+// keepMarkerLayout gives a directory that was scanned again the .scummvm files its
+// previous result had, in the same format, so that a scan from the server doesn't move
+// them back to where a plain scan puts them. A directory that failed before has nothing to
+// keep.
+func keepMarkerLayout(rescanned match.ScummGameMatch, previous match.ScummGameMatch) match.ScummGameMatch {
+	if previous.ErrorKind == "" {
+		rescanned.MarkerFiles = previous.MarkerFiles
+		rescanned.MarkerFormat = previous.MarkerFormat
+		rescanned.MarkerTemplate = previous.MarkerTemplate
+		rescanned.RenameTo = previous.RenameTo
+	}
+	return rescanned
+}
+
+// rescan scans a directory that is already in the results again, saves the new result
+// in place of the old one, and writes its .scummvm file if a game was found.
+func (s *reviewServer) rescan(r *http.Request, directory string) (match.ScummGameMatch, error) {
+	// Make sure the directory is one of ours, so the page can't be used to run scummvm
+	// on anything else
+	scummvmOutputSlice, scummvmOutputErrorSlice, err := s.lockedResults()
+	if err != nil {
+		return match.ScummGameMatch{}, err
+	}
+	var previous *match.ScummGameMatch
+	for _, scummGameMatch := range append(scummvmOutputSlice, scummvmOutputErrorSlice...) {
+		if scummGameMatch.Directory == directory {
+			scummGameMatch := scummGameMatch
+			previous = &scummGameMatch
+		}
+	}
+	if previous == nil {
+		return match.ScummGameMatch{}, fmt.Errorf("%s is not in the results", directory)
+	}
+
+	// Scan the directory, which can take a while, so the results aren't locked yet
+	scanner, err := scummer.NewScanner(scummer.Options{Scummvm: s.scummvmPath, Threshold: s.threshold})
+	if err != nil {
+		return match.ScummGameMatch{}, err
+	}
+	rescanned, err := s.scanDirectory(r.Context(), scanner, directory)
+	if err != nil {
+		return match.ScummGameMatch{}, err
+	}
+	s.progress.publish(progressEvent{Event: progressEventDirectory, Library: filepath.Dir(directory), Done: 1, Total: 1, Directory: directory, Status: scummGameMatchStatus(rescanned), GameID: rescanned.GameID, Description: rescanned.Description})
+
+	// The .scummvm files go where they went before
+	rescanned = keepMarkerLayout(rescanned, *previous)
+
+	// Put the new result in place of the old one, in whichever file it belongs in now
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := replaceDirectoryResult(s.successFile, s.errorFile, directory, rescanned); err != nil {
+		return match.ScummGameMatch{}, err
+	}
+	if rescanned.ErrorKind == "" {
+		if err := output.WriteMarkerFile(rescanned, s.changeJournal); err != nil {
+			return match.ScummGameMatch{}, err
+		}
+	}
+	return rescanned, nil
+}
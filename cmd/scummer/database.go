@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/furui/scummer/match"
+)
+
+// scanDatabaseSchema creates the tables of the results database. Every scan adds a
+// run, and the games, candidates and errors it found are recorded against that run, so
+// that runs can be compared with each other.
+const scanDatabaseSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	started TEXT NOT NULL,
+	library TEXT NOT NULL,
+	scummvm_version TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS games (
+	run_id INTEGER NOT NULL REFERENCES runs(id),
+	directory TEXT NOT NULL,
+	game_id TEXT NOT NULL,
+	description TEXT NOT NULL,
+	title TEXT NOT NULL,
+	confidence REAL NOT NULL,
+	chosen_by TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS candidates (
+	run_id INTEGER NOT NULL REFERENCES runs(id),
+	directory TEXT NOT NULL,
+	game_id TEXT NOT NULL,
+	description TEXT NOT NULL,
+	similarity REAL NOT NULL
+);
+CREATE TABLE IF NOT EXISTS errors (
+	run_id INTEGER NOT NULL REFERENCES runs(id),
+	directory TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	description TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS games_directory ON games (directory, run_id);
+`
+
+// absoluteDirectory returns the full path to a directory, so that the same directory is
+// recorded the same way no matter where scummer was run from.
+func absoluteDirectory(directory string) string {
+	if absolutePath, err := filepath.Abs(directory); err == nil {
+		return absolutePath
+	}
+	return directory
+}
+
+// recordScanRun adds the results of a scan to the SQLite database in databaseFile,
+// creating it if it doesn't exist yet.
+func recordScanRun(databaseFile string, started time.Time, library string, scummvmVersion string, scummGameMatches []match.ScummGameMatch, errorSlice []match.ScummGameMatch) error {
+	// Open the database and make sure it has the tables
+	db, err := sql.Open("sqlite", databaseFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec(scanDatabaseSchema); err != nil {
+		return err
+	}
+
+	// Record the whole run at once, so that a run is either there or it isn't
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Add the run
+	result, err := tx.Exec("INSERT INTO runs (started, library, scummvm_version) VALUES (?, ?, ?)",
+		started.UTC().Format(time.RFC3339), absoluteDirectory(library), strings.TrimSpace(scummvmVersion))
+	if err != nil {
+		return err
+	}
+	runID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	// Add the games and the candidates scummvm found for them
+	for _, scummGameMatch := range scummGameMatches {
+		directory := absoluteDirectory(scummGameMatch.Directory)
+		if _, err := tx.Exec("INSERT INTO games (run_id, directory, game_id, description, title, confidence, chosen_by) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			runID, directory, scummGameMatch.GameID, scummGameMatch.Description, scummGameMatch.Title, scummGameMatch.Confidence, scummGameMatch.ChosenBy); err != nil {
+			return err
+		}
+		for _, candidate := range scummGameMatch.Candidates {
+			if _, err := tx.Exec("INSERT INTO candidates (run_id, directory, game_id, description, similarity) VALUES (?, ?, ?, ?, ?)",
+				runID, directory, candidate.GameID, candidate.Description, candidate.Similarity); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Add the errors
+	for _, scummGameMatch := range errorSlice {
+		if _, err := tx.Exec("INSERT INTO errors (run_id, directory, kind, description) VALUES (?, ?, ?, ?)",
+			runID, absoluteDirectory(scummGameMatch.Directory), scummGameMatch.ErrorKind, scummGameMatch.Description); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
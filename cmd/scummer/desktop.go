@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// defaultDesktopEntryDirectory returns where the desktop looks for the applications of
+// the current user: applications/ in $XDG_DATA_HOME, which defaults to ~/.local/share.
+func defaultDesktopEntryDirectory() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "applications"), nil
+	}
+	homeDirectory, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDirectory, ".local", "share", "applications"), nil
+}
+
+// desktopExecArgument quotes an argument for the Exec key of a desktop entry. Arguments
+// with reserved characters go in double quotes, with the characters that are special
+// inside them escaped, and percent signs are doubled so they aren't taken for field
+// codes.
+func desktopExecArgument(argument string) string {
+	argument = strings.ReplaceAll(argument, "%", "%%")
+	if argument != "" && !strings.ContainsAny(argument, " \t\n\"'\\><~|&;$*?#()`") {
+		return argument
+	}
+	escaped := strings.NewReplacer(`"`, `\"`, "`", "\\`", "$", `\$`, `\`, `\\`).Replace(argument)
+	return `"` + escaped + `"`
+}
+
+// desktopEntryValue escapes a string for a desktop entry, where backslashes and line
+// breaks have to be escaped.
+func desktopEntryValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(value)
+}
+
+// desktopEntry returns the desktop entry that starts a game.
+func desktopEntry(scummvmBinary detect.Command, scummGameMatch match.ScummGameMatch, options exportOptions) string {
+	// Build the command line
+	execArguments := []string{desktopExecArgument(scummvmBinary.Path)}
+	for _, argument := range scummvmLaunchArguments(scummvmBinary, scummGameMatch, options) {
+		execArguments = append(execArguments, desktopExecArgument(argument))
+	}
+
+	// Use the game's cover as its icon if it has one
+	icon := "scummvm"
+	if artFile, ok := findGameArt(scummGameMatch.Directory); ok {
+		icon = absoluteDirectory(artFile)
+	}
+
+	var entry strings.Builder
+	entry.WriteString("[Desktop Entry]\n")
+	entry.WriteString("Type=Application\n")
+	entry.WriteString("Name=" + desktopEntryValue(match.ParseDescriptionVariant(scummGameMatch.Description).Title) + "\n")
+	entry.WriteString("Comment=" + desktopEntryValue(scummGameMatch.Description) + "\n")
+	entry.WriteString("Exec=" + desktopEntryValue(strings.Join(execArguments, " ")) + "\n")
+	entry.WriteString("Path=" + desktopEntryValue(absoluteDirectory(scummGameMatch.Directory)) + "\n")
+	entry.WriteString("Icon=" + desktopEntryValue(icon) + "\n")
+	entry.WriteString("Terminal=false\n")
+	entry.WriteString("Categories=Game;AdventureGame;\n")
+	return entry.String()
+}
+
+// exportDesktopEntries writes a freedesktop desktop entry for each game, so that the
+// games show up in the application menus of GNOME, KDE and the like. They go in the
+// user's applications directory unless --output gives another directory.
+func exportDesktopEntries(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputDirectory := options.OutputFile
+	if outputDirectory == "" {
+		var err error
+		if outputDirectory, err = defaultDesktopEntryDirectory(); err != nil {
+			return err
+		}
+	}
+
+	// Create the directory
+	if err := options.Journal.MkdirAll(outputDirectory, 0755); err != nil {
+		return err
+	}
+
+	// Every game is started by the same scummvm
+	scummvmBinary := exportScummvmCommand(options)
+
+	for _, scummGameMatch := range scummGameMatches {
+		// Name the file so that scummer's entries are easy to tell apart from the others
+		entryFile := filepath.Join(outputDirectory, "scummer-"+output.SanitizeMarkerName(gameArtName(scummGameMatch))+".desktop")
+		if err := options.Journal.WriteFile(entryFile, []byte(desktopEntry(scummvmBinary, scummGameMatch, options)), 0644); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Wrote %d desktop entries to %s\n", len(scummGameMatches), outputDirectory)
+	return nil
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/furui/scummer/checksum"
+	"github.com/furui/scummer/discimage"
+	"github.com/furui/scummer/journal"
+)
+
+// The ways --disc-images can handle the game directories that only have a CD image in
+// them.
+const (
+	// discImagesIgnore leaves them alone, as scummer always has.
+	discImagesIgnore = "ignore"
+
+	// discImagesDetect reads the files that are needed from the image into a temporary
+	// directory for as long as it takes scummvm to detect the game, and reports them.
+	discImagesDetect = "detect"
+
+	// discImagesExtract writes the files that are needed from the image, and its audio
+	// tracks, into the game's directory for good, which is then scanned like any other.
+	discImagesExtract = "extract"
+)
+
+// discImageCruftNames are the files on game CDs that ScummVM never needs, by their names
+// in lower case.
+var discImageCruftNames = []string{"autorun.inf", "desktop.ini", "thumbs.db"}
+
+// discImageCruftExtensions are the extensions of the files on game CDs that ScummVM
+// never needs.
+var discImageCruftExtensions = []string{".ico"}
+
+// discImageCruftDirectories are the directories at the top of game CDs that hold the
+// installers of other programs, which ScummVM never needs, by their names in lower case.
+var discImageCruftDirectories = []string{"directx", "quicktime", "qtw", "qtinstal", "acrobat", "acroread", "drivers"}
+
+// extraFileExtensions are the files that may be next to the files of a game, such as a
+// CD image, in a game directory without being part of the game, such as a cover, a
+// readme or the files scummer wrote.
+var extraFileExtensions = []string{".txt", ".nfo", ".md", ".jpg", ".jpeg", ".png", ".pdf", ".sha256", ".scummvm"}
+
+// isExtraFile returns whether a file in a game directory is one that may be next to the
+// files of a game without being part of it.
+func isExtraFile(name string) bool {
+	for _, extension := range extraFileExtensions {
+		if strings.EqualFold(filepath.Ext(name), extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// discImageContents is what is needed from the CD image of a game directory.
+type discImageContents struct {
+	image string
+
+	// files are the files of the data track that are needed, and audioTracks are the
+	// audio tracks, which are written out as trackNN.wav.
+	files       []discimage.File
+	audioTracks []discimage.Track
+}
+
+// size returns how much room the files that are needed take up, not counting the
+// audio tracks.
+func (c discImageContents) size() int64 {
+	size := int64(0)
+	for _, file := range c.files {
+		size += file.Size
+	}
+	return size
+}
+
+// paths returns the paths of the files that are needed, including the audio tracks.
+func (c discImageContents) paths() []string {
+	paths := make([]string, 0, len(c.files)+len(c.audioTracks))
+	for _, file := range c.files {
+		paths = append(paths, file.Path)
+	}
+	for _, track := range c.audioTracks {
+		paths = append(paths, audioTrackName(track))
+	}
+	return paths
+}
+
+// isDiscImage returns whether a file is a CD image scummer can read.
+func isDiscImage(path string) bool {
+	for _, extension := range discimage.Extensions {
+		if strings.EqualFold(filepath.Ext(path), extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDiscImageCruft returns whether a file of a CD image is one ScummVM never needs.
+func isDiscImageCruft(filePath string) bool {
+	name := strings.ToLower(path.Base(filePath))
+	for _, cruftName := range discImageCruftNames {
+		if name == cruftName {
+			return true
+		}
+	}
+	for _, extension := range discImageCruftExtensions {
+		if strings.EqualFold(path.Ext(name), extension) {
+			return true
+		}
+	}
+	topDirectory, _, found := strings.Cut(filePath, "/")
+	if found {
+		for _, cruftDirectory := range discImageCruftDirectories {
+			if strings.EqualFold(topDirectory, cruftDirectory) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// audioTrackName is the name an audio track is written out as, which is one of the
+// names ScummVM looks for CD audio under.
+func audioTrackName(track discimage.Track) string {
+	return fmt.Sprintf("track%02d.wav", track.Number)
+}
+
+// findDiscImage returns the CD image in a game directory, if the image is all there is
+// in it: a single .iso file, or a single .cue sheet and the files its tracks are in,
+// along with nothing but a readme, a cover or files scummer wrote.
+func findDiscImage(directory string) (string, bool) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return "", false
+	}
+	images := make([]string, 0)
+	otherFiles := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			return "", false
+		}
+		name := entry.Name()
+		switch {
+		case strings.EqualFold(filepath.Ext(name), ".cue"):
+			images = append(images, name)
+		case strings.EqualFold(name, checksum.ManifestName), isExtraFile(name):
+		default:
+			otherFiles[strings.ToLower(name)] = true
+		}
+	}
+
+	// A .cue sheet has to account for every other file, and an .iso file has to be the
+	// only other file
+	if len(images) == 1 {
+		image, err := discimage.Open(filepath.Join(directory, images[0]))
+		if err != nil {
+			return "", false
+		}
+		defer image.Close()
+		for _, source := range image.Sources()[1:] {
+			delete(otherFiles, strings.ToLower(filepath.Base(source)))
+		}
+		return image.Path, len(otherFiles) == 0
+	}
+	if len(images) == 0 && len(otherFiles) == 1 {
+		for name := range otherFiles {
+			for _, entry := range entries {
+				if strings.ToLower(entry.Name()) == name && strings.EqualFold(filepath.Ext(name), ".iso") {
+					return filepath.Join(directory, entry.Name()), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// readDiscImageContents works out which files of a CD image are needed to play the game.
+// That is every file of its data track other than the ones game CDs have for Windows
+// and the installers of other programs, and its audio tracks.
+func readDiscImageContents(imagePath string) (discImageContents, error) {
+	image, err := discimage.Open(imagePath)
+	if err != nil {
+		return discImageContents{}, err
+	}
+	defer image.Close()
+
+	files, err := image.Files()
+	if err != nil {
+		return discImageContents{}, err
+	}
+	contents := discImageContents{image: imagePath, audioTracks: image.AudioTracks()}
+	for _, file := range files {
+		if !filepath.IsLocal(filepath.FromSlash(file.Path)) {
+			return discImageContents{}, fmt.Errorf("%s has a file at %s, which is outside of it", imagePath, file.Path)
+		}
+		if !isDiscImageCruft(file.Path) {
+			contents.files = append(contents.files, file)
+		}
+	}
+	if len(contents.files) == 0 {
+		return discImageContents{}, fmt.Errorf("%s has no files on its data track", imagePath)
+	}
+	return contents, nil
+}
+
+// extractDiscImageFiles writes the files that are needed from a CD image into a
+// directory, to be detected, leaving out the audio tracks, which scummvm doesn't need
+// to detect the game.
+func extractDiscImageFiles(imagePath string, destination string) error {
+	contents, err := readDiscImageContents(imagePath)
+	if err != nil {
+		return err
+	}
+	image, err := discimage.Open(imagePath)
+	if err != nil {
+		return err
+	}
+	defer image.Close()
+
+	for _, file := range contents.files {
+		filePath := filepath.Join(destination, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		if err := writeDiscImageFile(filePath, func(w io.Writer) error { return image.WriteFile(file, w) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDiscImageFile creates a file and has write fill it in.
+func writeDiscImageFile(filePath string, write func(w io.Writer) error) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// extractDiscImage writes the files that are needed from a CD image, and its audio
+// tracks, into the game directory it is in for good, recording each of them in the
+// journal. Files that are already there are refused rather than overwritten.
+func extractDiscImage(contents discImageContents, directory string, changeJournal *journal.Journal) error {
+	image, err := discimage.Open(contents.image)
+	if err != nil {
+		return err
+	}
+	defer image.Close()
+
+	// Make sure nothing would be overwritten before writing anything
+	for _, filePath := range contents.paths() {
+		if _, err := os.Lstat(filepath.Join(directory, filepath.FromSlash(filePath))); err == nil {
+			return fmt.Errorf("can't extract %s into %s: %s is already there", filepath.Base(contents.image), directory, filePath)
+		}
+	}
+
+	for _, file := range contents.files {
+		filePath := filepath.Join(directory, filepath.FromSlash(file.Path))
+		if err := changeJournal.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		err := changeJournal.ChangeFile(filePath, func() error {
+			return writeDiscImageFile(filePath, func(w io.Writer) error { return image.WriteFile(file, w) })
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, track := range contents.audioTracks {
+		filePath := filepath.Join(directory, audioTrackName(track))
+		err := changeJournal.ChangeFile(filePath, func() error {
+			return writeDiscImageFile(filePath, func(w io.Writer) error { return image.WriteWAV(track, w) })
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeDiscImageContents says which files of a CD image have to be kept to play the
+// game, listing them if there aren't too many.
+func describeDiscImageContents(contents discImageContents) string {
+	names := make([]string, 0, len(contents.files))
+	for _, file := range contents.files {
+		names = append(names, file.Path)
+	}
+	if len(names) > 8 {
+		names = append(names[:8], fmt.Sprintf("and %d more", len(names)-8))
+	}
+	description := fmt.Sprintf("keep %d files (%s) from %s: %s", len(contents.files), formatByteSize(contents.size()), filepath.Base(contents.image), strings.Join(names, ", "))
+	if len(contents.audioTracks) == 1 {
+		description += fmt.Sprintf(", and its audio track as %s", audioTrackName(contents.audioTracks[0]))
+	} else if len(contents.audioTracks) > 1 {
+		description += fmt.Sprintf(", and its %d audio tracks as trackNN.wav", len(contents.audioTracks))
+	}
+	return description
+}
+
+// prepareDiscImages finds the game directories that only have a CD image in them, and
+// gets them ready to be scanned the way mode says: extracted into the directory for
+// good, to be scanned like the others, or added to staging to be detected in a
+// temporary copy of the files that are needed. The staging is created if there isn't
+// one yet, and returned.
+func prepareDiscImages(library string, directories []string, staging *archiveStaging, mode string, noWrite bool, changeJournal *journal.Journal) (*archiveStaging, error) {
+	if mode == discImagesIgnore {
+		return staging, nil
+	}
+
+	for _, directoryName := range directories {
+		directory := filepath.Join(library, directoryName)
+		imagePath, ok := findDiscImage(directory)
+		if !ok {
+			continue
+		}
+		contents, err := readDiscImageContents(imagePath)
+		if err != nil {
+			fmt.Printf("⚠️  %s\n", err)
+			continue
+		}
+
+		// Extract it for good if we were told to, and aren't leaving the library as it is
+		if mode == discImagesExtract && !noWrite {
+			fmt.Printf("Extracting %s... ", imagePath)
+			if err := extractDiscImage(contents, directory, changeJournal); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Printf("✅\n   %s, the image can go once the game is known to work\n", describeDiscImageContents(contents))
+			continue
+		}
+
+		// Otherwise detect it in a temporary copy
+		if staging == nil {
+			if staging, err = newArchiveStaging(); err != nil {
+				return nil, err
+			}
+		}
+		staging.addDiscImage(directory, contents)
+	}
+	return staging, nil
+}
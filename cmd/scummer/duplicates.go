@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/furui/scummer/match"
+)
+
+// These are the roles a directory can have when several directories hold the same game.
+const (
+	// duplicateRoleCanonical is the copy of the game that should be kept.
+	duplicateRoleCanonical = "canonical"
+
+	// duplicateRoleDuplicate is another copy of the same variant of the game.
+	duplicateRoleDuplicate = "duplicate"
+
+	// duplicateRoleVariant is a different variant of the game, such as the floppy
+	// version next to the CD version.
+	duplicateRoleVariant = "variant"
+)
+
+// findDuplicateScummGames groups the indexes of the matches that have the same GameID.
+// Only groups with more than one match are returned, in the order the games were found.
+// The discs of a multi-disc game are one game, so only its first disc is counted.
+func findDuplicateScummGames(scummGameMatches []match.ScummGameMatch) [][]int {
+	groupIndexes := make(map[string]int)
+	groups := make([][]int, 0)
+	for i, scummGameMatch := range scummGameMatches {
+		if scummGameMatch.FirstDisc != "" {
+			continue
+		}
+		groupIndex, ok := groupIndexes[scummGameMatch.GameID]
+		if !ok {
+			groupIndex = len(groups)
+			groupIndexes[scummGameMatch.GameID] = groupIndex
+			groups = append(groups, nil)
+		}
+		groups[groupIndex] = append(groups[groupIndex], i)
+	}
+
+	// Leave out the games that are only in one directory
+	duplicateGroups := make([][]int, 0)
+	for _, group := range groups {
+		if len(group) > 1 {
+			duplicateGroups = append(duplicateGroups, group)
+		}
+	}
+	return duplicateGroups
+}
+
+// defaultCanonicalScummGame returns the match in a group of duplicates that scummer
+// would keep: the one it is most confident about, or the first one found if it is
+// equally confident about them.
+func defaultCanonicalScummGame(scummGameMatches []match.ScummGameMatch, group []int) int {
+	canonical := group[0]
+	for _, i := range group[1:] {
+		if scummGameMatches[i].Confidence > scummGameMatches[canonical].Confidence {
+			canonical = i
+		}
+	}
+	return canonical
+}
+
+// defaultDuplicateRole returns whether a match is another copy of the canonical match,
+// or a different variant of the game, by comparing their Descriptions.
+func defaultDuplicateRole(scummGameMatch match.ScummGameMatch, canonical match.ScummGameMatch) string {
+	if scummGameMatch.Description == canonical.Description {
+		return duplicateRoleDuplicate
+	}
+	return duplicateRoleVariant
+}
+
+// markDuplicateScummGames annotates a group of duplicates with the canonical match and
+// the role of every other match.
+func markDuplicateScummGames(scummGameMatches []match.ScummGameMatch, group []int, canonical int, roles map[int]string) {
+	for _, i := range group {
+		if i == canonical {
+			scummGameMatches[i].DuplicateRole = duplicateRoleCanonical
+			scummGameMatches[i].CanonicalDirectory = ""
+			continue
+		}
+		scummGameMatches[i].DuplicateRole = roles[i]
+		scummGameMatches[i].CanonicalDirectory = scummGameMatches[canonical].Directory
+	}
+}
+
+// resolveDuplicateScummGames reports every game that was found in more than one
+// directory, and annotates the matches with which directory is the canonical one. When
+// interactive is set, the user is asked to choose, otherwise scummer decides.
+func resolveDuplicateScummGames(scummGameMatches []match.ScummGameMatch, interactive bool) {
+	duplicateGroups := findDuplicateScummGames(scummGameMatches)
+	if len(duplicateGroups) == 0 {
+		return
+	}
+
+	fmt.Printf("%d games were found in more than one directory:\n", len(duplicateGroups))
+	for _, group := range duplicateGroups {
+		// Print the group
+		fmt.Printf("\n  %s\n", scummGameMatches[group[0]].GameID)
+		for n, i := range group {
+			fmt.Printf("    %d) %s  %s\n", n+1, scummGameMatches[i].Directory, scummGameMatches[i].Description)
+		}
+
+		// Work out what scummer would do
+		canonical := defaultCanonicalScummGame(scummGameMatches, group)
+		roles := make(map[int]string)
+		for _, i := range group {
+			roles[i] = defaultDuplicateRole(scummGameMatches[i], scummGameMatches[canonical])
+		}
+
+		// Let the user decide instead
+		if interactive {
+			canonical, roles = promptForDuplicateRoles(scummGameMatches, group, canonical, roles)
+		}
+
+		markDuplicateScummGames(scummGameMatches, group, canonical, roles)
+		fmt.Printf("  Keeping %s\n", scummGameMatches[canonical].Directory)
+	}
+	fmt.Println()
+}
+
+// promptForDuplicateRoles asks the user which directory of a group of duplicates is the
+// canonical one, and whether each of the others is a duplicate or a variant. Entering
+// nothing accepts scummer's suggestion.
+func promptForDuplicateRoles(scummGameMatches []match.ScummGameMatch, group []int, canonical int, roles map[int]string) (int, map[int]string) {
+	// Ask for the canonical directory
+	for {
+		suggested := 0
+		for n, i := range group {
+			if i == canonical {
+				suggested = n
+			}
+		}
+		fmt.Printf("  Which one should be kept? [%d]: ", suggested+1)
+
+		answer, err := stdinReader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			if err != nil {
+				fmt.Println()
+			}
+			break
+		}
+		choice, err := strconv.Atoi(answer)
+		if err != nil || choice < 1 || choice > len(group) {
+			fmt.Printf("  Please enter a number between 1 and %d.\n", len(group))
+			continue
+		}
+		canonical = group[choice-1]
+		break
+	}
+
+	// Ask about the others
+	for _, i := range group {
+		if i == canonical {
+			continue
+		}
+		suggested := defaultDuplicateRole(scummGameMatches[i], scummGameMatches[canonical])
+		for {
+			fmt.Printf("  Is %s a duplicate or a variant? [%s]: ", scummGameMatches[i].Directory, suggested)
+
+			answer, err := stdinReader.ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			if answer == "" {
+				if err != nil {
+					fmt.Println()
+				}
+				roles[i] = suggested
+				break
+			}
+			if strings.HasPrefix(duplicateRoleDuplicate, answer) {
+				roles[i] = duplicateRoleDuplicate
+				break
+			}
+			if strings.HasPrefix(duplicateRoleVariant, answer) {
+				roles[i] = duplicateRoleVariant
+				break
+			}
+			fmt.Println("  Please enter \"duplicate\" or \"variant\".")
+		}
+	}
+
+	return canonical, roles
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+)
+
+// duplicatesReport is what the duplicates report is made from: the games that were
+// found in more than one directory.
+type duplicatesReport struct {
+	Games []duplicatesReportGame
+
+	// ExtraSize is how much room the copies other than the ones to keep take up.
+	ExtraSize int64
+}
+
+// duplicatesReportGame is a game that was found in more than one directory.
+type duplicatesReportGame struct {
+	GameID string
+	Title  string
+
+	// Copies are the directories the game is in, the one to keep first.
+	Copies []duplicatesReportCopy
+}
+
+// duplicatesReportCopy is one of the directories a game was found in, with what sets it
+// apart from the others.
+type duplicatesReportCopy struct {
+	Directory   string
+	Role        string
+	Description string
+	Confidence  float64
+
+	// Variant is the tags of the Description other than the language and platform, such
+	// as "CD" or "EGA".
+	Variant  string
+	Language string
+	Platform string
+
+	// Files and Size are what is in the directory, when it can be read.
+	Files     int
+	Size      int64
+	SizeKnown bool
+}
+
+// newDuplicatesReport groups the matches that have the same GameID. The copy to keep is
+// the one the scan chose, or the one scummer would choose for results from before
+// duplicates were marked.
+func newDuplicatesReport(scummGameMatches []match.ScummGameMatch) duplicatesReport {
+	report := duplicatesReport{Games: make([]duplicatesReportGame, 0)}
+	for _, group := range findDuplicateScummGames(scummGameMatches) {
+		canonical := defaultCanonicalScummGame(scummGameMatches, group)
+		for _, i := range group {
+			if scummGameMatches[i].DuplicateRole == duplicateRoleCanonical {
+				canonical = i
+			}
+		}
+
+		// The copy to keep goes first
+		orderedGroup := []int{canonical}
+		for _, i := range group {
+			if i != canonical {
+				orderedGroup = append(orderedGroup, i)
+			}
+		}
+
+		game := duplicatesReportGame{GameID: scummGameMatches[canonical].GameID, Title: scummGameMatches[canonical].Title}
+		for _, i := range orderedGroup {
+			reportCopy := newDuplicatesReportCopy(scummGameMatches[i], scummGameMatches[canonical], i == canonical)
+			if game.Title == "" {
+				game.Title = match.ParseDescriptionVariant(reportCopy.Description).Title
+			}
+			if i != canonical {
+				report.ExtraSize += reportCopy.Size
+			}
+			game.Copies = append(game.Copies, reportCopy)
+		}
+		report.Games = append(report.Games, game)
+	}
+	return report
+}
+
+// newDuplicatesReportCopy describes a directory a game was found in.
+func newDuplicatesReportCopy(scummGameMatch match.ScummGameMatch, canonical match.ScummGameMatch, isCanonical bool) duplicatesReportCopy {
+	variant := match.ParseDescriptionVariant(scummGameMatch.Description)
+	variantTags := make([]string, 0, len(variant.Tags))
+	for _, tag := range variant.Tags {
+		if tag != variant.Language && tag != variant.Platform {
+			variantTags = append(variantTags, tag)
+		}
+	}
+
+	// Results from before duplicates were marked don't say what each copy is
+	role := scummGameMatch.DuplicateRole
+	if isCanonical {
+		role = duplicateRoleCanonical
+	} else if role == "" || role == duplicateRoleCanonical {
+		role = defaultDuplicateRole(scummGameMatch, canonical)
+	}
+
+	reportCopy := duplicatesReportCopy{
+		Directory:   scummGameMatch.Directory,
+		Role:        role,
+		Description: scummGameMatch.Description,
+		Confidence:  scummGameMatch.Confidence,
+		Variant:     strings.Join(variantTags, " "),
+		Language:    variant.Language,
+		Platform:    variant.Platform,
+	}
+	if files, size, err := journal.DirectoryContents(scummGameMatch.Directory); err == nil {
+		reportCopy.Files = files
+		reportCopy.Size = size
+		reportCopy.SizeKnown = true
+	}
+	return reportCopy
+}
+
+// formatByteSize gives a size in bytes the way file managers do, such as "1.2 GB".
+func formatByteSize(size int64) string {
+	units := []string{"bytes", "KB", "MB", "GB", "TB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", size, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// duplicatesReportTemplate is the duplicates report, in markdown.
+var duplicatesReportTemplate = template.Must(template.New("duplicates").Funcs(template.FuncMap{"cell": markdownCell, "size": formatByteSize}).Parse(`# Duplicate games
+{{if .Games}}
+Games found in more than one directory: {{len .Games}}. The copies other than the ones to keep take up {{size .ExtraSize}}.
+{{range .Games}}
+## {{.Title}} ({{.GameID}})
+
+| Directory | Role | Variant | Language | Platform | Size | Files | Confidence | Description |
+| --- | --- | --- | --- | --- | --- | --- | --- | --- |
+{{range .Copies}}| {{cell .Directory}} | {{.Role}} | {{cell .Variant}} | {{cell .Language}} | {{cell .Platform}} | {{if .SizeKnown}}{{size .Size}}{{else}}?{{end}} | {{if .SizeKnown}}{{.Files}}{{else}}?{{end}} | {{printf "%.2f" .Confidence}} | {{cell .Description}} |
+{{end}}{{end}}{{else}}
+No game was found in more than one directory.
+{{end}}`))
+
+// writeDuplicatesReport writes the duplicates report of the results to a file.
+func writeDuplicatesReport(scummGameMatches []match.ScummGameMatch, outputFile string, changeJournal *journal.Journal) error {
+	report := newDuplicatesReport(scummGameMatches)
+	var reportMarkdown bytes.Buffer
+	if err := duplicatesReportTemplate.Execute(&reportMarkdown, report); err != nil {
+		return err
+	}
+
+	// Write the file
+	if err := changeJournal.WriteFile(outputFile, reportMarkdown.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote the duplicates report to %s, %d games were found more than once\n", outputFile, len(report.Games))
+	return nil
+}
+
+// exportDuplicatesReport writes a report of the games that were found in more than one
+// directory, in markdown.
+func exportDuplicatesReport(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputFile := options.OutputFile
+	if outputFile == "" {
+		outputFile = "duplicates.md"
+	}
+	return writeDuplicatesReport(scummGameMatches, outputFile, options.Journal)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/match"
+)
+
+// targetScummvmCommand returns the scummvm given with --target-scummvm, which is given
+// the same way as --scummvm but is only asked which engines it has.
+func targetScummvmCommand(name string) (detect.Command, error) {
+	if packagedCommand, ok, err := detect.PackagedCommand(name); ok {
+		return packagedCommand, err
+	}
+	return detect.Command{Path: detect.ResolveAppBundle(name)}, nil
+}
+
+// missingEngineGames returns the games whose engine isn't one of engines, so they
+// can't be played with the scummvm the engines came from. Games whose GameID doesn't
+// say what their engine is are left out, since there is nothing to check.
+func missingEngineGames(scummGameMatches []match.ScummGameMatch, engines map[string]string) []match.ScummGameMatch {
+	missing := make([]match.ScummGameMatch, 0)
+	for _, scummGameMatch := range scummGameMatches {
+		engine := match.CandidateEngine(scummGameMatch.GameID)
+		if _, ok := engines[engine]; engine != "" && !ok {
+			missing = append(missing, scummGameMatch)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// exportOptions are the options shared by the exporters.
+type exportOptions struct {
+	// OutputFile is the file given with --output, or an empty string to use the
+	// exporter's usual location.
+	OutputFile string
+
+	// Preset is the frontend the games are being exported for, if any.
+	Preset scummerPreset
+
+	// CorePath is the emulator core that launches the games, for the formats that
+	// need one.
+	CorePath string
+
+	// ScummvmPath is the scummvm binary given with --scummvm, for the formats that start
+	// scummvm themselves. If it is empty, an installed scummvm is looked for.
+	ScummvmPath string
+
+	// ScummvmArgs are extra arguments given with --scummvm-args, such as --fullscreen,
+	// that scummvm is started with, for the formats that start scummvm themselves.
+	ScummvmArgs []string
+
+	// ScriptType is the kind of launch script to write for the scripts format, or an
+	// empty string for the kind that runs on this platform.
+	ScriptType string
+
+	// SteamUser is the Steam user whose shortcuts the games are added to, for the steam
+	// format. It can be left out when only one user has logged in to Steam.
+	SteamUser string
+
+	// SteamGrid places each game's cover art in Steam's grid directory, for the steam
+	// format.
+	SteamGrid bool
+
+	// Errors are the directories the scan couldn't match, for the formats that list
+	// them too.
+	Errors []match.ScummGameMatch
+
+	// Journal records the changes to the library, for the formats that write into it
+	// during a scan, such as the gamelist.xml.
+	Journal *journal.Journal
+}
+
+// scummGameExporter writes the results of a scan out in a format another program
+// understands.
+type scummGameExporter func(scummGameMatches []match.ScummGameMatch, options exportOptions) error
+
+// scummGameExporters are the formats that can be chosen with "scummer export --format".
+var scummGameExporters = map[string]scummGameExporter{
+	"csv":              exportCsv,
+	"desktop":          exportDesktopEntries,
+	"duplicates":       exportDuplicatesReport,
+	"gamelist":         exportGamelist,
+	"html":             exportHTMLReport,
+	"hyperspin":        exportHyperspin,
+	"markdown":         exportMarkdownReport,
+	"playnite":         exportPlaynite,
+	"retroarch":        exportRetroarchPlaylist,
+	"scripts":          exportLaunchScripts,
+	"scummvm-ini":      exportScummvmIni,
+	"shortcuts":        exportShortcuts,
+	"srm":              exportSrmManifest,
+	"steam":            exportSteamShortcuts,
+	"tsv":              exportTsv,
+	"unknown-variants": exportUnknownVariantsReport,
+	"yaml":             exportYaml,
+	"zip":              exportZips,
+}
+
+// scummGameReportFormats are the export formats that report on every directory,
+// rather than adding the games to another program.
+var scummGameReportFormats = map[string]bool{
+	"csv":              true,
+	"duplicates":       true,
+	"html":             true,
+	"markdown":         true,
+	"tsv":              true,
+	"unknown-variants": true,
+	"yaml":             true,
+}
+
+// scummGameExporterNames returns the names of the export formats in alphabetical order.
+func scummGameExporterNames() []string {
+	names := make([]string, 0, len(scummGameExporters))
+	for name := range scummGameExporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// exportScummGameMatches exports the results of a scan to a format. Reports list every
+// directory, but other programs only get the first disc of a grouped multi-disc game.
+func exportScummGameMatches(format string, scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	exporter, ok := scummGameExporters[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, must be one of %s", format, strings.Join(scummGameExporterNames(), ", "))
+	}
+	if !scummGameReportFormats[format] {
+		scummGameMatches = output.WithoutLaterDiscs(scummGameMatches)
+	}
+	return exporter(scummGameMatches, options)
+}
+
+// runExport writes the results of an earlier scan out in another format.
+func runExport(args []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "", "format to export to: "+strings.Join(scummGameExporterNames(), ", ")+", or one of the exporters of the config file")
+	outputFile := flags.String("output", "", "file to export to; defaults to the usual location for the format")
+	corePath := flags.String("core", "", "path to the ScummVM libretro core, for the retroarch format")
+	scummvmPath := flags.String("scummvm", "", "path to the scummvm binary the games are launched with, for the formats that start scummvm; if not given, scummer looks for an installed scummvm")
+	scummvmArgs := flags.String("scummvm-args", "", "extra arguments to start scummvm with, such as \"--fullscreen --render-mode=ega\", for the formats that start scummvm")
+	scriptType := flags.String("script-type", "", "kind of launch script for the scripts format: sh or bat; defaults to the one that runs on this platform")
+	steamUser := flags.String("steam-user", "", "numeric ID of the Steam user to add the shortcuts to, for the steam format")
+	steamGrid := flags.Bool("steam-grid", false, "copy each game's cover art to Steam's grid directory, for the steam format")
+	errorFile := flags.String("errors", stateFile("error.json"), "error file of the scan, for the formats that list the directories that weren't matched too")
+	presetName := flags.String("preset", "", "frontend to export for: "+strings.Join(scummerPresetNames(), ", "))
+	configFile := flags.String("config", "", "config file, whose exporters can be used as formats too; defaults to "+stateFile(defaultConfigFile)+" if it exists")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer export --format <format> [flags] [<success.json>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// Read the config file, which may have exporters of its own
+	config, err := readScummerConfig(stateFile(defaultConfigFile), false)
+	if *configFile != "" {
+		config, err = readScummerConfig(*configFile, true)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Check that the format is one we know about
+	plugin, isPlugin := findExporterPlugin(config.Exporters, *format)
+	if _, ok := scummGameExporters[*format]; !ok && !isPlugin {
+		fmt.Printf("The --format flag must be one of %s, or one of the exporters of the config file\n", strings.Join(scummGameExporterNames(), ", "))
+		return
+	}
+
+	// Check that the preset is one we know about
+	options := exportOptions{OutputFile: *outputFile, Preset: defaultPreset, CorePath: *corePath, ScummvmPath: *scummvmPath, ScummvmArgs: strings.Fields(*scummvmArgs), ScriptType: *scriptType, SteamUser: *steamUser, SteamGrid: *steamGrid}
+	if *presetName != "" {
+		preset, ok := scummerPresets[*presetName]
+		if !ok {
+			fmt.Printf("The --preset flag must be one of %s\n", strings.Join(scummerPresetNames(), ", "))
+			return
+		}
+		options.Preset = preset
+	}
+
+	// The results file defaults to the one scan writes
+	successFile := stateFile("success.json")
+	if flags.NArg() > 0 {
+		successFile = flags.Arg(0)
+	}
+
+	// Load the results
+	scummvmOutputSlice, err := output.ReadResults(successFile)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Load the errors too, if there are any
+	options.Errors, err = output.ReadResults(*errorFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		options.Errors = nil
+	} else if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Record what is written, so that it can be undone
+	options.Journal = newRunJournal()
+	defer finishRunJournal(options.Journal)
+
+	// Export them
+	if isPlugin {
+		if err := plugin.run(scummvmOutputSlice, options); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+	if err := exportScummGameMatches(*format, scummvmOutputSlice, options); err != nil {
+		fmt.Println(err)
+		return
+	}
+}
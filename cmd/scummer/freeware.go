@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/furui/scummer"
+	"github.com/furui/scummer/output"
+)
+
+// freewareMirror is where scummvm.org hosts the freeware games.
+const freewareMirror = "https://downloads.scummvm.org/frs/extras"
+
+// freewareDownloadTimeout is how long downloading a freeware game may take.
+const freewareDownloadTimeout = 10 * time.Minute
+
+// freewareGame is a game its owners allowed scummvm.org to give away.
+type freewareGame struct {
+	// Name is what the game is chosen by with --games.
+	Name  string
+	Title string
+
+	// File is where the game's zip archive is, below the mirror.
+	File string
+}
+
+// freewareGames are the freeware games scummvm.org hosts, in the order they are fetched.
+var freewareGames = []freewareGame{
+	{Name: "bass", Title: "Beneath a Steel Sky", File: "Beneath a Steel Sky/bass-cd-1.2.zip"},
+	{Name: "drascula", Title: "Drascula: The Vampire Strikes Back", File: "Drascula/drascula-1.0.zip"},
+	{Name: "dreamweb", Title: "DreamWeb", File: "Dreamweb/dreamweb-cd-uk-1.1.zip"},
+	{Name: "fotaq", Title: "Flight of the Amazon Queen", File: "Flight of the Amazon Queen/FOTAQ_Talkie-1.1.zip"},
+	{Name: "lure", Title: "Lure of the Temptress", File: "Lure of the Temptress/lure-1.1.zip"},
+	{Name: "soltys", Title: "Soltys", File: "Soltys/soltys-en-v1.0.zip"},
+	{Name: "sfinx", Title: "Sfinx", File: "Sfinx/sfinx-en-v1.1.zip"},
+}
+
+// freewareGameNames returns the names the freeware games are chosen by.
+func freewareGameNames() []string {
+	names := make([]string, 0, len(freewareGames))
+	for _, game := range freewareGames {
+		names = append(names, game.Name)
+	}
+	return names
+}
+
+// runFetchFreeware downloads the freeware games into a library, unpacks them, and scans
+// them, so that a new library has something in it.
+func runFetchFreeware(args []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("fetch-freeware", flag.ExitOnError)
+	scummvmBinaryFlag := flags.String("scummvm", "", "path to the scummvm binary, flatpak for the ScummVM Flatpak, or docker:<image> to run it in a container; if not given, scummer looks for an installed scummvm")
+	similarityThreshold := flags.Float64("threshold", 0.5, "similarity (0 to 1) below which an ambiguous match is low confidence")
+	successFile := flags.String("results", stateFile("success.json"), "file the games that are found are added to")
+	errorFile := flags.String("errors", stateFile("error.json"), "file the games scummvm couldn't be sure about are added to")
+	gamesFlag := flags.String("games", "", "comma separated games to fetch, out of "+strings.Join(freewareGameNames(), ", ")+"; if not given, all of them are")
+	mirror := flags.String("mirror", freewareMirror, "where the games are downloaded from")
+	list := flags.Bool("list", false, "list the freeware games without fetching any")
+	noWrite := flags.Bool("no-write", false, "only save the results, without writing .scummvm files")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer fetch-freeware [flags] <scummvm data file directory>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// List the games, if that is all we were asked to do
+	if *list {
+		for _, game := range freewareGames {
+			fmt.Printf("%-10s %s\n", game.Name, game.Title)
+		}
+		return
+	}
+
+	// The data file directory is the only argument
+	if flags.NArg() != 1 {
+		fmt.Println("Please provide the scummvm data file directory")
+		return
+	}
+	library := flags.Arg(0)
+	if d, err := os.Stat(library); err != nil || !d.IsDir() {
+		fmt.Println("The scummvm data file directory is not a directory")
+		return
+	}
+
+	// Check that the games are ones we know about
+	games := freewareGames
+	if *gamesFlag != "" {
+		games = make([]freewareGame, 0)
+		for _, name := range strings.Split(*gamesFlag, ",") {
+			game, ok := findFreewareGame(strings.TrimSpace(name))
+			if !ok {
+				fmt.Printf("The --games flag must only have %s in it\n", strings.Join(freewareGameNames(), ", "))
+				return
+			}
+			games = append(games, game)
+		}
+	}
+
+	// In portable mode, a scummvm next to scummer is used before an installed one
+	if *scummvmBinaryFlag == "" {
+		*scummvmBinaryFlag = portableScummvm()
+	}
+
+	// Setup the scanner, which checks the rest of the flags and that scummvm works
+	scanner, err := scummer.NewScanner(scummer.Options{Scummvm: *scummvmBinaryFlag, Threshold: *similarityThreshold})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Fetch each game, carrying on with the others if one fails
+	changeJournal := newRunJournal()
+	defer finishRunJournal(changeJournal)
+	fetched := 0
+	for _, game := range games {
+		fmt.Printf("%s... ", game.Title)
+		directory := filepath.Join(library, output.SanitizeMarkerName(game.Title))
+		if _, err := os.Stat(directory); err == nil {
+			fmt.Printf("⏭️  already in the library\n")
+			continue
+		}
+
+		if err := fetchFreewareGame(strings.TrimSuffix(*mirror, "/")+"/"+game.File, directory); err != nil {
+			fmt.Printf("❌ %s\n", err)
+			continue
+		}
+		if err := changeJournal.AddedDirectory(directory); err != nil {
+			fmt.Printf("❌ %s\n", err)
+			continue
+		}
+
+		// Scan it, and save it before writing the .scummvm file, the way a scan does
+		scummGameMatch, err := scanner.ScanDirectory(context.Background(), directory)
+		if err != nil {
+			fmt.Printf("❌ %s\n", err)
+			continue
+		}
+		if err := replaceDirectoryResult(*successFile, *errorFile, directory, scummGameMatch); err != nil {
+			fmt.Printf("❌ %s\n", err)
+			continue
+		}
+		if scummGameMatch.ErrorKind != "" {
+			fmt.Printf("❌ %s\n", scummGameMatch.Description)
+			continue
+		}
+		if !*noWrite {
+			if err := output.WriteMarkerFile(scummGameMatch, changeJournal); err != nil {
+				fmt.Printf("❌ %s\n", err)
+				continue
+			}
+		}
+		fmt.Printf("✅ %s\n", scummGameMatch.GameID)
+		fetched++
+	}
+	fmt.Printf("Fetched %d of %d freeware games into %s\n", fetched, len(games), library)
+}
+
+// findFreewareGame returns the freeware game with the given name.
+func findFreewareGame(name string) (freewareGame, bool) {
+	for _, game := range freewareGames {
+		if strings.EqualFold(game.Name, name) {
+			return game, true
+		}
+	}
+	return freewareGame{}, false
+}
+
+// fetchFreewareGame downloads the zip archive of a freeware game and unpacks it into
+// directory. Nothing is left behind if it fails part of the way through.
+func fetchFreewareGame(url string, directory string) error {
+	// Download the archive to a temporary file
+	zipFile, err := os.CreateTemp("", "scummer-freeware-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(zipFile.Name())
+	defer zipFile.Close()
+
+	client := &http.Client{Timeout: freewareDownloadTimeout}
+	response, err := client.Get(strings.ReplaceAll(url, " ", "%20"))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading %s: %s", url, response.Status)
+	}
+	if _, err := io.Copy(zipFile, response.Body); err != nil {
+		return err
+	}
+	if err := zipFile.Close(); err != nil {
+		return err
+	}
+
+	// Unpack it
+	if err := extractZip(zipFile.Name(), directory); err != nil {
+		os.RemoveAll(directory)
+		return fmt.Errorf("unpacking %s: %w", url, err)
+	}
+	return nil
+}
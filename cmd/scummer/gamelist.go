@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// EmulationStation keeps the games of each system in a gamelist.xml in the root of the
+// system's directory. It looks like:
+//
+// <?xml version="1.0"?>
+// <gameList>
+//   <game>
+//     <path>./Loom (CD DOS VGA).scummvm</path>
+//     <name>Loom</name>
+//     <image>./images/Loom (CD DOS VGA)-image.png</image>
+//   </game>
+// </gameList>
+
+// gamelistFileName is the name EmulationStation gives its game lists.
+const gamelistFileName = "gamelist.xml"
+
+// gamelistElement is an element of gamelist.xml that scummer doesn't use, which is kept
+// exactly as it was.
+type gamelistElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
+}
+
+// gamelistGame is a <game> entry of gamelist.xml.
+type gamelistGame struct {
+	XMLName  xml.Name          `xml:"game"`
+	Attrs    []xml.Attr        `xml:",any,attr"`
+	Path     string            `xml:"path"`
+	Name     string            `xml:"name"`
+	Image    string            `xml:"image,omitempty"`
+	Marquee  string            `xml:"marquee,omitempty"`
+	Emulator string            `xml:"emulator,omitempty"`
+	Core     string            `xml:"core,omitempty"`
+	Other    []gamelistElement `xml:",any"`
+}
+
+// gamelist is the contents of gamelist.xml. Anything other than games, such as
+// <folder> entries, is kept exactly as it was.
+type gamelist struct {
+	XMLName xml.Name          `xml:"gameList"`
+	Attrs   []xml.Attr        `xml:",any,attr"`
+	Games   []gamelistGame    `xml:"game"`
+	Other   []gamelistElement `xml:",any"`
+}
+
+// libraryRoot returns the scummvm data file directory the games were found in.
+func libraryRoot(scummGameMatches []match.ScummGameMatch) string {
+	if len(scummGameMatches) == 0 {
+		return "."
+	}
+	return filepath.Dir(scummGameMatches[0].Directory)
+}
+
+// gamelistPath returns a path the way gamelist.xml spells them: relative to the library
+// root, starting with "./" and using forward slashes.
+func gamelistPath(root string, path string) string {
+	relativePath, err := filepath.Rel(root, path)
+	if err != nil {
+		relativePath = filepath.Base(path)
+	}
+	return "./" + filepath.ToSlash(relativePath)
+}
+
+// gamelistGameFile returns the file a game list entry points at. That is the .scummvm
+// file, unless the game's directory ends in .scummvm, in which case frontends treat the
+// directory itself as the game.
+func gamelistGameFile(scummGameMatch match.ScummGameMatch) string {
+	directory := scummGameMatch.Directory
+	if scummGameMatch.RenameTo != "" {
+		directory = scummGameMatch.RenameTo
+	}
+	if strings.HasSuffix(directory, output.MarkerExtension) {
+		return directory
+	}
+	return output.MarkerFileNames(scummGameMatch)[0]
+}
+
+// gameArtName returns the name a game's art is named after, which is the name of the
+// file the game list points at without its extension.
+func gameArtName(scummGameMatch match.ScummGameMatch) string {
+	gameFile := gamelistGameFile(scummGameMatch)
+	return strings.TrimSuffix(filepath.Base(gameFile), filepath.Ext(gameFile))
+}
+
+// normalizeGamelistPath makes two spellings of the same gamelist.xml path comparable.
+func normalizeGamelistPath(path string) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "./")
+}
+
+// readGamelist loads a gamelist.xml. A file that doesn't exist yet is treated as an
+// empty game list.
+func readGamelist(gamelistFile string) (*gamelist, error) {
+	list := &gamelist{}
+
+	gamelistXML, err := os.ReadFile(gamelistFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return list, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := xml.Unmarshal(gamelistXML, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// mergeGamelist adds an entry for every game that isn't in the game list yet. Entries
+// that are already there are left alone, since they may have been edited by hand. The
+// preset's emulator and core are written to the new entries. It returns the number of
+// entries that were added.
+func mergeGamelist(list *gamelist, root string, scummGameMatches []match.ScummGameMatch, preset scummerPreset) int {
+	// Remember which games are already listed
+	listedPaths := make(map[string]bool)
+	for _, game := range list.Games {
+		listedPaths[normalizeGamelistPath(game.Path)] = true
+	}
+
+	added := 0
+	for _, scummGameMatch := range scummGameMatches {
+		path := gamelistPath(root, gamelistGameFile(scummGameMatch))
+		if listedPaths[normalizeGamelistPath(path)] {
+			continue
+		}
+		listedPaths[normalizeGamelistPath(path)] = true
+
+		// Name the game after its Description without the variant tags, and leave
+		// placeholders for the images a scraper will fill in
+		game := gamelistGame{
+			Path:     path,
+			Name:     match.ParseDescriptionVariant(scummGameMatch.Description).Title,
+			Emulator: preset.Emulator,
+			Core:     preset.Core,
+		}
+		imageName := gameArtName(scummGameMatch)
+		if preset.Image != "" {
+			game.Image = fmt.Sprintf(preset.Image, imageName)
+		}
+		if preset.Marquee != "" {
+			game.Marquee = fmt.Sprintf(preset.Marquee, imageName)
+		}
+		list.Games = append(list.Games, game)
+		added++
+	}
+	return added
+}
+
+// writeGamelist saves a gamelist.xml, recording it in the journal.
+func writeGamelist(gamelistFile string, list *gamelist, changeJournal *journal.Journal) error {
+	gamelistXML, err := xml.MarshalIndent(list, "", "\t")
+	if err != nil {
+		return err
+	}
+	return changeJournal.WriteFile(gamelistFile, append([]byte(xml.Header), append(gamelistXML, '\n')...), 0644)
+}
+
+// exportGamelist adds the games to the gamelist.xml in the library root, creating it if
+// it doesn't exist yet.
+func exportGamelist(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputFile := options.OutputFile
+	if outputFile == "" {
+		outputFile = filepath.Join(libraryRoot(scummGameMatches), options.Preset.gamelistFileName())
+	}
+
+	list, err := readGamelist(outputFile)
+	if err != nil {
+		return err
+	}
+	added := mergeGamelist(list, filepath.Dir(outputFile), scummGameMatches, options.Preset)
+	if err := writeGamelist(outputFile, list, options.Journal); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added %d games to %s\n", added, outputFile)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// runImportGOG imports the ScummVM games installed from GOG.com, with GOG Galaxy or the
+// offline installers.
+func runImportGOG(args []string) {
+	runGameImport("import-gog", "GOG", args, func(map[string]string) []string {
+		return gogInstalledGames()
+	})
+}
+
+// gogInstalledGames returns the directories of the games installed from GOG.com: the
+// ones GOG Galaxy and the installers registered on Windows, and the ones in the
+// directories they install games into by default.
+func gogInstalledGames() []string {
+	installDirectories := gogRegisteredGames()
+
+	// The directories every game is installed into by default
+	var gameRoots []string
+	switch runtime.GOOS {
+	case "windows":
+		gameRoots = append(gameRoots, `C:\GOG Games`)
+		for _, programFiles := range []string{os.Getenv("ProgramFiles(x86)"), os.Getenv("ProgramFiles")} {
+			if programFiles != "" {
+				gameRoots = append(gameRoots, filepath.Join(programFiles, "GOG Galaxy", "Games"))
+			}
+		}
+	default:
+		// The offline installers, and Heroic, which installs GOG games on Linux and macOS
+		if homeDirectory, err := os.UserHomeDir(); err == nil {
+			gameRoots = append(gameRoots, filepath.Join(homeDirectory, "GOG Games"), filepath.Join(homeDirectory, "Games", "Heroic"))
+		}
+	}
+	for _, gameRoot := range gameRoots {
+		installDirectories = append(installDirectories, importSubdirectories(gameRoot)...)
+	}
+
+	return uniqueDirectories(installDirectories)
+}
+
+// uniqueDirectories returns the directories without the ones that are listed more than
+// once, such as a game that was both registered and found where it is installed by
+// default.
+func uniqueDirectories(directories []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(directories))
+	for _, directory := range directories {
+		key := absoluteDirectory(directory)
+		if runtime.GOOS == "windows" {
+			key = strings.ToLower(key)
+		}
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, directory)
+		}
+	}
+	return unique
+}
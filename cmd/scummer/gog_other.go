@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+// gogRegisteredGames returns nothing, since only Windows has a registry for GOG to
+// register its games in.
+func gogRegisteredGames() []string {
+	return nil
+}
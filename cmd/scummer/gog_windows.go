@@ -0,0 +1,32 @@
+package main
+
+import (
+	"golang.org/x/sys/windows/registry"
+)
+
+// gogGamesKeyPath is where GOG Galaxy and the GOG installers register the games they
+// install, with the directory each is installed in as its "path".
+const gogGamesKeyPath = `SOFTWARE\WOW6432Node\GOG.com\Games`
+
+// gogRegisteredGames returns the directories of the games GOG has registered.
+func gogRegisteredGames() []string {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, gogGamesKeyPath, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return nil
+	}
+	gameIDs, _ := key.ReadSubKeyNames(-1)
+	key.Close()
+
+	installDirectories := make([]string, 0, len(gameIDs))
+	for _, gameID := range gameIDs {
+		gameKey, err := registry.OpenKey(registry.LOCAL_MACHINE, gogGamesKeyPath+`\`+gameID, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+		if installDirectory, _, err := gameKey.GetStringValue("path"); err == nil && installDirectory != "" {
+			installDirectories = append(installDirectories, installDirectory)
+		}
+		gameKey.Close()
+	}
+	return installDirectories
+}
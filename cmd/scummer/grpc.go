@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/rpc"
+)
+
+// watchInterval is how often Watch checks whether the results have changed.
+const watchInterval = time.Second
+
+// grpcServer serves the gRPC API, from the same results as the review page.
+type grpcServer struct {
+	rpc.UnimplementedScummerServer
+
+	review *reviewServer
+}
+
+// rpcMatch turns a match into its gRPC message.
+func rpcMatch(scummGameMatch match.ScummGameMatch) *rpc.Match {
+	rpcMatch := &rpc.Match{
+		GameId:      scummGameMatch.GameID,
+		Description: scummGameMatch.Description,
+		Directory:   scummGameMatch.Directory,
+		Title:       scummGameMatch.Title,
+		ErrorKind:   scummGameMatch.ErrorKind,
+		ChosenBy:    scummGameMatch.ChosenBy,
+		Similarity:  scummGameMatch.Similarity,
+		Confidence:  scummGameMatch.Confidence,
+	}
+	if scummGameMatch.Reason != nil {
+		rpcMatch.Reason = scummGameMatch.Reason.Rule
+	}
+	for _, candidate := range scummGameMatch.Candidates {
+		rpcMatch.Candidates = append(rpcMatch.Candidates, &rpc.Candidate{GameId: candidate.GameID, Description: candidate.Description, Similarity: candidate.Similarity, Score: candidate.Score})
+	}
+	return rpcMatch
+}
+
+// rpcResults turns the results of a scan into their gRPC message.
+func rpcResults(scummGameMatches []match.ScummGameMatch, scummGameErrors []match.ScummGameMatch) *rpc.Results {
+	results := &rpc.Results{}
+	for _, scummGameMatch := range scummGameMatches {
+		results.Matches = append(results.Matches, rpcMatch(scummGameMatch))
+	}
+	for _, scummGameError := range scummGameErrors {
+		results.Errors = append(results.Errors, rpcMatch(scummGameError))
+	}
+	return results
+}
+
+// resultsVersion returns something that changes whenever the results files do.
+func (s *grpcServer) resultsVersion() string {
+	var version strings.Builder
+	for _, fileName := range []string{s.review.successFile, s.review.errorFile} {
+		if info, err := os.Stat(fileName); err == nil {
+			fmt.Fprintf(&version, "%d:%d;", info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return version.String()
+}
+
+// Scan scans a library, sending the result of each directory as soon as it is known,
+// and then saves the results.
+func (s *grpcServer) Scan(request *rpc.ScanRequest, stream rpc.Scummer_ScanServer) error {
+	scan := libraryScan{Library: request.Library, Threshold: request.Threshold, OnLowConfidence: request.OnLowConfidence, WriteMarkers: request.WriteMarkers}
+	err := s.review.scanLibrary(stream.Context(), scan, func(done int, total int, scummGameMatch match.ScummGameMatch) error {
+		return stream.Send(&rpc.ScanProgress{Done: int32(done), Total: int32(total), Match: rpcMatch(scummGameMatch)})
+	})
+	switch {
+	case err == nil:
+		return nil
+	case stream.Context().Err() != nil:
+		return status.FromContextError(stream.Context().Err()).Err()
+	case errors.Is(err, errScanRunning):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, fs.ErrNotExist):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// Watch sends the results, and then sends them again whenever they change, until the
+// client goes away.
+func (s *grpcServer) Watch(request *rpc.WatchRequest, stream rpc.Scummer_WatchServer) error {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	lastVersion := ""
+	for {
+		if version := s.resultsVersion(); version != lastVersion {
+			scummvmOutputSlice, scummvmOutputErrorSlice, err := s.review.lockedResults()
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err := stream.Send(rpcResults(scummvmOutputSlice, scummvmOutputErrorSlice)); err != nil {
+				return err
+			}
+			lastVersion = version
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Resolve picks one of the candidates of a directory, or skips it.
+func (s *grpcServer) Resolve(ctx context.Context, request *rpc.ResolveRequest) (*rpc.ResolveResponse, error) {
+	// Make sure the choice is one of the directory's candidates
+	scummvmOutputSlice, _, err := s.review.lockedResults()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	found := false
+	for _, scummGameMatch := range scummvmOutputSlice {
+		if scummGameMatch.Directory != request.Directory {
+			continue
+		}
+		found = true
+		if request.GameId != skipAnswer && candidateIndex(scummGameMatch.Candidates, request.GameId) < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "%s is not one of the candidates of %s", request.GameId, request.Directory)
+		}
+	}
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "%s is not in the results", request.Directory)
+	}
+
+	// Apply the choice, and send back what the directory looks like now
+	if _, _, err := s.review.resolve(map[string]string{request.Directory: request.GameId}); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	scummvmOutputSlice, scummvmOutputErrorSlice, err := s.review.lockedResults()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	for _, scummGameMatch := range append(scummvmOutputSlice, scummvmOutputErrorSlice...) {
+		if scummGameMatch.Directory == request.Directory {
+			return &rpc.ResolveResponse{Match: rpcMatch(scummGameMatch)}, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "%s is not in the results", request.Directory)
+}
+
+// Export exports the results to one of the formats of "scummer export".
+func (s *grpcServer) Export(ctx context.Context, request *rpc.ExportRequest) (*rpc.ExportResponse, error) {
+	// Check that the format and preset are ones we know about
+	if _, ok := scummGameExporters[request.Format]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown format %q, must be one of %s", request.Format, strings.Join(scummGameExporterNames(), ", "))
+	}
+	options := exportOptions{OutputFile: request.Output, Preset: defaultPreset, ScummvmPath: s.review.scummvmPath, Journal: s.review.changeJournal}
+	if request.Preset != "" {
+		preset, ok := scummerPresets[request.Preset]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown preset %q, must be one of %s", request.Preset, strings.Join(scummerPresetNames(), ", "))
+		}
+		options.Preset = preset
+	}
+
+	// Export the results
+	s.review.mutex.Lock()
+	defer s.review.mutex.Unlock()
+	scummvmOutputSlice, scummvmOutputErrorSlice, err := s.review.readResults()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	options.Errors = scummvmOutputErrorSlice
+	if err := exportScummGameMatches(request.Format, scummvmOutputSlice, options); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &rpc.ExportResponse{}, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// The points of a scan where a hook can be run.
+const (
+	hookPreScan   = "pre-scan"
+	hookPostMatch = "post-match"
+	hookPostWrite = "post-write"
+	hookPostRun   = "post-run"
+)
+
+// scummerHooks are the commands run at points of a scan, each one a program followed by
+// its arguments. PreScan, PostMatch and PostWrite are run for each directory, and PostRun
+// once the scan is over.
+type scummerHooks struct {
+	PreScan   []string `yaml:"pre_scan"`
+	PostMatch []string `yaml:"post_match"`
+	PostWrite []string `yaml:"post_write"`
+	PostRun   []string `yaml:"post_run"`
+}
+
+// runHook runs a hook, if it is set, giving it input as JSON on its standard input and
+// env on top of our own environment. What the hook prints goes straight to the console.
+func runHook(hook string, hookCommand []string, input interface{}, env []string) error {
+	if len(hookCommand) == 0 {
+		return nil
+	}
+
+	inputJSON := []byte{}
+	if input != nil {
+		var err error
+		if inputJSON, err = json.Marshal(input); err != nil {
+			return err
+		}
+	}
+
+	command := exec.Command(hookCommand[0], hookCommand[1:]...)
+	command.Stdin = bytes.NewReader(inputJSON)
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	command.Env = append(append(os.Environ(), "SCUMMER_HOOK="+hook), env...)
+	if err := command.Run(); err != nil {
+		return fmt.Errorf("%s hook: %w", hook, err)
+	}
+	return nil
+}
+
+// preScan runs the pre-scan hook before a directory of the library is scanned.
+func (h scummerHooks) preScan(library string, directory string) error {
+	return runHook(hookPreScan, h.PreScan, nil, []string{"SCUMMER_LIBRARY=" + library, "SCUMMER_DIRECTORY=" + directory})
+}
+
+// postMatch runs the post-match hook with the result of a directory, whether it was
+// matched or not.
+func (h scummerHooks) postMatch(library string, scummGameMatch match.ScummGameMatch) error {
+	return runHook(hookPostMatch, h.PostMatch, scummGameMatch, scummGameMatchHookEnv(library, scummGameMatch))
+}
+
+// postWrite runs the post-write hook for a game once its .scummvm file is written, also
+// telling it where the file is in SCUMMER_MARKER_FILE.
+func (h scummerHooks) postWrite(library string, scummGameMatch match.ScummGameMatch) error {
+	markerFile := ""
+	if markerFileNames := output.MarkerFileNames(scummGameMatch); len(markerFileNames) > 0 {
+		markerFile = markerFileNames[0]
+	}
+	env := append(scummGameMatchHookEnv(library, scummGameMatch), "SCUMMER_MARKER_FILE="+markerFile)
+	return runHook(hookPostWrite, h.PostWrite, scummGameMatch, env)
+}
+
+// postRun runs the post-run hook with the summary of the scan.
+func (h scummerHooks) postRun(library string, summary scanSummary) error {
+	env := []string{
+		"SCUMMER_LIBRARY=" + library,
+		fmt.Sprintf("SCUMMER_DETECTED=%d", summary.Detected),
+		fmt.Sprintf("SCUMMER_AMBIGUOUS=%d", summary.Ambiguous),
+		fmt.Sprintf("SCUMMER_FAILED=%d", summary.Failed),
+		fmt.Sprintf("SCUMMER_SKIPPED=%d", summary.Skipped),
+	}
+	return runHook(hookPostRun, h.PostRun, summary, env)
+}
+
+// scummGameMatchHookEnv is what the hooks of a directory are told about its result in
+// their environment.
+func scummGameMatchHookEnv(library string, scummGameMatch match.ScummGameMatch) []string {
+	return []string{
+		"SCUMMER_LIBRARY=" + library,
+		"SCUMMER_DIRECTORY=" + scummGameMatch.Directory,
+		"SCUMMER_GAMEID=" + scummGameMatch.GameID,
+		"SCUMMER_DESCRIPTION=" + scummGameMatch.Description,
+		"SCUMMER_STATUS=" + scummGameMatchStatus(scummGameMatch),
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/furui/scummer/match"
+)
+
+// HyperSpin lists the games of each system in a database XML file, such as
+// Databases/ScummVM/ScummVM.xml. It looks like:
+//
+// <?xml version="1.0" encoding="UTF-8"?>
+// <menu>
+//   <header>
+//     <listname>ScummVM</listname>
+//   </header>
+//   <game name="Loom (CD DOS VGA)">
+//     <description>Loom</description>
+//     <enabled>Yes</enabled>
+//   </game>
+// </menu>
+
+// hyperspinFileName is the name HyperSpin gives the ScummVM database.
+const hyperspinFileName = "ScummVM.xml"
+
+// hyperspinRegions maps the region codes found in Descriptions and directory names to
+// the region names HyperSpin uses.
+var hyperspinRegions = map[string]string{
+	"USA": "USA",
+	"US":  "USA",
+	"EUR": "Europe",
+	"EU":  "Europe",
+	"UK":  "UK",
+	"JPN": "Japan",
+	"JP":  "Japan",
+}
+
+// hyperspinHeader is the <header> of a HyperSpin database.
+type hyperspinHeader struct {
+	ListName        string `xml:"listname"`
+	LastListUpdate  string `xml:"lastlistupdate"`
+	ListVersion     string `xml:"listversion"`
+	ExporterVersion string `xml:"exporterversion"`
+}
+
+// hyperspinGame is a <game> entry of a HyperSpin database. The name is the name of the
+// file HyperSpin launches, without its extension.
+type hyperspinGame struct {
+	Name         string `xml:"name,attr"`
+	Index        string `xml:"index,attr"`
+	Image        string `xml:"image,attr"`
+	Description  string `xml:"description"`
+	CloneOf      string `xml:"cloneof"`
+	CRC          string `xml:"crc"`
+	Manufacturer string `xml:"manufacturer"`
+	Year         string `xml:"year"`
+	Genre        string `xml:"genre"`
+	Rating       string `xml:"rating"`
+	Region       string `xml:"region,omitempty"`
+	Language     string `xml:"language,omitempty"`
+	Enabled      string `xml:"enabled"`
+}
+
+// hyperspinDatabase is the contents of a HyperSpin database.
+type hyperspinDatabase struct {
+	XMLName xml.Name        `xml:"menu"`
+	Header  hyperspinHeader `xml:"header"`
+	Games   []hyperspinGame `xml:"game"`
+}
+
+// hyperspinRegion returns the region a game is for, from the region codes in its
+// Description or directory name, or an empty string if neither says.
+func hyperspinRegion(scummGameMatch match.ScummGameMatch) string {
+	for _, text := range []string{scummGameMatch.Description, filepath.Base(scummGameMatch.Directory)} {
+		if code := match.RegionCodeMatcher.FindString(text); code != "" {
+			if region, ok := hyperspinRegions[code]; ok {
+				return region
+			}
+		}
+	}
+	return ""
+}
+
+// exportHyperspin writes a HyperSpin database of the games, sorted by name as
+// HyperSpin expects.
+func exportHyperspin(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputFile := options.OutputFile
+	if outputFile == "" {
+		outputFile = hyperspinFileName
+	}
+
+	database := hyperspinDatabase{Header: hyperspinHeader{
+		ListName:        strings.TrimSuffix(filepath.Base(outputFile), filepath.Ext(outputFile)),
+		LastListUpdate:  time.Now().Format("01/02/2006"),
+		ListVersion:     time.Now().Format("2006.01.02"),
+		ExporterVersion: "scummer",
+	}}
+	for _, scummGameMatch := range scummGameMatches {
+		variant := match.ParseDescriptionVariant(scummGameMatch.Description)
+		database.Games = append(database.Games, hyperspinGame{
+			Name:        gameArtName(scummGameMatch),
+			Description: variant.Title,
+			Region:      hyperspinRegion(scummGameMatch),
+			Language:    variant.Language,
+			Enabled:     "Yes",
+		})
+	}
+	sort.Slice(database.Games, func(i, j int) bool {
+		return strings.ToLower(database.Games[i].Name) < strings.ToLower(database.Games[j].Name)
+	})
+
+	databaseXML, err := xml.MarshalIndent(database, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := options.Journal.WriteFile(outputFile, append([]byte(xml.Header), append(databaseXML, '\n')...), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d games to %s\n", len(database.Games), outputFile)
+	return nil
+}
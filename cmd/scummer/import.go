@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/furui/scummer"
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// importSearchDepth is how far below an installed game's directory its game data is
+// looked for.
+const importSearchDepth = 2
+
+// importSkippedDirectories are the directories of installed games that hold what the
+// store ships alongside the game, such as its own copy of scummvm, rather than the game.
+var importSkippedDirectories = []string{"__installer", "__redist", "__support", "commonappdata", "dosbox", "scummvm", "_commonredist"}
+
+// gameImport adds the games a store installed to the results, either where they are
+// or copied into the library.
+type gameImport struct {
+	successFile string
+	errorFile   string
+
+	// CopyTo is the library the game data is copied into, if it isn't used in place.
+	copyTo string
+
+	// noWrite only saves the results, without copying anything or writing .scummvm
+	// files.
+	noWrite bool
+
+	// changeJournal records the games copied into the library and their .scummvm files.
+	changeJournal *journal.Journal
+}
+
+// importedGame is an installed game that scummvm found a game in.
+type importedGame struct {
+	installDirectory string
+	scummGameMatch   match.ScummGameMatch
+}
+
+// runGameImport runs an import command, such as "scummer import-gog", which imports
+// the games of a store from the directories given on the command line, or from the
+// directories installedGames finds if none are. installedGames is given the titles of
+// the games scummvm knows about, for stores that install far more than ScummVM games.
+func runGameImport(command string, storeName string, args []string, installedGames func(gameTitles map[string]string) []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	scummvmBinaryFlag := flags.String("scummvm", "", "path to the scummvm binary, flatpak for the ScummVM Flatpak, or docker:<image> to run it in a container; if not given, scummer looks for an installed scummvm")
+	similarityThreshold := flags.Float64("threshold", 0.5, "similarity (0 to 1) below which an ambiguous match is low confidence")
+	successFile := flags.String("results", stateFile("success.json"), "file the games that are found are added to")
+	errorFile := flags.String("errors", stateFile("error.json"), "file the games scummvm couldn't be sure about are added to")
+	copyTo := flags.String("copy-to", "", "library to copy the game data into, each game in a directory named after its install directory; if not given, the games are used where they are installed")
+	noWrite := flags.Bool("no-write", false, "only save the results, without copying anything or writing .scummvm files")
+	yes := flags.Bool("yes", false, "add the games that are found without asking first")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: scummer %s [flags] [<%s game directory>...]\n", command, storeName)
+		fmt.Fprintf(flags.Output(), "Without any directories, the games are looked for where %s usually installs them.\n", storeName)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// Check that the library the games are copied into is a directory
+	if *copyTo != "" {
+		if d, err := os.Stat(*copyTo); err != nil || !d.IsDir() {
+			fmt.Println("The --copy-to directory is not a directory")
+			return
+		}
+	}
+
+	// In portable mode, a scummvm next to scummer is used before an installed one
+	if *scummvmBinaryFlag == "" {
+		*scummvmBinaryFlag = portableScummvm()
+	}
+
+	// Setup the scanner, which checks the rest of the flags and that scummvm works
+	scanner, err := scummer.NewScanner(scummer.Options{Scummvm: *scummvmBinaryFlag, Threshold: *similarityThreshold})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Find the installed games, unless we were told where they are
+	installDirectories := flags.Args()
+	if len(installDirectories) == 0 {
+		installDirectories = installedGames(scanner.GameTitles())
+		if len(installDirectories) == 0 {
+			fmt.Printf("Could not find any %s games; give the directories they are installed in\n", storeName)
+			return
+		}
+	}
+
+	// Find the game data of each game, carrying on with the others if one fails
+	importedGames := make([]importedGame, 0)
+	for _, installDirectory := range installDirectories {
+		fmt.Printf("%s... ", installDirectory)
+		scummGameMatch, found, err := findGameData(context.Background(), scanner, installDirectory)
+		switch {
+		case err != nil:
+			fmt.Printf("❌ %s\n", err)
+		case !found:
+			fmt.Printf("⏭️  no ScummVM game\n")
+		case scummGameMatch.ErrorKind != "":
+			fmt.Printf("❌ %s\n", scummGameMatch.Description)
+			importedGames = append(importedGames, importedGame{installDirectory: installDirectory, scummGameMatch: scummGameMatch})
+		default:
+			fmt.Printf("✅ %s in %s\n", scummGameMatch.GameID, scummGameMatch.Directory)
+			importedGames = append(importedGames, importedGame{installDirectory: installDirectory, scummGameMatch: scummGameMatch})
+		}
+	}
+	if len(importedGames) == 0 {
+		fmt.Printf("None of the %d %s games are ScummVM games\n", len(installDirectories), storeName)
+		return
+	}
+
+	// Offer to add them, unless we were told to go ahead or nobody is around to ask
+	if !*yes && isInteractive() && !promptYesNo(fmt.Sprintf("Add the %d games that were found to %s?", len(importedGames), *successFile)) {
+		fmt.Println("No games were added")
+		return
+	}
+
+	// Add each game, carrying on with the others if one fails
+	changeJournal := newRunJournal()
+	defer finishRunJournal(changeJournal)
+	gameImport := gameImport{successFile: *successFile, errorFile: *errorFile, copyTo: *copyTo, noWrite: *noWrite, changeJournal: changeJournal}
+	imported := 0
+	for _, importedGame := range importedGames {
+		scummGameMatch, err := gameImport.addGame(importedGame.installDirectory, importedGame.scummGameMatch)
+		if err != nil {
+			fmt.Printf("❌ %s: %s\n", importedGame.installDirectory, err)
+		} else if scummGameMatch.ErrorKind == "" {
+			imported++
+		}
+	}
+	fmt.Printf("Imported %d of %d %s games\n", imported, len(installDirectories), storeName)
+}
+
+// addGame copies the game data of an installed game into the library if asked to, adds
+// it to the results and writes its .scummvm file.
+func (i gameImport) addGame(installDirectory string, scummGameMatch match.ScummGameMatch) (match.ScummGameMatch, error) {
+	// Copy the game data into the library, unless it is already there
+	if i.copyTo != "" && !i.noWrite && scummGameMatch.ErrorKind == "" {
+		libraryDirectory := filepath.Join(i.copyTo, filepath.Base(filepath.Clean(installDirectory)))
+		if _, err := os.Stat(libraryDirectory); err == nil {
+			return scummGameMatch, fmt.Errorf("%s is already in the library", libraryDirectory)
+		}
+		if err := copyDirectory(scummGameMatch.Directory, libraryDirectory); err != nil {
+			return scummGameMatch, err
+		}
+		if err := i.changeJournal.AddedDirectory(libraryDirectory); err != nil {
+			return scummGameMatch, err
+		}
+		scummGameMatch.Directory = libraryDirectory
+	}
+
+	// Save it before writing the .scummvm file, the way a scan does
+	if err := replaceDirectoryResult(i.successFile, i.errorFile, scummGameMatch.Directory, scummGameMatch); err != nil {
+		return scummGameMatch, err
+	}
+	if !i.noWrite && scummGameMatch.ErrorKind == "" {
+		if err := output.WriteMarkerFile(scummGameMatch, i.changeJournal); err != nil {
+			return scummGameMatch, err
+		}
+	}
+	return scummGameMatch, nil
+}
+
+// findGameData looks for the directory of an installed game that scummvm finds a game
+// in, starting with the directory itself and then going down, nearest first, since
+// stores often keep the game data in a directory of its own. Games scummvm wasn't sure
+// about are returned too, with their ErrorKind set.
+func findGameData(ctx context.Context, scanner *scummer.Scanner, installDirectory string) (match.ScummGameMatch, bool, error) {
+	directories := []string{installDirectory}
+	for depth := 0; depth <= importSearchDepth && len(directories) > 0; depth++ {
+		subdirectories := make([]string, 0)
+		for _, directory := range directories {
+			scummGameMatch, err := scanner.ScanDirectory(ctx, directory)
+			if err != nil {
+				return scummGameMatch, false, err
+			}
+			if scummGameMatch.ErrorKind == "" || scummGameMatch.ErrorKind == match.ErrorKindLowConfidence {
+				return scummGameMatch, true, nil
+			}
+			subdirectories = append(subdirectories, importSubdirectories(directory)...)
+		}
+		directories = subdirectories
+	}
+	return match.ScummGameMatch{}, false, nil
+}
+
+// importSubdirectories returns the directories in a directory that might hold game
+// data, leaving out the ones that hold what the store ships alongside the game.
+func importSubdirectories(directory string) []string {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil
+	}
+	subdirectories := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() || isImportSkippedDirectory(entry.Name()) {
+			continue
+		}
+		subdirectories = append(subdirectories, filepath.Join(directory, entry.Name()))
+	}
+	return subdirectories
+}
+
+// isImportSkippedDirectory returns whether a directory of an installed game is one of
+// the ones the store ships alongside the game.
+func isImportSkippedDirectory(name string) bool {
+	for _, skippedDirectory := range importSkippedDirectories {
+		if strings.EqualFold(name, skippedDirectory) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyDirectory copies a directory and everything in it to destination, which must not
+// exist yet.
+func copyDirectory(source string, destination string) error {
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		destinationPath := filepath.Join(destination, relativePath)
+		if entry.IsDir() {
+			return os.MkdirAll(destinationPath, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, destinationPath)
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ScummVM keeps its configuration in a plain INI file. The "[scummvm]" section holds
+// the global options, and every other section is a target (a configured game) whose
+// name is the target ID. A target section looks like:
+//
+// [loom]
+// description=Loom (VGA/DOS/English)
+// path=G:\example\scummvm\Loom (CD DOS VGA)\
+// engineid=scumm
+// gameid=loom
+
+// scummvmIniEntry is a single key=value line from a scummvm.ini section.
+type scummvmIniEntry struct {
+	Key   string
+	Value string
+}
+
+// scummvmIniSection is a single [section] from a scummvm.ini file. The entries are
+// kept in the order they were read.
+type scummvmIniSection struct {
+	Name    string
+	Entries []scummvmIniEntry
+}
+
+// scummvmIni is a parsed scummvm.ini file. The sections are kept in the order they
+// were read.
+type scummvmIni struct {
+	Sections []*scummvmIniSection
+}
+
+// Get returns the value of the given key in the section, or an empty string if the
+// key is not present.
+func (s *scummvmIniSection) Get(key string) string {
+	for _, entry := range s.Entries {
+		if strings.EqualFold(entry.Key, key) {
+			return entry.Value
+		}
+	}
+	return ""
+}
+
+// parseScummvmIni reads a scummvm.ini file from the given reader.
+func parseScummvmIni(r io.Reader) (*scummvmIni, error) {
+	ini := &scummvmIni{}
+
+	// Keep track of the section we are currently adding entries to
+	var currentSection *scummvmIniSection
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip blank lines and comments
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		// Start a new section
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			currentSection = &scummvmIniSection{Name: strings.TrimSpace(line[1 : len(line)-1])}
+			ini.Sections = append(ini.Sections, currentSection)
+			continue
+		}
+
+		// Ignore entries that appear before the first section or that are malformed
+		key, value, found := strings.Cut(line, "=")
+		if currentSection == nil || !found {
+			continue
+		}
+
+		currentSection.Entries = append(currentSection.Entries, scummvmIniEntry{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ini, nil
+}
+
+// readScummvmIni opens and parses the scummvm.ini file at the given location.
+func readScummvmIni(scummvmIniFile string) (*scummvmIni, error) {
+	f, err := os.Open(scummvmIniFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseScummvmIni(f)
+}
+
+// Targets returns every section that describes a game, skipping the global
+// "[scummvm]" section and any other section without a path.
+func (ini *scummvmIni) Targets() []*scummvmIniSection {
+	targets := make([]*scummvmIniSection, 0)
+	for _, section := range ini.Sections {
+		if strings.EqualFold(section.Name, "scummvm") || section.Get("path") == "" {
+			continue
+		}
+		targets = append(targets, section)
+	}
+	return targets
+}
+
+// TargetPaths returns a map of normalized game paths to the name of the target that
+// uses that path. The paths are made absolute, the way ScummVM writes them, so look them
+// up with normalizeTargetPath(absoluteDirectory(directory)).
+func (ini *scummvmIni) TargetPaths() map[string]string {
+	targetPaths := make(map[string]string)
+	for _, target := range ini.Targets() {
+		targetPaths[normalizeTargetPath(absoluteDirectory(target.Get("path")))] = target.Name
+	}
+	return targetPaths
+}
+
+// normalizeTargetPath cleans up a path so that paths written by ScummVM (which usually
+// end with a separator) can be compared with the paths scummer builds itself. Windows
+// paths are compared case-insensitively.
+func normalizeTargetPath(targetPath string) string {
+	normalizedPath := filepath.Clean(targetPath)
+	if runtime.GOOS == "windows" {
+		normalizedPath = strings.ToLower(normalizedPath)
+	}
+	return normalizedPath
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/match"
+)
+
+// exportScummvmCommand returns the scummvm that the exported games are launched with.
+// That is the one given with --scummvm, otherwise an installed scummvm if one can be
+// found, and otherwise just "scummvm", leaving it to the PATH. A macOS .app bundle
+// stands for the scummvm inside it, and a relative path is made absolute, since the
+// games are launched from somewhere else.
+func exportScummvmCommand(options exportOptions) detect.Command {
+	if options.ScummvmPath != "" {
+		scummvmPath := detect.ResolveAppBundle(options.ScummvmPath)
+		if filepath.Base(scummvmPath) != scummvmPath {
+			if absolutePath, err := filepath.Abs(scummvmPath); err == nil {
+				scummvmPath = absolutePath
+			}
+		}
+		return detect.Command{Path: scummvmPath}
+	}
+	if discoveredBinary, err := detect.Discover(); err == nil {
+		return discoveredBinary
+	}
+	return detect.Command{Path: "scummvm"}
+}
+
+// scummvmLaunchArguments returns the arguments that make scummvm start a game straight
+// away, without adding it to scummvm.ini first. They are any arguments the scummvm
+// command needs, the extra arguments given with --scummvm-args, the full path to the
+// game's directory, and its GameID.
+func scummvmLaunchArguments(scummvmBinary detect.Command, scummGameMatch match.ScummGameMatch, options exportOptions) []string {
+	launchArguments := append([]string{}, scummvmBinary.Args...)
+	launchArguments = append(launchArguments, options.ScummvmArgs...)
+	return append(launchArguments, "-p", absoluteDirectory(scummGameMatch.Directory), scummGameMatch.GameID)
+}
+
+// quoteLaunchArgument puts double quotes around an argument that has spaces in it, the
+// way Windows, Steam and most launchers split a command line back into arguments.
+func quoteLaunchArgument(argument string) string {
+	if argument != "" && !strings.ContainsAny(argument, " \t\"") {
+		return argument
+	}
+	return `"` + strings.ReplaceAll(argument, `"`, `\"`) + `"`
+}
+
+// joinLaunchArguments turns arguments into a single command line, quoting the ones that
+// need it.
+func joinLaunchArguments(arguments []string) string {
+	quotedArguments := make([]string, len(arguments))
+	for i, argument := range arguments {
+		quotedArguments[i] = quoteLaunchArgument(argument)
+	}
+	return strings.Join(quotedArguments, " ")
+}
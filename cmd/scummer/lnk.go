@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// These are the parts of the Windows shell link (.lnk) format that scummer uses. The
+// format is described in Microsoft's [MS-SHLLINK] specification.
+const (
+	shellLinkHeaderSize         = 0x4c
+	shellLinkHasLinkInfo        = 0x00000002
+	shellLinkHasName            = 0x00000004
+	shellLinkHasWorkingDir      = 0x00000010
+	shellLinkHasArguments       = 0x00000020
+	shellLinkHasIconLocation    = 0x00000040
+	shellLinkIsUnicode          = 0x00000080
+	shellLinkFileAttributeFile  = 0x00000020
+	shellLinkShowNormal         = 0x00000001
+	shellLinkVolumeIDAndPath    = 0x00000001
+	shellLinkInfoHeaderSize     = 0x24
+	shellLinkVolumeIDHeaderSize = 0x10
+	shellLinkDriveFixed         = 0x00000003
+)
+
+// shellLinkCLSID is the class identifier every shell link starts with,
+// 00021401-0000-0000-C000-000000000046.
+var shellLinkCLSID = [16]byte{0x01, 0x14, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+
+// shellLinkANSI returns a NUL terminated string in the ANSI code page, which only has
+// room for ASCII here. Windows uses the Unicode copy that follows it instead.
+func shellLinkANSI(s string) []byte {
+	ansi := make([]byte, 0, len(s)+1)
+	for _, r := range s {
+		if r > 0x7f {
+			r = '?'
+		}
+		ansi = append(ansi, byte(r))
+	}
+	return append(ansi, 0)
+}
+
+// shellLinkUnicode returns a string as UTF-16, little endian.
+func shellLinkUnicode(s string) []byte {
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, utf16.Encode([]rune(s)))
+	return buffer.Bytes()
+}
+
+// shellLinkInfo returns the LinkInfo of a shell link that points at a file on a local
+// drive.
+func shellLinkInfo(target string) []byte {
+	// The volume the file is on, which Windows only uses to find the file again if the
+	// path stops working, so it is left empty
+	var volumeID bytes.Buffer
+	binary.Write(&volumeID, binary.LittleEndian, []uint32{shellLinkVolumeIDHeaderSize + 1, shellLinkDriveFixed, 0, shellLinkVolumeIDHeaderSize})
+	volumeID.WriteByte(0)
+
+	// The path to the file, in ANSI and then in Unicode, each followed by an empty
+	// common path suffix
+	localBasePath := shellLinkANSI(target)
+	localBasePathUnicode := append(shellLinkUnicode(target), 0, 0)
+	volumeIDOffset := uint32(shellLinkInfoHeaderSize)
+	localBasePathOffset := volumeIDOffset + uint32(volumeID.Len())
+	commonPathSuffixOffset := localBasePathOffset + uint32(len(localBasePath))
+	localBasePathUnicodeOffset := commonPathSuffixOffset + 1
+	commonPathSuffixUnicodeOffset := localBasePathUnicodeOffset + uint32(len(localBasePathUnicode))
+	size := commonPathSuffixUnicodeOffset + 2
+
+	var linkInfo bytes.Buffer
+	binary.Write(&linkInfo, binary.LittleEndian, []uint32{
+		size,
+		shellLinkInfoHeaderSize,
+		shellLinkVolumeIDAndPath,
+		volumeIDOffset,
+		localBasePathOffset,
+		0,
+		commonPathSuffixOffset,
+		localBasePathUnicodeOffset,
+		commonPathSuffixUnicodeOffset,
+	})
+	linkInfo.Write(volumeID.Bytes())
+	linkInfo.Write(localBasePath)
+	linkInfo.WriteByte(0)
+	linkInfo.Write(localBasePathUnicode)
+	linkInfo.Write([]byte{0, 0})
+	return linkInfo.Bytes()
+}
+
+// shellLink returns a Windows shortcut that runs target with the given arguments from
+// workingDir, with a description and an icon.
+func shellLink(target string, arguments string, workingDir string, description string, iconLocation string) []byte {
+	var link bytes.Buffer
+
+	// The header
+	flags := uint32(shellLinkHasLinkInfo | shellLinkHasName | shellLinkHasWorkingDir | shellLinkHasArguments | shellLinkIsUnicode)
+	if iconLocation != "" {
+		flags |= shellLinkHasIconLocation
+	}
+	binary.Write(&link, binary.LittleEndian, uint32(shellLinkHeaderSize))
+	link.Write(shellLinkCLSID[:])
+	binary.Write(&link, binary.LittleEndian, []uint32{flags, shellLinkFileAttributeFile})
+	link.Write(make([]byte, 24)) // creation, access and write times
+	binary.Write(&link, binary.LittleEndian, []uint32{0, 0, shellLinkShowNormal})
+	link.Write(make([]byte, 12)) // hot key and reserved fields
+
+	// Where the target is
+	link.Write(shellLinkInfo(target))
+
+	// The strings, in the order the flags are in
+	linkStrings := []string{description, workingDir, arguments}
+	if iconLocation != "" {
+		linkStrings = append(linkStrings, iconLocation)
+	}
+	for _, s := range linkStrings {
+		unicode := shellLinkUnicode(s)
+		binary.Write(&link, binary.LittleEndian, uint16(len(unicode)/2))
+		link.Write(unicode)
+	}
+
+	// No extra data blocks
+	binary.Write(&link, binary.LittleEndian, uint32(0))
+	return link.Bytes()
+}
+
+// defaultShortcutDirectory returns the current user's Start Menu folder for the games.
+func defaultShortcutDirectory() (string, error) {
+	appData := os.Getenv("APPDATA")
+	if appData == "" {
+		return "", fmt.Errorf("could not find the start menu; use --output to say where the shortcuts go")
+	}
+	return filepath.Join(appData, "Microsoft", "Windows", "Start Menu", "Programs", "ScummVM Games"), nil
+}
+
+// exportShortcuts writes a Windows shortcut for each game that starts it in scummvm.
+// They go in a ScummVM Games folder in the Start Menu unless --output gives another
+// directory.
+func exportShortcuts(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputDirectory := options.OutputFile
+	if outputDirectory == "" {
+		var err error
+		if outputDirectory, err = defaultShortcutDirectory(); err != nil {
+			return err
+		}
+	}
+
+	// A shortcut needs the full path to scummvm.exe
+	scummvmBinary := exportScummvmCommand(options)
+	if !filepath.IsAbs(scummvmBinary.Path) {
+		return fmt.Errorf("could not find scummvm; use --scummvm to say where scummvm.exe is")
+	}
+
+	// Create the directory
+	if err := options.Journal.MkdirAll(outputDirectory, 0755); err != nil {
+		return err
+	}
+
+	for _, scummGameMatch := range scummGameMatches {
+		// Shortcuts can only take their icon from an .ico or a program, so they all use
+		// scummvm's own
+		link := shellLink(
+			scummvmBinary.Path,
+			joinLaunchArguments(scummvmLaunchArguments(scummvmBinary, scummGameMatch, options)),
+			absoluteDirectory(scummGameMatch.Directory),
+			scummGameMatch.Description,
+			scummvmBinary.Path,
+		)
+		linkFile := filepath.Join(outputDirectory, output.SanitizeMarkerName(gameArtName(scummGameMatch))+".lnk")
+		if err := options.Journal.WriteFile(linkFile, link, 0644); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Wrote %d shortcuts to %s\n", len(scummGameMatches), outputDirectory)
+	return nil
+}
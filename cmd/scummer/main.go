@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// This is an app that takes the location of the scummvm binary file and the location
+// of the scummvm data files that have been already unzipped into directories.
+// The app gets a list of the directories, and runs each one through the scummvm binary
+// using the "--detect" command line option. The output of the scummvm binary is then
+// parsed to get the GameID. The GameID is then used to generate a .scummvm text file
+// that contains just the GameID. The .scummvm file is then placed in the directory
+// that contains all of the scummvm data file directories.
+
+// There are several possibilities for the output of the scummvm binary. First, its
+// possible that it can't find any games at the location given. Second, its possible
+// that it can find a game and returns its GameID. Third, its possible that it can
+// find a game, but it is not sure of what it is, so it returns a list of possible
+// GameIDs. The app will handle each of these cases. In the third case, the app will
+// stem the Description and directory name of each GameID and then compare the stemmed
+// Description and directory name to see if they are similar using Levenshtein distance.
+// If the stemmed Description and directory name are similar enough, then the app will
+// use that GameID. If the stemmed Description and directory name are not similar
+// enough, then the app will print out the GameID and the Description and directory
+// name and ask the user to choose which one to use. The app will then use the chosen
+// GameID. Finally, scummvm can be executed with the "--version" command line option
+// to get the version of scummvm. The app will use this output as a sanity check to
+// make sure that the scummvm binary can be used.
+
+func main() {
+	// Keep everything next to the executable in portable mode
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "--portable" || args[0] == "-portable") {
+		args = args[1:]
+		if err := enablePortableMode(); err != nil {
+			fmt.Println(err)
+			return
+		}
+	} else if portableMarked() {
+		if err := enablePortableMode(); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	// Check if we were given a command, otherwise default to scanning
+	command := "scan"
+	if len(args) > 0 {
+		switch args[0] {
+		case "scan", "review", "apply", "serve", "export", "schema", "watch", "import-gog", "import-steam", "fetch-freeware", "undo", "verify", "check", "saves":
+			command = args[0]
+			args = args[1:]
+		}
+	}
+
+	// Run the command
+	switch command {
+	case "scan":
+		// Exit with the scan's status once it has cleaned up after itself
+		if exitStatus := runScan(args); exitStatus != 0 {
+			os.Exit(exitStatus)
+		}
+	case "review":
+		runReview(args)
+	case "apply":
+		runApply(args)
+	case "serve":
+		runServe(args)
+	case "export":
+		runExport(args)
+	case "schema":
+		runSchema(args)
+	case "watch":
+		runWatch(args)
+	case "import-gog":
+		runImportGOG(args)
+	case "import-steam":
+		runImportSteam(args)
+	case "fetch-freeware":
+		runFetchFreeware(args)
+	case "undo":
+		runUndo(args)
+	case "verify":
+		runVerify(args)
+	case "check":
+		runCheck(args)
+	case "saves":
+		runSaves(args)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/furui/scummer"
+	"github.com/furui/scummer/match"
+)
+
+// detectionDurationBuckets are the upper bounds, in seconds, of the buckets of the
+// detection duration histogram. Most directories take well under a second, but scummvm
+// can take much longer over a slow network share.
+var detectionDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// serveMetrics counts what the scans started from the server did, for /metrics.
+type serveMetrics struct {
+	mutex sync.Mutex
+
+	// directoriesScanned counts the scanned directories by result: detected, failed or
+	// skipped. failures counts the failed ones by ErrorKind.
+	directoriesScanned map[string]uint64
+	failures           map[string]uint64
+
+	// detectionDurationBuckets counts the detections that took up to each of the
+	// detectionDurationBuckets, cumulatively, the way Prometheus histograms do.
+	detectionDurationBuckets []uint64
+	detectionDurationSum     float64
+	detectionDurationCount   uint64
+}
+
+// newServeMetrics returns metrics that haven't counted anything yet.
+func newServeMetrics() *serveMetrics {
+	return &serveMetrics{
+		directoriesScanned:       make(map[string]uint64),
+		failures:                 make(map[string]uint64),
+		detectionDurationBuckets: make([]uint64, len(detectionDurationBuckets)),
+	}
+}
+
+// observe counts the result of scanning a directory, and how long it took.
+func (m *serveMetrics) observe(scummGameMatch match.ScummGameMatch, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	switch scummGameMatch.ErrorKind {
+	case "":
+		m.directoriesScanned["detected"]++
+	case match.ErrorKindSkipped:
+		m.directoriesScanned["skipped"]++
+	default:
+		m.directoriesScanned["failed"]++
+		m.failures[scummGameMatch.ErrorKind]++
+	}
+
+	seconds := duration.Seconds()
+	for i, bucket := range detectionDurationBuckets {
+		if seconds <= bucket {
+			m.detectionDurationBuckets[i]++
+		}
+	}
+	m.detectionDurationSum += seconds
+	m.detectionDurationCount++
+}
+
+// write writes the metrics in the Prometheus text format.
+func (m *serveMetrics) write(w io.Writer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	fmt.Fprintln(w, "# HELP scummer_directories_scanned_total Directories scanned by the server, by result.")
+	fmt.Fprintln(w, "# TYPE scummer_directories_scanned_total counter")
+	for _, result := range []string{"detected", "failed", "skipped"} {
+		fmt.Fprintf(w, "scummer_directories_scanned_total{result=%q} %d\n", result, m.directoriesScanned[result])
+	}
+
+	fmt.Fprintln(w, "# HELP scummer_scan_failures_total Directories the server failed to detect a game in, by error kind.")
+	fmt.Fprintln(w, "# TYPE scummer_scan_failures_total counter")
+	for _, errorKind := range sortedKeys(m.failures) {
+		fmt.Fprintf(w, "scummer_scan_failures_total{kind=%q} %d\n", errorKind, m.failures[errorKind])
+	}
+
+	fmt.Fprintln(w, "# HELP scummer_detection_duration_seconds How long scanning a directory took.")
+	fmt.Fprintln(w, "# TYPE scummer_detection_duration_seconds histogram")
+	for i, bucket := range detectionDurationBuckets {
+		fmt.Fprintf(w, "scummer_detection_duration_seconds_bucket{le=\"%g\"} %d\n", bucket, m.detectionDurationBuckets[i])
+	}
+	fmt.Fprintf(w, "scummer_detection_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.detectionDurationCount)
+	fmt.Fprintf(w, "scummer_detection_duration_seconds_sum %g\n", m.detectionDurationSum)
+	fmt.Fprintf(w, "scummer_detection_duration_seconds_count %d\n", m.detectionDurationCount)
+}
+
+// sortedKeys returns the keys of a map of counts in order, so the metrics come out the
+// same way every time.
+func sortedKeys(counts map[string]uint64) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// scanDirectory scans a directory with a scanner, counting the result in the metrics.
+func (s *reviewServer) scanDirectory(ctx context.Context, scanner *scummer.Scanner, directory string) (match.ScummGameMatch, error) {
+	started := time.Now()
+	scummGameMatch, err := scanner.ScanDirectory(ctx, directory)
+	if err != nil {
+		return scummGameMatch, err
+	}
+	s.metrics.observe(scummGameMatch, time.Since(started))
+	return scummGameMatch, nil
+}
+
+// handleMetrics serves the metrics of the scans started from the server, along with
+// the state of the library as of the last scan, for Prometheus to scrape.
+func (s *reviewServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	scummvmOutputSlice, scummvmOutputErrorSlice, err := s.lockedResults()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	// The state of the library, as of the last scan
+	summary := newScanSummary(scummvmOutputSlice, scummvmOutputErrorSlice, 0, nil)
+	fmt.Fprintln(w, "# HELP scummer_library_games Games found in the library.")
+	fmt.Fprintln(w, "# TYPE scummer_library_games gauge")
+	fmt.Fprintf(w, "scummer_library_games %d\n", summary.Detected)
+	fmt.Fprintln(w, "# HELP scummer_library_ambiguous_games Games scummvm found more than one candidate for.")
+	fmt.Fprintln(w, "# TYPE scummer_library_ambiguous_games gauge")
+	fmt.Fprintf(w, "scummer_library_ambiguous_games %d\n", summary.Ambiguous)
+	fmt.Fprintln(w, "# HELP scummer_library_engine_games Games found in the library, by engine.")
+	fmt.Fprintln(w, "# TYPE scummer_library_engine_games gauge")
+	engineGames := make(map[string]uint64)
+	for engine, games := range summary.Engines {
+		engineGames[engine] = uint64(games)
+	}
+	for _, engine := range sortedKeys(engineGames) {
+		fmt.Fprintf(w, "scummer_library_engine_games{engine=%q} %d\n", engine, engineGames[engine])
+	}
+	fmt.Fprintln(w, "# HELP scummer_library_failures Directories of the library no game was found in, by error kind.")
+	fmt.Fprintln(w, "# TYPE scummer_library_failures gauge")
+	failures := make(map[string]uint64)
+	for _, scummGameMatch := range scummvmOutputErrorSlice {
+		failures[scummGameMatch.ErrorKind]++
+	}
+	for _, errorKind := range sortedKeys(failures) {
+		fmt.Fprintf(w, "scummer_library_failures{kind=%q} %d\n", errorKind, failures[errorKind])
+	}
+	if info, err := os.Stat(s.successFile); err == nil {
+		fmt.Fprintln(w, "# HELP scummer_last_scan_timestamp_seconds When the results were last written.")
+		fmt.Fprintln(w, "# TYPE scummer_last_scan_timestamp_seconds gauge")
+		fmt.Fprintf(w, "scummer_last_scan_timestamp_seconds %d\n", info.ModTime().Unix())
+	}
+
+	// What the scans started from the server did
+	s.metrics.write(w)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/furui/scummer/journal"
+)
+
+// The ways --nested can handle the game directories whose files are a level or more
+// further down, where scummvm doesn't look for them.
+const (
+	// nestedReport points them out, and says so in error.json when no game is found.
+	nestedReport = "report"
+
+	// nestedFlatten moves their files up into the game directory before it is scanned.
+	nestedFlatten = "flatten"
+
+	// nestedPrompt asks about each of them whether to flatten it.
+	nestedPrompt = "prompt"
+)
+
+// discDirectoryMatcher matches the names of the directories the discs of a game are
+// copied into, such as "CD1", "Disc 2" or "DISK_3".
+var discDirectoryMatcher = regexp.MustCompile(`(?i)^(?:disc|disk|cd)[\s._-]*\d+$`)
+
+// flatteningDirectoryName is the name the directory a game's files are nested in is
+// moved to while they are moved up out of it, in case one of them has the same name.
+const flatteningDirectoryName = ".scummer-flattening"
+
+// nesting is how the files of a game directory are nested further down in it.
+type nesting struct {
+	directory string
+
+	// inner is the directory the files are in, for a game whose files are in a single
+	// directory, such as Game/GAME, or Game/GAME/DATA.
+	inner string
+
+	// discs are the directories of the discs, for a game whose discs were each copied
+	// into a directory of their own, such as Game/CD1 and Game/CD2.
+	discs []string
+}
+
+// subdirectories returns the directories the files are in, relative to the game
+// directory, with forward slashes.
+func (n nesting) subdirectories() []string {
+	directories := n.discs
+	if n.inner != "" {
+		directories = []string{n.inner}
+	}
+	subdirectories := make([]string, 0, len(directories))
+	for _, directory := range directories {
+		relativePath, err := filepath.Rel(n.directory, directory)
+		if err != nil {
+			relativePath = directory
+		}
+		subdirectories = append(subdirectories, filepath.ToSlash(relativePath))
+	}
+	return subdirectories
+}
+
+// describe says where the files of the game are.
+func (n nesting) describe() string {
+	if n.inner != "" {
+		return fmt.Sprintf("has its files in %s", n.subdirectories()[0])
+	}
+	return fmt.Sprintf("has its discs in %s", strings.Join(n.subdirectories(), ", "))
+}
+
+// onlySubdirectories returns the directories in a directory, as long as there is
+// nothing else in it but files that aren't part of a game, such as a readme.
+func onlySubdirectories(directory string) ([]string, bool) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, false
+	}
+	subdirectories := make([]string, 0)
+	for _, entry := range entries {
+		switch {
+		case entry.IsDir():
+			subdirectories = append(subdirectories, entry.Name())
+		case isExtraFile(entry.Name()), strings.HasPrefix(entry.Name(), "."):
+		default:
+			return nil, false
+		}
+	}
+	return subdirectories, len(subdirectories) > 0
+}
+
+// findNesting returns how the files of a game directory are nested further down in it,
+// if they are: all in a single directory, and maybe a single directory in that, or in a
+// directory for each disc. A game directory with files of the game at the top isn't
+// nested, whatever else is in it.
+func findNesting(directory string) (nesting, bool) {
+	subdirectories, ok := onlySubdirectories(directory)
+	if !ok {
+		return nesting{}, false
+	}
+
+	// The files are all in one directory, which may be the only thing in another
+	if len(subdirectories) == 1 {
+		inner := filepath.Join(directory, subdirectories[0])
+		for {
+			entries, err := os.ReadDir(inner)
+			if err != nil || len(entries) == 0 {
+				return nesting{}, false
+			}
+			if len(entries) != 1 || !entries[0].IsDir() {
+				break
+			}
+			inner = filepath.Join(inner, entries[0].Name())
+		}
+		return nesting{directory: directory, inner: inner}, true
+	}
+
+	// Or each disc is in a directory of its own
+	discs := make([]string, 0, len(subdirectories))
+	for _, subdirectory := range subdirectories {
+		if !discDirectoryMatcher.MatchString(subdirectory) {
+			return nesting{}, false
+		}
+		discs = append(discs, filepath.Join(directory, subdirectory))
+	}
+	return nesting{directory: directory, discs: discs}, true
+}
+
+// flattenNesting moves the files of a nested game directory up into it, and removes the
+// directories they were in, recording the moves in the journal. The files of every disc
+// go into the game directory together, the way ScummVM wants the discs of most games,
+// so the discs can't have files of the same name; the ones that do are left for the
+// user to merge, since ScummVM needs them renamed in a way that depends on the game.
+// Nothing is moved unless everything can be.
+func flattenNesting(n nesting, changeJournal *journal.Journal) error {
+	sources := n.discs
+	if n.inner != "" {
+		sources = []string{n.inner}
+	}
+
+	// Make sure no two files would end up with the same name, without regard to case
+	// since the library may be on a filesystem that ignores it
+	taken := make(map[string]string)
+	topEntries, err := os.ReadDir(n.directory)
+	if err != nil {
+		return err
+	}
+	for _, entry := range topEntries {
+		if n.inner == "" || !entry.IsDir() {
+			taken[strings.ToLower(entry.Name())] = filepath.Base(n.directory)
+		}
+	}
+	for _, source := range sources {
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if takenBy, ok := taken[strings.ToLower(entry.Name())]; ok {
+				return fmt.Errorf("can't flatten %s: %s and %s both have %s in them", n.directory, takenBy, filepath.Base(source), entry.Name())
+			}
+			taken[strings.ToLower(entry.Name())] = filepath.Base(source)
+		}
+	}
+
+	// Move a single directory out of the way first, in case something in it has the
+	// name of a directory it is in, and remove the directories it was in
+	if n.inner != "" {
+		flatteningDirectory := filepath.Join(n.directory, flatteningDirectoryName)
+		if _, err := os.Lstat(flatteningDirectory); !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("can't flatten %s: %s is in the way", n.directory, flatteningDirectory)
+		}
+		if err := os.Rename(n.inner, flatteningDirectory); err != nil {
+			return err
+		}
+		if err := changeJournal.Renamed(n.inner, flatteningDirectory); err != nil {
+			return err
+		}
+		for directory := filepath.Dir(n.inner); directory != n.directory; directory = filepath.Dir(directory) {
+			if err := os.Remove(directory); err != nil {
+				return err
+			}
+		}
+		sources = []string{flatteningDirectory}
+	}
+
+	// Move the files up, and remove the directories they were in once they are empty
+	for _, source := range sources {
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			from := filepath.Join(source, entry.Name())
+			to := filepath.Join(n.directory, entry.Name())
+			if err := os.Rename(from, to); err != nil {
+				return err
+			}
+			if err := changeJournal.Renamed(from, to); err != nil {
+				return err
+			}
+		}
+		if err := os.Remove(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareNestedDirectories finds the game directories of the library whose files are
+// nested further down in them, and flattens them the way mode says, or only shows what
+// would be flattened with noWrite. It returns how the directories that are still nested
+// are nested, by their path in the library, to be pointed out if no game is found in
+// them.
+func prepareNestedDirectories(library string, directories []string, mode string, noWrite bool, changeJournal *journal.Journal) map[string]nesting {
+	nestedDirectories := make(map[string]nesting)
+	for _, directoryName := range directories {
+		directory := filepath.Join(library, directoryName)
+		n, ok := findNesting(directory)
+		if !ok {
+			continue
+		}
+
+		// Flatten it if we were told to
+		flatten := mode == nestedFlatten
+		if mode == nestedPrompt && isInteractive() {
+			flatten = promptYesNo(fmt.Sprintf("%s %s, move them up into it?", directory, n.describe()))
+		}
+		if !flatten {
+			if mode == nestedReport {
+				fmt.Printf("⚠️  %s %s, where scummvm doesn't look for them; --nested flatten moves them up\n", directory, n.describe())
+			}
+			nestedDirectories[directory] = n
+			continue
+		}
+		if noWrite {
+			fmt.Printf("Would flatten %s, which %s\n", directory, n.describe())
+			nestedDirectories[directory] = n
+			continue
+		}
+		fmt.Printf("Flattening %s, which %s... ", directory, n.describe())
+		if err := flattenNesting(n, changeJournal); err != nil {
+			fmt.Println(err)
+			nestedDirectories[directory] = n
+			continue
+		}
+		fmt.Printf("✅\n")
+	}
+	return nestedDirectories
+}
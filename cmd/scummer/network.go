@@ -0,0 +1,29 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/furui/scummer/match"
+)
+
+// slowListingNotice is how long listing the library can take before the user is told
+// that it is still going.
+const slowListingNotice = 5 * time.Second
+
+// takeRecheckedFailures returns the directories of the library whose failures are worth
+// scanning once more, along with the failures that aren't. Directories that were
+// skipped, or whose matches weren't close enough, would only fail the same way again.
+func takeRecheckedFailures(errorSlice []match.ScummGameMatch, library string) ([]string, []match.ScummGameMatch) {
+	directories := make([]string, 0)
+	remaining := make([]match.ScummGameMatch, 0, len(errorSlice))
+	for _, scummGameMatch := range errorSlice {
+		relativePath, err := filepath.Rel(library, scummGameMatch.Directory)
+		if err != nil || scummGameMatch.ErrorKind == match.ErrorKindSkipped || scummGameMatch.ErrorKind == match.ErrorKindLowConfidence {
+			remaining = append(remaining, scummGameMatch)
+			continue
+		}
+		directories = append(directories, relativePath)
+	}
+	return directories, remaining
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// notifyService tells systemd how the service is doing, such as "READY=1", when it was
+// started by a unit with Type=notify. It does nothing otherwise.
+func notifyService(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+
+	// A socket starting with @ is in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte(state))
+}
+
+// serviceWatchdogInterval returns how often systemd's watchdog needs to hear from the
+// service, which is half of WatchdogSec= to be on the safe side, or 0 if there is no
+// watchdog.
+func serviceWatchdogInterval() time.Duration {
+	// The watchdog may be meant for another process
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	microseconds, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || microseconds <= 0 {
+		return 0
+	}
+	return time.Duration(microseconds) * time.Microsecond / 2
+}
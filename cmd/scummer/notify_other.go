@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import (
+	"time"
+)
+
+// notifyService does nothing, since only Linux has systemd.
+func notifyService(state string) {}
+
+// serviceWatchdogInterval returns 0, since only Linux has systemd's watchdog.
+func serviceWatchdogInterval() time.Duration {
+	return 0
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/furui/scummer/match"
+)
+
+// pickerVisibleEntries is how many games the picker shows at once.
+const pickerVisibleEntries = 10
+
+// pickerEntry is a game that can be picked.
+type pickerEntry struct {
+	GameID string
+	Title  string
+	score  int
+}
+
+// pickerModel is the bubbletea model for the game picker. It narrows down the list of
+// every known game as the user types, in the style of fzf.
+type pickerModel struct {
+	entries []pickerEntry
+	matches []pickerEntry
+	query   string
+	cursor  int
+
+	// chosen is the GameID the user picked, if they picked one.
+	chosen string
+}
+
+// fuzzyTermScore scores how well a single search term matches some text. Every
+// character of the term has to appear in the text in order, and characters that follow
+// each other or start a word score higher.
+func fuzzyTermScore(term []rune, text []rune) (int, bool) {
+	score := 0
+	termIndex := 0
+	lastMatch := -2
+	for textIndex, r := range text {
+		if termIndex == len(term) {
+			break
+		}
+		if r != term[termIndex] {
+			continue
+		}
+		score++
+		if lastMatch == textIndex-1 {
+			score += 2
+		}
+		if textIndex == 0 || strings.ContainsRune(" :-_", text[textIndex-1]) {
+			score += 3
+		}
+		lastMatch = textIndex
+		termIndex++
+	}
+	return score, termIndex == len(term)
+}
+
+// fuzzyMatchScore scores how well a query matches some text. The query is split into
+// terms at spaces, and every term has to match.
+func fuzzyMatchScore(query string, text string) (int, bool) {
+	lowerText := []rune(strings.ToLower(text))
+	score := 0
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		termScore, ok := fuzzyTermScore([]rune(term), lowerText)
+		if !ok {
+			return 0, false
+		}
+		score += termScore
+	}
+	return score, true
+}
+
+// newPickerModel creates the picker for the given games.
+func newPickerModel(gameTitles map[string]string) *pickerModel {
+	model := &pickerModel{}
+	for gameID, title := range gameTitles {
+		model.entries = append(model.entries, pickerEntry{GameID: gameID, Title: title})
+	}
+	sort.Slice(model.entries, func(i, j int) bool {
+		return model.entries[i].GameID < model.entries[j].GameID
+	})
+	model.filter()
+	return model
+}
+
+// filter narrows down the games to the ones that match the query, best first.
+func (m *pickerModel) filter() {
+	m.matches = m.matches[:0]
+	for _, entry := range m.entries {
+		score, ok := fuzzyMatchScore(m.query, entry.GameID+" "+entry.Title)
+		if !ok {
+			continue
+		}
+		entry.score = score
+		m.matches = append(m.matches, entry)
+	}
+	sort.SliceStable(m.matches, func(i, j int) bool {
+		return m.matches[i].score > m.matches[j].score
+	})
+	m.cursor = 0
+}
+
+func (m *pickerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch keyMsg.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		// Leave without picking anything
+		return m, tea.Quit
+	case tea.KeyEnter:
+		// Pick the highlighted game
+		if len(m.matches) > 0 {
+			m.chosen = m.matches[m.cursor].GameID
+		}
+		return m, tea.Quit
+	case tea.KeyUp, tea.KeyCtrlP:
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case tea.KeyDown, tea.KeyCtrlN:
+		if m.cursor < len(m.matches)-1 && m.cursor < pickerVisibleEntries-1 {
+			m.cursor++
+		}
+	case tea.KeyBackspace:
+		if len(m.query) > 0 {
+			queryRunes := []rune(m.query)
+			m.query = string(queryRunes[:len(queryRunes)-1])
+			m.filter()
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.query += string(keyMsg.Runes)
+		m.filter()
+	}
+
+	return m, nil
+}
+
+func (m *pickerModel) View() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "> %s\n", m.query)
+	fmt.Fprintf(&b, "  %d/%d\n", len(m.matches), len(m.entries))
+	for i, entry := range m.matches {
+		if i == pickerVisibleEntries {
+			break
+		}
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+		fmt.Fprintf(&b, "%s%-30s %s\n", cursor, entry.GameID, entry.Title)
+	}
+
+	b.WriteString("\ntype to search • ↑/↓ move • enter pick • esc cancel\n")
+	return b.String()
+}
+
+// pickScummGame lets the user search every known game for the one in a directory. It
+// returns the GameID that was picked, or false if the user cancelled.
+func pickScummGame(gameTitles map[string]string) (string, bool) {
+	model := newPickerModel(gameTitles)
+	if _, err := tea.NewProgram(model).Run(); err != nil {
+		fmt.Println(err)
+		return "", false
+	}
+	return model.chosen, model.chosen != ""
+}
+
+// pickedCandidateIndex returns the index of the candidate with the GameID the user
+// picked. A game that scummvm didn't suggest is added to the candidates, using its full
+// title as the Description.
+func pickedCandidateIndex(candidates []match.ScummGameMatch, gameID string, gameTitles map[string]string) ([]match.ScummGameMatch, int) {
+	if index := answeredCandidateIndex(candidates, gameID); index >= 0 {
+		return candidates, index
+	}
+
+	title, _ := match.GameTitle(gameTitles, gameID)
+	candidates = append(candidates, match.ScummGameMatch{GameID: gameID, Description: title, Directory: candidates[0].Directory})
+	return candidates, len(candidates) - 1
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/furui/scummer/match"
+)
+
+// playniteFileName is where the Playnite import is written unless --output says
+// otherwise.
+const playniteFileName = "playnite.json"
+
+// playniteGameAction is a way of starting a game in Playnite, named the way Playnite's
+// own game database names its fields.
+type playniteGameAction struct {
+	Name         string
+	Type         string
+	Path         string
+	Arguments    string
+	WorkingDir   string
+	IsPlayAction bool
+}
+
+// playniteGame is a game in the Playnite import.
+type playniteGame struct {
+	Name             string
+	GameId           string
+	Source           string
+	InstallDirectory string
+	IsInstalled      bool
+	GameActions      []playniteGameAction
+}
+
+// exportPlaynite writes a Playnite import listing every game with its install directory
+// and a play action that starts it in scummvm by its GameID.
+func exportPlaynite(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputFile := options.OutputFile
+	if outputFile == "" {
+		outputFile = playniteFileName
+	}
+
+	// Every game is started by the same scummvm
+	scummvmBinary := exportScummvmCommand(options)
+
+	games := make([]playniteGame, 0, len(scummGameMatches))
+	for _, scummGameMatch := range scummGameMatches {
+		// Playnite needs the full path to the game
+		installDirectory, err := filepath.Abs(scummGameMatch.Directory)
+		if err != nil {
+			return err
+		}
+
+		games = append(games, playniteGame{
+			Name:             match.ParseDescriptionVariant(scummGameMatch.Description).Title,
+			GameId:           scummGameMatch.GameID,
+			Source:           "ScummVM",
+			InstallDirectory: installDirectory,
+			IsInstalled:      true,
+			GameActions: []playniteGameAction{{
+				Name:         "Play",
+				Type:         "File",
+				Path:         scummvmBinary.Path,
+				Arguments:    joinLaunchArguments(scummvmLaunchArguments(scummvmBinary, scummGameMatch, options)),
+				WorkingDir:   installDirectory,
+				IsPlayAction: true,
+			}},
+		})
+	}
+
+	gamesJSON, err := json.MarshalIndent(games, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := options.Journal.WriteFile(outputFile, append(gamesJSON, '\n'), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d games to %s\n", len(games), outputFile)
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// exporterPlugin is an external command that exports the results of a scan to a format
+// scummer doesn't know about. It is given the results as JSON on its standard input.
+type exporterPlugin struct {
+	// Name is the name of the format, for "scummer export --format".
+	Name string `yaml:"name"`
+
+	// Command is the program to run, followed by its arguments.
+	Command []string `yaml:"command"`
+}
+
+// exporterPluginInput is what an exporter plugin is given on its standard input.
+type exporterPluginInput struct {
+	SchemaVersion int                    `json:"schema_version"`
+	Library       string                 `json:"Library"`
+	Games         []match.ScummGameMatch `json:"Games"`
+	Errors        []match.ScummGameMatch `json:"Errors"`
+}
+
+// checkExporterPlugins makes sure every plugin has a name of its own and a command.
+func checkExporterPlugins(plugins []exporterPlugin) error {
+	names := make(map[string]bool)
+	for _, plugin := range plugins {
+		if plugin.Name == "" {
+			return fmt.Errorf("every exporter needs a name")
+		}
+		if _, ok := scummGameExporters[plugin.Name]; ok || names[plugin.Name] {
+			return fmt.Errorf("exporter %q has the same name as another format", plugin.Name)
+		}
+		if len(plugin.Command) == 0 {
+			return fmt.Errorf("exporter %q has no command", plugin.Name)
+		}
+		names[plugin.Name] = true
+	}
+	return nil
+}
+
+// findExporterPlugin returns the plugin with a name, if there is one.
+func findExporterPlugin(plugins []exporterPlugin, name string) (exporterPlugin, bool) {
+	for _, plugin := range plugins {
+		if plugin.Name == name {
+			return plugin, true
+		}
+	}
+	return exporterPlugin{}, false
+}
+
+// run runs the plugin, giving it the results on its standard input. What the plugin
+// prints goes straight to the console. The plugin is also told the library and the file
+// given with --output, if any, in the SCUMMER_LIBRARY and SCUMMER_OUTPUT environment
+// variables.
+func (p exporterPlugin) run(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	errorSlice := options.Errors
+	if errorSlice == nil {
+		errorSlice = make([]match.ScummGameMatch, 0)
+	}
+	library := libraryRoot(append(append([]match.ScummGameMatch{}, scummGameMatches...), errorSlice...))
+	inputJSON, err := json.Marshal(exporterPluginInput{SchemaVersion: output.SchemaVersion, Library: library, Games: scummGameMatches, Errors: errorSlice})
+	if err != nil {
+		return err
+	}
+
+	command := exec.Command(p.Command[0], p.Command[1:]...)
+	command.Stdin = bytes.NewReader(inputJSON)
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	command.Env = append(os.Environ(), "SCUMMER_FORMAT="+p.Name, "SCUMMER_LIBRARY="+library, "SCUMMER_OUTPUT="+options.OutputFile)
+	if err := command.Run(); err != nil {
+		return fmt.Errorf("exporter %s: %w", p.Name, err)
+	}
+	return nil
+}
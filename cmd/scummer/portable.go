@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// portableMarkerFile turns on portable mode when it is next to the executable, for
+// copies of scummer on a USB stick that are started without a command line.
+const portableMarkerFile = "scummer.portable"
+
+// portableDirectory is the directory of the executable in portable mode, where the
+// config file, the results and everything else scummer keeps go by default. It is empty
+// otherwise, and they go in the current directory.
+var portableDirectory string
+
+// enablePortableMode keeps everything next to the executable from now on.
+func enablePortableMode() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return err
+	}
+	portableDirectory = filepath.Dir(executable)
+	return nil
+}
+
+// portableMarked returns whether the portable marker file is next to the executable.
+func portableMarked() bool {
+	executable, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(filepath.Dir(executable), portableMarkerFile))
+	return err == nil
+}
+
+// stateFile returns where a file scummer keeps, such as success.json, goes by default:
+// next to the executable in portable mode, and in the current directory otherwise.
+func stateFile(name string) string {
+	if portableDirectory == "" {
+		return name
+	}
+	return filepath.Join(portableDirectory, name)
+}
+
+// portableScummvm returns the scummvm next to the executable in portable mode, if there
+// is one, so that a USB stick with both on it works wherever it is plugged in.
+func portableScummvm() string {
+	if portableDirectory == "" {
+		return ""
+	}
+	scummvmNames := []string{"scummvm"}
+	if runtime.GOOS == "windows" {
+		scummvmNames = []string{"scummvm-console.exe", "scummvm.exe"}
+	}
+	for _, scummvmName := range scummvmNames {
+		scummvmPath := filepath.Join(portableDirectory, scummvmName)
+		if f, err := os.Stat(scummvmPath); err == nil && !f.IsDir() {
+			return scummvmPath
+		}
+	}
+	return ""
+}
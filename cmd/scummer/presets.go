@@ -0,0 +1,113 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/furui/scummer/output"
+)
+
+// scummerPreset sets scummer up for a particular frontend, so that the games it finds
+// show up there without being rearranged by hand.
+type scummerPreset struct {
+	// Layout is where the .scummvm files go.
+	Layout output.MarkerLayout
+
+	// Library is where the frontend keeps its scummvm games, which is scanned when no
+	// scummvm data file directory is given.
+	Library string
+
+	// Gamelist adds the games to the gamelist.xml in the library after the .scummvm
+	// files are written. Emulator and Core are written to each new game list entry.
+	Gamelist bool
+	Emulator string
+	Core     string
+
+	// GamelistFile is the name of the game list, gamelist.xml unless set.
+	GamelistFile string
+
+	// Image and Marquee are where each game's art goes, with %s standing for the name
+	// of its .scummvm file without the extension. A game list entry leaves out the
+	// ones that aren't set. Without a preset, both are under ./images/.
+	Image   string
+	Marquee string
+}
+
+// defaultPreset is how scummer lays things out without a preset.
+var defaultPreset = scummerPreset{
+	Layout:  output.DefaultMarkerLayout,
+	Image:   "./images/%s-image.png",
+	Marquee: "./images/%s-marquee.png",
+}
+
+// scummerPresets are the presets that can be chosen with --preset.
+var scummerPresets = map[string]scummerPreset{
+	// ES-DE expects the .scummvm file inside the game's directory, named after the
+	// directory. With --directory-as-game the directory is also renamed to end in
+	// .scummvm, which ES-DE shows as a single game.
+	"es-de": {Layout: output.MarkerLayout{Placement: output.MarkerPlacementInside}, Image: defaultPreset.Image, Marquee: defaultPreset.Marquee},
+
+	// Batocera and Recalbox expect each game in a directory named gamename.scummvm,
+	// with a gamename.scummvm file inside it, and list the games in gamelist.xml.
+	"batocera": {
+		Layout:   output.MarkerLayout{Placement: output.MarkerPlacementInside, DirectoryAsGame: true},
+		Library:  "/userdata/roms/scummvm",
+		Gamelist: true,
+		Emulator: "scummvm",
+		Core:     "scummvm",
+		Image:    defaultPreset.Image,
+		Marquee:  defaultPreset.Marquee,
+	},
+	"recalbox": {
+		Layout:   output.MarkerLayout{Placement: output.MarkerPlacementInside, DirectoryAsGame: true},
+		Library:  "/recalbox/share/roms/scummvm",
+		Gamelist: true,
+		Emulator: "libretro",
+		Core:     "scummvm",
+		Image:    defaultPreset.Image,
+		Marquee:  defaultPreset.Marquee,
+	},
+
+	// Onion OS on the Miyoo Mini launches .svm files next to the game directories that
+	// hold the game's short name, and lists the games in miyoogamelist.xml with their
+	// art in Imgs/.
+	"onion": {
+		Layout:       output.MarkerLayout{Placement: output.MarkerPlacementSibling, Extension: ".svm", Format: output.MarkerFormatBare},
+		Library:      "/mnt/SDCARD/Roms/SCUMMVM",
+		Gamelist:     true,
+		GamelistFile: "miyoogamelist.xml",
+		Image:        "./Imgs/%s.png",
+	},
+
+	// muOS expects the .scummvm file inside the game's directory, and can't launch
+	// files with some characters in their names.
+	"muos": {
+		Layout:  output.MarkerLayout{Placement: output.MarkerPlacementInside, SanitizeNames: true},
+		Library: "/mnt/mmc/ROMS/ScummVM",
+	},
+
+	// GarlicOS on the RG35XX launches .scummvm files next to the game directories, and
+	// shows art from Imgs/ named after them.
+	"garlic": {
+		Layout:  output.DefaultMarkerLayout,
+		Library: "/mnt/mmc/Roms/SCUMMVM",
+		Image:   "./Imgs/%s.png",
+	},
+}
+
+// gamelistFileName returns the name of the preset's game list.
+func (preset scummerPreset) gamelistFileName() string {
+	if preset.GamelistFile == "" {
+		return gamelistFileName
+	}
+	return preset.GamelistFile
+}
+
+// scummerPresetNames returns the names of the presets in alphabetical order.
+func scummerPresetNames() []string {
+	names := make([]string, 0, len(scummerPresets))
+	for name := range scummerPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/furui/scummer"
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// progressEventBuffer is how many events a client that is slow to read can fall behind
+// before it starts missing some.
+const progressEventBuffer = 64
+
+// progressKeepAlive is how often a comment is sent to clients while nothing happens, so
+// that proxies don't close the connection.
+const progressKeepAlive = 15 * time.Second
+
+// errScanRunning is returned when a scan is started while another one is running.
+var errScanRunning = errors.New("a scan is already running")
+
+// These are the kinds of progress events.
+const (
+	// progressEventStarted is sent when a scan starts, with the number of directories.
+	progressEventStarted = "started"
+
+	// progressEventDirectory is sent when a directory has been scanned.
+	progressEventDirectory = "directory"
+
+	// progressEventFinished is sent when a scan is over and its results are saved.
+	progressEventFinished = "finished"
+
+	// progressEventFailed is sent when a scan stopped before it was over.
+	progressEventFailed = "failed"
+)
+
+// progressEvent is something that happened during a scan started from the server.
+type progressEvent struct {
+	Event       string `json:"-"`
+	Library     string
+	Done        int
+	Total       int
+	Directory   string `json:",omitempty"`
+	Status      string `json:",omitempty"`
+	GameID      string `json:",omitempty"`
+	Description string `json:",omitempty"`
+	Error       string `json:",omitempty"`
+}
+
+// progressBroadcaster hands the progress events of scans to every client that is
+// listening.
+type progressBroadcaster struct {
+	mutex     sync.Mutex
+	listeners map[chan progressEvent]bool
+}
+
+// newProgressBroadcaster returns a broadcaster nobody is listening to yet.
+func newProgressBroadcaster() *progressBroadcaster {
+	return &progressBroadcaster{listeners: make(map[chan progressEvent]bool)}
+}
+
+// subscribe returns a channel the events are sent to until unsubscribe is called.
+func (b *progressBroadcaster) subscribe() chan progressEvent {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	events := make(chan progressEvent, progressEventBuffer)
+	b.listeners[events] = true
+	return events
+}
+
+// unsubscribe stops sending events to a channel.
+func (b *progressBroadcaster) unsubscribe(events chan progressEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.listeners, events)
+}
+
+// publish sends an event to every listener. A listener whose buffer is full misses it,
+// rather than holding up the scan.
+func (b *progressBroadcaster) publish(event progressEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for events := range b.listeners {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// libraryScan is a scan of a whole library started from the server.
+type libraryScan struct {
+	Library         string
+	Threshold       float64
+	OnLowConfidence string
+
+	// WriteMarkers writes the .scummvm files of the games that were found.
+	WriteMarkers bool
+
+	// Incremental only scans the directories that aren't in success.json yet.
+	Incremental bool
+}
+
+// scanLibrary scans a library, publishing the result of each directory as soon as it is
+// known and handing it to onDirectory, and then saves the results in place of the old
+// ones. Only one scan runs at a time.
+func (s *reviewServer) scanLibrary(ctx context.Context, scan libraryScan, onDirectory func(done int, total int, scummGameMatch match.ScummGameMatch) error) error {
+	if !s.scanning.CompareAndSwap(false, true) {
+		return errScanRunning
+	}
+	defer s.scanning.Store(false)
+	s.scans.Add(1)
+	defer s.scans.Done()
+
+	err := s.runLibraryScan(ctx, scan, onDirectory)
+	if err != nil {
+		s.progress.publish(progressEvent{Event: progressEventFailed, Library: scan.Library, Error: err.Error()})
+	}
+	return err
+}
+
+// runLibraryScan does the work of scanLibrary.
+func (s *reviewServer) runLibraryScan(ctx context.Context, scan libraryScan, onDirectory func(done int, total int, scummGameMatch match.ScummGameMatch) error) error {
+	if scan.Threshold == 0 {
+		scan.Threshold = s.threshold
+	}
+	scanner, err := scummer.NewScanner(scummer.Options{Scummvm: s.scummvmPath, Threshold: scan.Threshold, OnLowConfidence: scan.OnLowConfidence})
+	if err != nil {
+		return err
+	}
+
+	// Get a list of all the scummvm data file directories
+	directories, err := detect.GameDirectories(scan.Library, detect.ListingOptions{})
+	if err != nil {
+		return err
+	}
+
+	// The games that were already found keep their .scummvm files where they are
+	previousSlice, _, err := s.lockedResults()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	found := make(map[string]match.ScummGameMatch)
+	for _, scummGameMatch := range previousSlice {
+		found[scummGameMatch.Directory] = scummGameMatch
+	}
+
+	// An incremental scan keeps the games that were already found, and only scans the
+	// directories that are new or failed last time
+	scummvmOutputSlice := make([]match.ScummGameMatch, 0)
+	scummvmOutputErrorSlice := make([]match.ScummGameMatch, 0)
+	if scan.Incremental {
+		newDirectories := make([]string, 0)
+		for _, directory := range directories {
+			if scummGameMatch, ok := found[filepath.Join(scan.Library, directory)]; ok {
+				scummvmOutputSlice = append(scummvmOutputSlice, scummGameMatch)
+			} else {
+				newDirectories = append(newDirectories, directory)
+			}
+		}
+		directories = newDirectories
+	}
+	s.progress.publish(progressEvent{Event: progressEventStarted, Library: scan.Library, Total: len(directories)})
+
+	// Scan each directory, passing its result along
+	foundSlice := make([]match.ScummGameMatch, 0)
+	for i, directory := range directories {
+		scummGameMatch, err := s.scanDirectory(ctx, scanner, filepath.Join(scan.Library, directory))
+		if err != nil {
+			return err
+		}
+		if scummGameMatch.ErrorKind != "" {
+			scummvmOutputErrorSlice = append(scummvmOutputErrorSlice, scummGameMatch)
+		} else {
+			if previous, ok := found[scummGameMatch.Directory]; ok {
+				scummGameMatch = keepMarkerLayout(scummGameMatch, previous)
+			}
+			foundSlice = append(foundSlice, scummGameMatch)
+		}
+		s.progress.publish(progressEvent{
+			Event:       progressEventDirectory,
+			Library:     scan.Library,
+			Done:        i + 1,
+			Total:       len(directories),
+			Directory:   scummGameMatch.Directory,
+			Status:      scummGameMatchStatus(scummGameMatch),
+			GameID:      scummGameMatch.GameID,
+			Description: scummGameMatch.Description,
+		})
+		if onDirectory != nil {
+			if err := onDirectory(i+1, len(directories), scummGameMatch); err != nil {
+				return err
+			}
+		}
+	}
+	scummvmOutputSlice = append(scummvmOutputSlice, foundSlice...)
+
+	// Save the results in place of the old ones, and write the .scummvm files if asked to
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := output.WriteResults(s.successFile, scummvmOutputSlice); err != nil {
+		return err
+	}
+	if err := output.WriteResults(s.errorFile, scummvmOutputErrorSlice); err != nil {
+		return err
+	}
+	if scan.WriteMarkers {
+		fmt.Println("Writing entries out to .scummvm files...")
+		if err := output.WriteMarkerFiles(foundSlice, s.changeJournal, detect.Retry{}); err != nil {
+			return err
+		}
+	}
+	s.progress.publish(progressEvent{Event: progressEventFinished, Library: scan.Library, Done: len(directories), Total: len(directories)})
+	return nil
+}
+
+// handleEvents streams the progress of the scans started from the server as
+// Server-Sent Events, until the client goes away.
+func (s *reviewServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	events := s.progress.subscribe()
+	defer s.progress.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(progressKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.stopping.Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-events:
+			eventJSON, err := json.Marshal(event)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Event, eventJSON)
+		}
+		flusher.Flush()
+	}
+}
+
+// libraryToScan returns the library the scans started from the server scan: the one in
+// the config file, or else the one the results came from.
+func (s *reviewServer) libraryToScan() (string, error) {
+	if s.library != "" {
+		return s.library, nil
+	}
+	scummvmOutputSlice, scummvmOutputErrorSlice, err := s.lockedResults()
+	if err != nil {
+		return "", err
+	}
+	return libraryRoot(append(scummvmOutputSlice, scummvmOutputErrorSlice...)), nil
+}
+
+// handleScan starts a scan of the library in the background. Its progress can be
+// followed from /events.
+func (s *reviewServer) handleScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Scan the library from the config file, or the one the results came from
+	library, err := s.libraryToScan()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if s.scanning.Load() {
+		http.Error(w, errScanRunning.Error(), http.StatusConflict)
+		return
+	}
+
+	// The scan goes on after the request is over, so it runs until the server stops
+	// rather than until the request does
+	go func() {
+		if err := s.scanLibrary(s.stopping, libraryScan{Library: library, WriteMarkers: r.PostForm.Get("write_markers") != ""}, nil); err != nil && s.stopping.Err() == nil {
+			fmt.Println(err)
+		}
+	}()
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/furui/scummer/match"
+)
+
+// stdinReader is shared by every prompt so that buffered input isn't lost between them.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// isInteractive reports whether stdin is a terminal, so we know whether there is someone
+// around to answer a prompt.
+func isInteractive() bool {
+	stdinInfo, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stdinInfo.Mode()&os.ModeCharDevice != 0
+}
+
+// promptForScummGameMatch lists the candidates scummvm found for a directory and asks
+// the user to choose one. The user can enter the number of a candidate, "skip", nothing
+// at all to accept the suggested candidate, or "none" to search every known game for the
+// right one. It returns the GameID of the chosen game, or false if the user chose to
+// skip the directory.
+func promptForScummGameMatch(directory string, candidates []match.ScummGameMatch, suggestedIndex int, similarity float64, gameTitles map[string]string) (string, bool) {
+	fmt.Printf("\n  %s doesn't clearly match any of these games (best similarity %.2f):\n", directory, similarity)
+	for i, candidate := range candidates {
+		fmt.Printf("    %d) %-30s %.2f  %s\n", i+1, candidate.GameID, candidate.Similarity, candidate.Description)
+	}
+
+	for {
+		fmt.Printf("  Choose a number, \"none\" to search all games, or \"skip\" [%d]: ", suggestedIndex+1)
+
+		// Read the answer, treating the end of input as accepting the suggestion
+		answer, err := stdinReader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			if err != nil {
+				fmt.Println()
+			}
+			return candidates[suggestedIndex].GameID, true
+		}
+
+		// Check if the user wants to skip this directory
+		if strings.EqualFold(answer, "skip") || strings.EqualFold(answer, "s") {
+			return "", false
+		}
+
+		// Check if none of the candidates are right, and let the user search for the game
+		if strings.EqualFold(answer, "none") || strings.EqualFold(answer, "n") {
+			if gameID, ok := pickScummGame(gameTitles); ok {
+				return gameID, true
+			}
+			continue
+		}
+
+		// Otherwise it has to be the number of one of the candidates
+		choice, err := strconv.Atoi(answer)
+		if err != nil || choice < 1 || choice > len(candidates) {
+			fmt.Printf("  Please enter a number between 1 and %d, \"none\" or \"skip\".\n", len(candidates))
+			continue
+		}
+
+		return candidates[choice-1].GameID, true
+	}
+}
+
+// promptYesNo asks the user a question, which is only answered yes if they say so. An
+// empty answer, or stdin being closed, is no, since the questions are about moving or
+// unpacking things in the library.
+func promptYesNo(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, _ := stdinReader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
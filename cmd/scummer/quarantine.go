@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+)
+
+// The ways --quarantine can take the directories scummvm couldn't identify out of the
+// way.
+const (
+	// quarantineMove moves the directories into the quarantine directory.
+	quarantineMove = "move"
+
+	// quarantineSymlink leaves the directories where they are, and links to them from
+	// the quarantine directory.
+	quarantineSymlink = "symlink"
+)
+
+// quarantineDirectories puts the directories scummvm couldn't identify any game in into
+// the quarantine directory, creating it if it doesn't exist, so that they can be sorted
+// out later. A game left in its archive has the archive quarantined instead. Moved
+// directories are recorded in their results, which point at where they are now, and
+// everything is recorded in the journal. It returns the number of directories that were
+// quarantined.
+func quarantineDirectories(errorSlice []match.ScummGameMatch, quarantineDirectory string, mode string, changeJournal *journal.Journal) (int, error) {
+	if err := changeJournal.MkdirAll(quarantineDirectory, 0755); err != nil {
+		return 0, err
+	}
+
+	quarantined := 0
+	for i := range errorSlice {
+		if errorSlice[i].ErrorKind != match.ErrorKindDetection {
+			continue
+		}
+
+		source, name, extension := errorSlice[i].Directory, filepath.Base(errorSlice[i].Directory), ""
+		if errorSlice[i].Archive != "" {
+			source, name, extension = errorSlice[i].Archive, archiveDirectoryName(errorSlice[i].Archive), filepath.Ext(errorSlice[i].Archive)
+		}
+		absoluteDirectory, err := filepath.Abs(source)
+		if err != nil {
+			return quarantined, err
+		}
+
+		// Don't overwrite a directory that was quarantined before with the same name, and
+		// don't link to a directory again that is already linked to
+		destination := filepath.Join(quarantineDirectory, filepath.Base(source))
+		alreadyLinked := false
+		for n := 2; ; n++ {
+			if _, err := os.Lstat(destination); errors.Is(err, os.ErrNotExist) {
+				break
+			}
+			if target, err := os.Readlink(destination); err == nil && target == absoluteDirectory {
+				alreadyLinked = true
+				break
+			}
+			destination = filepath.Join(quarantineDirectory, name+" ("+strconv.Itoa(n)+")"+extension)
+		}
+		if alreadyLinked {
+			continue
+		}
+
+		switch mode {
+		case quarantineSymlink:
+			if err := os.Symlink(absoluteDirectory, destination); err != nil {
+				return quarantined, err
+			}
+			if err := changeJournal.Linked(destination, absoluteDirectory); err != nil {
+				return quarantined, err
+			}
+		default:
+			if err := moveDirectory(source, destination); err != nil {
+				return quarantined, err
+			}
+			if err := changeJournal.Renamed(source, destination); err != nil {
+				return quarantined, err
+			}
+			errorSlice[i].QuarantinedFrom = source
+			if errorSlice[i].Archive != "" {
+				errorSlice[i].Archive = destination
+				errorSlice[i].Directory = filepath.Join(quarantineDirectory, archiveDirectoryName(destination))
+			} else {
+				errorSlice[i].Directory = destination
+			}
+		}
+		quarantined++
+	}
+	return quarantined, nil
+}
+
+// moveDirectory moves a directory, copying it and removing the original if it is going
+// to another filesystem, which a rename can't do.
+func moveDirectory(source string, destination string) error {
+	err := os.Rename(source, destination)
+	if err == nil || !isCrossDeviceError(err) {
+		return err
+	}
+
+	if err := copyDirectory(source, destination); err != nil {
+		os.RemoveAll(destination)
+		return fmt.Errorf("moving %s to %s: %w", source, destination, err)
+	}
+	return os.RemoveAll(source)
+}
+
+// withoutQuarantineDirectory leaves the quarantine directory out of the directories of
+// the library, if it is one of them, so that what was put in it isn't scanned again.
+func withoutQuarantineDirectory(directories []string, library string, quarantineDirectory string) []string {
+	if quarantineDirectory == "" {
+		return directories
+	}
+	remaining := make([]string, 0, len(directories))
+	for _, directory := range directories {
+		if absoluteDirectory(filepath.Join(library, directory)) != absoluteDirectory(quarantineDirectory) {
+			remaining = append(remaining, directory)
+		}
+	}
+	return remaining
+}
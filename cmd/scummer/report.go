@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/furui/scummer/match"
+)
+
+// scanReport is what the html and markdown reports are made from.
+type scanReport struct {
+	GameCount       int
+	ErrorCount      int
+	AmbiguousCount  int
+	IncompleteCount int
+	Engines         []scanReportEngine
+	Ambiguous       []match.ScummGameMatch
+	Incomplete      []match.ScummGameMatch
+	Errors          []match.ScummGameMatch
+}
+
+// scanReportEngine is the games of one engine in a report.
+type scanReportEngine struct {
+	Engine string
+	Games  []match.ScummGameMatch
+}
+
+// newScanReport sums up the results of a scan. The games are grouped by engine, and the
+// ambiguous matches are the ones scummvm found more than one candidate for. The
+// incomplete games are the ones scummvm warned have files missing.
+func newScanReport(scummGameMatches []match.ScummGameMatch, errorSlice []match.ScummGameMatch) scanReport {
+	report := scanReport{GameCount: len(scummGameMatches), ErrorCount: len(errorSlice), Errors: errorSlice}
+
+	// Group the games by engine
+	engineGames := make(map[string][]match.ScummGameMatch)
+	for _, scummGameMatch := range scummGameMatches {
+		engine := match.CandidateEngine(scummGameMatch.GameID)
+		if engine == "" {
+			engine = "unknown"
+		}
+		engineGames[engine] = append(engineGames[engine], scummGameMatch)
+
+		if len(scummGameMatch.Candidates) > 1 {
+			report.Ambiguous = append(report.Ambiguous, scummGameMatch)
+		}
+		if scummGameMatch.Incomplete {
+			report.Incomplete = append(report.Incomplete, scummGameMatch)
+		}
+	}
+	for engine, games := range engineGames {
+		sort.Slice(games, func(i, j int) bool {
+			return games[i].Directory < games[j].Directory
+		})
+		report.Engines = append(report.Engines, scanReportEngine{Engine: engine, Games: games})
+	}
+	sort.Slice(report.Engines, func(i, j int) bool {
+		return report.Engines[i].Engine < report.Engines[j].Engine
+	})
+	report.AmbiguousCount = len(report.Ambiguous)
+	report.IncompleteCount = len(report.Incomplete)
+
+	return report
+}
+
+// markdownCell makes text safe to put in a markdown table cell.
+func markdownCell(text string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(text)
+}
+
+// markdownReportTemplate is the markdown report, for pasting into a forum post.
+var markdownReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{"cell": markdownCell}).Parse(`# scummer report
+
+- Games found: {{.GameCount}}
+- Ambiguous matches: {{.AmbiguousCount}}
+- Incomplete games: {{.IncompleteCount}}
+- Errors: {{.ErrorCount}}
+{{range .Engines}}
+## {{.Engine}} ({{len .Games}})
+
+| Directory | GameID | Description | match.Confidence |
+| --- | --- | --- | --- |
+{{range .Games}}| {{cell .Directory}} | {{cell .GameID}} | {{cell .Description}} | {{printf "%.2f" .Confidence}} |
+{{end}}{{end}}{{if .Ambiguous}}
+## Ambiguous matches
+{{range .Ambiguous}}
+### {{.Directory}}
+
+Chose {{.GameID}}{{if .ChosenBy}} ({{.ChosenBy}}){{end}}, confidence {{printf "%.2f" .Confidence}}.
+
+| GameID | Description | Similarity |
+| --- | --- | --- |
+{{range .Candidates}}| {{cell .GameID}} | {{cell .Description}} | {{printf "%.2f" .Similarity}} |
+{{end}}{{end}}{{end}}{{if .Incomplete}}
+## Incomplete games
+
+| Directory | GameID | Warnings |
+| --- | --- | --- |
+{{range .Incomplete}}| {{cell .Directory}} | {{cell .GameID}} | {{range $i, $warning := .Warnings}}{{if $i}}<br>{{end}}{{cell $warning}}{{end}} |
+{{end}}{{end}}{{if .Errors}}
+## Errors
+
+| Directory | Kind | Error |
+| --- | --- | --- |
+{{range .Errors}}| {{cell .Directory}} | {{cell .ErrorKind}} | {{cell .Description}} |
+{{end}}{{end}}`))
+
+// htmlReportTemplate is the html report, which reads well on a phone.
+var htmlReportTemplate = htmltemplate.Must(htmltemplate.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>scummer report</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; }
+.number { text-align: right; }
+</style>
+</head>
+<body>
+<h1>scummer report</h1>
+<ul>
+<li>Games found: {{.GameCount}}</li>
+<li>Ambiguous matches: {{.AmbiguousCount}}</li>
+<li>Incomplete games: {{.IncompleteCount}}</li>
+<li>Errors: {{.ErrorCount}}</li>
+</ul>
+{{range .Engines}}
+<h2>{{.Engine}} ({{len .Games}})</h2>
+<table>
+<tr><th>Directory</th><th>GameID</th><th>Description</th><th>match.Confidence</th></tr>
+{{range .Games}}<tr><td>{{.Directory}}</td><td>{{.GameID}}</td><td>{{.Description}}</td><td class="number">{{printf "%.2f" .Confidence}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Ambiguous}}
+<h2>Ambiguous matches</h2>
+{{range .Ambiguous}}
+<h3>{{.Directory}}</h3>
+<p>Chose {{.GameID}}{{if .ChosenBy}} ({{.ChosenBy}}){{end}}, confidence {{printf "%.2f" .Confidence}}.</p>
+<table>
+<tr><th>GameID</th><th>Description</th><th>Similarity</th></tr>
+{{range .Candidates}}<tr><td>{{.GameID}}</td><td>{{.Description}}</td><td class="number">{{printf "%.2f" .Similarity}}</td></tr>
+{{end}}</table>
+{{end}}
+{{end}}
+{{if .Incomplete}}
+<h2>Incomplete games</h2>
+<table>
+<tr><th>Directory</th><th>GameID</th><th>Warnings</th></tr>
+{{range .Incomplete}}<tr><td>{{.Directory}}</td><td>{{.GameID}}</td><td>{{range $i, $warning := .Warnings}}{{if $i}}<br>{{end}}{{$warning}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Errors}}
+<h2>Errors</h2>
+<table>
+<tr><th>Directory</th><th>Kind</th><th>Error</th></tr>
+{{range .Errors}}<tr><td>{{.Directory}}</td><td>{{.ErrorKind}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// reportTemplate is either of the report templates.
+type reportTemplate interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// writeScanReport writes a report of the results with the given template.
+func writeScanReport(scummGameMatches []match.ScummGameMatch, options exportOptions, defaultOutputFile string, reportTemplate reportTemplate) error {
+	outputFile := options.OutputFile
+	if outputFile == "" {
+		outputFile = defaultOutputFile
+	}
+
+	var report bytes.Buffer
+	if err := reportTemplate.Execute(&report, newScanReport(scummGameMatches, options.Errors)); err != nil {
+		return err
+	}
+
+	// Write the file
+	if err := options.Journal.WriteFile(outputFile, report.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote the report to %s\n", outputFile)
+	return nil
+}
+
+// exportHTMLReport writes a report of the results as a web page.
+func exportHTMLReport(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	return writeScanReport(scummGameMatches, options, "report.html", htmlReportTemplate)
+}
+
+// exportMarkdownReport writes a report of the results in markdown.
+func exportMarkdownReport(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	return writeScanReport(scummGameMatches, options, "report.md", markdownReportTemplate)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// retroarchPlaylistFileName is the name RetroArch gives the ScummVM playlist, which
+// also ties the playlist to the ScummVM thumbnails.
+const retroarchPlaylistFileName = "ScummVM.lpl"
+
+// retroarchCoreName is the name of the ScummVM libretro core.
+const retroarchCoreName = "ScummVM"
+
+// retroarchDetect tells RetroArch to work out the core or checksum itself.
+const retroarchDetect = "DETECT"
+
+// retroarchPlaylistItem is a game in a RetroArch playlist.
+type retroarchPlaylistItem struct {
+	Path     string `json:"path"`
+	Label    string `json:"label"`
+	CorePath string `json:"core_path"`
+	CoreName string `json:"core_name"`
+	CRC32    string `json:"crc32"`
+	DBName   string `json:"db_name"`
+}
+
+// retroarchPlaylist is a RetroArch playlist in the JSON format RetroArch 1.7.6 and
+// later use.
+type retroarchPlaylist struct {
+	Version            string                  `json:"version"`
+	DefaultCorePath    string                  `json:"default_core_path"`
+	DefaultCoreName    string                  `json:"default_core_name"`
+	LabelDisplayMode   int                     `json:"label_display_mode"`
+	RightThumbnailMode int                     `json:"right_thumbnail_mode"`
+	LeftThumbnailMode  int                     `json:"left_thumbnail_mode"`
+	SortMode           int                     `json:"sort_mode"`
+	Items              []retroarchPlaylistItem `json:"items"`
+}
+
+// exportRetroarchPlaylist writes a RetroArch playlist that launches each game's
+// .scummvm file with the ScummVM core. Without --core, RetroArch asks which core to use
+// the first time a game is launched.
+func exportRetroarchPlaylist(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputFile := options.OutputFile
+	if outputFile == "" {
+		outputFile = retroarchPlaylistFileName
+	}
+
+	corePath, coreName := retroarchDetect, retroarchDetect
+	if options.CorePath != "" {
+		corePath, coreName = options.CorePath, retroarchCoreName
+	}
+
+	playlist := retroarchPlaylist{Version: "1.5", DefaultCorePath: options.CorePath, Items: make([]retroarchPlaylistItem, 0, len(scummGameMatches))}
+	if options.CorePath != "" {
+		playlist.DefaultCoreName = retroarchCoreName
+	}
+	for _, scummGameMatch := range scummGameMatches {
+		// RetroArch needs the full path to the game
+		gamePath, err := filepath.Abs(output.MarkerFileNames(scummGameMatch)[0])
+		if err != nil {
+			return err
+		}
+
+		playlist.Items = append(playlist.Items, retroarchPlaylistItem{
+			Path:     gamePath,
+			Label:    match.ParseDescriptionVariant(scummGameMatch.Description).Title,
+			CorePath: corePath,
+			CoreName: coreName,
+			CRC32:    retroarchDetect,
+			DBName:   retroarchPlaylistFileName,
+		})
+	}
+
+	playlistJSON, err := json.MarshalIndent(playlist, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := options.Journal.WriteFile(outputFile, append(playlistJSON, '\n'), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d games to %s\n", len(playlist.Items), outputFile)
+	return nil
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+	"github.com/furui/scummer/parse"
+)
+
+// reviewDecision is what the user decided to do with a match while reviewing it.
+type reviewDecision int
+
+const (
+	reviewUndecided reviewDecision = iota
+	reviewAccepted
+	reviewOverridden
+	reviewSkipped
+)
+
+// reviewItem is a single match that is being reviewed.
+type reviewItem struct {
+	// MatchIndex is the index of the match in the reviewed slice.
+	MatchIndex int
+
+	// Cursor is the candidate that is currently highlighted.
+	Cursor int
+
+	// Chosen is the candidate that the user picked.
+	Chosen int
+
+	Decision reviewDecision
+}
+
+// reviewModel is the bubbletea model for the review screen. It shows one match at a
+// time together with every candidate scummvm found for it.
+type reviewModel struct {
+	matches   []match.ScummGameMatch
+	items     []reviewItem
+	current   int
+	threshold float64
+
+	// saved is set when the user finishes the review, as opposed to cancelling it.
+	saved bool
+
+	// status is a message about the last key pressed, shown under the candidates.
+	status string
+}
+
+// needsReview reports whether a match should be shown on the review screen, which is
+// the case whenever scummvm wasn't sure which game it found.
+func needsReview(scummGameMatch match.ScummGameMatch) bool {
+	return len(scummGameMatch.Candidates) > 1
+}
+
+// candidateIndex returns the index of the candidate with the given GameID, or -1 if
+// none of the candidates have it.
+func candidateIndex(candidates []match.ScummGameCandidate, gameID string) int {
+	for i, candidate := range candidates {
+		if candidate.GameID == gameID {
+			return i
+		}
+	}
+	return -1
+}
+
+// newReviewModel creates the review screen for every match that needs reviewing.
+func newReviewModel(matches []match.ScummGameMatch, threshold float64) *reviewModel {
+	model := &reviewModel{matches: matches, threshold: threshold}
+	for i, scummGameMatch := range matches {
+		if !needsReview(scummGameMatch) {
+			continue
+		}
+		chosen := candidateIndex(scummGameMatch.Candidates, scummGameMatch.GameID)
+		cursor := chosen
+		if cursor < 0 {
+			cursor = 0
+		}
+		model.items = append(model.items, reviewItem{MatchIndex: i, Cursor: cursor, Chosen: chosen})
+	}
+	return model
+}
+
+func (m *reviewModel) Init() tea.Cmd {
+	return nil
+}
+
+// next moves on to the next match, staying on the last one.
+func (m *reviewModel) next() {
+	if m.current < len(m.items)-1 {
+		m.current++
+	}
+}
+
+func (m *reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	item := &m.items[m.current]
+	candidates := m.matches[item.MatchIndex].Candidates
+	m.status = ""
+
+	switch keyMsg.String() {
+	case "ctrl+c", "esc":
+		// Leave without saving anything
+		return m, tea.Quit
+	case "q":
+		// Finish the review
+		m.saved = true
+		return m, tea.Quit
+	case "up", "k":
+		if item.Cursor > 0 {
+			item.Cursor--
+		}
+	case "down", "j":
+		if item.Cursor < len(candidates)-1 {
+			item.Cursor++
+		}
+	case "enter", " ":
+		// Use the highlighted candidate
+		if item.Cursor == candidateIndex(candidates, m.matches[item.MatchIndex].GameID) {
+			item.Decision = reviewAccepted
+		} else {
+			item.Decision = reviewOverridden
+		}
+		item.Chosen = item.Cursor
+		m.next()
+	case "a":
+		// Keep the candidate that scummer picked. A hand-edited success.json may have a
+		// GameID that isn't one of the candidates, which is then kept as it is
+		item.Decision = reviewAccepted
+		item.Chosen = candidateIndex(candidates, m.matches[item.MatchIndex].GameID)
+		if item.Chosen < 0 {
+			m.status = fmt.Sprintf("%s isn't one of the candidates, keeping it as it is", m.matches[item.MatchIndex].GameID)
+		}
+		m.next()
+	case "s":
+		// Don't write a .scummvm file for this directory
+		item.Decision = reviewSkipped
+		m.next()
+	case "left", "h", "p":
+		if m.current > 0 {
+			m.current--
+		}
+	case "right", "l", "n", "tab":
+		m.next()
+	}
+
+	return m, nil
+}
+
+func (m *reviewModel) View() string {
+	var b strings.Builder
+
+	item := m.items[m.current]
+	scummGameMatch := m.matches[item.MatchIndex]
+
+	fmt.Fprintf(&b, "Reviewing %d of %d\n\n", m.current+1, len(m.items))
+	fmt.Fprintf(&b, "  %s\n", scummGameMatch.Directory)
+	if scummGameMatch.Confidence < m.threshold {
+		fmt.Fprintf(&b, "  ⚠️  low confidence (%.2f)\n", scummGameMatch.Confidence)
+	} else {
+		fmt.Fprintf(&b, "  confidence %.2f\n", scummGameMatch.Confidence)
+	}
+	fmt.Fprintf(&b, "  %s\n\n", reviewDecisionText(item, scummGameMatch))
+
+	for i, candidate := range scummGameMatch.Candidates {
+		cursor := "  "
+		if i == item.Cursor {
+			cursor = "> "
+		}
+		chosen := " "
+		if item.Decision != reviewSkipped && i == item.Chosen {
+			chosen = "✓"
+		}
+		fmt.Fprintf(&b, "%s%s %-30s %.2f  %s\n", cursor, chosen, candidate.GameID, candidate.Similarity, candidate.Description)
+	}
+
+	if m.status != "" {
+		fmt.Fprintf(&b, "\n  %s\n", m.status)
+	}
+
+	b.WriteString("\n↑/↓ move • enter choose • a accept • s skip • ←/→ previous/next • q finish • esc cancel\n")
+	return b.String()
+}
+
+// reviewDecisionText describes the decision that has been made for a match so far.
+func reviewDecisionText(item reviewItem, scummGameMatch match.ScummGameMatch) string {
+	switch item.Decision {
+	case reviewAccepted:
+		return "accepted " + scummGameMatch.GameID
+	case reviewOverridden:
+		return "overridden with " + scummGameMatch.Candidates[item.Chosen].GameID
+	case reviewSkipped:
+		return "skipped"
+	default:
+		return "scummer picked " + scummGameMatch.GameID
+	}
+}
+
+// reviewScummGameMatches shows the review screen for the matches that need it. It
+// returns the matches with the user's overrides applied, the matches the user chose to
+// skip, and whether the user finished the review rather than cancelling it.
+func reviewScummGameMatches(matches []match.ScummGameMatch, threshold float64) ([]match.ScummGameMatch, []match.ScummGameMatch, bool, error) {
+	model := newReviewModel(matches, threshold)
+	if len(model.items) == 0 {
+		return matches, nil, true, nil
+	}
+
+	// Run the review screen
+	if _, err := tea.NewProgram(model).Run(); err != nil {
+		return matches, nil, false, err
+	}
+	if !model.saved {
+		return matches, nil, false, nil
+	}
+
+	// Apply the decisions
+	decisions := make(map[int]reviewItem)
+	for _, item := range model.items {
+		decisions[item.MatchIndex] = item
+	}
+	reviewedMatches := make([]match.ScummGameMatch, 0, len(matches))
+	skippedMatches := make([]match.ScummGameMatch, 0)
+	for i, scummGameMatch := range matches {
+		item, ok := decisions[i]
+		switch {
+		case ok && item.Decision == reviewSkipped:
+			skippedMatches = append(skippedMatches, scummGameMatch)
+			continue
+		case ok && (item.Decision == reviewOverridden || item.Decision == reviewAccepted):
+			scummGameMatch = chooseScummGameCandidate(scummGameMatch, item.Chosen)
+		}
+		reviewedMatches = append(reviewedMatches, scummGameMatch)
+	}
+
+	return reviewedMatches, skippedMatches, true, nil
+}
+
+// chooseScummGameCandidate returns the match with the candidate at the given index
+// chosen by the user. The title is looked up in the bundled game list. The match is
+// returned as it is if there is no candidate at that index.
+func chooseScummGameCandidate(scummGameMatch match.ScummGameMatch, chosen int) match.ScummGameMatch {
+	if chosen < 0 || chosen >= len(scummGameMatch.Candidates) {
+		return scummGameMatch
+	}
+	scummGameMatch.GameID = scummGameMatch.Candidates[chosen].GameID
+	scummGameMatch.Description = scummGameMatch.Candidates[chosen].Description
+	scummGameMatch.Title, _ = match.GameTitle(parse.BundledGameTitles, scummGameMatch.GameID)
+	scummGameMatch.ChosenBy = "user"
+	scummGameMatch.Reason = &match.Reason{Rule: match.ReasonUser}
+	scummGameMatch.Reason.ExplainRunnerUp(scummGameMatch.Candidates, chosen)
+	scummGameMatch.Similarity = scummGameMatch.Candidates[chosen].Similarity
+	scummGameMatch.Score = scummGameMatch.Candidates[chosen].Score
+	scummGameMatch.Confidence = match.Confidence(len(scummGameMatch.Candidates), scummGameMatch.Similarity, scummGameMatch.ChosenBy)
+	return scummGameMatch
+}
+
+// skippedScummGameMatch turns a match the user skipped into an entry for error.json.
+func skippedScummGameMatch(scummGameMatch match.ScummGameMatch) match.ScummGameMatch {
+	return match.ScummGameMatch{GameID: "unknown", Description: "skipped by user", Directory: scummGameMatch.Directory, ErrorKind: match.ErrorKindSkipped, Candidates: scummGameMatch.Candidates, UnknownVariants: scummGameMatch.UnknownVariants}
+}
+
+// runReview loads the results of an earlier scan, lets the user review the ambiguous
+// matches, and then saves the results and updates the .scummvm files to match.
+func runReview(args []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("review", flag.ExitOnError)
+	errorFile := flags.String("errors", stateFile("error.json"), "error file of the scan; skipped directories are added to it")
+	similarityThreshold := flags.Float64("threshold", 0.5, "confidence (0 to 1) below which a match is flagged as low confidence")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer review [flags] [<success.json>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// The results file defaults to the one scan writes
+	successFile := stateFile("success.json")
+	if flags.NArg() > 0 {
+		successFile = flags.Arg(0)
+	}
+
+	// Load the results of the scan
+	scummvmOutputSlice, err := output.ReadResults(successFile)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	scummvmOutputErrorSlice, err := output.ReadResults(*errorFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		scummvmOutputErrorSlice = make([]match.ScummGameMatch, 0)
+	} else if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Check that there is something to review
+	if len(newReviewModel(scummvmOutputSlice, *similarityThreshold).items) == 0 {
+		fmt.Println("Nothing to review")
+		return
+	}
+
+	// Review the matches
+	reviewedSlice, skippedSlice, saved, err := reviewScummGameMatches(scummvmOutputSlice, *similarityThreshold)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if !saved {
+		fmt.Println("Review cancelled, nothing was changed")
+		return
+	}
+
+	// Save the reviewed results and update the .scummvm files to match
+	changeJournal := newRunJournal()
+	defer finishRunJournal(changeJournal)
+	if err := saveReviewedScummGameMatches(successFile, *errorFile, scummvmOutputSlice, reviewedSlice, skippedSlice, scummvmOutputErrorSlice, changeJournal); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("Saved %d games, skipped %d\n", len(reviewedSlice), len(skippedSlice))
+}
+
+// saveReviewedScummGameMatches rewrites the .scummvm files of the games whose GameID was
+// changed during a review, removes the .scummvm files of the games that were skipped,
+// and then saves the reviewed results. The skipped games are added to the errors. The
+// changes to the .scummvm files are recorded in the journal.
+func saveReviewedScummGameMatches(successFile string, errorFile string, originalSlice []match.ScummGameMatch, reviewedSlice []match.ScummGameMatch, skippedSlice []match.ScummGameMatch, errorSlice []match.ScummGameMatch, changeJournal *journal.Journal) error {
+	// Update the .scummvm files of the games whose GameID was changed
+	originalGameIDs := make(map[string]string)
+	for _, original := range originalSlice {
+		originalGameIDs[original.Directory] = original.GameID
+	}
+	for _, reviewed := range reviewedSlice {
+		// Games whose directory is still waiting to be renamed don't have .scummvm files yet
+		if originalGameIDs[reviewed.Directory] == reviewed.GameID || reviewed.RenameTo != "" {
+			continue
+		}
+		if err := output.WriteMarkerFile(reviewed, changeJournal); err != nil {
+			return err
+		}
+	}
+
+	// Remove the .scummvm files of the games that were skipped, as long as they are
+	// still the ones scummer wrote
+	for _, skipped := range skippedSlice {
+		expectedContents, err := output.MarkerContents(skipped)
+		for _, markerFileName := range output.MarkerFileNames(skipped) {
+			if contents, readErr := os.ReadFile(markerFileName); err == nil && readErr == nil && string(contents) == expectedContents {
+				if err := changeJournal.RemoveFile(markerFileName); err != nil {
+					fmt.Println(err)
+				}
+			}
+		}
+		errorSlice = append(errorSlice, skippedScummGameMatch(skipped))
+	}
+
+	// Save the reviewed results
+	if err := output.WriteResults(successFile, reviewedSlice); err != nil {
+		return err
+	}
+	return output.WriteResults(errorFile, errorSlice)
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// saveFileMatcher matches the names scummvm gives savegames: the target, then a dot and
+// the slot, such as monkey2.s01 for the SCUMM games and kq4.003 for most of the others.
+var saveFileMatcher = regexp.MustCompile(`(?i)^(.+)\.(s\d+|\d+)$`)
+
+// numberedTargetMatcher matches the names scummvm gives the second and later targets of
+// the same game, such as monkey2-1, and captures the GameID.
+var numberedTargetMatcher = regexp.MustCompile(`^(.+)-\d+$`)
+
+// savegame is a savegame scummvm wrote, and the target it belongs to.
+type savegame struct {
+	path   string
+	target string
+	size   int64
+}
+
+// countSavegames says how many savegames there are, such as "1 savegame".
+func countSavegames(n int) string {
+	if n == 1 {
+		return "1 savegame"
+	}
+	return fmt.Sprintf("%d savegames", n)
+}
+
+// savegameGroup is the savegames of a game of the scan results.
+type savegameGroup struct {
+	scummGameMatch match.ScummGameMatch
+	savegames      []savegame
+}
+
+// size returns how much room the savegames take up.
+func (g *savegameGroup) size() int64 {
+	size := int64(0)
+	for _, save := range g.savegames {
+		size += save.size
+	}
+	return size
+}
+
+// directoryName is the name of the directory the savegames of the game are copied or
+// moved into: the game's title, the way --rename-to-title names directories.
+func (g *savegameGroup) directoryName() string {
+	if name := output.CanonicalDirectoryName(g.scummGameMatch); name != "" {
+		return name
+	}
+	return match.BareGameID(g.scummGameMatch.GameID)
+}
+
+// defaultSavePath returns where scummvm keeps the savegames on this platform, when
+// scummvm.ini doesn't say.
+func defaultSavePath() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		appData := os.Getenv("APPDATA")
+		if appData == "" {
+			return "", fmt.Errorf("could not find where scummvm keeps the savegames; use --savepath to say where they are")
+		}
+		return filepath.Join(appData, "ScummVM", "Saved games"), nil
+	case "darwin":
+		homeDirectory, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(homeDirectory, "Documents", "ScummVM Savegames"), nil
+	default:
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			homeDirectory, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			dataHome = filepath.Join(homeDirectory, ".local", "share")
+		}
+		return filepath.Join(dataHome, "scummvm", "saves"), nil
+	}
+}
+
+// savegameDirectories returns the directories scummvm keeps savegames in: the savepath
+// given, or the one in the [scummvm] section of scummvm.ini, or the default one, along
+// with the savepath of each target that has its own.
+func savegameDirectories(ini *scummvmIni, savePath string) ([]string, error) {
+	if savePath == "" && ini != nil {
+		for _, section := range ini.Sections {
+			if strings.EqualFold(section.Name, "scummvm") {
+				savePath = section.Get("savepath")
+			}
+		}
+	}
+	if savePath == "" {
+		var err error
+		if savePath, err = defaultSavePath(); err != nil {
+			return nil, err
+		}
+	}
+
+	directories := []string{savePath}
+	seen := map[string]bool{normalizeTargetPath(savePath): true}
+	if ini != nil {
+		for _, target := range ini.Targets() {
+			targetSavePath := target.Get("savepath")
+			if targetSavePath != "" && !seen[normalizeTargetPath(targetSavePath)] {
+				directories = append(directories, targetSavePath)
+				seen[normalizeTargetPath(targetSavePath)] = true
+			}
+		}
+	}
+	return directories, nil
+}
+
+// findSavegames lists the savegames in the directories scummvm keeps them in. A
+// directory that doesn't exist has none.
+func findSavegames(directories []string) ([]savegame, error) {
+	savegames := make([]savegame, 0)
+	for _, directory := range directories {
+		entries, err := os.ReadDir(directory)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			matches := saveFileMatcher.FindStringSubmatch(entry.Name())
+			if matches == nil || !entry.Type().IsRegular() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+			savegames = append(savegames, savegame{path: filepath.Join(directory, entry.Name()), target: matches[1], size: info.Size()})
+		}
+	}
+	return savegames, nil
+}
+
+// savegameGames is the games of the scan results and the targets of scummvm.ini,
+// indexed to work out which game the savegames of each target belong to.
+type savegameGames struct {
+	targets      map[string]*scummvmIniSection
+	byPath       map[string]match.ScummGameMatch
+	byGameID     map[string]match.ScummGameMatch
+	byBareGameID map[string]match.ScummGameMatch
+}
+
+// newSavegameGames indexes the scan results and the targets of scummvm.ini, which may be
+// nil.
+func newSavegameGames(scummGameMatches []match.ScummGameMatch, ini *scummvmIni) *savegameGames {
+	games := &savegameGames{
+		targets:      make(map[string]*scummvmIniSection),
+		byPath:       make(map[string]match.ScummGameMatch),
+		byGameID:     make(map[string]match.ScummGameMatch),
+		byBareGameID: make(map[string]match.ScummGameMatch),
+	}
+	if ini != nil {
+		for _, target := range ini.Targets() {
+			games.targets[strings.ToLower(target.Name)] = target
+		}
+	}
+	for _, scummGameMatch := range scummGameMatches {
+		path := normalizeTargetPath(absoluteDirectory(scummGameMatch.Directory))
+		if _, ok := games.byPath[path]; !ok {
+			games.byPath[path] = scummGameMatch
+		}
+		if _, ok := games.byGameID[scummGameMatch.GameID]; !ok {
+			games.byGameID[scummGameMatch.GameID] = scummGameMatch
+		}
+		if _, ok := games.byBareGameID[match.BareGameID(scummGameMatch.GameID)]; !ok {
+			games.byBareGameID[match.BareGameID(scummGameMatch.GameID)] = scummGameMatch
+		}
+	}
+	return games
+}
+
+// game returns the game of the scan results the savegames of a target belong to. A
+// target in scummvm.ini is the game in its path, or the game with its GameID if its
+// path isn't one of the scan results' directories. A target that isn't in scummvm.ini
+// is taken to be named after its GameID, the way scummvm names them, such as monkey2,
+// or monkey2-1 for the second one.
+func (games *savegameGames) game(targetName string) (match.ScummGameMatch, bool) {
+	if target, ok := games.targets[strings.ToLower(targetName)]; ok {
+		if scummGameMatch, ok := games.byPath[normalizeTargetPath(absoluteDirectory(target.Get("path")))]; ok {
+			return scummGameMatch, true
+		}
+		if engine := target.Get("engineid"); engine != "" {
+			scummGameMatch, ok := games.byGameID[engine+":"+target.Get("gameid")]
+			return scummGameMatch, ok
+		}
+		scummGameMatch, ok := games.byBareGameID[target.Get("gameid")]
+		return scummGameMatch, ok
+	}
+	gameID := strings.ToLower(targetName)
+	if _, ok := games.byBareGameID[gameID]; !ok {
+		if matches := numberedTargetMatcher.FindStringSubmatch(gameID); matches != nil {
+			gameID = matches[1]
+		}
+	}
+	scummGameMatch, ok := games.byBareGameID[gameID]
+	return scummGameMatch, ok
+}
+
+// groupSavegames groups the savegames by the game of the scan results they belong to,
+// in the order of the games' titles, and returns the ones that belong to none of them.
+func groupSavegames(savegames []savegame, games *savegameGames) ([]*savegameGroup, []savegame) {
+	groupsByGameID := make(map[string]*savegameGroup)
+	groups := make([]*savegameGroup, 0)
+	unknown := make([]savegame, 0)
+	for _, save := range savegames {
+		scummGameMatch, ok := games.game(save.target)
+		if !ok {
+			unknown = append(unknown, save)
+			continue
+		}
+		group, ok := groupsByGameID[scummGameMatch.GameID]
+		if !ok {
+			group = &savegameGroup{scummGameMatch: scummGameMatch}
+			groupsByGameID[scummGameMatch.GameID] = group
+			groups = append(groups, group)
+		}
+		group.savegames = append(group.savegames, save)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].directoryName()) < strings.ToLower(groups[j].directoryName())
+	})
+	return groups, unknown
+}
+
+// copySavegames copies the savegames of each game into a directory of its own in
+// destination, named after the game, and removes the ones it copied from where they
+// were if move is set. A savegame that is already in the game's directory is left
+// alone. Everything is recorded in the journal, so that undo can remove the copies and
+// bring back the savegames that were moved. With dryRun, what would be done is only
+// shown. It returns how many savegames were copied or moved.
+func copySavegames(groups []*savegameGroup, destination string, move bool, dryRun bool, changeJournal *journal.Journal) (int, error) {
+	verb := "Copy"
+	if move {
+		verb = "Move"
+	}
+	copied := 0
+	for _, group := range groups {
+		gameDirectory := filepath.Join(destination, group.directoryName())
+		for _, save := range group.savegames {
+			savePath := filepath.Join(gameDirectory, filepath.Base(save.path))
+			if _, err := os.Lstat(savePath); !errors.Is(err, fs.ErrNotExist) {
+				fmt.Printf("⏭️  %s: %s is already there\n", save.path, savePath)
+				continue
+			}
+			if dryRun {
+				fmt.Printf("%s %s to %s\n", verb, save.path, savePath)
+				copied++
+				continue
+			}
+			if err := changeJournal.MkdirAll(gameDirectory, 0755); err != nil {
+				return copied, err
+			}
+			if err := changeJournal.ChangeFile(savePath, func() error { return copyFile(save.path, savePath) }); err != nil {
+				return copied, err
+			}
+			if move {
+				if err := changeJournal.RemoveFile(save.path); err != nil {
+					return copied, err
+				}
+			}
+			copied++
+		}
+	}
+	return copied, nil
+}
+
+// runSaves lists the savegames scummvm wrote, grouped by the game of an earlier scan
+// they belong to, and copies or moves them into a directory for each game, such as to
+// take them along to another device.
+func runSaves(args []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("saves", flag.ExitOnError)
+	scummvmIniFile := flags.String("scummvm-ini", "", "scummvm.ini to read the targets and their savepaths from; defaults to the one scummvm uses")
+	savePath := flags.String("savepath", "", "directory scummvm keeps the savegames in; defaults to the savepath in scummvm.ini, or to where scummvm keeps them on this platform")
+	copyTo := flags.String("copy-to", "", "directory to copy the savegames into, in a directory for each game named after it")
+	moveTo := flags.String("move-to", "", "directory to move the savegames into, in a directory for each game named after it")
+	dryRun := flags.Bool("dry-run", false, "show what would be copied or moved without changing anything")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer saves [flags] [<success.json>]")
+		fmt.Fprintln(flags.Output(), "Without --copy-to or --move-to, the savegames of each game are listed.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// Check that we weren't told to both copy and move them
+	if *copyTo != "" && *moveTo != "" {
+		fmt.Println("The --copy-to and --move-to flags can't be used together")
+		return
+	}
+
+	// The results file defaults to the one scan writes
+	successFile := stateFile("success.json")
+	if flags.NArg() > 0 {
+		successFile = flags.Arg(0)
+	}
+
+	// Load the results of the scan
+	scummvmOutputSlice, err := output.ReadResults(successFile)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Read the targets from scummvm.ini, if there is one
+	iniFile := *scummvmIniFile
+	if iniFile == "" {
+		if iniFile, err = defaultScummvmIniFile(); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	ini, err := readScummvmIni(iniFile)
+	if errors.Is(err, fs.ErrNotExist) && *scummvmIniFile == "" {
+		fmt.Printf("⚠️  There is no %s, the savegames are matched to the games by their names\n", iniFile)
+		ini = nil
+	} else if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Find the savegames and group them by game
+	directories, err := savegameDirectories(ini, *savePath)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	savegames, err := findSavegames(directories)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	groups, unknown := groupSavegames(savegames, newSavegameGames(scummvmOutputSlice, ini))
+
+	// List them, unless we are copying or moving them
+	destination, move := *copyTo, false
+	if *moveTo != "" {
+		destination, move = *moveTo, true
+	}
+	if destination == "" {
+		for _, group := range groups {
+			fmt.Printf("%s (%s): %s, %s\n", group.directoryName(), group.scummGameMatch.GameID, countSavegames(len(group.savegames)), formatByteSize(group.size()))
+			for _, save := range group.savegames {
+				fmt.Printf("   %s\n", save.path)
+			}
+		}
+		if len(unknown) > 0 {
+			fmt.Printf("Savegames of targets that aren't games of %s:\n", successFile)
+			for _, save := range unknown {
+				fmt.Printf("   %s\n", save.path)
+			}
+		}
+		fmt.Printf("\nFound %s of %d games in %s\n", countSavegames(len(savegames)-len(unknown)), len(groups), strings.Join(directories, ", "))
+		return
+	}
+
+	// Copy or move them into a directory for each game
+	changeJournal := newRunJournal()
+	defer finishRunJournal(changeJournal)
+	copied, err := copySavegames(groups, destination, move, *dryRun, changeJournal)
+	if err != nil {
+		fmt.Println(err)
+	}
+	switch {
+	case *dryRun:
+		fmt.Printf("Would have put %s of %d games in %s\n", countSavegames(copied), len(groups), destination)
+	case move:
+		fmt.Printf("Moved %s of %d games to %s, point the savepath of each game's target at its directory for scummvm to find them\n", countSavegames(copied), len(groups), destination)
+	default:
+		fmt.Printf("Copied %s of %d games to %s\n", countSavegames(copied), len(groups), destination)
+	}
+	if len(unknown) > 0 {
+		fmt.Printf("%s of targets that aren't games of %s stayed where they were\n", countSavegames(len(unknown)), successFile)
+	}
+}
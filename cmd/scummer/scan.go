@@ -0,0 +1,1037 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+	"github.com/furui/scummer/parse"
+	"github.com/furui/scummer/remote"
+)
+
+// runScan scans every directory in the scummvm data file directory, writes the results
+// to success.json and error.json, and then writes a .scummvm file for every game found
+// unless --no-write is given. It returns the status scummer exits with, which is 1 when
+// --on-low-confidence error turned any matches into errors.
+func runScan(args []string) (exitStatus int) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	scummvmBinaryFlag := flags.String("scummvm", "", "path to the scummvm binary, flatpak for the ScummVM Flatpak, docker:<image> to run it in a container, or ssh://[user@]host[:port][/path/to/scummvm] to run it on another machine; if not given, scummer looks for an installed scummvm")
+	minVersion := flags.String("min-version", "", "oldest scummvm version to scan with, such as 2.7; defaults to min_scummvm_version from the config file")
+	minVersionPolicy := flags.String("min-version-policy", "refuse", "what to do when scummvm is older than --min-version: refuse to scan, or warn and scan anyway")
+	targetScummvm := flags.String("target-scummvm", "", "scummvm the games will be played with, such as a handheld's build, given the same way as --scummvm; the games whose engine it doesn't have are warned about. Defaults to the scummvm scanning them")
+	remoteLibrary := flags.String("remote-library", "", "where the scummvm data file directory is on the machine scummvm runs on, with --scummvm ssh://...; defaults to the same path")
+	scummvmIniFile := flags.String("scummvm-ini", "", "path to a scummvm.ini file; directories already configured as targets in it are skipped or annotated")
+	registeredMode := flags.String("registered", "skip", "what to do with directories already in scummvm.ini: skip or annotate")
+	followSymlinks := flags.Bool("follow-symlinks", false, "scan symlinks that point at game directories")
+	similarityThreshold := flags.Float64("threshold", 0.5, "similarity (0 to 1) below which an ambiguous match is offered to the user to choose from")
+	metricName := flags.String("metric", "levenshtein", "string metric used to compare candidates with the directory name: "+strings.Join(match.StringMetricNames(), ", "))
+	levenshteinCosts := flags.String("levenshtein-costs", "1,1,2", "insert,delete,replace costs of the levenshtein metric")
+	aliasesFile := flags.String("aliases", "", "JSON file with extra abbreviations used in directory names, added to the built-in ones")
+	preferLanguage := flags.String("prefer-language", "", "comma separated languages, most preferred first (e.g. en,de), used to pick between candidates that only differ by language")
+	preferPlatform := flags.String("prefer-platform", "", "comma separated platforms, most preferred first (e.g. DOS,Windows,Amiga), used to pick between equally close candidates")
+	successFile := flags.String("results", stateFile("success.json"), "file the successful detections are saved to")
+	errorFile := flags.String("errors", stateFile("error.json"), "file the unsuccessful detections are saved to")
+	noWrite := flags.Bool("no-write", false, "only save the results, without writing .scummvm files; use \"scummer apply\" to write them later")
+	lowConfidencePolicy := flags.String("on-low-confidence", match.LowConfidencePrompt, "what to do when no candidate is similar enough to the directory name: skip, prompt, best-guess or error")
+	answersFile := flags.String("answers", "", "YAML file mapping directories to the GameID to use (or \"skip\"); choices made at the prompt are added to it")
+	reviewMatches := flags.Bool("review", false, "review ambiguous matches on a full-screen review screen before the .scummvm files are written")
+	includeHidden := flags.Bool("include-hidden", false, "scan hidden and system directories such as dot-directories and $RECYCLE.BIN")
+	presetName := flags.String("preset", "", "lay the .scummvm files out the way a frontend expects: "+strings.Join(scummerPresetNames(), ", "))
+	markerPlacement := flags.String("marker-placement", "", "where the .scummvm files go: "+strings.Join(output.MarkerPlacements, ", ")+"; defaults to sibling, or to where the preset puts them")
+	gameIDFormat := flags.String("gameid-format", "", "how the GameID is written in the .scummvm files: qualified (scumm:loom) or bare (loom); defaults to qualified, or to what the preset uses")
+	markerNameTemplate := flags.String("marker-name", "", "text/template for the names of the .scummvm files, such as \"{{.Title}}.scummvm\"; defaults to the directory name")
+	markerContentsTemplate := flags.String("marker-contents", "", "text/template for the contents of the .scummvm files, such as \"{{.BareGameID}}\"; defaults to the GameID")
+	directoryAsGame := flags.Bool("directory-as-game", false, "rename each game directory to end in .scummvm, with the .scummvm file inside it (needs a preset that puts it there)")
+	renameToTitle := flags.Bool("rename-to-title", false, "rename each game directory to the game's full title, such as mi2cd to \"Monkey Island 2 - LeChuck's Revenge\"; with --no-write the renames are only shown, for \"scummer apply\" to make")
+	writeChecksums := flags.Bool("checksums", false, "write a checksums.sha256 manifest of each game's files into its directory, for \"scummer check\" to find files that went bad later")
+	copyArt := flags.Bool("copy-art", false, "copy a cover.png (or folder.png, boxart.png, ...) from each game's directory to where the preset expects its art")
+	writeGamelistFile := flags.Bool("gamelist", false, "add the games to the EmulationStation gamelist.xml in the scummvm data file directory")
+	groupDiscs := flags.Bool("group-discs", false, "write a single .scummvm file for a game that is on several discs, such as \"Game (Disc 1)\" and \"Game (Disc 2)\", along with an .m3u listing the discs")
+	resolveDuplicates := flags.Bool("resolve-duplicates", false, "ask which directory to keep when the same game is found in more than one")
+	duplicatesReportFile := flags.String("duplicates-report", "", "markdown file to write a report of the games found in more than one directory to, with the variant, size and role of each copy")
+	unknownVariantsFile := flags.String("unknown-variants", stateFile("unknown-variants.md"), "markdown file to write the game variants scummvm doesn't know to, when it finds any, with what it asks to be reported to the ScummVM team; empty to not write it")
+	configFile := flags.String("config", "", "config file; defaults to "+stateFile(defaultConfigFile)+" if it exists")
+	databaseFile := flags.String("database", "", "SQLite database to add the results of this scan to, keeping the results of every earlier scan")
+	summaryFile := flags.String("summary", stateFile("summary.json"), "file the summary of the scan is saved to")
+	jsonlFile := flags.String("jsonl", "", "file to write each directory's result to as soon as it is known, one JSON object per line")
+	logFormat := flags.String("log-format", logFormatText, "text, or json to also write a JSON object for every directory and phase of the scan to stderr, one per line")
+	suggestGameIDs := flags.Bool("suggest", false, "for directories scummvm detects nothing in, suggest the closest known game in error.json")
+	retries := flags.Int("retries", 0, "how many more times to try listing the library, reading a directory or writing a .scummvm file when it fails with an I/O error or a file that is briefly missing, as a network share can")
+	retryDelay := flags.Duration("retry-delay", time.Second, "how long to wait before the first retry; the wait doubles after each one")
+	recheckFailures := flags.Bool("recheck-failures", false, "once every directory has been scanned, scan the ones that failed once more, other than the skipped and low confidence ones")
+	quarantineDirectory := flags.String("quarantine", "", "directory to put the directories scummvm couldn't identify any game in, such as <library>/_unidentified, so that the library only has games in it")
+	quarantineMode := flags.String("quarantine-mode", quarantineMove, "how --quarantine puts the directories there: move, or symlink to leave them where they are")
+	splitMode := flags.String("split", splitIgnore, "what to do with the directories scummvm finds no game in, but several games further down in, such as a collection: ignore them, report the games in error.json, split them by moving each game into a directory of its own named after it, or prompt for each; with --no-write the moves are only shown")
+	nestedMode := flags.String("nested", nestedReport, "what to do with the game directories whose files are all in a directory further down, such as Game/GAME, or in a directory for each disc, such as Game/CD1 and Game/CD2, where scummvm doesn't look: report them, flatten them by moving the files up, or prompt for each; with --no-write the flattening is only shown")
+	discImageMode := flags.String("disc-images", discImagesIgnore, "what to do with the game directories that only have a CD image (.iso, or .cue and .bin) in them: ignore them, detect them in a temporary copy of the files that are needed and report those, or extract those files and the audio tracks into the directory")
+	archiveMode := flags.String("archives", archivesIgnore, "what to do with the games kept in an archive ("+strings.Join(archiveExtensions(), ", ")+") at the top of the library: ignore them, detect them where they are and write the .scummvm file next to the archive, unpack them into a directory of their own, or prompt for each")
+	stagingRoot := flags.String("staging", os.TempDir(), "directory a library on remote storage (smb://, sftp://, s3:// or rclone:) is copied into, one game at a time, to be scanned")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer [scan] [flags] [<scummvm binary file>] <scummvm data file directory>")
+		fmt.Fprintln(flags.Output(), "The scummvm data file directory can be left out with a preset that knows where the games are.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	started := time.Now()
+
+	// Look up the preset, which may know where the games are
+	preset := defaultPreset
+	if *presetName != "" {
+		var ok bool
+		if preset, ok = scummerPresets[*presetName]; !ok {
+			fmt.Printf("The --preset flag must be one of %s\n", strings.Join(scummerPresetNames(), ", "))
+			return
+		}
+	}
+
+	// First check if we have the right number of arguments
+	if (flags.NArg() < 1 && preset.Library == "") || flags.NArg() > 2 {
+		fmt.Println("Please provide the scummvm data file directory, optionally preceded by the scummvm binary file")
+		return
+	}
+
+	// The data file directory is always the last argument, unless the preset knows
+	// where it is. The binary can either be given with --scummvm, or as the first of two
+	// arguments.
+	scummvmDataFileDirectory := preset.Library
+	if flags.NArg() > 0 {
+		scummvmDataFileDirectory = flags.Arg(flags.NArg() - 1)
+	}
+	scummvmBinaryFile := *scummvmBinaryFlag
+	if flags.NArg() == 2 {
+		if scummvmBinaryFile != "" {
+			fmt.Println("The scummvm binary file was given both with --scummvm and as an argument")
+			return
+		}
+		scummvmBinaryFile = flags.Arg(0)
+	}
+
+	// In portable mode, a scummvm next to scummer is used before an installed one
+	if scummvmBinaryFile == "" {
+		scummvmBinaryFile = portableScummvm()
+	}
+
+	// Check that the minimum version policy is one we know about
+	if *minVersionPolicy != "refuse" && *minVersionPolicy != "warn" {
+		fmt.Println("The --min-version-policy flag must be either refuse or warn")
+		return
+	}
+
+	// Check that the registered mode is one we know about
+	if *registeredMode != "skip" && *registeredMode != "annotate" {
+		fmt.Println("The --registered flag must be either skip or annotate")
+		return
+	}
+
+	// Setup the string metric used to compare candidates with the directory name
+	metric, err := match.NewStringMetric(*metricName, *levenshteinCosts)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	options := match.Options{Metric: metric, Aliases: match.BuiltinAliases}
+
+	// Read the config file
+	config, err := readScummerConfig(stateFile(defaultConfigFile), false)
+	if *configFile != "" {
+		config, err = readScummerConfig(*configFile, true)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// The preferences given on the command line take precedence over the ones in the
+	// config file
+	if config.Ranking != nil {
+		if *preferLanguage == "" {
+			*preferLanguage = strings.Join(config.Ranking.Languages, ",")
+		}
+		if *preferPlatform == "" {
+			*preferPlatform = strings.Join(config.Ranking.Platforms, ",")
+		}
+	}
+
+	// Setup the language preference
+	if options.PreferredLanguages, err = match.ParseLanguagePreference(*preferLanguage); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Setup the platform preference
+	if options.PreferredPlatforms, err = match.ParsePlatformPreference(*preferPlatform); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Setup the weighted ranking, recording the preferences that were actually used
+	if config.Ranking != nil {
+		config.Ranking.Languages = options.PreferredLanguages
+		config.Ranking.Platforms = options.PreferredPlatforms
+		options.Ranking = config.Ranking
+	}
+
+	// Add the user's own aliases to the built-in ones
+	if *aliasesFile != "" {
+		extraAliases, err := match.ReadAliases(*aliasesFile)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		options.Aliases = match.MergeAliases(extraAliases)
+	}
+
+	// Read the targets that are already configured in scummvm.ini
+	registeredTargetPaths := make(map[string]string)
+	if *scummvmIniFile != "" {
+		scummvmIni, err := readScummvmIni(*scummvmIniFile)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		registeredTargetPaths = scummvmIni.TargetPaths()
+	}
+
+	// Work out where the .scummvm files go
+	layout := preset.Layout
+	switch *markerPlacement {
+	case "":
+	case output.MarkerPlacementSibling, output.MarkerPlacementInside, output.MarkerPlacementBoth:
+		layout.Placement = *markerPlacement
+	default:
+		fmt.Printf("The --marker-placement flag must be one of %s\n", strings.Join(output.MarkerPlacements, ", "))
+		return
+	}
+	switch *gameIDFormat {
+	case "":
+	case output.MarkerFormatQualified:
+		layout.Format = ""
+	case output.MarkerFormatBare:
+		layout.Format = output.MarkerFormatBare
+	default:
+		fmt.Println("The --gameid-format flag must be either qualified or bare")
+		return
+	}
+	if *directoryAsGame {
+		if !layout.PutsMarkerInside() {
+			fmt.Println("The --directory-as-game flag needs the .scummvm file inside the game's directory, with --marker-placement or a preset that puts it there")
+			return
+		}
+		layout.DirectoryAsGame = true
+	}
+	if *markerNameTemplate != "" {
+		layout.NameTemplate = *markerNameTemplate
+	}
+	if *markerContentsTemplate != "" {
+		layout.ContentsTemplate = *markerContentsTemplate
+	}
+	if err := layout.CheckTemplates(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Check that the log format is one we know about
+	var eventLog *scanLog
+	switch *logFormat {
+	case logFormatText:
+	case logFormatJSON:
+		eventLog = &scanLog{writer: os.Stderr}
+	default:
+		fmt.Println("The --log-format flag must be either text or json")
+		return
+	}
+
+	// Check that the quarantine mode is one we know about
+	if *quarantineMode != quarantineMove && *quarantineMode != quarantineSymlink {
+		fmt.Println("The --quarantine-mode flag must be either move or symlink")
+		return
+	}
+
+	// Check that the archive mode is one we know about
+	if *archiveMode != archivesIgnore && *archiveMode != archivesDetect && *archiveMode != archivesUnpack && *archiveMode != archivesPrompt {
+		fmt.Println("The --archives flag must be one of ignore, detect, unpack or prompt")
+		return
+	}
+
+	// Check that the split mode is one we know about
+	if *splitMode != splitIgnore && *splitMode != splitReport && *splitMode != splitSplit && *splitMode != splitPrompt {
+		fmt.Println("The --split flag must be one of ignore, report, split or prompt")
+		return
+	}
+
+	// Check that the nested directory mode is one we know about
+	if *nestedMode != nestedReport && *nestedMode != nestedFlatten && *nestedMode != nestedPrompt {
+		fmt.Println("The --nested flag must be one of report, flatten or prompt")
+		return
+	}
+
+	// Check that the disc image mode is one we know about
+	if *discImageMode != discImagesIgnore && *discImageMode != discImagesDetect && *discImageMode != discImagesExtract {
+		fmt.Println("The --disc-images flag must be one of ignore, detect or extract")
+		return
+	}
+
+	// Check that the number of retries makes sense
+	if *retries < 0 {
+		fmt.Println("The --retries flag can't be negative")
+		return
+	}
+	retry := detect.Retry{Attempts: *retries, Delay: *retryDelay}
+
+	// Check that the low confidence policy is one we know about
+	switch *lowConfidencePolicy {
+	case match.LowConfidenceSkip, match.LowConfidencePrompt, match.LowConfidenceBestGuess, match.LowConfidenceError:
+	default:
+		fmt.Println("The --on-low-confidence flag must be one of skip, prompt, best-guess or error")
+		return
+	}
+
+	// Read the answers to earlier prompts
+	answers := make(scummvmAnswers)
+	if *answersFile != "" {
+		if answers, err = readScummvmAnswers(*answersFile); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	// Find scummvm if we weren't told where it is, otherwise check that we were given a file
+	var scummvmBinary detect.Command
+	if scummvmBinaryFile == "" {
+		discoveredBinary, err := detect.Discover()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Using %s\n", discoveredBinary)
+		scummvmBinary = discoveredBinary
+	} else if packagedCommand, ok, err := detect.PackagedCommand(scummvmBinaryFile); ok {
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		scummvmBinary = packagedCommand
+	} else {
+		// A macOS .app bundle stands for the scummvm inside it
+		scummvmBinaryFile = detect.ResolveAppBundle(scummvmBinaryFile)
+		f, err := os.Stat(scummvmBinaryFile)
+		if err != nil || f.IsDir() {
+			fmt.Println("The scummvm binary file is not a file")
+			return
+		}
+		scummvmBinary = detect.Command{Path: scummvmBinaryFile}
+	}
+
+	// A library on remote storage is copied into a staging directory one game at a time,
+	// since scummvm can only look at local files, and the .scummvm files are copied back
+	var staging *remoteStaging
+	if remoteStorage, isRemote, err := remote.Parse(scummvmDataFileDirectory); isRemote {
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if scummvmBinary.IsSSH() {
+			fmt.Println("A library on remote storage can't be scanned with a scummvm on another machine, use --scummvm ssh://... and --remote-library instead")
+			return
+		}
+		if layout.DirectoryAsGame || *renameToTitle || *copyArt || *writeChecksums || *quarantineDirectory != "" || *archiveMode != archivesIgnore || *discImageMode != discImagesIgnore || *nestedMode != nestedReport || *splitMode != splitIgnore {
+			fmt.Println("The --directory-as-game, --rename-to-title, --copy-art, --checksums, --quarantine, --archives, --disc-images, --nested and --split flags can't be used with a library on remote storage")
+			return
+		}
+		fmt.Printf("Listing %s... ", remoteStorage)
+		if staging, err = newRemoteStaging(context.Background(), remoteStorage, *stagingRoot); err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer staging.remove()
+		fmt.Printf("staging the games in %s\n", staging.directory)
+		scummvmDataFileDirectory = staging.directory
+	}
+
+	// Record what is changed in a local library, so that it can be undone
+	var changeJournal *journal.Journal
+	if staging == nil {
+		changeJournal = newRunJournal()
+		defer finishRunJournal(changeJournal)
+	}
+
+	// Tell a scummvm on another machine where the library is over there
+	if *remoteLibrary != "" {
+		if !scummvmBinary.IsSSH() {
+			fmt.Println("The --remote-library flag needs --scummvm ssh://...")
+			return
+		}
+		if scummvmBinary, err = scummvmBinary.WithRemoteLibrary(scummvmDataFileDirectory, *remoteLibrary); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	// Check if the second argument is a directory
+	if d, err := os.Stat(scummvmDataFileDirectory); err != nil || !d.IsDir() {
+		fmt.Println("The scummvm data file directory is not a directory")
+		return
+	}
+
+	// Check if the scummvm binary file returns a version
+	scummvmVersion, err := detect.Verify(scummvmBinary)
+	if err != nil {
+		fmt.Println(scummvmVersion)
+		fmt.Println(err)
+		return
+	}
+
+	// Make sure scummvm isn't sandboxed away from the library, since every directory would
+	// fail otherwise
+	if err := detect.CheckSandboxAccess(scummvmBinary, scummvmDataFileDirectory); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Work out the version of scummvm, which every result records, and check that it
+	// is new enough if we were told how new it has to be
+	scummvmVersionString := ""
+	parsedVersion, err := detect.ParseVersion(scummvmVersion)
+	if err == nil {
+		scummvmVersionString = parsedVersion.String()
+	}
+	if *minVersion == "" {
+		*minVersion = config.MinScummvmVersion
+	}
+	if *minVersion != "" {
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		minimumVersion, err := detect.ParseMinimumVersion(*minVersion)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := detect.CheckMinimumVersion(parsedVersion, minimumVersion); err != nil {
+			if *minVersionPolicy == "refuse" {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf("⚠️  %s\n", err)
+		}
+	}
+
+	// Load the full titles of the games scummvm knows about
+	options.GameTitles = detect.LoadGameTitles(scummvmBinary)
+
+	// Find out which engines the scummvm the games will be played with has. That is the
+	// one scanning them unless we were told otherwise, and if it can't tell us, there
+	// is nothing to check the games against
+	engineScummvm := scummvmBinary
+	if *targetScummvm != "" {
+		if engineScummvm, err = targetScummvmCommand(*targetScummvm); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	engines, err := detect.LoadEngines(engineScummvm)
+	if err != nil && *targetScummvm != "" {
+		fmt.Println(err)
+		return
+	}
+
+	// Get a list of all the scummvm data file directories, letting the user know that a
+	// listing that is slow to answer, as one over a network share can be, hasn't hung
+	listStarted := time.Now()
+	slowListing := time.AfterFunc(slowListingNotice, func() {
+		fmt.Printf("Still listing %s, it is slow to answer...\n", staging.remotePath(scummvmDataFileDirectory))
+	})
+	var scummvmDataFileDirectories []string
+	err = retry.Do(func() error {
+		var err error
+		scummvmDataFileDirectories, err = detect.GameDirectories(scummvmDataFileDirectory, detect.ListingOptions{FollowSymlinks: *followSymlinks, IncludeHidden: *includeHidden})
+		return err
+	})
+	slowListing.Stop()
+	if err != nil {
+		eventLog.event(scanLogEvent{Level: "error", Phase: scanPhaseList, Directory: scummvmDataFileDirectory, Outcome: "failed", Error: err.Error()}, listStarted)
+		fmt.Println(err)
+		return
+	}
+	scummvmDataFileDirectories = withoutQuarantineDirectory(scummvmDataFileDirectories, scummvmDataFileDirectory, *quarantineDirectory)
+
+	// Add the games kept in archives, unpacking them or getting them ready to be
+	// detected where they are
+	var archives *archiveStaging
+	if *archiveMode != archivesIgnore {
+		gameArchives, err := detect.GameArchives(scummvmDataFileDirectory, archiveExtensions(), detect.ListingOptions{IncludeHidden: *includeHidden})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		archiveDirectories, staging, err := prepareGameArchives(scummvmDataFileDirectory, gameArchives, scummvmDataFileDirectories, *archiveMode, *noWrite, changeJournal)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		archives = staging
+		scummvmDataFileDirectories = append(scummvmDataFileDirectories, archiveDirectories...)
+	}
+
+	// Flatten the game directories whose files are further down in them, or point them
+	// out. A library on remote storage isn't looked into until it is scanned
+	nestedDirectories := make(map[string]nesting)
+	if staging == nil {
+		nestedDirectories = prepareNestedDirectories(scummvmDataFileDirectory, scummvmDataFileDirectories, *nestedMode, *noWrite, changeJournal)
+	}
+
+	// Get the game directories that only have a CD image in them ready too, extracting
+	// the files that are needed or getting them ready to be detected in a temporary copy
+	if archives, err = prepareDiscImages(scummvmDataFileDirectory, scummvmDataFileDirectories, archives, *discImageMode, *noWrite, changeJournal); err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer archives.remove()
+	eventLog.event(scanLogEvent{Phase: scanPhaseList, Directory: scummvmDataFileDirectory, Outcome: "ok", Count: len(scummvmDataFileDirectories)}, listStarted)
+
+	// Create a slice to hold successfully parsed ScummGameMatch structs
+	scummvmOutputSlice := make([]match.ScummGameMatch, 0)
+
+	// Create a slice to hold unsuccessfully parsed ScummGameMatch structs
+	scummvmOutputErrorSlice := make([]match.ScummGameMatch, 0)
+
+	// Count the low confidence matches that were turned into errors
+	lowConfidenceErrors := 0
+
+	// Open the stream the results are written to as they come in. Every result is added
+	// to its slice through the stream, which does nothing if there isn't one
+	var stream *resultStream
+	if *jsonlFile != "" {
+		if stream, err = openResultStream(*jsonlFile); err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer stream.close()
+	}
+
+	// Every result records the version of scummvm and goes through the post-match hook
+	// before it is added, and a hook that fails is reported without stopping the scan
+	addResult := func(scummGameMatches []match.ScummGameMatch, scummGameMatch match.ScummGameMatch) []match.ScummGameMatch {
+		scummGameMatch.ScummvmVersion = scummvmVersionString
+		scummGameMatch.Archive = archives.archive(scummGameMatch.Directory)
+		if contents, ok := archives.discImage(scummGameMatch.Directory); ok {
+			scummGameMatch.DiscImage = contents.image
+			scummGameMatch.DiscImageFiles = contents.paths()
+		}
+		if n, ok := nestedDirectories[scummGameMatch.Directory]; ok && scummGameMatch.ErrorKind != "" {
+			scummGameMatch.NestedIn = n.subdirectories()
+		}
+		if err := config.Hooks.postMatch(scummvmDataFileDirectory, scummGameMatch); err != nil {
+			fmt.Println(err)
+		}
+		if err := stream.write(staging.result(scummGameMatch)); err != nil {
+			fmt.Println(err)
+		}
+		return append(scummGameMatches, scummGameMatch)
+	}
+
+	// Keep track of how long scummvm takes over each directory
+	timings := make([]directoryTiming, 0, len(scummvmDataFileDirectories))
+
+	// Loop through each scummvm data file directory
+	// and execute "scummvm --detect --path=<scummvm data file directory>"
+	// and then parse the output to get the GameID and Description
+	// With --recheck-failures, the directories that failed are then scanned once more,
+	// since a flaky network share can fail a directory that is fine the next time. The
+	// directories a collection of games is split into with --split are added to the
+	// directories being scanned as they are made
+	directoriesToScan := scummvmDataFileDirectories
+	for recheck := false; len(directoriesToScan) > 0; recheck = true {
+		if recheck {
+			fmt.Printf("Scanning the %d directories that failed once more...\n", len(directoriesToScan))
+		}
+		for i := 0; i < len(directoriesToScan); i++ {
+			scummvmDataFilePath := directoriesToScan[i]
+			// Join the scummvm data file directory with the scummvm data file directory path
+			scummvmJoinedDataFilePath := filepath.Join(scummvmDataFileDirectory, scummvmDataFilePath)
+			directoryStarted := time.Now()
+
+			// Run the pre-scan hook, which may get the directory ready, such as by mounting it
+			if err := config.Hooks.preScan(scummvmDataFileDirectory, scummvmJoinedDataFilePath); err != nil {
+				fmt.Println(err)
+			}
+
+			// A game left in its archive goes by the archive's name
+			if archive := archives.archive(scummvmJoinedDataFilePath); archive != "" {
+				fmt.Printf("%s... ", archive)
+			} else {
+				fmt.Printf("%s... ", staging.remotePath(scummvmJoinedDataFilePath))
+			}
+
+			// Check if the directory is already configured as a target in scummvm.ini
+			registeredTarget := registeredTargetPaths[normalizeTargetPath(absoluteDirectory(scummvmJoinedDataFilePath))]
+			if registeredTarget != "" && *registeredMode == "skip" {
+				eventLog.event(scanLogEvent{Phase: scanPhaseMatch, Directory: scummvmJoinedDataFilePath, Outcome: "registered"}, directoryStarted)
+				fmt.Printf("⏭️  already configured as [%s]\n", registeredTarget)
+				continue
+			}
+
+			// Copy the directory from remote storage, recording it like an unreadable one if
+			// it can't be
+			if err := staging.stage(context.Background(), scummvmDataFilePath); err != nil {
+				scummvmOutputErrorSlice = addResult(scummvmOutputErrorSlice, eventLog.result(directoryStarted, match.ScummGameMatch{GameID: "unknown", Description: err.Error(), Directory: scummvmJoinedDataFilePath, ErrorKind: match.ErrorKindFilesystem}))
+				fmt.Printf("❌\n")
+				continue
+			}
+
+			// Unpack a game kept in an archive into the temporary directory it is detected
+			// in, recording it like an unreadable directory if it can't be
+			if err := archives.stage(scummvmJoinedDataFilePath); err != nil {
+				scummvmOutputErrorSlice = addResult(scummvmOutputErrorSlice, eventLog.result(directoryStarted, match.ScummGameMatch{GameID: "unknown", Description: err.Error(), Directory: scummvmJoinedDataFilePath, ErrorKind: match.ErrorKindFilesystem}))
+				fmt.Printf("❌\n")
+				continue
+			}
+			scummvmDetectedPath := archives.path(scummvmJoinedDataFilePath)
+
+			// Make sure the directory can actually be read, so that a single unreadable
+			// directory is recorded and skipped rather than derailing the whole scan
+			if err := retry.Do(func() error { return detect.CheckDirectoryReadable(scummvmDetectedPath) }); err != nil {
+				archives.unstage(scummvmJoinedDataFilePath)
+				// Add the ScummGameMatch struct to the scummvmOutputErrorSlice
+				scummvmOutputErrorSlice = addResult(scummvmOutputErrorSlice, eventLog.result(directoryStarted, match.ScummGameMatch{GameID: "unknown", Description: err.Error(), Directory: scummvmJoinedDataFilePath, ErrorKind: detect.ClassifyFilesystemError(err)}))
+				fmt.Printf("❌\n")
+				continue
+			}
+
+			// Execute "scummvm --detect --path=<scummvm data file directory>", timing how
+			// long it takes
+			detectStarted := time.Now()
+			scummvmOutput, err := detect.Run(scummvmBinary, []string{"--detect", "--path=" + scummvmDetectedPath})
+			timings = append(timings, directoryTiming{Directory: staging.remotePath(scummvmJoinedDataFilePath), Seconds: time.Since(detectStarted).Seconds()})
+
+			// Note what is in the directory, before the files copied from remote storage or
+			// unpacked from an archive are removed again now that scummvm is done with them.
+			// A game left in its archive has no directory to compare the fingerprint with
+			fingerprint := ""
+			if archives.archive(scummvmJoinedDataFilePath) == "" {
+				fingerprint, _ = detect.DirectoryFingerprint(scummvmJoinedDataFilePath)
+			}
+			if err := staging.unstage(scummvmDataFilePath); err != nil {
+				fmt.Printf("⚠️  %s\n", err)
+			}
+			if err := archives.unstage(scummvmJoinedDataFilePath); err != nil {
+				fmt.Printf("⚠️  %s\n", err)
+			}
+			if err != nil {
+				eventLog.event(scanLogEvent{Level: "warn", Phase: scanPhaseDetect, Directory: scummvmJoinedDataFilePath, Outcome: "failed", Error: err.Error()}, detectStarted)
+			} else {
+				eventLog.event(scanLogEvent{Phase: scanPhaseDetect, Directory: scummvmJoinedDataFilePath, Outcome: "ok"}, detectStarted)
+			}
+			if err != nil {
+				// Add the ScummGameMatch struct to the scummvmOutputErrorSlice
+				scummvmOutputErrorSlice = addResult(scummvmOutputErrorSlice, eventLog.result(directoryStarted, match.ScummGameMatch{GameID: "unknown", Description: err.Error(), Directory: scummvmJoinedDataFilePath, ErrorKind: match.ErrorKindScummvm}))
+				fmt.Printf("❌\n")
+				continue
+			}
+
+			// Parse the output
+			candidates, err := parse.DetectOutput(scummvmOutput)
+			if err != nil {
+				scummGameMatch := match.ScummGameMatch{GameID: "unknown", Description: err.Error(), Directory: scummvmJoinedDataFilePath, ErrorKind: match.ErrorKindDetection}
+
+				// Suggest the game the directory name is closest to, so the user knows where
+				// to start looking
+				if *suggestGameIDs {
+					suspectedGameID, suspectedTitle, suspectedSimilarity := match.SuspectedGame(scummvmDataFilePath, options)
+					if suspectedSimilarity >= *similarityThreshold {
+						scummGameMatch.SuspectedGameID = suspectedGameID
+						scummGameMatch.SuspectedTitle = suspectedTitle
+						scummGameMatch.SuspectedSimilarity = suspectedSimilarity
+					}
+				}
+
+				// Look for a collection of games further down in it
+				if *splitMode != splitIgnore && archives.source(scummvmJoinedDataFilePath) == "" && staging == nil {
+					if scummGameMatch.GamesInside, err = findGamesInside(scummvmBinary, scummvmDetectedPath, options); err != nil {
+						eventLog.event(scanLogEvent{Level: "warn", Phase: scanPhaseDetect, Directory: scummvmJoinedDataFilePath, Outcome: "failed", Error: err.Error()}, directoryStarted)
+					}
+				}
+
+				// Add the ScummGameMatch struct to the scummvmOutputErrorSlice
+				scummvmOutputErrorSlice = addResult(scummvmOutputErrorSlice, eventLog.result(directoryStarted, scummGameMatch))
+				fmt.Printf("❌\n")
+
+				// Split the collection into a directory for each game, to be scanned next,
+				// if we were told to
+				if len(scummGameMatch.GamesInside) > 0 {
+					fmt.Printf("   %d games are further down in it:\n", len(scummGameMatch.GamesInside))
+					for _, gameInside := range scummGameMatch.GamesInside {
+						fmt.Printf("   %s: %s\n", gameInside.Directory, gameInside.Description)
+					}
+					split := *splitMode == splitSplit
+					if *splitMode == splitPrompt && isInteractive() {
+						split = promptYesNo(fmt.Sprintf("   Move each of them into a directory of its own next to %s?", scummvmDataFilePath))
+					}
+					if *splitMode == splitReport {
+						fmt.Println("   --split split moves each of them into a directory of its own")
+					}
+					if split {
+						splitDirectories, err := splitMultiGameDirectory(scummvmDataFileDirectory, scummvmJoinedDataFilePath, scummGameMatch.GamesInside, *noWrite, changeJournal)
+						if err != nil {
+							fmt.Printf("⚠️  %s\n", err)
+						}
+						directoriesToScan = append(directoriesToScan, splitDirectories...)
+					}
+				}
+				continue
+			}
+
+			// Keep what scummvm asks to be reported when it doesn't know the variant
+			unknownVariants := parse.UnknownVariants(scummvmOutput)
+
+			// Pick the candidate that is closest to the directory name
+			chosenIndex, similarity, reason := match.Closest(candidates, options)
+			chosenBy := ""
+
+			// Use the answer from the answers file if there is one
+			if answer, ok := answers.Lookup(scummvmJoinedDataFilePath); ok {
+				if answer == skipAnswer {
+					// Add the ScummGameMatch struct to the scummvmOutputErrorSlice
+					scummvmOutputErrorSlice = addResult(scummvmOutputErrorSlice, eventLog.result(directoryStarted, skippedScummGameMatch(match.ScummGameMatch{Directory: scummvmJoinedDataFilePath, Candidates: match.Candidates(candidates), UnknownVariants: unknownVariants})))
+					fmt.Printf("⏭️\n")
+					continue
+				}
+				candidates, chosenIndex = pickedCandidateIndex(candidates, answer, options.GameTitles)
+				chosenBy = "answers"
+			}
+
+			// If scummvm wasn't sure and none of the candidates are similar enough to the
+			// directory name, then follow the low confidence policy
+			lowConfidence := chosenBy == "" && len(candidates) > 1 && similarity < *similarityThreshold
+			if lowConfidence && *lowConfidencePolicy == match.LowConfidenceSkip {
+				// Add the ScummGameMatch struct to the scummvmOutputErrorSlice
+				scummvmOutputErrorSlice = addResult(scummvmOutputErrorSlice, eventLog.result(directoryStarted, skippedScummGameMatch(match.ScummGameMatch{Directory: scummvmJoinedDataFilePath, Candidates: match.Candidates(candidates), UnknownVariants: unknownVariants})))
+				fmt.Printf("⏭️  low confidence\n")
+				continue
+			}
+			if lowConfidence && *lowConfidencePolicy == match.LowConfidenceError {
+				// Add the ScummGameMatch struct to the scummvmOutputErrorSlice
+				scummvmOutputErrorSlice = addResult(scummvmOutputErrorSlice, eventLog.result(directoryStarted, match.ScummGameMatch{GameID: "unknown", Description: fmt.Sprintf("no candidate is similar enough to the directory name (best similarity %.2f)", similarity), Directory: scummvmJoinedDataFilePath, ErrorKind: match.ErrorKindLowConfidence, Candidates: match.Candidates(candidates), UnknownVariants: unknownVariants}))
+				lowConfidenceErrors++
+				fmt.Printf("❌\n")
+				continue
+			}
+			if lowConfidence && *lowConfidencePolicy == match.LowConfidencePrompt && isInteractive() {
+				userGameID, ok := promptForScummGameMatch(scummvmJoinedDataFilePath, candidates, chosenIndex, similarity, options.GameTitles)
+				if !ok {
+					// Remember that the user skipped this directory
+					if *answersFile != "" {
+						answers.Record(scummvmJoinedDataFilePath, skipAnswer)
+					}
+
+					// Add the ScummGameMatch struct to the scummvmOutputErrorSlice
+					scummvmOutputErrorSlice = addResult(scummvmOutputErrorSlice, eventLog.result(directoryStarted, skippedScummGameMatch(match.ScummGameMatch{Directory: scummvmJoinedDataFilePath, Candidates: match.Candidates(candidates), UnknownVariants: unknownVariants})))
+					fmt.Printf("⏭️\n")
+					continue
+				}
+				chosenBy = "user"
+
+				// The user may have picked a game scummvm didn't suggest
+				candidates, chosenIndex = pickedCandidateIndex(candidates, userGameID, options.GameTitles)
+
+				// Remember the user's choice for the next run
+				if *answersFile != "" {
+					answers.Record(scummvmJoinedDataFilePath, candidates[chosenIndex].GameID)
+				}
+			}
+
+			// Create the ScummGameMatch struct, keeping every candidate if scummvm wasn't sure
+			scummGameMatch := match.Chosen(candidates, chosenIndex, reason, chosenBy, options)
+			scummGameMatch.Directory = scummvmJoinedDataFilePath
+			scummGameMatch.RegisteredTarget = registeredTarget
+			scummGameMatch.Fingerprint = fingerprint
+			scummGameMatch.Warnings = parse.DetectWarnings(scummvmOutput)
+			scummGameMatch.Incomplete = parse.MissingFiles(scummGameMatch.Warnings)
+			scummGameMatch.UnknownVariants = unknownVariants
+
+			// Add the ScummGameMatch struct to the scummvmOutputSlice
+			scummvmOutputSlice = addResult(scummvmOutputSlice, eventLog.result(directoryStarted, scummGameMatch))
+
+			fmt.Printf("✅\n")
+
+			// Pass on what scummvm warned about, such as files of the game it couldn't find
+			for _, warning := range scummGameMatch.Warnings {
+				fmt.Printf("   ⚠️  %s\n", warning)
+			}
+			if scummGameMatch.Incomplete {
+				eventLog.event(scanLogEvent{Level: "warn", Phase: scanPhaseMatch, Directory: scummvmJoinedDataFilePath, Outcome: "incomplete", GameID: scummGameMatch.GameID}, time.Now())
+			}
+
+			// Say which files of a CD image have to be kept to play the game
+			if contents, ok := archives.discImage(scummvmJoinedDataFilePath); ok {
+				fmt.Printf("   %s\n", describeDiscImageContents(contents))
+			}
+		}
+
+		directoriesToScan = nil
+		if !recheck && *recheckFailures {
+			directoriesToScan, scummvmOutputErrorSlice = takeRecheckedFailures(scummvmOutputErrorSlice, scummvmDataFileDirectory)
+		}
+	}
+
+	// Save the answers so the same choices are made next time
+	if *answersFile != "" {
+		if err := writeScummvmAnswers(*answersFile, answers); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	// Find the games that are on more than one disc, so that their discs aren't taken
+	// for duplicates
+	output.MarkMultiDiscGames(scummvmOutputSlice, *groupDiscs)
+
+	// Report the games that were found in more than one directory
+	resolveDuplicateScummGames(scummvmOutputSlice, *resolveDuplicates && isInteractive())
+
+	// Warn about the games the scummvm they will be played with has no engine for
+	if engines != nil {
+		for _, scummGameMatch := range missingEngineGames(scummvmOutputSlice, engines) {
+			engine := match.CandidateEngine(scummGameMatch.GameID)
+			eventLog.event(scanLogEvent{Level: "warn", Phase: scanPhaseMatch, Directory: scummGameMatch.Directory, Outcome: "missing-engine", GameID: scummGameMatch.GameID}, time.Now())
+			fmt.Printf("⚠️  %s is a %s game, but %s doesn't have the %s engine\n", scummGameMatch.Directory, engine, engineScummvm, engine)
+		}
+	}
+
+	// Let the user review the ambiguous matches before anything is written
+	writeMarkers := true
+	if *reviewMatches {
+		reviewedSlice, skippedSlice, saved, err := reviewScummGameMatches(scummvmOutputSlice, *similarityThreshold)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if saved {
+			scummvmOutputSlice = reviewedSlice
+			for _, skipped := range skippedSlice {
+				scummvmOutputErrorSlice = append(scummvmOutputErrorSlice, skippedScummGameMatch(skipped))
+			}
+		} else {
+			// Keep the results so the review can be picked up again with "scummer review"
+			fmt.Println("Review cancelled, not writing .scummvm files")
+			writeMarkers = false
+		}
+	}
+
+	// Write the duplicates report, once the review has settled which game each directory
+	// has and before any directory is renamed
+	if *duplicatesReportFile != "" {
+		if err := writeDuplicatesReport(scummvmOutputSlice, *duplicatesReportFile, changeJournal); err != nil {
+			fmt.Printf("⚠️  %s\n", err)
+		}
+	}
+
+	// Write the unknown variants report, so what scummvm asks to be reported isn't lost
+	scannedDirectories := append(append([]match.ScummGameMatch{}, scummvmOutputSlice...), scummvmOutputErrorSlice...)
+	if *unknownVariantsFile != "" && hasUnknownVariants(scannedDirectories) {
+		if err := writeUnknownVariantsReport(scannedDirectories, *unknownVariantsFile, changeJournal); err != nil {
+			fmt.Printf("⚠️  %s\n", err)
+		}
+	}
+
+	// Plan the renames to the games' titles, and show them, so that a scan with --no-write
+	// previews them
+	if *renameToTitle {
+		output.PlanCanonicalDirectories(scummvmOutputSlice)
+		renames := 0
+		for _, scummGameMatch := range scummvmOutputSlice {
+			if scummGameMatch.RenameTo != "" {
+				fmt.Printf("  %s → %s\n", filepath.Base(scummGameMatch.Directory), filepath.Base(scummGameMatch.RenameTo))
+				renames++
+			}
+		}
+		if renames > 0 && (*noWrite || !writeMarkers) {
+			fmt.Printf("%d directories will be renamed to their games' titles when \"scummer apply\" is run\n", renames)
+		}
+	}
+
+	// Plan where the .scummvm files go, and rename the directories that need it if we
+	// are writing them now
+	if layout != output.DefaultMarkerLayout || *renameToTitle {
+		if err := output.PlanMarkerFiles(scummvmOutputSlice, layout); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	if !*noWrite && writeMarkers {
+		if _, err := output.RenameGameDirectories(scummvmOutputSlice, changeJournal); err != nil {
+			fmt.Println(err)
+			fmt.Println("Not writing .scummvm files, run \"scummer apply\" to try again")
+			writeMarkers = false
+		}
+	}
+
+	// Put the directories scummvm couldn't identify in the quarantine directory, before
+	// the results are saved so that they say where the directories are now
+	if *quarantineDirectory != "" && !*noWrite && writeMarkers {
+		quarantined, err := quarantineDirectories(scummvmOutputErrorSlice, *quarantineDirectory, *quarantineMode, changeJournal)
+		if err != nil {
+			fmt.Println(err)
+		}
+		if quarantined > 0 {
+			fmt.Printf("Quarantined %d unidentified directories in %s\n", quarantined, *quarantineDirectory)
+		}
+	}
+
+	// Keep the failures of the last scan, so the webhooks can be told about new ones
+	var previousErrorSlice []match.ScummGameMatch
+	if len(config.Webhooks) > 0 {
+		previousErrorSlice, _ = output.ReadResults(*errorFile)
+	}
+
+	// Save the scummvmOutputSlice to a JSON file
+	if err := output.WriteResults(*successFile, staging.results(scummvmOutputSlice)); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Save the scummvmOutputErrorSlice to a JSON file
+	if err := output.WriteResults(*errorFile, staging.results(scummvmOutputErrorSlice)); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Sum up the scan
+	summary := newScanSummary(scummvmOutputSlice, scummvmOutputErrorSlice, time.Since(started), timings)
+	if err := writeScanSummary(*summaryFile, summary); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Add the results to the database
+	if *databaseFile != "" {
+		if err := recordScanRun(*databaseFile, started, staging.remotePath(scummvmDataFileDirectory), scummvmVersion, staging.results(scummvmOutputSlice), staging.results(scummvmOutputErrorSlice)); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	// Write each scummvmOutputSlice entry to a file that ends with .scummvm and contains the GameID
+	if *noWrite {
+		fmt.Printf("Results saved to %s, run \"scummer apply %s\" to write the .scummvm files\n", *successFile, *successFile)
+	}
+	writtenSlice := scummvmOutputSlice
+	if !*noWrite && writeMarkers {
+		writeStarted := time.Now()
+		fmt.Println("Writing entries out to .scummvm files...")
+		if err := output.WriteMarkerFiles(scummvmOutputSlice, changeJournal, retry); err != nil {
+			eventLog.event(scanLogEvent{Level: "error", Phase: scanPhaseWrite, Directory: scummvmDataFileDirectory, Outcome: "failed", Error: err.Error()}, writeStarted)
+			fmt.Println(err)
+
+			// Carry on with the games whose .scummvm files were written, if there are any
+			var writeErr *output.MarkerWriteError
+			if !errors.As(err, &writeErr) || writeErr.Written == 0 {
+				return
+			}
+			fmt.Printf("Run \"scummer apply %s\" to write the rest once that is sorted out\n", *successFile)
+			writtenSlice = withoutFailedGames(scummvmOutputSlice, writeErr.Failed)
+		} else {
+			eventLog.event(scanLogEvent{Phase: scanPhaseWrite, Directory: scummvmDataFileDirectory, Outcome: "ok", Count: len(scummvmOutputSlice)}, writeStarted)
+		}
+	}
+
+	// Run the post-write hook for each game whose .scummvm file was written
+	if !*noWrite && writeMarkers {
+		for _, scummGameMatch := range output.WithoutLaterDiscs(writtenSlice) {
+			if err := config.Hooks.postWrite(scummvmDataFileDirectory, scummGameMatch); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+
+	// Write the checksums of the games' files, to check them against later
+	if *writeChecksums && !*noWrite && writeMarkers {
+		written := writeChecksumManifests(writtenSlice, changeJournal)
+		fmt.Printf("Wrote the checksums of %d of the %d game directories\n", written, len(writtenSlice))
+	}
+
+	// Copy the cover art to where the frontend looks for it
+	if *copyArt && !*noWrite && writeMarkers {
+		copied, err := copyGameArt(output.WithoutLaterDiscs(writtenSlice), scummvmDataFileDirectory, preset, changeJournal)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Copied %d covers\n", copied)
+	}
+
+	// Add the games to the EmulationStation game list
+	if (*writeGamelistFile || preset.Gamelist) && !*noWrite && writeMarkers {
+		if err := exportGamelist(output.WithoutLaterDiscs(writtenSlice), exportOptions{OutputFile: filepath.Join(scummvmDataFileDirectory, preset.gamelistFileName()), Preset: preset, Journal: changeJournal}); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	// Copy the .scummvm files, and anything else written next to the games, back to the
+	// library on remote storage
+	if !*noWrite && writeMarkers {
+		if err := staging.upload(context.Background()); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	// Run the exporters of the config file, carrying on with the others if one fails
+	if !*noWrite && writeMarkers {
+		for _, plugin := range config.Exporters {
+			if err := plugin.run(staging.results(writtenSlice), exportOptions{Preset: preset, Errors: staging.results(scummvmOutputErrorSlice)}); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+
+	// Run the post-run hook now that everything is written
+	if err := config.Hooks.postRun(scummvmDataFileDirectory, summary); err != nil {
+		fmt.Println(err)
+	}
+
+	// Tell the webhooks the scan is over
+	if len(config.Webhooks) > 0 {
+		notifyWebhooks(config.Webhooks, staging.remotePath(scummvmDataFileDirectory), summary, newFailures(staging.results(scummvmOutputErrorSlice), previousErrorSlice), config.ReportURL)
+	}
+
+	eventLog.event(scanLogEvent{Phase: scanPhaseScan, Directory: scummvmDataFileDirectory, Outcome: "finished", Detected: summary.Detected, Failed: summary.Failed, Skipped: summary.Skipped}, started)
+
+	// Show the summary last, so it is the first thing the user sees
+	summary.print()
+
+	// Fail the run if any low confidence matches were turned into errors
+	if lowConfidenceErrors > 0 {
+		fmt.Printf("%d directories had no candidate that was similar enough to the directory name\n", lowConfidenceErrors)
+		return 1
+	}
+	return 0
+}
+
+// withoutFailedGames returns the games other than the ones whose .scummvm files couldn't
+// be written.
+func withoutFailedGames(scummGameMatches []match.ScummGameMatch, failedSlice []match.ScummGameMatch) []match.ScummGameMatch {
+	failedDirectories := make(map[string]bool)
+	for _, failed := range failedSlice {
+		failedDirectories[failed.Directory] = true
+	}
+	remaining := make([]match.ScummGameMatch, 0, len(scummGameMatches))
+	for _, scummGameMatch := range scummGameMatches {
+		if !failedDirectories[scummGameMatch.Directory] {
+			remaining = append(remaining, scummGameMatch)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/furui/scummer/match"
+)
+
+// These are the formats the scan can log in.
+const (
+	// logFormatText only shows the progress on the console.
+	logFormatText = "text"
+
+	// logFormatJSON also writes a JSON object to stderr for everything the scan does.
+	logFormatJSON = "json"
+)
+
+// These are the phases of a scan that are logged.
+const (
+	// scanPhaseList is listing the directories of the library.
+	scanPhaseList = "list"
+
+	// scanPhaseDetect is scummvm looking at a directory.
+	scanPhaseDetect = "detect"
+
+	// scanPhaseMatch is deciding what is in a directory, once scummvm is done with it.
+	scanPhaseMatch = "match"
+
+	// scanPhaseWrite is writing the .scummvm files.
+	scanPhaseWrite = "write"
+
+	// scanPhaseScan is the whole scan.
+	scanPhaseScan = "scan"
+)
+
+// scanLogEvent is a line of the JSON log. The names are the ones log collectors such
+// as Loki and Elasticsearch expect.
+type scanLogEvent struct {
+	Time            string  `json:"time"`
+	Level           string  `json:"level"`
+	Phase           string  `json:"phase"`
+	Directory       string  `json:"directory,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Outcome         string  `json:"outcome"`
+	GameID          string  `json:"game_id,omitempty"`
+	Error           string  `json:"error,omitempty"`
+
+	// Count is how many directories were listed or games written. Detected, Failed and
+	// Skipped sum up the whole scan.
+	Count    int `json:"count,omitempty"`
+	Detected int `json:"detected,omitempty"`
+	Failed   int `json:"failed,omitempty"`
+	Skipped  int `json:"skipped,omitempty"`
+}
+
+// scanLog writes a scan's events to a JSON log, one per line. A nil scanLog ignores
+// them, so the scan doesn't have to check whether it was asked for one.
+type scanLog struct {
+	writer io.Writer
+}
+
+// event logs something the scan did, which started at started.
+func (l *scanLog) event(event scanLogEvent, started time.Time) {
+	if l == nil {
+		return
+	}
+	now := time.Now()
+	event.Time = now.UTC().Format(time.RFC3339Nano)
+	event.DurationSeconds = now.Sub(started).Seconds()
+	if event.Level == "" {
+		event.Level = "info"
+	}
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Write the whole line at once, so that lines never end up interleaved
+	l.writer.Write(append(eventJSON, '\n'))
+}
+
+// result logs what was decided about a directory that started being scanned at
+// started, and returns the result so it can be passed straight on.
+func (l *scanLog) result(started time.Time, scummGameMatch match.ScummGameMatch) match.ScummGameMatch {
+	event := scanLogEvent{Phase: scanPhaseMatch, Directory: scummGameMatch.Directory, Outcome: scummGameMatchStatus(scummGameMatch)}
+	if scummGameMatch.ErrorKind == "" {
+		event.GameID = scummGameMatch.GameID
+	} else if scummGameMatch.ErrorKind != match.ErrorKindSkipped {
+		event.Level = "warn"
+		event.Error = scummGameMatch.Description
+	}
+	l.event(event, started)
+	return scummGameMatch
+}
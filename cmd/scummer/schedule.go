@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronField is the values one of the five fields of a cron schedule allows, as a bit
+// for each value.
+type cronField uint64
+
+// has returns whether the field allows a value.
+func (f cronField) has(value int) bool {
+	return f&(1<<uint(value)) != 0
+}
+
+// cronSchedule is a cron-style schedule, such as "0 4 * * *" for every day at 4am.
+type cronSchedule struct {
+	minutes  cronField
+	hours    cronField
+	days     cronField
+	months   cronField
+	weekdays cronField
+
+	// Like cron, when both the day of the month and the day of the week are restricted,
+	// a day matching either of them will do.
+	daysRestricted     bool
+	weekdaysRestricted bool
+}
+
+// cronShorthands are the named schedules cron understands.
+var cronShorthands = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// parseCronSchedule parses a schedule in the usual five fields of cron: minute (0-59),
+// hour (0-23), day of the month (1-31), month (1-12) and day of the week (0-6, with 0
+// and 7 both being Sunday). Each field is "*", a number, a range such as "1-5", any of
+// those followed by a step such as "*/15", or a comma separated list of them.
+func parseCronSchedule(spec string) (cronSchedule, error) {
+	schedule := cronSchedule{}
+	if shorthand, ok := cronShorthands[strings.TrimSpace(spec)]; ok {
+		spec = shorthand
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return schedule, fmt.Errorf("schedule %q must have 5 fields: minute, hour, day of month, month and day of week", spec)
+	}
+
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return schedule, fmt.Errorf("schedule %q: minute: %w", spec, err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return schedule, fmt.Errorf("schedule %q: hour: %w", spec, err)
+	}
+	if schedule.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return schedule, fmt.Errorf("schedule %q: day of month: %w", spec, err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return schedule, fmt.Errorf("schedule %q: month: %w", spec, err)
+	}
+	if schedule.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return schedule, fmt.Errorf("schedule %q: day of week: %w", spec, err)
+	}
+
+	// Sunday can be written as 7 too
+	if schedule.weekdays.has(7) {
+		schedule.weekdays |= 1
+	}
+	schedule.daysRestricted = fields[2] != "*"
+	schedule.weekdaysRestricted = fields[4] != "*"
+
+	return schedule, nil
+}
+
+// parseCronField parses one field of a cron schedule, whose values go from min to max.
+func parseCronField(field string, min int, max int) (cronField, error) {
+	var values cronField
+	for _, part := range strings.Split(field, ",") {
+		// Split off the step
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		// Work out the range the step goes over
+		first, last := min, max
+		if rangePart != "*" {
+			firstPart, lastPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if first, err = strconv.Atoi(firstPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", firstPart)
+			}
+			last = first
+			if isRange {
+				if last, err = strconv.Atoi(lastPart); err != nil {
+					return 0, fmt.Errorf("invalid value %q", lastPart)
+				}
+			} else if hasStep {
+				// "5/15" means from 5 to the end, every 15
+				last = max
+			}
+		}
+		if first < min || last > max || first > last {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for value := first; value <= last; value += step {
+			values |= 1 << uint(value)
+		}
+	}
+	return values, nil
+}
+
+// matchesDay returns whether the schedule runs on a day.
+func (c cronSchedule) matchesDay(t time.Time) bool {
+	dayMatches := c.days.has(t.Day())
+	weekdayMatches := c.weekdays.has(int(t.Weekday()))
+	if c.daysRestricted && c.weekdaysRestricted {
+		return dayMatches || weekdayMatches
+	}
+	return dayMatches && weekdayMatches
+}
+
+// next returns the first time after a time that the schedule runs at, or the zero time
+// if it never does, such as on the 31st of February.
+func (c cronSchedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+
+	// Skipping whole months, days and hours at a time, five years is plenty to find any
+	// date that exists
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !c.months.has(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.hours.has(t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.minutes.has(t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// scummerSchedule is when the server scans the library by itself, from the config file.
+// For example:
+//
+//	schedule:
+//	  rescan: "0 4 * * *"
+//	  incremental: "*/30 * * * *"
+//	  write_markers: true
+type scummerSchedule struct {
+	// Rescan is when the whole library is scanned again, and Incremental is when only
+	// the directories that aren't in success.json yet are scanned.
+	Rescan      string `yaml:"rescan"`
+	Incremental string `yaml:"incremental"`
+
+	// Library is the library that is scanned; it is the one the results came from if
+	// empty.
+	Library string `yaml:"library"`
+
+	// WriteMarkers writes the .scummvm files of the games that were found.
+	WriteMarkers bool `yaml:"write_markers"`
+}
+
+// scheduledScan is a scan that runs on a schedule.
+type scheduledScan struct {
+	schedule    cronSchedule
+	incremental bool
+}
+
+// scheduledScans parses the schedules of the config file.
+func (s scummerSchedule) scheduledScans() ([]scheduledScan, error) {
+	scans := make([]scheduledScan, 0, 2)
+	if s.Rescan != "" {
+		schedule, err := parseCronSchedule(s.Rescan)
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, scheduledScan{schedule: schedule})
+	}
+	if s.Incremental != "" {
+		schedule, err := parseCronSchedule(s.Incremental)
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, scheduledScan{schedule: schedule, incremental: true})
+	}
+	return scans, nil
+}
+
+// runSchedule scans the library whenever one of the scheduled scans is due, until the
+// context is done. A scan that is due while another one is still running is skipped.
+func (s *reviewServer) runSchedule(ctx context.Context, schedule scummerSchedule, scans []scheduledScan) {
+	for {
+		// Work out which scan is due first
+		now := time.Now()
+		var due time.Time
+		dueScan := scheduledScan{}
+		for _, scan := range scans {
+			next := scan.schedule.next(now)
+			if next.IsZero() {
+				continue
+			}
+			// A full scan covers an incremental one that is due at the same time
+			if due.IsZero() || next.Before(due) || (next.Equal(due) && !scan.incremental) {
+				due = next
+				dueScan = scan
+			}
+		}
+		if due.IsZero() {
+			return
+		}
+
+		// Wait for it
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Scan the library from the config file, or the one the results came from
+		library, err := s.libraryToScan()
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		kind := "Rescanning"
+		if dueScan.incremental {
+			kind = "Scanning new directories of"
+		}
+		fmt.Printf("%s %s on schedule\n", kind, library)
+		err = s.scanLibrary(ctx, libraryScan{Library: library, WriteMarkers: schedule.WriteMarkers, Incremental: dueScan.incremental}, nil)
+		if errors.Is(err, errScanRunning) {
+			fmt.Println("Skipping the scheduled scan, a scan is already running")
+		} else if err != nil && ctx.Err() == nil {
+			fmt.Println(err)
+		}
+	}
+}
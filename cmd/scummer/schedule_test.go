@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	// A Saturday
+	saturday := time.Date(2026, time.October, 17, 10, 7, 0, 0, time.UTC)
+
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{"*/15 * * * *", saturday, time.Date(2026, time.October, 17, 10, 15, 0, 0, time.UTC)},
+		{"5/15 * * * *", saturday, time.Date(2026, time.October, 17, 10, 20, 0, 0, time.UTC)},
+		{"0 9-17 * * *", saturday, time.Date(2026, time.October, 17, 11, 0, 0, 0, time.UTC)},
+		{"0 9-17 * * *", saturday.Add(7*time.Hour + 30*time.Minute), time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC)},
+		{"30 4 * * 1-5", saturday, time.Date(2026, time.October, 19, 4, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", saturday, time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", saturday, time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)},
+
+		// The day of the month or the day of the week, whichever comes first
+		{"0 0 1 * 1", saturday, time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 1", time.Date(2026, time.October, 31, 10, 7, 0, 0, time.UTC), time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)},
+
+		{"@weekly", saturday, time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", saturday, time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", saturday, time.Time{}},
+	}
+	for _, test := range tests {
+		schedule, err := parseCronSchedule(test.spec)
+		if err != nil {
+			t.Errorf("parseCronSchedule(%q) returned %v", test.spec, err)
+			continue
+		}
+		if got := schedule.next(test.after); !got.Equal(test.want) {
+			t.Errorf("%q after %s = %s, want %s", test.spec, test.after, got, test.want)
+		}
+	}
+}
+
+func TestParseCronScheduleRejects(t *testing.T) {
+	for _, spec := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@fortnightly",
+	} {
+		if _, err := parseCronSchedule(spec); err == nil {
+			t.Errorf("parseCronSchedule(%q) returned no error", spec)
+		}
+	}
+}
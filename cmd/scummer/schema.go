@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/furui/scummer/output"
+)
+
+// runSchema prints the JSON Schema of success.json and error.json, so that programs
+// reading them can check they understand the files before relying on them.
+func runSchema(args []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("schema", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer schema")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// Print the schema
+	schema, err := output.Schema()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(string(schema))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/furui/scummer/match"
+)
+
+// These are the kinds of launch scripts that can be chosen with --script-type.
+const (
+	// scriptTypeShell is a POSIX shell script, for Linux, macOS and handheld port
+	// launchers.
+	scriptTypeShell = "sh"
+
+	// scriptTypeBatch is a Windows batch file.
+	scriptTypeBatch = "bat"
+)
+
+// defaultScriptType returns the kind of launch script that runs on this platform.
+func defaultScriptType() string {
+	if runtime.GOOS == "windows" {
+		return scriptTypeBatch
+	}
+	return scriptTypeShell
+}
+
+// shellQuote quotes an argument for a POSIX shell, in single quotes, which the shell
+// doesn't look inside.
+func shellQuote(argument string) string {
+	return "'" + strings.ReplaceAll(argument, "'", `'\''`) + "'"
+}
+
+// shellLaunchScript returns a shell script that runs a command, passing on any
+// arguments the script was given.
+func shellLaunchScript(path string, arguments []string) string {
+	quotedArguments := []string{shellQuote(path)}
+	for _, argument := range arguments {
+		quotedArguments = append(quotedArguments, shellQuote(argument))
+	}
+	return "#!/bin/sh\nexec " + strings.Join(quotedArguments, " ") + " \"$@\"\n"
+}
+
+// batchLaunchScript returns a batch file that runs a command, passing on any arguments
+// the batch file was given. Percent signs are doubled, since cmd.exe would otherwise
+// take them for variables.
+func batchLaunchScript(path string, arguments []string) string {
+	commandLine := joinLaunchArguments(append([]string{path}, arguments...))
+	return "@echo off\r\n" + strings.ReplaceAll(commandLine, "%", "%%") + " %*\r\n"
+}
+
+// exportLaunchScripts writes a script for each game that starts it in scummvm, named
+// after the game's .scummvm file, into the directory given with --output.
+func exportLaunchScripts(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputDirectory := options.OutputFile
+	if outputDirectory == "" {
+		outputDirectory = "launchers"
+	}
+
+	// Work out what kind of script to write
+	scriptType := options.ScriptType
+	if scriptType == "" {
+		scriptType = defaultScriptType()
+	}
+	if scriptType != scriptTypeShell && scriptType != scriptTypeBatch {
+		return fmt.Errorf("the script type must be either %s or %s", scriptTypeShell, scriptTypeBatch)
+	}
+
+	// Create the directory
+	if err := options.Journal.MkdirAll(outputDirectory, 0755); err != nil {
+		return err
+	}
+
+	// Every game is started by the same scummvm
+	scummvmBinary := exportScummvmCommand(options)
+
+	for _, scummGameMatch := range scummGameMatches {
+		launchArguments := scummvmLaunchArguments(scummvmBinary, scummGameMatch, options)
+		scriptFile := filepath.Join(outputDirectory, gameArtName(scummGameMatch)+"."+scriptType)
+
+		// Write the script, which has to be executable to be launched
+		var err error
+		if scriptType == scriptTypeBatch {
+			err = options.Journal.WriteFile(scriptFile, []byte(batchLaunchScript(scummvmBinary.Path, launchArguments)), 0644)
+		} else {
+			err = options.Journal.WriteFile(scriptFile, []byte(shellLaunchScript(scummvmBinary.Path, launchArguments)), 0755)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Wrote %d launch scripts to %s\n", len(scummGameMatches), outputDirectory)
+	return nil
+}
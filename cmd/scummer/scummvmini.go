@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/furui/scummer/match"
+)
+
+// defaultScummvmIniFile returns where scummvm keeps its scummvm.ini on this platform,
+// or next to scummer in portable mode, where a portable scummvm keeps it too.
+func defaultScummvmIniFile() (string, error) {
+	if portableDirectory != "" {
+		return stateFile("scummvm.ini"), nil
+	}
+	switch runtime.GOOS {
+	case "windows":
+		appData := os.Getenv("APPDATA")
+		if appData == "" {
+			return "", fmt.Errorf("could not find scummvm.ini; use --output to say where it is")
+		}
+		return filepath.Join(appData, "ScummVM", "scummvm.ini"), nil
+	case "darwin":
+		homeDirectory, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(homeDirectory, "Library", "Preferences", "ScummVM Preferences"), nil
+	default:
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			homeDirectory, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			configHome = filepath.Join(homeDirectory, ".config")
+		}
+		return filepath.Join(configHome, "scummvm", "scummvm.ini"), nil
+	}
+}
+
+// scummvmIniTarget returns the [target] section scummvm would add for a game, with the
+// GameID split into its engine and game the way scummvm 2.x writes them.
+func scummvmIniTarget(scummGameMatch match.ScummGameMatch) *scummvmIniSection {
+	target := &scummvmIniSection{Name: match.BareGameID(scummGameMatch.GameID)}
+	target.Entries = append(target.Entries, scummvmIniEntry{Key: "description", Value: scummGameMatch.Description})
+	target.Entries = append(target.Entries, scummvmIniEntry{Key: "path", Value: absoluteDirectory(scummGameMatch.Directory)})
+	if engine := match.CandidateEngine(scummGameMatch.GameID); engine != "" {
+		target.Entries = append(target.Entries, scummvmIniEntry{Key: "engineid", Value: engine})
+	}
+	target.Entries = append(target.Entries, scummvmIniEntry{Key: "gameid", Value: match.BareGameID(scummGameMatch.GameID)})
+	return target
+}
+
+// String returns the section the way it is written in scummvm.ini.
+func (s *scummvmIniSection) String() string {
+	var b strings.Builder
+	b.WriteString("[" + s.Name + "]\n")
+	for _, entry := range s.Entries {
+		b.WriteString(entry.Key + "=" + entry.Value + "\n")
+	}
+	return b.String()
+}
+
+// uniqueScummvmTargetName returns a target name that isn't used yet, the way scummvm
+// picks them: the GameID, or the GameID followed by -1, -2 and so on if it is taken.
+// Target names are compared without regard to case, as scummvm does.
+func uniqueScummvmTargetName(gameID string, usedNames map[string]bool) string {
+	name := gameID
+	for n := 1; usedNames[strings.ToLower(name)]; n++ {
+		name = fmt.Sprintf("%s-%d", gameID, n)
+	}
+	return name
+}
+
+// mergeScummvmIni adds a target for each game to the contents of a scummvm.ini,
+// returning the new contents and how many targets were added. Everything that is
+// already there, including the options set for each game, comments and blank lines,
+// is kept exactly as it was. Games whose path already has a target are left alone, and
+// every new target gets a name that isn't taken yet.
+func mergeScummvmIni(iniContents []byte, scummGameMatches []match.ScummGameMatch) ([]byte, int, error) {
+	ini, err := parseScummvmIni(bytes.NewReader(iniContents))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Remember the names and paths that are already taken
+	usedNames := make(map[string]bool)
+	for _, section := range ini.Sections {
+		usedNames[strings.ToLower(section.Name)] = true
+	}
+	targetPaths := ini.TargetPaths()
+
+	// Add the targets to the end, after making sure the last line is finished
+	merged := append([]byte{}, iniContents...)
+	if len(merged) > 0 && merged[len(merged)-1] != '\n' {
+		merged = append(merged, '\n')
+	}
+	added := 0
+	for _, scummGameMatch := range scummGameMatches {
+		target := scummvmIniTarget(scummGameMatch)
+		path := normalizeTargetPath(target.Get("path"))
+		if targetPaths[path] != "" {
+			continue
+		}
+
+		target.Name = uniqueScummvmTargetName(target.Name, usedNames)
+		usedNames[strings.ToLower(target.Name)] = true
+		targetPaths[path] = target.Name
+
+		if len(merged) > 0 {
+			merged = append(merged, '\n')
+		}
+		merged = append(merged, target.String()...)
+		added++
+	}
+
+	return merged, added, nil
+}
+
+// exportScummvmIni adds a target to scummvm.ini for each game, like "Mass Add" in the
+// scummvm launcher does. The targets are added to the scummvm.ini given with --output,
+// or to the one scummvm uses, leaving everything that is already there alone. Close
+// scummvm first, since it rewrites scummvm.ini when it exits.
+func exportScummvmIni(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	iniFile := options.OutputFile
+	if iniFile == "" {
+		var err error
+		if iniFile, err = defaultScummvmIniFile(); err != nil {
+			return err
+		}
+	}
+
+	// Read what is there already, if scummvm has written it yet
+	iniContents, err := os.ReadFile(iniFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// Keep the permissions it has, which may keep others from reading it
+	iniMode := fs.FileMode(0644)
+	if info, err := os.Stat(iniFile); err == nil {
+		iniMode = info.Mode().Perm()
+	}
+
+	merged, added, err := mergeScummvmIni(iniContents, scummGameMatches)
+	if err != nil {
+		return err
+	}
+
+	// Write the new scummvm.ini next to the old one and then move it into place, so
+	// that scummvm.ini is never left half written
+	if err := options.Journal.MkdirAll(filepath.Dir(iniFile), 0755); err != nil {
+		return err
+	}
+	err = options.Journal.ChangeFile(iniFile, func() error {
+		temporaryFile := iniFile + ".scummer"
+		if err := os.WriteFile(temporaryFile, merged, iniMode); err != nil {
+			return err
+		}
+		if err := os.Chmod(temporaryFile, iniMode); err != nil {
+			os.Remove(temporaryFile)
+			return err
+		}
+		if err := os.Rename(temporaryFile, iniFile); err != nil {
+			os.Remove(temporaryFile)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Added %d targets to %s, %d games already had one\n", added, iniFile, len(scummGameMatches)-added)
+	return nil
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// reviewPageTemplate is the page that lists the ambiguous matches of a scan, with a
+// radio button for every candidate.
+var reviewPageTemplate = template.Must(template.New("review").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>scummer</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+fieldset { margin-bottom: 1em; }
+.low { color: #b00; }
+.similarity { color: #666; font-size: smaller; }
+</style>
+</head>
+<body>
+<h1>Ambiguous matches</h1>
+<p><a href="/dashboard">Library status</a></p>
+{{if .Message}}<p><strong>{{.Message}}</strong></p>{{end}}
+{{if .Matches}}
+<form method="post" action="/resolve">
+{{range .Matches}}
+<fieldset>
+<legend>{{.Directory}}</legend>
+{{if .LowConfidence}}<p class="low">low confidence ({{printf "%.2f" .Confidence}})</p>{{else}}<p>confidence {{printf "%.2f" .Confidence}}{{if .ChosenBy}}, chosen by {{.ChosenBy}}{{end}}</p>{{end}}
+{{$directory := .Directory}}
+{{range .Candidates}}
+<label><input type="radio" name="choice:{{$directory}}" value="{{.GameID}}"{{if .Chosen}} checked{{end}}> {{.GameID}} &mdash; {{.Description}} <span class="similarity">{{printf "%.2f" .Similarity}}</span></label><br>
+{{end}}
+<label><input type="radio" name="choice:{{$directory}}" value="skip"> skip, don't write a .scummvm file</label>
+</fieldset>
+{{end}}
+<button type="submit">Apply</button>
+</form>
+{{else}}
+<p>Nothing to review.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// reviewPageCandidate is a candidate as it is shown on the review page.
+type reviewPageCandidate struct {
+	match.ScummGameCandidate
+	Chosen bool
+}
+
+// reviewPageMatch is an ambiguous match as it is shown on the review page.
+type reviewPageMatch struct {
+	Directory     string
+	Confidence    float64
+	LowConfidence bool
+	ChosenBy      string
+	Candidates    []reviewPageCandidate
+}
+
+// reviewServer serves the results of a scan so that the ambiguous matches can be
+// resolved from a web browser.
+type reviewServer struct {
+	successFile string
+	errorFile   string
+	threshold   float64
+
+	// scummvmPath is the scummvm binary directories are scanned with. An installed
+	// scummvm is looked for if it is empty.
+	scummvmPath string
+
+	// metrics counts what the scans started from the server did.
+	metrics *serveMetrics
+
+	// progress hands the progress of scans to the clients of /events, and scanning is
+	// whether a scan is running.
+	progress *progressBroadcaster
+	scanning atomic.Bool
+
+	// stopping is done when the server is shutting down, which stops the scans that run
+	// in the background, and scans is the scans that are running, so the server can wait
+	// for them to stop.
+	stopping context.Context
+	scans    sync.WaitGroup
+
+	// library is the library the scans started from the server scan, from the schedule
+	// in the config file. It is the one the results came from if it is empty.
+	library string
+
+	// changeJournal records the changes made to the library while the server runs, so
+	// they can be undone.
+	changeJournal *journal.Journal
+
+	// mutex makes sure only one request reads or writes the results at a time.
+	mutex sync.Mutex
+}
+
+// readResults loads the results of the scan. A missing error file counts as empty.
+func (s *reviewServer) readResults() ([]match.ScummGameMatch, []match.ScummGameMatch, error) {
+	scummvmOutputSlice, err := output.ReadResults(s.successFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	scummvmOutputErrorSlice, err := output.ReadResults(s.errorFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		scummvmOutputErrorSlice = make([]match.ScummGameMatch, 0)
+	} else if err != nil {
+		return nil, nil, err
+	}
+	return scummvmOutputSlice, scummvmOutputErrorSlice, nil
+}
+
+// lockedResults loads the results of the scan while no other request is changing them.
+func (s *reviewServer) lockedResults() ([]match.ScummGameMatch, []match.ScummGameMatch, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.readResults()
+}
+
+// handleReviewPage shows every match that needs reviewing.
+func (s *reviewServer) handleReviewPage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	scummvmOutputSlice, _, err := s.readResults()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Build the page from the matches that need reviewing
+	pageMatches := make([]reviewPageMatch, 0)
+	for _, scummGameMatch := range scummvmOutputSlice {
+		if !needsReview(scummGameMatch) {
+			continue
+		}
+		pageMatch := reviewPageMatch{Directory: scummGameMatch.Directory, Confidence: scummGameMatch.Confidence, LowConfidence: scummGameMatch.Confidence < s.threshold, ChosenBy: scummGameMatch.ChosenBy}
+		for _, candidate := range scummGameMatch.Candidates {
+			pageMatch.Candidates = append(pageMatch.Candidates, reviewPageCandidate{ScummGameCandidate: candidate, Chosen: candidate.GameID == scummGameMatch.GameID})
+		}
+		pageMatches = append(pageMatches, pageMatch)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := reviewPageTemplate.Execute(w, map[string]interface{}{"Matches": pageMatches, "Message": r.URL.Query().Get("message")}); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// resolve applies choices of GameIDs, or skipAnswer, keyed by directory, in the same way
+// as finishing "scummer review". It returns how many games were changed and skipped.
+func (s *reviewServer) resolve(choices map[string]string) (int, int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	scummvmOutputSlice, scummvmOutputErrorSlice, err := s.readResults()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Apply the choices. Matches are looked up by directory so that the choices still
+	// land on the right games if the results were changed since they were made.
+	reviewedSlice := make([]match.ScummGameMatch, 0, len(scummvmOutputSlice))
+	skippedSlice := make([]match.ScummGameMatch, 0)
+	changed := 0
+	for _, scummGameMatch := range scummvmOutputSlice {
+		choice := choices[scummGameMatch.Directory]
+		if choice == skipAnswer {
+			skippedSlice = append(skippedSlice, scummGameMatch)
+			continue
+		}
+		if chosen := candidateIndex(scummGameMatch.Candidates, choice); chosen >= 0 && choice != scummGameMatch.GameID {
+			scummGameMatch = chooseScummGameCandidate(scummGameMatch, chosen)
+			changed++
+		}
+		reviewedSlice = append(reviewedSlice, scummGameMatch)
+	}
+
+	// Save the results and update the .scummvm files to match
+	if err := saveReviewedScummGameMatches(s.successFile, s.errorFile, scummvmOutputSlice, reviewedSlice, skippedSlice, scummvmOutputErrorSlice, s.changeJournal); err != nil {
+		return 0, 0, err
+	}
+	return changed, len(skippedSlice), nil
+}
+
+// handleResolve applies the choices submitted from the review page, in the same way
+// as finishing "scummer review".
+func (s *reviewServer) handleResolve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Apply the choices, which are named after the directories
+	choices := make(map[string]string)
+	for name := range r.PostForm {
+		if directory, ok := strings.CutPrefix(name, "choice:"); ok {
+			choices[directory] = r.PostForm.Get(name)
+		}
+	}
+	changed, skipped, err := s.resolve(choices)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	message := fmt.Sprintf("Changed %d games, skipped %d", changed, skipped)
+	fmt.Println(message)
+	http.Redirect(w, r, "/?message="+template.URLQueryEscaper(message), http.StatusSeeOther)
+}
+
+// handleMatches returns the results of the scan as JSON.
+func (s *reviewServer) handleMatches(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	scummvmOutputSlice, _, err := s.readResults()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(scummvmOutputSlice); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// runServe serves a web page for resolving the ambiguous matches of an earlier scan,
+// for when a terminal isn't convenient.
+func runServe(args []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listenAddress := flags.String("listen", "127.0.0.1:8085", "address to serve on")
+	errorFile := flags.String("errors", stateFile("error.json"), "error file of the scan; skipped directories are added to it")
+	similarityThreshold := flags.Float64("threshold", 0.5, "confidence (0 to 1) below which a match is flagged as low confidence")
+	grpcListenAddress := flags.String("grpc-listen", "", "address to serve the gRPC API on, such as 127.0.0.1:8086; it isn't served unless this is given")
+	scummvmPath := flags.String("scummvm", "", "path to the scummvm binary that scans started from the server use, flatpak for the ScummVM Flatpak, or docker:<image> to run it in a container; if not given, scummer looks for an installed scummvm")
+	configFile := flags.String("config", "", "config file, whose schedule says when to scan the library; defaults to "+stateFile(defaultConfigFile)+" if it exists")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer serve [flags] [<success.json>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// The results file defaults to the one scan writes
+	successFile := stateFile("success.json")
+	if flags.NArg() > 0 {
+		successFile = flags.Arg(0)
+	}
+
+	// Make sure the results can be read before starting
+	// In portable mode, a scummvm next to scummer is used before an installed one
+	if *scummvmPath == "" {
+		*scummvmPath = portableScummvm()
+	}
+	server := &reviewServer{successFile: successFile, errorFile: *errorFile, threshold: *similarityThreshold, scummvmPath: *scummvmPath, metrics: newServeMetrics(), progress: newProgressBroadcaster(), changeJournal: newRunJournal()}
+	defer finishRunJournal(server.changeJournal)
+	if _, _, err := server.readResults(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Read the config file
+	config, err := readScummerConfig(stateFile(defaultConfigFile), false)
+	if *configFile != "" {
+		config, err = readScummerConfig(*configFile, true)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Check the schedules before starting
+	scheduledScans, err := config.Schedule.scheduledScans()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	options := serveOptions{ListenAddress: *listenAddress, GRPCListenAddress: *grpcListenAddress, Schedule: config.Schedule, ScheduledScans: scheduledScans}
+
+	// Let the Windows service manager start and stop the server, if it started us
+	if isService, err := runWindowsService(func(ctx context.Context) error { return server.serve(ctx, options) }); isService || err != nil {
+		if err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+
+	// Otherwise serve until we are interrupted or told to stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := server.serve(ctx, options); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/furui/scummer/rpc"
+)
+
+// shutdownTimeout is how long the server waits for requests to finish when it is
+// shutting down.
+const shutdownTimeout = 10 * time.Second
+
+// serveOptions are where the server listens, and when it scans by itself.
+type serveOptions struct {
+	ListenAddress     string
+	GRPCListenAddress string
+	Schedule          scummerSchedule
+	ScheduledScans    []scheduledScan
+}
+
+// serve serves the web pages, and the gRPC API if there is an address for it, until the
+// context is done. It then stops the scans that are running and waits for them, so that
+// no results are left half written, before returning.
+func (s *reviewServer) serve(ctx context.Context, options serveOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.stopping = ctx
+	s.library = options.Schedule.Library
+
+	// Setup the routes
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleReviewPage)
+	mux.HandleFunc("/resolve", sameOrigin(s.handleResolve))
+	mux.HandleFunc("/api/matches", s.handleMatches)
+	mux.HandleFunc("/dashboard", s.handleDashboard)
+	mux.HandleFunc("/rescan", sameOrigin(s.handleRescan))
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/scan", sameOrigin(s.handleScan))
+
+	// Listen before saying we are ready, so that a port that is taken is reported
+	listener, err := net.Listen("tcp", options.ListenAddress)
+	if err != nil {
+		return err
+	}
+	httpServer := &http.Server{Handler: mux}
+	serveErrors := make(chan error, 2)
+	go func() {
+		serveErrors <- httpServer.Serve(listener)
+	}()
+	fmt.Printf("Serving %s on http://%s/\n", s.successFile, options.ListenAddress)
+
+	// Serve the gRPC API alongside the web page if we were asked to
+	var rpcServer *grpc.Server
+	if options.GRPCListenAddress != "" {
+		rpcListener, err := net.Listen("tcp", options.GRPCListenAddress)
+		if err != nil {
+			httpServer.Close()
+			return err
+		}
+		rpcServer = grpc.NewServer()
+		rpc.RegisterScummerServer(rpcServer, &grpcServer{review: s})
+		go func() {
+			serveErrors <- rpcServer.Serve(rpcListener)
+		}()
+		fmt.Printf("Serving the gRPC API on %s\n", options.GRPCListenAddress)
+	}
+
+	// Scan the library on schedule, if there is one
+	if len(options.ScheduledScans) > 0 {
+		go s.runSchedule(ctx, options.Schedule, options.ScheduledScans)
+	}
+
+	// Tell systemd we are up, and keep telling its watchdog we are still alive
+	notifyService("READY=1")
+	if interval := serviceWatchdogInterval(); interval > 0 {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					notifyService("WATCHDOG=1")
+				}
+			}
+		}()
+	}
+
+	// Serve until we are told to stop, or can't serve anymore
+	select {
+	case <-ctx.Done():
+	case err = <-serveErrors:
+	}
+
+	// Stop taking requests, and stop the scans that are running, and wait for them
+	fmt.Println("Shutting down")
+	notifyService("STOPPING=1")
+	cancel()
+	shutdownContext, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if shutdownErr := httpServer.Shutdown(shutdownContext); shutdownErr != nil {
+		httpServer.Close()
+	}
+	if rpcServer != nil {
+		rpcServer.Stop()
+	}
+	s.scans.Wait()
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// sameOrigin turns away the POSTs to a handler that come from another site, so that a web
+// page open in the same browser can't resolve matches, rescan directories or start scans
+// behind the user's back. Requests that don't come from a browser, such as curl's, carry
+// neither header and are let through.
+func sameOrigin(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && !isSameOriginRequest(r) {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// isSameOriginRequest returns whether a request came from one of the server's own pages,
+// or wasn't sent by a web page at all, going by the headers browsers add to it.
+func isSameOriginRequest(r *http.Request) bool {
+	// Browsers that send Sec-Fetch-Site say where the request came from themselves
+	if fetchSite := r.Header.Get("Sec-Fetch-Site"); fetchSite != "" {
+		return fetchSite == "same-origin" || fetchSite == "none"
+	}
+
+	// Otherwise the page's origin has to be the server's
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return originURL.Host == r.Host
+}
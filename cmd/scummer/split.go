@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+	"github.com/furui/scummer/parse"
+)
+
+// The ways --split can handle the directories scummvm finds no game in, but several
+// games further down in, such as a collection copied from a compilation CD.
+const (
+	// splitIgnore doesn't look further down, as scummer always has.
+	splitIgnore = "ignore"
+
+	// splitReport lists the games further down, and records them in error.json.
+	splitReport = "report"
+
+	// splitSplit moves each of the games into a directory of its own next to the
+	// directory, named after the game, where it is scanned like any other.
+	splitSplit = "split"
+
+	// splitPrompt asks about each directory whether to split it.
+	splitPrompt = "prompt"
+)
+
+// relativeGamePath returns where a directory scummvm found a game in is, relative to the
+// directory it was asked to look in, or false if it isn't further down in it. scummvm
+// may print the paths as it was given them or in full, so both are tried.
+func relativeGamePath(directory string, gameDirectory string) (string, bool) {
+	gameDirectory = filepath.Clean(strings.TrimRight(gameDirectory, `/\`))
+	for _, base := range []string{directory, absoluteDirectory(directory)} {
+		relativePath, err := filepath.Rel(base, gameDirectory)
+		if err == nil && relativePath != "." && filepath.IsLocal(relativePath) {
+			return relativePath, true
+		}
+	}
+	return "", false
+}
+
+// findGamesInside has scummvm look for games in every directory further down in a
+// directory it found no game in, and returns the one it would pick in each directory,
+// with Directory relative to the directory. The games in a directory inside another
+// game's directory are left out, since they go wherever that game goes. It returns
+// none unless there are at least two different games, since a single game further
+// down is a nested game directory, and the same game more than once is a game with a
+// directory for each disc.
+func findGamesInside(scummvmBinary detect.Command, directory string, options match.Options) ([]match.GameInside, error) {
+	scummvmOutput, err := detect.Run(scummvmBinary, []string{"--detect", "--recursive", "--path=" + directory})
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := parse.DetectOutput(scummvmOutput)
+	if err != nil {
+		return nil, nil
+	}
+
+	// Group the candidates by the directory they were found in
+	candidatesByDirectory := make(map[string][]match.ScummGameMatch)
+	for _, candidate := range candidates {
+		relativePath, ok := relativeGamePath(directory, candidate.Directory)
+		if !ok {
+			continue
+		}
+		candidate.Directory = filepath.Join(directory, relativePath)
+		candidatesByDirectory[relativePath] = append(candidatesByDirectory[relativePath], candidate)
+	}
+	relativePaths := make([]string, 0, len(candidatesByDirectory))
+	for relativePath := range candidatesByDirectory {
+		relativePaths = append(relativePaths, relativePath)
+	}
+	sort.Strings(relativePaths)
+
+	// Pick the game in each directory the way a scan would, other than in the ones
+	// inside another game's directory, which sort after it
+	gamesInside := make([]match.GameInside, 0, len(relativePaths))
+	gameIDs := make(map[string]bool)
+	for _, relativePath := range relativePaths {
+		if isInsideGameDirectory(relativePath, gamesInside) {
+			continue
+		}
+		directoryCandidates := candidatesByDirectory[relativePath]
+		chosenIndex, _, _ := match.Closest(directoryCandidates, options)
+		chosen := directoryCandidates[chosenIndex]
+		title, _ := match.GameTitle(options.GameTitles, chosen.GameID)
+		gamesInside = append(gamesInside, match.GameInside{Directory: relativePath, GameID: chosen.GameID, Description: chosen.Description, Title: title})
+		gameIDs[chosen.GameID] = true
+	}
+	if len(gameIDs) < 2 {
+		return nil, nil
+	}
+	return gamesInside, nil
+}
+
+// isInsideGameDirectory returns whether a directory is inside the directory of one of
+// the games found further down.
+func isInsideGameDirectory(relativePath string, gamesInside []match.GameInside) bool {
+	for _, gameInside := range gamesInside {
+		if strings.HasPrefix(relativePath, gameInside.Directory+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitMultiGameDirectory moves each of the games found further down in a directory of
+// the library into a directory of its own next to it, named after the game's title the
+// way --rename-to-title names them, and records the moves in the journal. With noWrite
+// the moves are only shown. Whatever else is in the directory is left where it is. It
+// returns the names of the directories the games were moved to, to be scanned.
+func splitMultiGameDirectory(library string, directory string, gamesInside []match.GameInside, noWrite bool, changeJournal *journal.Journal) ([]string, error) {
+	takenNames := make(map[string]bool)
+	splitDirectories := make([]string, 0, len(gamesInside))
+	for _, gameInside := range gamesInside {
+		// Name the directory after the game, numbering it if the name is taken
+		name := output.CanonicalDirectoryName(match.ScummGameMatch{Title: gameInside.Title, Description: gameInside.Description})
+		if name == "" {
+			name = filepath.Base(gameInside.Directory)
+		}
+		destinationName := name
+		for n := 2; ; n++ {
+			_, err := os.Lstat(filepath.Join(library, destinationName))
+			if !takenNames[strings.ToLower(destinationName)] && errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+			destinationName = name + " (" + strconv.Itoa(n) + ")"
+		}
+		takenNames[strings.ToLower(destinationName)] = true
+
+		source := filepath.Join(directory, gameInside.Directory)
+		destination := filepath.Join(library, destinationName)
+		if noWrite {
+			fmt.Printf("   Would move %s to %s\n", source, destination)
+			continue
+		}
+		if err := moveDirectory(source, destination); err != nil {
+			return splitDirectories, err
+		}
+		if err := changeJournal.Renamed(source, destination); err != nil {
+			return splitDirectories, err
+		}
+		fmt.Printf("   Moved %s to %s\n", source, destination)
+		splitDirectories = append(splitDirectories, destinationName)
+	}
+	return splitDirectories, nil
+}
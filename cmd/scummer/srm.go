@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/furui/scummer/match"
+)
+
+// srmManifestFileName is the name Steam ROM Manager's manual parser looks for.
+const srmManifestFileName = "manifests.json"
+
+// srmManifestEntry is a game in a Steam ROM Manager manifest.
+type srmManifestEntry struct {
+	Title         string `json:"title"`
+	Target        string `json:"target"`
+	StartIn       string `json:"startIn"`
+	LaunchOptions string `json:"launchOptions"`
+}
+
+// exportSrmManifest writes a manifest for Steam ROM Manager's manual parser, which adds
+// each game to Steam launching scummvm with the game's directory and GameID, and finds
+// artwork for it by its title.
+func exportSrmManifest(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputFile := options.OutputFile
+	if outputFile == "" {
+		outputFile = srmManifestFileName
+	}
+
+	// Every game is started by the same scummvm
+	scummvmBinary := exportScummvmCommand(options)
+
+	manifest := make([]srmManifestEntry, 0, len(scummGameMatches))
+	for _, scummGameMatch := range scummGameMatches {
+		manifest = append(manifest, srmManifestEntry{
+			Title:         match.ParseDescriptionVariant(scummGameMatch.Description).Title,
+			Target:        scummvmBinary.Path,
+			StartIn:       absoluteDirectory(scummGameMatch.Directory),
+			LaunchOptions: joinLaunchArguments(scummvmLaunchArguments(scummvmBinary, scummGameMatch, options)),
+		})
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := options.Journal.WriteFile(outputFile, append(manifestJSON, '\n'), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d games to %s\n", len(manifest), outputFile)
+	return nil
+}
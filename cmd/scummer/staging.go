@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/remote"
+)
+
+// remoteStaging is the local directory a library on remote storage is scanned in. Each
+// game directory is copied into it just long enough for scummvm to detect the game,
+// and what is written into it afterwards, such as the .scummvm files, is copied back. A
+// nil remoteStaging is a local library, so the scan doesn't have to check which it has.
+type remoteStaging struct {
+	library   remote.Library
+	directory string
+}
+
+// newRemoteStaging creates the staging directory of a library on remote storage in
+// stagingRoot, with the library's directories in it, empty, and the files at the top of
+// it. The staging directory is named after the library, so that the answers file sees
+// the same directories every time it is scanned.
+func newRemoteStaging(ctx context.Context, library remote.Library, stagingRoot string) (*remoteStaging, error) {
+	libraryHash := fnv.New32a()
+	libraryHash.Write([]byte(library.URL))
+	directory := filepath.Join(stagingRoot, fmt.Sprintf("scummer-staging-%08x", libraryHash.Sum32()))
+
+	// Start over, in case an earlier scan was stopped part of the way through
+	if err := os.RemoveAll(directory); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	s := &remoteStaging{library: library, directory: directory}
+
+	directories, err := library.Directories(ctx)
+	if err != nil {
+		s.remove()
+		return nil, err
+	}
+	for _, gameDirectory := range directories {
+		if err := os.Mkdir(filepath.Join(directory, gameDirectory), 0755); err != nil {
+			s.remove()
+			return nil, err
+		}
+	}
+	if err := library.DownloadFiles(ctx, directory); err != nil {
+		s.remove()
+		return nil, err
+	}
+	return s, nil
+}
+
+// stage copies a game directory of the library into its empty directory in the staging
+// directory.
+func (s *remoteStaging) stage(ctx context.Context, gameDirectory string) error {
+	if s == nil {
+		return nil
+	}
+	return s.library.Download(ctx, gameDirectory, filepath.Join(s.directory, gameDirectory))
+}
+
+// unstage empties a game directory of the staging directory once scummvm is done with
+// it, so that only one game at a time takes up space, and only what scummer writes into
+// it is copied back.
+func (s *remoteStaging) unstage(gameDirectory string) error {
+	if s == nil {
+		return nil
+	}
+	stagedDirectory := filepath.Join(s.directory, gameDirectory)
+	if err := os.RemoveAll(stagedDirectory); err != nil {
+		return err
+	}
+	return os.Mkdir(stagedDirectory, 0755)
+}
+
+// upload copies what was written into the staging directory back to the library.
+func (s *remoteStaging) upload(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	return s.library.Upload(ctx, s.directory)
+}
+
+// remove deletes the staging directory.
+func (s *remoteStaging) remove() {
+	if s != nil {
+		os.RemoveAll(s.directory)
+	}
+}
+
+// remotePath returns the URL of a path in the staging directory, or the path as it is
+// if it isn't in it.
+func (s *remoteStaging) remotePath(path string) string {
+	if s == nil || path == "" {
+		return path
+	}
+	relativePath, err := filepath.Rel(s.directory, path)
+	if err != nil || strings.HasPrefix(relativePath, "..") {
+		return path
+	}
+	return s.library.Path(filepath.ToSlash(relativePath))
+}
+
+// result returns a result with its paths in the staging directory changed to where they
+// are in the library, for the results that outlive the scan.
+func (s *remoteStaging) result(scummGameMatch match.ScummGameMatch) match.ScummGameMatch {
+	if s == nil {
+		return scummGameMatch
+	}
+	scummGameMatch.Directory = s.remotePath(scummGameMatch.Directory)
+	scummGameMatch.RenameTo = s.remotePath(scummGameMatch.RenameTo)
+	scummGameMatch.CanonicalDirectory = s.remotePath(scummGameMatch.CanonicalDirectory)
+	if len(scummGameMatch.MarkerFiles) > 0 {
+		markerFiles := make([]string, 0, len(scummGameMatch.MarkerFiles))
+		for _, markerFile := range scummGameMatch.MarkerFiles {
+			markerFiles = append(markerFiles, s.remotePath(markerFile))
+		}
+		scummGameMatch.MarkerFiles = markerFiles
+	}
+	return scummGameMatch
+}
+
+// results returns the results with their paths changed the way result does.
+func (s *remoteStaging) results(scummGameMatches []match.ScummGameMatch) []match.ScummGameMatch {
+	if s == nil {
+		return scummGameMatches
+	}
+	remoteMatches := make([]match.ScummGameMatch, 0, len(scummGameMatches))
+	for _, scummGameMatch := range scummGameMatches {
+		remoteMatches = append(remoteMatches, s.result(scummGameMatch))
+	}
+	return remoteMatches
+}
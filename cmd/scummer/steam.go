@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+)
+
+// steamShortcutsFileName is the file each Steam user's non-Steam game shortcuts are
+// kept in, in userdata/<user>/config.
+const steamShortcutsFileName = "shortcuts.vdf"
+
+// steamDirectories returns the places Steam might be installed on this platform, in the
+// order they should be tried.
+func steamDirectories() []string {
+	var directories []string
+	homeDirectory, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		for _, programFiles := range []string{os.Getenv("ProgramFiles(x86)"), os.Getenv("ProgramFiles")} {
+			if programFiles != "" {
+				directories = append(directories, filepath.Join(programFiles, "Steam"))
+			}
+		}
+	case "darwin":
+		directories = append(directories, filepath.Join(homeDirectory, "Library", "Application Support", "Steam"))
+	default:
+		directories = append(directories,
+			filepath.Join(homeDirectory, ".steam", "steam"),
+			filepath.Join(homeDirectory, ".local", "share", "Steam"),
+			filepath.Join(homeDirectory, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+		)
+	}
+	return directories
+}
+
+// findSteamShortcutsFile works out which shortcuts.vdf to add the games to. The Steam
+// user can be left out if only one user has ever logged in to Steam.
+func findSteamShortcutsFile(steamUser string) (string, error) {
+	for _, steamDirectory := range steamDirectories() {
+		userdataDirectory := filepath.Join(steamDirectory, "userdata")
+		entries, err := os.ReadDir(userdataDirectory)
+		if err != nil {
+			continue
+		}
+
+		// Find the users that have logged in to this Steam
+		users := make([]string, 0)
+		for _, entry := range entries {
+			if _, err := strconv.ParseUint(entry.Name(), 10, 32); entry.IsDir() && err == nil && entry.Name() != "0" {
+				users = append(users, entry.Name())
+			}
+		}
+		sort.Strings(users)
+
+		if steamUser == "" {
+			if len(users) != 1 {
+				return "", fmt.Errorf("found steam users %v in %s; choose one with --steam-user", users, userdataDirectory)
+			}
+			steamUser = users[0]
+		}
+		return filepath.Join(userdataDirectory, steamUser, "config", steamShortcutsFileName), nil
+	}
+	return "", fmt.Errorf("could not find steam; use --output to give the path to shortcuts.vdf")
+}
+
+// steamShortcutAppID works out the ID Steam gives a non-Steam game shortcut, which it
+// uses to name the shortcut's grid images.
+func steamShortcutAppID(exe string, appName string) uint32 {
+	return crc32.ChecksumIEEE([]byte(exe+appName)) | 0x80000000
+}
+
+// newSteamShortcut creates the shortcuts.vdf entry for a game.
+func newSteamShortcut(index int, appID uint32, appName string, exe string, startDir string, launchOptions string) vdfValue {
+	return vdfValue{Type: vdfMap, Name: strconv.Itoa(index), Children: []vdfValue{
+		{Type: vdfInt32, Name: "appid", Int: uint64(appID)},
+		{Type: vdfString, Name: "AppName", String: appName},
+		{Type: vdfString, Name: "Exe", String: exe},
+		{Type: vdfString, Name: "StartDir", String: startDir},
+		{Type: vdfString, Name: "icon"},
+		{Type: vdfString, Name: "ShortcutPath"},
+		{Type: vdfString, Name: "LaunchOptions", String: launchOptions},
+		{Type: vdfInt32, Name: "IsHidden"},
+		{Type: vdfInt32, Name: "AllowDesktopConfig", Int: 1},
+		{Type: vdfInt32, Name: "AllowOverlay", Int: 1},
+		{Type: vdfInt32, Name: "OpenVR"},
+		{Type: vdfInt32, Name: "Devkit"},
+		{Type: vdfString, Name: "DevkitGameID"},
+		{Type: vdfInt32, Name: "DevkitOverrideAppID"},
+		{Type: vdfInt32, Name: "LastPlayTime"},
+		{Type: vdfString, Name: "FlatpakAppID"},
+		{Type: vdfMap, Name: "tags", Children: []vdfValue{
+			{Type: vdfString, Name: "0", String: "ScummVM"},
+		}},
+	}}
+}
+
+// readSteamShortcuts loads a shortcuts.vdf. A file that doesn't exist yet is treated
+// as having no shortcuts.
+func readSteamShortcuts(shortcutsFile string) (vdfValue, error) {
+	shortcutsVdf, err := os.ReadFile(shortcutsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return vdfValue{Type: vdfMap, Children: []vdfValue{{Type: vdfMap, Name: "shortcuts"}}}, nil
+	} else if err != nil {
+		return vdfValue{}, err
+	}
+	return parseVdf(shortcutsVdf)
+}
+
+// placeSteamGridImage copies a game's cover art to the Steam grid directory, as the
+// portrait image of its shortcut. Art that is already there is left alone.
+func placeSteamGridImage(scummGameMatch match.ScummGameMatch, gridDirectory string, appID uint32, changeJournal *journal.Journal) (bool, error) {
+	artFile, ok := findGameArt(scummGameMatch.Directory)
+	if !ok {
+		return false, nil
+	}
+
+	destination := filepath.Join(gridDirectory, fmt.Sprintf("%dp.png", appID))
+	if _, err := os.Stat(destination); err == nil {
+		return false, nil
+	}
+	if err := changeJournal.MkdirAll(gridDirectory, 0755); err != nil {
+		return false, err
+	}
+	return true, changeJournal.ChangeFile(destination, func() error { return copyFile(artFile, destination) })
+}
+
+// exportSteamShortcuts adds a non-Steam game shortcut for each game to a Steam user's
+// shortcuts.vdf, launching scummvm with the game's directory and GameID. Games that
+// already have a shortcut are left alone. Steam has to be closed while this runs,
+// otherwise it overwrites the file when it exits.
+func exportSteamShortcuts(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	// Work out which shortcuts.vdf to add to
+	shortcutsFile := options.OutputFile
+	if shortcutsFile == "" {
+		var err error
+		if shortcutsFile, err = findSteamShortcutsFile(options.SteamUser); err != nil {
+			return err
+		}
+	}
+
+	root, err := readSteamShortcuts(shortcutsFile)
+	if err != nil {
+		return err
+	}
+	shortcuts, ok := root.child("shortcuts")
+	if !ok {
+		return fmt.Errorf("%s has no shortcuts in it", shortcutsFile)
+	}
+
+	// Remember which games already have a shortcut
+	existingShortcuts := make(map[string]bool)
+	for _, shortcut := range shortcuts.Children {
+		existingShortcuts[shortcut.childString("LaunchOptions")] = true
+	}
+
+	// Every game is started by the same scummvm
+	scummvmBinary := exportScummvmCommand(options)
+	// Steam always keeps the program and the directory it starts in in quotes
+	exe := `"` + scummvmBinary.Path + `"`
+
+	added, placed := 0, 0
+	for _, scummGameMatch := range scummGameMatches {
+		launchOptions := joinLaunchArguments(scummvmLaunchArguments(scummvmBinary, scummGameMatch, options))
+		if existingShortcuts[launchOptions] {
+			continue
+		}
+		existingShortcuts[launchOptions] = true
+
+		// Add the shortcut
+		appName := match.ParseDescriptionVariant(scummGameMatch.Description).Title
+		appID := steamShortcutAppID(exe, appName)
+		startDir := `"` + absoluteDirectory(scummGameMatch.Directory) + `"`
+		shortcuts.Children = append(shortcuts.Children, newSteamShortcut(len(shortcuts.Children), appID, appName, exe, startDir, launchOptions))
+		added++
+
+		// Put the cover art where Steam looks for it
+		if options.SteamGrid {
+			ok, err := placeSteamGridImage(scummGameMatch, filepath.Join(filepath.Dir(shortcutsFile), "grid"), appID, options.Journal)
+			if err != nil {
+				return err
+			}
+			if ok {
+				placed++
+			}
+		}
+	}
+
+	// Save the shortcuts
+	if err := options.Journal.MkdirAll(filepath.Dir(shortcutsFile), 0755); err != nil {
+		return err
+	}
+	if err := options.Journal.WriteFile(shortcutsFile, formatVdf(root), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added %d games to %s\n", added, shortcutsFile)
+	if options.SteamGrid {
+		fmt.Printf("Placed %d grid images\n", placed)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// steamStoreWords are the words Steam adds to the names of games that aren't part of
+// their titles, such as "King's Quest Collection" or "Myst: Masterpiece Edition", along
+// with the little words titles leave out as often as not.
+var steamStoreWords = map[string]bool{
+	"a": true, "an": true, "and": true, "of": true, "the": true,
+	"anniversary": true, "bundle": true, "classic": true, "classics": true, "collection": true,
+	"complete": true, "cut": true, "definitive": true, "deluxe": true, "director's": true,
+	"edition": true, "enhanced": true, "masterpiece": true, "pack": true, "remastered": true,
+	"special": true,
+}
+
+// runImportSteam imports the ScummVM games installed in Steam's library folders.
+func runImportSteam(args []string) {
+	runGameImport("import-steam", "Steam", args, steamInstalledGames)
+}
+
+// steamInstalledGames returns the directories of the games installed in every Steam
+// library whose names look like a game scummvm knows about. Steam installs far more than
+// ScummVM games, and running scummvm on every one of them would take a long time.
+func steamInstalledGames(gameTitles map[string]string) []string {
+	installDirectories := make([]string, 0)
+	for _, library := range steamLibraries() {
+		manifestFiles, _ := filepath.Glob(filepath.Join(library, "steamapps", "appmanifest_*.acf"))
+		for _, manifestFile := range manifestFiles {
+			data, err := os.ReadFile(manifestFile)
+			if err != nil {
+				fmt.Printf("⚠️  %s\n", err)
+				continue
+			}
+			manifest, err := parseTextVdf(data)
+			if err != nil {
+				fmt.Printf("⚠️  %s: %s\n", manifestFile, err)
+				continue
+			}
+
+			// Each manifest is an AppState map saying what the game is called and which
+			// directory of steamapps/common it is installed in
+			appState, ok := manifest.child("AppState")
+			if !ok {
+				continue
+			}
+			installDirectory := appState.childString("installdir")
+			if installDirectory == "" || !isKnownGameName(appState.childString("name"), gameTitles) {
+				continue
+			}
+			installDirectories = append(installDirectories, filepath.Join(library, "steamapps", "common", installDirectory))
+		}
+	}
+	return uniqueDirectories(installDirectories)
+}
+
+// steamLibraries returns the library folders of every Steam that is installed, which
+// are Steam's own directory and the ones listed in its steamapps/libraryfolders.vdf.
+// Symlinks are followed, since ~/.steam/steam is usually a link to another of the
+// directories Steam is looked for in.
+func steamLibraries() []string {
+	libraries := make([]string, 0)
+	for _, steamDirectory := range steamDirectories() {
+		if _, err := os.Stat(filepath.Join(steamDirectory, "steamapps")); err != nil {
+			continue
+		}
+		libraries = append(libraries, steamDirectory)
+
+		libraryFoldersFile := filepath.Join(steamDirectory, "steamapps", "libraryfolders.vdf")
+		data, err := os.ReadFile(libraryFoldersFile)
+		if err != nil {
+			continue
+		}
+		libraryFolders, err := parseTextVdf(data)
+		if err != nil {
+			fmt.Printf("⚠️  %s: %s\n", libraryFoldersFile, err)
+			continue
+		}
+		libraries = append(libraries, steamLibraryFolderPaths(libraryFolders)...)
+	}
+
+	for i, library := range libraries {
+		if resolvedLibrary, err := filepath.EvalSymlinks(library); err == nil {
+			libraries[i] = resolvedLibrary
+		}
+	}
+	return uniqueDirectories(libraries)
+}
+
+// steamLibraryFolderPaths returns the library folders listed in libraryfolders.vdf.
+// Each one is numbered, and is a map with its "path" in it, or just the path in the
+// files older versions of Steam wrote.
+func steamLibraryFolderPaths(libraryFolders vdfValue) []string {
+	paths := make([]string, 0)
+	for _, root := range libraryFolders.Children {
+		for _, libraryFolder := range root.Children {
+			if _, err := strconv.Atoi(libraryFolder.Name); err != nil {
+				continue
+			}
+			if libraryFolder.Type == vdfMap {
+				if path := libraryFolder.childString("path"); path != "" {
+					paths = append(paths, path)
+				}
+			} else if libraryFolder.String != "" {
+				paths = append(paths, libraryFolder.String)
+			}
+		}
+	}
+	return paths
+}
+
+// isKnownGameName returns whether a name Steam gives a game looks like the title of a
+// game scummvm knows about, because every word of it, other than the ones in
+// steamStoreWords, is in the title. Steam's names are often longer than the titles, or
+// name a collection of several games, so they can't be compared as a whole.
+func isKnownGameName(name string, gameTitles map[string]string) bool {
+	nameWords := make([]string, 0)
+	for _, word := range titleWords(name) {
+		if !steamStoreWords[word] {
+			nameWords = append(nameWords, word)
+		}
+	}
+	if len(nameWords) == 0 {
+		return false
+	}
+
+	for _, title := range gameTitles {
+		titleWordSet := make(map[string]bool)
+		for _, word := range titleWords(title) {
+			titleWordSet[word] = true
+		}
+		allInTitle := true
+		for _, word := range nameWords {
+			if !titleWordSet[word] {
+				allInTitle = false
+				break
+			}
+		}
+		if allInTitle {
+			return true
+		}
+	}
+	return false
+}
+
+// titleWords splits the title of a game into lower case words, keeping apostrophes so
+// that "King's" stays one word.
+func titleWords(title string) []string {
+	title = strings.ToLower(strings.ReplaceAll(title, "’", "'"))
+	return strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/furui/scummer/match"
+)
+
+// resultStream writes each directory's result to a JSON Lines file as soon as it is
+// known, so that other programs can follow a scan as it happens, and so that the
+// results aren't lost if the scan dies before success.json is written.
+type resultStream struct {
+	file *os.File
+}
+
+// streamedResult is a line of the JSON Lines file: a directory's result, along with its
+// status as the csv format spells it.
+type streamedResult struct {
+	Status string
+	match.ScummGameMatch
+}
+
+// openResultStream creates the JSON Lines file, replacing it if it already exists.
+func openResultStream(fileName string) (*resultStream, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &resultStream{file: file}, nil
+}
+
+// write adds a directory's result to the stream. A nil stream ignores it, so the scan
+// doesn't have to check whether it was asked for one.
+func (s *resultStream) write(scummGameMatch match.ScummGameMatch) error {
+	if s == nil {
+		return nil
+	}
+	resultJSON, err := json.Marshal(streamedResult{Status: scummGameMatchStatus(scummGameMatch), ScummGameMatch: scummGameMatch})
+	if err != nil {
+		return err
+	}
+
+	// Write the whole line at once, so that anyone following the file never sees half
+	// of one
+	_, err = s.file.Write(append(resultJSON, '\n'))
+	return err
+}
+
+// add appends a directory's result to a slice of results, and writes it to the stream.
+// A result that can't be written is reported, but still kept in the slice.
+func (s *resultStream) add(scummGameMatches []match.ScummGameMatch, scummGameMatch match.ScummGameMatch) []match.ScummGameMatch {
+	if err := s.write(scummGameMatch); err != nil {
+		fmt.Println(err)
+	}
+	return append(scummGameMatches, scummGameMatch)
+}
+
+// close closes the stream. A nil stream has nothing to close.
+func (s *resultStream) close() error {
+	if s == nil {
+		return nil
+	}
+	return s.file.Close()
+}
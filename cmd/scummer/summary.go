@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/furui/scummer/match"
+)
+
+// summarySlowestDirectories is how many of the slowest directories the summary lists.
+const summarySlowestDirectories = 5
+
+// directoryTiming is how long scummvm took over a directory.
+type directoryTiming struct {
+	Directory string
+	Seconds   float64
+}
+
+// scanSummary sums up a scan once it is over.
+type scanSummary struct {
+	Detected           int
+	Ambiguous          int
+	Incomplete         int
+	Failed             int
+	Skipped            int
+	Engines            map[string]int
+	RuntimeSeconds     float64
+	SlowestDirectories []directoryTiming
+}
+
+// newScanSummary sums up the results of a scan that took runtime, given how long each
+// directory took.
+func newScanSummary(scummGameMatches []match.ScummGameMatch, errorSlice []match.ScummGameMatch, runtime time.Duration, timings []directoryTiming) scanSummary {
+	summary := scanSummary{Detected: len(scummGameMatches), Engines: make(map[string]int), RuntimeSeconds: runtime.Seconds()}
+
+	// Count the games of each engine, and the ones scummvm wasn't sure about
+	for _, scummGameMatch := range scummGameMatches {
+		summary.Engines[match.CandidateEngine(scummGameMatch.GameID)]++
+		if len(scummGameMatch.Candidates) > 1 {
+			summary.Ambiguous++
+		}
+		if scummGameMatch.Incomplete {
+			summary.Incomplete++
+		}
+	}
+
+	// Tell the directories that were skipped apart from the ones that failed
+	for _, scummGameMatch := range errorSlice {
+		if scummGameMatch.ErrorKind == match.ErrorKindSkipped {
+			summary.Skipped++
+		} else {
+			summary.Failed++
+		}
+	}
+
+	// Keep the slowest directories
+	summary.SlowestDirectories = append([]directoryTiming{}, timings...)
+	sort.SliceStable(summary.SlowestDirectories, func(i, j int) bool {
+		return summary.SlowestDirectories[i].Seconds > summary.SlowestDirectories[j].Seconds
+	})
+	if len(summary.SlowestDirectories) > summarySlowestDirectories {
+		summary.SlowestDirectories = summary.SlowestDirectories[:summarySlowestDirectories]
+	}
+
+	return summary
+}
+
+// print shows the summary at the end of a scan.
+func (s scanSummary) print() {
+	fmt.Printf("\n%d detected (%d ambiguous), %d failed, %d skipped in %.1fs\n", s.Detected, s.Ambiguous, s.Failed, s.Skipped, s.RuntimeSeconds)
+	if s.Incomplete == 1 {
+		fmt.Println("1 game looks incomplete, scummvm warned about missing files")
+	} else if s.Incomplete > 1 {
+		fmt.Printf("%d games look incomplete, scummvm warned about missing files\n", s.Incomplete)
+	}
+
+	// List the engines, most games first
+	engines := make([]string, 0, len(s.Engines))
+	for engine := range s.Engines {
+		engines = append(engines, engine)
+	}
+	sort.Slice(engines, func(i, j int) bool {
+		if s.Engines[engines[i]] != s.Engines[engines[j]] {
+			return s.Engines[engines[i]] > s.Engines[engines[j]]
+		}
+		return engines[i] < engines[j]
+	})
+	engineCounts := make([]string, len(engines))
+	for i, engine := range engines {
+		engineCounts[i] = fmt.Sprintf("%s %d", engine, s.Engines[engine])
+	}
+	if len(engineCounts) > 0 {
+		fmt.Printf("Engines: %s\n", strings.Join(engineCounts, ", "))
+	}
+
+	// List the slowest directories
+	if len(s.SlowestDirectories) > 0 {
+		fmt.Println("Slowest directories:")
+		for _, timing := range s.SlowestDirectories {
+			fmt.Printf("  %5.1fs  %s\n", timing.Seconds, timing.Directory)
+		}
+	}
+}
+
+// writeScanSummary saves the summary to a JSON file.
+func writeScanSummary(fileName string, summary scanSummary) error {
+	summaryJSON, err := json.MarshalIndent(summary, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, summaryJSON, 0644)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// unarchiver is a program that can unpack archives Go can't read on its own, such as
+// 7-Zip and The Unarchiver.
+type unarchiver struct {
+	// names are the names the program goes by on the PATH, most preferred first.
+	names []string
+
+	// installPaths are where it is installed when it isn't on the PATH, such as on
+	// Windows, where the 7-Zip installer doesn't add it.
+	installPaths []string
+
+	// args returns the arguments that unpack an archive into a destination directory,
+	// overwriting anything that is already there without asking.
+	args func(archive string, destination string) []string
+}
+
+// sevenZip is 7-Zip, or p7zip, which unpacks .7z archives, and .rar archives too in most
+// builds.
+var sevenZip = unarchiver{
+	names: []string{"7z", "7zz", "7za"},
+	installPaths: []string{
+		filepath.Join(os.Getenv("ProgramFiles"), "7-Zip", "7z.exe"),
+		filepath.Join(os.Getenv("ProgramFiles(x86)"), "7-Zip", "7z.exe"),
+	},
+	args: func(archive string, destination string) []string {
+		return []string{"x", "-y", "-bd", "-o" + destination, archive}
+	},
+}
+
+// theUnarchiver is unar, from The Unarchiver, which unpacks .rar archives.
+var theUnarchiver = unarchiver{
+	names: []string{"unar"},
+	args: func(archive string, destination string) []string {
+		return []string{"-quiet", "-force-overwrite", "-no-directory", "-output-directory", destination, archive}
+	},
+}
+
+// find returns the path of the program, if it is installed.
+func (u unarchiver) find() (string, bool) {
+	for _, name := range u.names {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, true
+		}
+	}
+	if runtime.GOOS == "windows" {
+		for _, installPath := range u.installPaths {
+			if info, err := os.Stat(installPath); err == nil && !info.IsDir() {
+				return installPath, true
+			}
+		}
+	}
+	return "", false
+}
+
+// extractWithUnarchivers returns an archive extractor that unpacks an archive with the
+// first of the programs that is installed, and an error naming them all if none is.
+func extractWithUnarchivers(format string, unarchivers ...unarchiver) func(archive string, destination string) error {
+	return func(archive string, destination string) error {
+		for _, u := range unarchivers {
+			path, ok := u.find()
+			if !ok {
+				continue
+			}
+			if err := os.MkdirAll(destination, 0755); err != nil {
+				return err
+			}
+			output, err := exec.Command(path, u.args(archive, destination)...).CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("%s couldn't unpack it: %w\n%s", filepath.Base(path), err, strings.TrimSpace(string(output)))
+			}
+			return flattenSingleDirectory(destination)
+		}
+
+		names := make([]string, 0, len(unarchivers))
+		for _, u := range unarchivers {
+			names = append(names, u.names[0])
+		}
+		return fmt.Errorf("there is no %s to unpack %s archives with, install 7-Zip (p7zip on Linux) or The Unarchiver (unar)", strings.Join(names, " or "), format)
+	}
+}
+
+// flattenSingleDirectory moves the files of the only directory in a directory up into
+// it, when there is nothing else in it, the way extractZip leaves out the directory the
+// files of a zip archive are in if they are all in one.
+func flattenSingleDirectory(directory string) error {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return nil
+	}
+
+	// Move the directory out of the way first, in case something in it has its name
+	singleDirectory := filepath.Join(directory, entries[0].Name())
+	movedDirectory := filepath.Join(directory, ".scummer-flattening")
+	if err := os.Rename(singleDirectory, movedDirectory); err != nil {
+		return err
+	}
+	innerEntries, err := os.ReadDir(movedDirectory)
+	if err != nil {
+		return err
+	}
+	for _, entry := range innerEntries {
+		if err := os.Rename(filepath.Join(movedDirectory, entry.Name()), filepath.Join(directory, entry.Name())); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(movedDirectory); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
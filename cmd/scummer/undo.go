@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/furui/scummer/journal"
+)
+
+// journalDirectory is where the journals of the runs that changed something are kept,
+// next to the results.
+func journalDirectory() string {
+	return stateFile("journal")
+}
+
+// newRunJournal starts the journal of this run, named after the command line it was
+// started with.
+func newRunJournal() *journal.Journal {
+	return journal.New(journalDirectory(), strings.Join(append([]string{"scummer"}, os.Args[1:]...), " "))
+}
+
+// finishRunJournal closes the journal of this run, and tells the user how to undo the
+// run if it changed anything.
+func finishRunJournal(changeJournal *journal.Journal) {
+	if err := changeJournal.Close(); err != nil {
+		fmt.Println(err)
+	}
+	if changeJournal.Changed() {
+		fmt.Printf("The changes were recorded as run %s, \"scummer undo %s\" puts them back\n", changeJournal.RunID, changeJournal.RunID)
+	}
+}
+
+// warnDamagedJournalLines tells the user about the lines of a run's journal that
+// couldn't be read and were skipped.
+func warnDamagedJournalLines(run journal.Run) {
+	for _, line := range run.DamagedLines {
+		fmt.Printf("⚠️  line %d of the journal of run %s is damaged, skipping it\n", line, run.RunID)
+	}
+}
+
+// runUndo reverts the changes a run made to the filesystem, newest first, or lists the
+// runs that can be undone.
+func runUndo(args []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("undo", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "show what would be put back without changing anything")
+	force := flags.Bool("force", false, "undo changes to files and directories that have been changed again since the run")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer undo [flags] [<run ID> | last]")
+		fmt.Fprintln(flags.Output(), "Without a run ID, the runs that can be undone are listed.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// List the runs, if we weren't given one
+	runs, err := journal.List(journalDirectory())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if flags.NArg() == 0 {
+		if len(runs) == 0 {
+			fmt.Println("There are no runs to undo")
+			return
+		}
+		for _, run := range runs {
+			warnDamagedJournalLines(run)
+			fmt.Printf("%-17s %s  %4d changes  %s\n", run.RunID, run.Started.Local().Format("2006-01-02 15:04"), len(run.Entries), run.Command)
+		}
+		return
+	}
+
+	// Find the run, where last is the newest one
+	runID := flags.Arg(0)
+	if runID == "last" {
+		if len(runs) == 0 {
+			fmt.Println("There are no runs to undo")
+			return
+		}
+		runID = runs[len(runs)-1].RunID
+	}
+	run, err := journal.Read(journalDirectory(), runID)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	warnDamagedJournalLines(run)
+
+	// Undo the changes newest first, so that a directory is moved back before the
+	// directory it was moved into is removed, keeping the ones that can't be undone
+	remainingEntries := make([]journal.Entry, 0)
+	undone := 0
+	for i := len(run.Entries) - 1; i >= 0; i-- {
+		entry := run.Entries[i]
+		if err := undoJournalEntry(entry, *force, *dryRun); err != nil {
+			fmt.Printf("⚠️  %s\n", err)
+			remainingEntries = append([]journal.Entry{entry}, remainingEntries...)
+			continue
+		}
+		fmt.Printf("↩️  %s\n", describeUndo(entry))
+		undone++
+	}
+
+	if *dryRun {
+		fmt.Printf("%d of the %d changes of run %s would be undone\n", undone, len(run.Entries), run.RunID)
+		return
+	}
+	if err := journal.Rewrite(journalDirectory(), run, remainingEntries); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Undid %d of the %d changes of run %s\n", undone, len(run.Entries), run.RunID)
+	if len(remainingEntries) > 0 {
+		fmt.Printf("The changes that weren't undone are still in the journal, run \"scummer undo %s\" again once they are sorted out, or add --force\n", run.RunID)
+	}
+}
+
+// describeUndo says what undoing a change does.
+func describeUndo(entry journal.Entry) string {
+	switch entry.Action {
+	case journal.ActionReplaced:
+		return "restore " + entry.Path
+	case journal.ActionRemoved:
+		return "bring back " + entry.Path
+	case journal.ActionRenamed:
+		return "move " + entry.Path + " back to " + entry.From
+	default:
+		return "remove " + entry.Path
+	}
+}
+
+// undoJournalEntry puts back a change. A change is left alone if what it made has been
+// changed again since, unless force is set, or if putting it back would overwrite
+// something. Nothing is changed if dryRun is set, but the checks are still made.
+func undoJournalEntry(entry journal.Entry, force bool, dryRun bool) error {
+	info, err := os.Lstat(entry.Path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	switch entry.Action {
+	case journal.ActionCreated, journal.ActionReplaced:
+		// A file that was replaced is brought back even if the new one is gone
+		if !exists && entry.Action == journal.ActionCreated {
+			return nil
+		}
+		if exists && !force && (info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime)) {
+			return fmt.Errorf("%s has been changed since, leaving it", entry.Path)
+		}
+		if dryRun {
+			return nil
+		}
+		if entry.Action == journal.ActionCreated {
+			return os.Remove(entry.Path)
+		}
+		return restoreJournalBackup(entry)
+
+	case journal.ActionRemoved:
+		if exists {
+			return fmt.Errorf("%s is back already, leaving it", entry.Path)
+		}
+		if dryRun {
+			return nil
+		}
+		return restoreJournalBackup(entry)
+
+	case journal.ActionRenamed:
+		if !exists {
+			return fmt.Errorf("%s isn't there anymore, so it can't be moved back to %s", entry.Path, entry.From)
+		}
+		// On a filesystem that ignores case, a rename that only changed the case of the
+		// name finds the directory itself where it was
+		if fromInfo, err := os.Lstat(entry.From); err == nil && !os.SameFile(info, fromInfo) {
+			return fmt.Errorf("can't move %s back to %s: it already exists", entry.Path, entry.From)
+		}
+		if dryRun {
+			return nil
+		}
+		// The directory it was in may have been removed once it was empty, as flattening
+		// a nested game directory does
+		if err := os.MkdirAll(filepath.Dir(entry.From), 0755); err != nil {
+			return err
+		}
+		return moveDirectory(entry.Path, entry.From)
+
+	case journal.ActionLinked:
+		if !exists {
+			return nil
+		}
+		if target, err := os.Readlink(entry.Path); err != nil || target != entry.From {
+			return fmt.Errorf("%s doesn't link to %s anymore, leaving it", entry.Path, entry.From)
+		}
+		if dryRun {
+			return nil
+		}
+		return os.Remove(entry.Path)
+
+	case journal.ActionMadeDirectory:
+		if !exists {
+			return nil
+		}
+		// Only empty it would be after the rest of the run was undone, unless something
+		// was put in it since
+		if directoryEntries, err := os.ReadDir(entry.Path); err != nil || (len(directoryEntries) > 0 && !dryRun) {
+			return fmt.Errorf("%s isn't empty, leaving it", entry.Path)
+		}
+		if dryRun {
+			return nil
+		}
+		return os.Remove(entry.Path)
+
+	case journal.ActionAddedDirectory:
+		if !exists {
+			return nil
+		}
+		files, size, err := journal.DirectoryContents(entry.Path)
+		if err != nil {
+			return err
+		}
+		if !force && (files != entry.Files || size != entry.Size) {
+			return fmt.Errorf("%s has been changed since, leaving it", entry.Path)
+		}
+		if dryRun {
+			return nil
+		}
+		return os.RemoveAll(entry.Path)
+	}
+	return fmt.Errorf("don't know how to undo %s of %s", entry.Action, entry.Path)
+}
+
+// restoreJournalBackup puts the contents a file had before the run back.
+func restoreJournalBackup(entry journal.Entry) error {
+	contents, err := os.ReadFile(entry.Backup)
+	if err != nil {
+		return fmt.Errorf("reading the backup of %s: %w", entry.Path, err)
+	}
+	if err := os.WriteFile(entry.Path, contents, entry.Mode); err != nil {
+		return err
+	}
+	return os.Chmod(entry.Path, entry.Mode)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+)
+
+// unknownVariantsReport is what the unknown variants report is made from: the games
+// scummvm took for variants it doesn't know, which it asks to be reported to the ScummVM
+// team.
+type unknownVariantsReport struct {
+	Variants []unknownVariantsReportVariant
+
+	// OperatingSystem is the one scummvm ran on.
+	OperatingSystem string
+}
+
+// unknownVariantsReportVariant is the data scummvm printed about a variant it doesn't
+// know, for one of the engines, along with what a ticket about it needs.
+type unknownVariantsReportVariant struct {
+	// Summary is the summary suggested for the ticket, in the "ENGINE: Game: ..." form the
+	// ScummVM bug tracker uses.
+	Summary string
+
+	Directory      string
+	DetectedAs     string
+	ScummvmVersion string
+	Engine         string
+	MatchedGameIDs []string
+	Files          []string
+}
+
+// newUnknownVariantsReport collects the unknown variants of the matches, and of the
+// directories that weren't matched, such as the ones that were skipped because scummvm
+// wasn't sure which game it was.
+func newUnknownVariantsReport(scummGameMatches []match.ScummGameMatch) unknownVariantsReport {
+	report := unknownVariantsReport{Variants: make([]unknownVariantsReportVariant, 0), OperatingSystem: runtime.GOOS}
+	for _, scummGameMatch := range scummGameMatches {
+		// Say what scummvm took the game for, or which games it wasn't sure between
+		title := scummGameMatch.Title
+		detectedAs := fmt.Sprintf("%s (%s)", scummGameMatch.GameID, scummGameMatch.Description)
+		if scummGameMatch.ErrorKind != "" {
+			candidateGameIDs := make([]string, 0, len(scummGameMatch.Candidates))
+			for _, candidate := range scummGameMatch.Candidates {
+				candidateGameIDs = append(candidateGameIDs, candidate.GameID)
+			}
+			detectedAs = "one of " + strings.Join(candidateGameIDs, ", ")
+			title = ""
+		}
+		if title == "" && scummGameMatch.ErrorKind == "" {
+			title = match.ParseDescriptionVariant(scummGameMatch.Description).Title
+		}
+		if title == "" {
+			title = filepath.Base(scummGameMatch.Directory)
+		}
+
+		for _, unknownVariant := range scummGameMatch.UnknownVariants {
+			// SCUMM doesn't name its engine, since it only reports the files of its own games
+			engine := unknownVariant.Engine
+			if engine == "" {
+				engine = match.CandidateEngine(scummGameMatch.GameID)
+			}
+			report.Variants = append(report.Variants, unknownVariantsReportVariant{
+				Summary:        fmt.Sprintf("%s: %s: Unknown game variant", strings.ToUpper(engine), title),
+				Directory:      scummGameMatch.Directory,
+				DetectedAs:     detectedAs,
+				ScummvmVersion: scummGameMatch.ScummvmVersion,
+				Engine:         engine,
+				MatchedGameIDs: unknownVariant.MatchedGameIDs,
+				Files:          unknownVariant.Files,
+			})
+		}
+	}
+	sort.SliceStable(report.Variants, func(i, j int) bool {
+		return report.Variants[i].Directory < report.Variants[j].Directory
+	})
+	return report
+}
+
+// unknownVariantsReportTemplate is the unknown variants report, in markdown.
+var unknownVariantsReportTemplate = template.Must(template.New("unknown-variants").Parse(`# Unknown game variants
+{{if .Variants}}
+scummvm found {{len .Variants}} game variant{{if ne (len .Variants) 1}}s{{end}} it doesn't know, and asks for them to be reported to the ScummVM team at https://bugs.scummvm.org/ so it can detect them. Open a ticket for each of them, with the summary suggested below, and copy its section into the description. Fill in what scummer can't know: the name of the game as it was sold, its version, language and platform, and where the files came from, such as the original floppies or CD, a compilation, or a store such as GOG or Steam.
+{{range .Variants}}
+## {{.Summary}}
+
+- Directory: {{.Directory}}
+- Detected as: {{.DetectedAs}}
+- Engine: {{.Engine}}{{if .MatchedGameIDs}}
+- Matched game IDs: {{range $i, $gameID := .MatchedGameIDs}}{{if $i}}, {{end}}{{$gameID}}{{end}}{{end}}
+- ScummVM version: {{if .ScummvmVersion}}{{.ScummvmVersion}}{{else}}unknown{{end}}
+- Operating system: {{$.OperatingSystem}}
+- Game name, version, language and platform:
+- Where the files came from:
+{{if .Files}}
+` + "```" + `
+{{range .Files}}{{.}}
+{{end}}` + "```" + `
+{{else}}
+scummvm didn't print the files of the game.
+{{end}}{{end}}{{else}}
+scummvm didn't find any game variant it doesn't know.
+{{end}}`))
+
+// writeUnknownVariantsReport writes the unknown variants report of the results to a
+// file.
+func writeUnknownVariantsReport(scummGameMatches []match.ScummGameMatch, outputFile string, changeJournal *journal.Journal) error {
+	report := newUnknownVariantsReport(scummGameMatches)
+	var reportMarkdown bytes.Buffer
+	if err := unknownVariantsReportTemplate.Execute(&reportMarkdown, report); err != nil {
+		return err
+	}
+
+	// Write the file
+	if err := changeJournal.WriteFile(outputFile, reportMarkdown.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote the unknown variants report to %s, to report them to the ScummVM team\n", outputFile)
+	return nil
+}
+
+// hasUnknownVariants returns whether scummvm took any of the games for a variant it
+// doesn't know.
+func hasUnknownVariants(scummGameMatches []match.ScummGameMatch) bool {
+	for _, scummGameMatch := range scummGameMatches {
+		if len(scummGameMatch.UnknownVariants) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// exportUnknownVariantsReport writes a report of the game variants scummvm doesn't know,
+// in markdown, to be reported to the ScummVM team.
+func exportUnknownVariantsReport(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputFile := options.OutputFile
+	if outputFile == "" {
+		outputFile = "unknown-variants.md"
+	}
+	return writeUnknownVariantsReport(append(append([]match.ScummGameMatch{}, scummGameMatches...), options.Errors...), outputFile, options.Journal)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Steam keeps non-Steam game shortcuts in a binary VDF file. Every value starts with a
+// byte saying what type it is, followed by its name as a NUL terminated string, and
+// then the value itself. A map holds more values, up to a vdfMapEnd byte.
+const (
+	vdfMap    = 0x00
+	vdfString = 0x01
+	vdfInt32  = 0x02
+	vdfUint64 = 0x07
+	vdfMapEnd = 0x08
+)
+
+// vdfValue is a value in a binary VDF file. Only the field that matches its Type is
+// used.
+type vdfValue struct {
+	Type     byte
+	Name     string
+	String   string
+	Int      uint64
+	Children []vdfValue
+}
+
+// child returns the value in a map with the given name.
+func (v *vdfValue) child(name string) (*vdfValue, bool) {
+	for i := range v.Children {
+		if v.Children[i].Name == name {
+			return &v.Children[i], true
+		}
+	}
+	return nil, false
+}
+
+// childString returns the string in a map with the given name, or an empty string if
+// there isn't one.
+func (v *vdfValue) childString(name string) string {
+	if child, ok := v.child(name); ok && child.Type == vdfString {
+		return child.String
+	}
+	return ""
+}
+
+// readVdfString reads a NUL terminated string.
+func readVdfString(reader *bufio.Reader) (string, error) {
+	s, err := reader.ReadString(0)
+	if err != nil {
+		return "", err
+	}
+	return s[:len(s)-1], nil
+}
+
+// readVdfMap reads the values of a map up to the byte that ends it.
+func readVdfMap(reader *bufio.Reader) ([]vdfValue, error) {
+	values := make([]vdfValue, 0)
+	for {
+		valueType, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if valueType == vdfMapEnd {
+			return values, nil
+		}
+
+		value := vdfValue{Type: valueType}
+		if value.Name, err = readVdfString(reader); err != nil {
+			return nil, err
+		}
+
+		switch valueType {
+		case vdfMap:
+			value.Children, err = readVdfMap(reader)
+		case vdfString:
+			value.String, err = readVdfString(reader)
+		case vdfInt32:
+			var i uint32
+			err = binary.Read(reader, binary.LittleEndian, &i)
+			value.Int = uint64(i)
+		case vdfUint64:
+			err = binary.Read(reader, binary.LittleEndian, &value.Int)
+		default:
+			err = fmt.Errorf("unknown vdf value type %#x", valueType)
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+}
+
+// parseVdf reads a binary VDF file. The file is one map without a name or a type,
+// ended by a vdfMapEnd byte.
+func parseVdf(data []byte) (vdfValue, error) {
+	children, err := readVdfMap(bufio.NewReader(bytes.NewReader(data)))
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return vdfValue{}, fmt.Errorf("the vdf file ends too early")
+	} else if err != nil {
+		return vdfValue{}, err
+	}
+	return vdfValue{Type: vdfMap, Children: children}, nil
+}
+
+// writeVdfMap writes the values of a map, followed by the byte that ends it.
+func writeVdfMap(buffer *bytes.Buffer, values []vdfValue) {
+	for _, value := range values {
+		buffer.WriteByte(value.Type)
+		buffer.WriteString(value.Name)
+		buffer.WriteByte(0)
+
+		switch value.Type {
+		case vdfMap:
+			writeVdfMap(buffer, value.Children)
+		case vdfString:
+			buffer.WriteString(value.String)
+			buffer.WriteByte(0)
+		case vdfInt32:
+			binary.Write(buffer, binary.LittleEndian, uint32(value.Int))
+		case vdfUint64:
+			binary.Write(buffer, binary.LittleEndian, value.Int)
+		}
+	}
+	buffer.WriteByte(vdfMapEnd)
+}
+
+// formatVdf turns a value read with parseVdf back into a binary VDF file.
+func formatVdf(root vdfValue) []byte {
+	var buffer bytes.Buffer
+	writeVdfMap(&buffer, root.Children)
+	return buffer.Bytes()
+}
+
+// Steam keeps its library folders and the manifests of installed games in text VDF
+// files instead, where every name and string is quoted and a map is a name followed by
+// its values in braces:
+//
+//	"AppState"
+//	{
+//		"appid"		"32360"
+//		"installdir"	"The Secret of Monkey Island Special Edition"
+//	}
+//
+// Text VDF files only have maps and strings, so that is all parseTextVdf returns.
+
+// readTextVdfToken reads the next quoted string or brace, skipping whitespace and
+// comments. It returns true for quoted strings, since a quoted string can be a brace.
+func readTextVdfToken(reader *bufio.Reader) (string, bool, error) {
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return "", false, err
+		}
+		switch {
+		case r == '{' || r == '}':
+			return string(r), false, nil
+		case r == '/':
+			// A comment runs to the end of the line
+			if _, err := reader.ReadString('\n'); err != nil {
+				return "", false, err
+			}
+		case r == '"':
+			var token strings.Builder
+			for {
+				r, _, err := reader.ReadRune()
+				if err != nil {
+					return "", false, err
+				}
+				if r == '"' {
+					return token.String(), true, nil
+				}
+				if r == '\\' {
+					// Steam escapes backslashes and quotes, mostly in Windows paths
+					if r, _, err = reader.ReadRune(); err != nil {
+						return "", false, err
+					}
+					switch r {
+					case 'n':
+						r = '\n'
+					case 't':
+						r = '\t'
+					}
+				}
+				token.WriteRune(r)
+			}
+		case !unicode.IsSpace(r):
+			return "", false, fmt.Errorf("unexpected %q in the vdf file", r)
+		}
+	}
+}
+
+// readTextVdfMap reads the values of a map up to the brace that ends it, or up to the
+// end of the file for the values at the top of it.
+func readTextVdfMap(reader *bufio.Reader, topLevel bool) ([]vdfValue, error) {
+	values := make([]vdfValue, 0)
+	for {
+		name, quoted, err := readTextVdfToken(reader)
+		if topLevel && errors.Is(err, io.EOF) {
+			return values, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if !quoted {
+			if name == "}" && !topLevel {
+				return values, nil
+			}
+			return nil, fmt.Errorf("unexpected %q in the vdf file", name)
+		}
+
+		value := vdfValue{Name: name}
+		token, quoted, err := readTextVdfToken(reader)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case quoted:
+			value.Type = vdfString
+			value.String = token
+		case token == "{":
+			value.Type = vdfMap
+			if value.Children, err = readTextVdfMap(reader, false); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q after %q in the vdf file", token, name)
+		}
+		values = append(values, value)
+	}
+}
+
+// parseTextVdf reads a text VDF file, returning a map without a name that holds the
+// values at the top of the file.
+func parseTextVdf(data []byte) (vdfValue, error) {
+	children, err := readTextVdfMap(bufio.NewReader(bytes.NewReader(data)), true)
+	if errors.Is(err, io.EOF) {
+		return vdfValue{}, fmt.Errorf("the vdf file ends too early")
+	} else if err != nil {
+		return vdfValue{}, err
+	}
+	return vdfValue{Type: vdfMap, Children: children}, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// shortcutsVdf is a small shortcuts.vdf, with a shortcut holding a string, an int32 and
+// a map of tags.
+var shortcutsVdf = []byte("\x00shortcuts\x00" +
+	"\x000\x00" +
+	"\x02appid\x00\x78\x56\x34\x12" +
+	"\x01AppName\x00Loom\x00" +
+	"\x00tags\x00" +
+	"\x010\x00scummvm\x00" +
+	"\x08" +
+	"\x02LastPlayTime\x00\x00\x00\x00\x00" +
+	"\x08" +
+	"\x08" +
+	"\x08")
+
+func TestParseVdfRoundTrip(t *testing.T) {
+	root, err := parseVdf(shortcutsVdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check the values were read
+	shortcuts, ok := root.child("shortcuts")
+	if !ok {
+		t.Fatal("shortcuts is missing")
+	}
+	shortcut, ok := shortcuts.child("0")
+	if !ok {
+		t.Fatal("shortcut 0 is missing")
+	}
+	if appName := shortcut.childString("AppName"); appName != "Loom" {
+		t.Errorf("AppName = %q, want %q", appName, "Loom")
+	}
+	if appID, ok := shortcut.child("appid"); !ok || appID.Type != vdfInt32 || appID.Int != 0x12345678 {
+		t.Errorf("appid = %+v, want the int32 0x12345678", appID)
+	}
+	if tags, ok := shortcut.child("tags"); !ok || tags.childString("0") != "scummvm" {
+		t.Errorf("tags = %+v, want a map holding scummvm", tags)
+	}
+
+	// Check it is written back the way it was read
+	if formatted := formatVdf(root); !bytes.Equal(formatted, shortcutsVdf) {
+		t.Errorf("formatVdf(parseVdf(data)) = %q, want %q", formatted, shortcutsVdf)
+	}
+}
+
+func TestParseVdfTruncated(t *testing.T) {
+	for length := 0; length < len(shortcutsVdf); length++ {
+		_, err := parseVdf(shortcutsVdf[:length])
+		if err == nil || !strings.Contains(err.Error(), "ends too early") {
+			t.Errorf("parseVdf of the first %d bytes returned %v, want the file ending too early", length, err)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/furui/scummer"
+	"github.com/furui/scummer/checksum"
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// The problems verify finds.
+const (
+	// verifyMismatch is a directory scummvm now finds a different game in.
+	verifyMismatch = "mismatch"
+
+	// verifyUndetected is a directory scummvm no longer finds any game in.
+	verifyUndetected = "undetected"
+
+	// verifyEmpty is a directory that has nothing left in it but its .scummvm file.
+	verifyEmpty = "empty"
+
+	// verifyStale is a .scummvm file whose directory is gone.
+	verifyStale = "stale"
+
+	// verifyMissing is a .scummvm file that should be there, but isn't.
+	verifyMissing = "missing"
+
+	// verifyEdited is a .scummvm file that doesn't say what the results say it should.
+	verifyEdited = "edited"
+)
+
+// verifyProblems are the problems verify can find, in the order they are summed up.
+var verifyProblems = []string{verifyMismatch, verifyUndetected, verifyEmpty, verifyStale, verifyMissing, verifyEdited}
+
+// verifyProblem is something wrong with a game in the library.
+type verifyProblem struct {
+	kind    string
+	message string
+}
+
+// runVerify checks that the .scummvm files of an earlier scan still say what is in the
+// game directories, by detecting each directory again, and reports the ones that don't.
+func runVerify(args []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	scummvmBinaryFlag := flags.String("scummvm", "", "path to the scummvm binary, flatpak for the ScummVM Flatpak, or docker:<image> to run it in a container; if not given, scummer looks for an installed scummvm")
+	similarityThreshold := flags.Float64("threshold", 0.5, "similarity (0 to 1) below which an ambiguous match is low confidence")
+	quick := flags.Bool("quick", false, "only detect the directories whose files have changed since the scan, trusting the rest")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer verify [flags] [<success.json>]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// The results file defaults to the one scan writes
+	successFile := stateFile("success.json")
+	if flags.NArg() > 0 {
+		successFile = flags.Arg(0)
+	}
+
+	// Load the results of the scan
+	scummvmOutputSlice, err := output.ReadResults(successFile)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// In portable mode, a scummvm next to scummer is used before an installed one
+	if *scummvmBinaryFlag == "" {
+		*scummvmBinaryFlag = portableScummvm()
+	}
+
+	// Setup the scanner when the first directory has to be detected, so that a quick
+	// verify of a library that hasn't changed doesn't need scummvm
+	var scanner *scummer.Scanner
+	newScanner := func() (*scummer.Scanner, error) {
+		if scanner != nil {
+			return scanner, nil
+		}
+		var err error
+		scanner, err = scummer.NewScanner(scummer.Options{Scummvm: *scummvmBinaryFlag, Threshold: *similarityThreshold})
+		return scanner, err
+	}
+
+	// Check each game that has .scummvm files
+	problemCounts := make(map[string]int)
+	markerFiles := make(map[string]bool)
+	libraryRoots := make(map[string]bool)
+	verified := 0
+	for _, scummGameMatch := range output.WithoutLaterDiscs(scummvmOutputSlice) {
+		for _, markerFile := range output.MarkerFileNames(scummGameMatch) {
+			markerFiles[absoluteDirectory(markerFile)] = true
+		}
+		libraryRoots[filepath.Dir(scummGameMatch.Directory)] = true
+
+		fmt.Printf("%s... ", scummGameMatch.Directory)
+		problems, err := verifyScummGame(context.Background(), scummGameMatch, *quick, newScanner)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		verified++
+		if len(problems) == 0 {
+			fmt.Printf("✅\n")
+			continue
+		}
+		messages := make([]string, 0, len(problems))
+		for _, problem := range problems {
+			problemCounts[problem.kind]++
+			messages = append(messages, problem.message)
+		}
+		fmt.Printf("❌ %s\n", strings.Join(messages, "; "))
+	}
+
+	// Look for .scummvm files next to the games that no result knows about and whose
+	// directory is gone, such as the ones left behind when a game was deleted
+	roots := make([]string, 0, len(libraryRoots))
+	for root := range libraryRoots {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	for _, root := range roots {
+		for _, markerFile := range staleMarkerFiles(root, markerFiles) {
+			problemCounts[verifyStale]++
+			fmt.Printf("%s... ❌ its directory is gone\n", markerFile)
+		}
+	}
+
+	// Sum up what was found
+	problemSummaries := make([]string, 0)
+	problems := 0
+	for _, kind := range verifyProblems {
+		if problemCounts[kind] > 0 {
+			problemSummaries = append(problemSummaries, fmt.Sprintf("%d %s", problemCounts[kind], kind))
+			problems += problemCounts[kind]
+		}
+	}
+	if problems == 0 {
+		fmt.Printf("\nVerified %d games, everything is as it was scanned\n", verified)
+		return
+	}
+	fmt.Printf("\nVerified %d games: %s\n", verified, strings.Join(problemSummaries, ", "))
+	os.Exit(1)
+}
+
+// verifyScummGame checks a game's directory and its .scummvm files. The directory is
+// detected again, unless quick is set and its files haven't changed since the scan.
+func verifyScummGame(ctx context.Context, scummGameMatch match.ScummGameMatch, quick bool, newScanner func() (*scummer.Scanner, error)) ([]verifyProblem, error) {
+	problems := make([]verifyProblem, 0)
+
+	// Check that the .scummvm files are there and say what they should
+	expectedContents, err := output.MarkerContents(scummGameMatch)
+	if err != nil {
+		return nil, err
+	}
+	for _, markerFile := range output.MarkerFileNames(scummGameMatch) {
+		contents, err := os.ReadFile(markerFile)
+		if errors.Is(err, fs.ErrNotExist) {
+			problems = append(problems, verifyProblem{kind: verifyMissing, message: filepath.Base(markerFile) + " is missing"})
+			continue
+		}
+		if err != nil {
+			problems = append(problems, verifyProblem{kind: verifyMissing, message: err.Error()})
+			continue
+		}
+		if strings.TrimSpace(string(contents)) != strings.TrimSpace(expectedContents) {
+			problems = append(problems, verifyProblem{kind: verifyEdited, message: fmt.Sprintf("%s says %q, not %q", filepath.Base(markerFile), strings.TrimSpace(string(contents)), expectedContents)})
+		}
+	}
+
+	// A game left in its archive is detected in a copy of it unpacked for the purpose,
+	// unless it has been unpacked since
+	if _, err := os.Stat(scummGameMatch.Directory); scummGameMatch.Archive != "" && errors.Is(err, fs.ErrNotExist) {
+		if _, err := os.Stat(scummGameMatch.Archive); errors.Is(err, fs.ErrNotExist) {
+			return append(problems, verifyProblem{kind: verifyStale, message: "the archive is gone"}), nil
+		}
+		var detectErr error
+		err := withTemporaryUnpack(scummGameMatch.Archive, scummGameMatch.Directory, func(unpackedDirectory string) error {
+			detectedProblems, err := detectScummGameAgain(ctx, scummGameMatch, unpackedDirectory, newScanner)
+			problems = append(problems, detectedProblems...)
+			detectErr = err
+			return nil
+		})
+		if err != nil {
+			return append(problems, verifyProblem{kind: verifyUndetected, message: err.Error()}), nil
+		}
+		return problems, detectErr
+	}
+
+	// Check that the directory is still there and has the game in it
+	if _, err := os.Stat(scummGameMatch.Directory); errors.Is(err, fs.ErrNotExist) {
+		return append(problems, verifyProblem{kind: verifyStale, message: "the directory is gone"}), nil
+	}
+	if isEmptyGameDirectory(scummGameMatch.Directory) {
+		return append(problems, verifyProblem{kind: verifyEmpty, message: "the directory is empty"}), nil
+	}
+
+	// Trust a directory whose files haven't changed, if asked to
+	if quick && scummGameMatch.Fingerprint != "" {
+		if fingerprint, err := detect.DirectoryFingerprint(scummGameMatch.Directory); err == nil && fingerprint == scummGameMatch.Fingerprint {
+			return problems, nil
+		}
+	}
+
+	// A game that is still only a CD image is detected in a copy of the files it needs,
+	// unless they have been extracted since
+	if scummGameMatch.DiscImage != "" {
+		if imagePath, ok := findDiscImage(scummGameMatch.Directory); ok {
+			var detectErr error
+			err := withTemporaryUnpack(imagePath, scummGameMatch.Directory, func(unpackedDirectory string) error {
+				detectedProblems, err := detectScummGameAgain(ctx, scummGameMatch, unpackedDirectory, newScanner)
+				problems = append(problems, detectedProblems...)
+				detectErr = err
+				return nil
+			})
+			if err != nil {
+				return append(problems, verifyProblem{kind: verifyUndetected, message: err.Error()}), nil
+			}
+			return problems, detectErr
+		}
+	}
+
+	// Detect it again
+	detectedProblems, err := detectScummGameAgain(ctx, scummGameMatch, scummGameMatch.Directory, newScanner)
+	return append(problems, detectedProblems...), err
+}
+
+// detectScummGameAgain has scummvm detect the game in a directory again, and reports
+// whether it still finds the game the .scummvm file is for.
+func detectScummGameAgain(ctx context.Context, scummGameMatch match.ScummGameMatch, directory string, newScanner func() (*scummer.Scanner, error)) ([]verifyProblem, error) {
+	problems := make([]verifyProblem, 0)
+	scanner, err := newScanner()
+	if err != nil {
+		return nil, err
+	}
+	rescanned, err := scanner.ScanDirectory(ctx, directory)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case rescanned.ErrorKind == match.ErrorKindDetection || rescanned.ErrorKind == match.ErrorKindFilesystem || rescanned.ErrorKind == match.ErrorKindScummvm:
+		problems = append(problems, verifyProblem{kind: verifyUndetected, message: "scummvm no longer finds a game in it: " + rescanned.Description})
+	case !isDetectedAs(rescanned, scummGameMatch.GameID):
+		problems = append(problems, verifyProblem{kind: verifyMismatch, message: fmt.Sprintf("the .scummvm file is for %s, but scummvm now finds %s", scummGameMatch.GameID, rescannedGameIDs(rescanned))})
+	}
+	return problems, nil
+}
+
+// isDetectedAs returns whether scummvm found the game with the given GameID in a
+// directory, either as the game that was chosen or as one of the candidates, since the
+// user may have picked another candidate than scummer would.
+func isDetectedAs(rescanned match.ScummGameMatch, gameID string) bool {
+	if match.BareGameID(rescanned.GameID) == match.BareGameID(gameID) {
+		return true
+	}
+	for _, candidate := range rescanned.Candidates {
+		if match.BareGameID(candidate.GameID) == match.BareGameID(gameID) {
+			return true
+		}
+	}
+	return false
+}
+
+// rescannedGameIDs returns the GameID scummvm found, or all of the candidates if it
+// wasn't sure.
+func rescannedGameIDs(rescanned match.ScummGameMatch) string {
+	if len(rescanned.Candidates) == 0 {
+		return rescanned.GameID
+	}
+	gameIDs := make([]string, 0, len(rescanned.Candidates))
+	for _, candidate := range rescanned.Candidates {
+		gameIDs = append(gameIDs, candidate.GameID)
+	}
+	return strings.Join(gameIDs, " or ")
+}
+
+// isEmptyGameDirectory returns whether a directory has no files left in it, other than
+// .scummvm files and its checksum manifest.
+func isEmptyGameDirectory(directory string) bool {
+	empty := true
+	filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			empty = false
+			return filepath.SkipAll
+		}
+		if !entry.IsDir() && !checksum.IsManifestFile(path) {
+			empty = false
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return empty
+}
+
+// staleMarkerFiles returns the .scummvm files in a library root that aren't one of the
+// known marker files, and whose directory, named the same without the extension, is
+// gone, as is any archive it could have been written for.
+func staleMarkerFiles(root string, knownMarkerFiles map[string]bool) []string {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	staleFiles := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), output.MarkerExtension) {
+			continue
+		}
+		markerFile := filepath.Join(root, entry.Name())
+		if knownMarkerFiles[absoluteDirectory(markerFile)] {
+			continue
+		}
+		if !isMarkerFileInUse(markerFile) {
+			staleFiles = append(staleFiles, markerFile)
+		}
+	}
+	return staleFiles
+}
+
+// isMarkerFileInUse returns whether there is a directory, or an archive, named the same
+// as a .scummvm file without the extension.
+func isMarkerFileInUse(markerFile string) bool {
+	name := strings.TrimSuffix(markerFile, filepath.Ext(markerFile))
+	for _, extension := range append([]string{""}, archiveExtensions()...) {
+		if _, err := os.Lstat(name + extension); !errors.Is(err, fs.ErrNotExist) {
+			return true
+		}
+	}
+	return false
+}
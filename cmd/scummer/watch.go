@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/furui/scummer"
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// watchPollInterval is how often the watcher checks for directories that have stopped
+// changing.
+const watchPollInterval = time.Second
+
+// libraryWatcher scans the directories that are added to a library, once they have
+// stopped changing.
+type libraryWatcher struct {
+	library     string
+	successFile string
+	errorFile   string
+	settle      time.Duration
+	layout      output.MarkerLayout
+	scanner     *scummer.Scanner
+	watcher     *fsnotify.Watcher
+
+	// changeJournal records the directories that are renamed and the .scummvm files
+	// that are written.
+	changeJournal *journal.Journal
+
+	// pending are the directories of the library that changed, and when they last did.
+	pending map[string]time.Time
+}
+
+// runWatch watches a library for new game directories, and scans each one and writes
+// its .scummvm file as soon as it has finished copying.
+func runWatch(args []string) {
+	// Setup the command line flags
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	scummvmBinaryFlag := flags.String("scummvm", "", "path to the scummvm binary, flatpak for the ScummVM Flatpak, or docker:<image> to run it in a container; if not given, scummer looks for an installed scummvm")
+	similarityThreshold := flags.Float64("threshold", 0.5, "similarity (0 to 1) below which an ambiguous match is low confidence")
+	lowConfidencePolicy := flags.String("on-low-confidence", match.LowConfidenceBestGuess, "what to do when no candidate is similar enough to the directory name: skip, best-guess or error")
+	successFile := flags.String("results", stateFile("success.json"), "file the successful detections are added to")
+	errorFile := flags.String("errors", stateFile("error.json"), "file the unsuccessful detections are added to")
+	settle := flags.Duration("settle", 10*time.Second, "how long a new directory has to stop changing before it is scanned")
+	presetName := flags.String("preset", "", "lay the .scummvm files out the way a frontend expects: "+strings.Join(scummerPresetNames(), ", "))
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: scummer watch [flags] <scummvm data file directory>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// Look up the preset, which may know where the games are
+	preset := defaultPreset
+	if *presetName != "" {
+		var ok bool
+		if preset, ok = scummerPresets[*presetName]; !ok {
+			fmt.Printf("The --preset flag must be one of %s\n", strings.Join(scummerPresetNames(), ", "))
+			return
+		}
+	}
+
+	// The data file directory is the only argument, unless the preset knows where it is
+	library := preset.Library
+	if flags.NArg() == 1 {
+		library = flags.Arg(0)
+	} else if flags.NArg() > 1 || library == "" {
+		fmt.Println("Please provide the scummvm data file directory")
+		return
+	}
+	if d, err := os.Stat(library); err != nil || !d.IsDir() {
+		fmt.Println("The scummvm data file directory is not a directory")
+		return
+	}
+
+	// Nobody is around to answer a prompt
+	if *lowConfidencePolicy == match.LowConfidencePrompt {
+		fmt.Println("The --on-low-confidence flag can't be prompt while watching, use skip, best-guess or error")
+		return
+	}
+
+	// In portable mode, a scummvm next to scummer is used before an installed one
+	if *scummvmBinaryFlag == "" {
+		*scummvmBinaryFlag = portableScummvm()
+	}
+
+	// Setup the scanner, which checks the rest of the flags and that scummvm works
+	scanner, err := scummer.NewScanner(scummer.Options{Scummvm: *scummvmBinaryFlag, Threshold: *similarityThreshold, OnLowConfidence: *lowConfidencePolicy})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Watch the library for new directories
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer watcher.Close()
+	if err := watcher.Add(library); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	libraryWatcher := &libraryWatcher{
+		library:     library,
+		successFile: *successFile,
+		errorFile:   *errorFile,
+		settle:      *settle,
+		layout:      preset.Layout,
+		scanner:     scanner,
+		watcher:     watcher,
+		pending:     make(map[string]time.Time),
+	}
+	libraryWatcher.changeJournal = newRunJournal()
+	defer finishRunJournal(libraryWatcher.changeJournal)
+	fmt.Printf("Watching %s for new games, press Ctrl+C to stop\n", library)
+	if err := libraryWatcher.run(); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// run handles the changes to the library until the watcher stops.
+func (w *libraryWatcher) run() error {
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return nil
+			}
+			w.changed(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Println(err)
+		case <-ticker.C:
+			w.scanSettled()
+		}
+	}
+}
+
+// libraryDirectory returns the directory of the library a path is in, or "" for the
+// library itself.
+func (w *libraryWatcher) libraryDirectory(path string) string {
+	relativePath, err := filepath.Rel(w.library, path)
+	if err != nil || relativePath == "." || strings.HasPrefix(relativePath, "..") {
+		return ""
+	}
+	directory, _, _ := strings.Cut(filepath.ToSlash(relativePath), "/")
+	return directory
+}
+
+// changed records that a directory of the library changed. A new directory is watched
+// all the way down, so that copying files into it keeps it from being scanned too early.
+func (w *libraryWatcher) changed(event fsnotify.Event) {
+	directory := w.libraryDirectory(event.Name)
+	if directory == "" {
+		return
+	}
+	w.pending[directory] = time.Now()
+
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			walkDirectories(event.Name, w.watcher.Add)
+		}
+	}
+}
+
+// walkDirectories calls do for a directory and every directory in it. Directories that
+// can't be read, or that disappear while being walked, are left out.
+func walkDirectories(root string, do func(string) error) {
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() {
+			do(path)
+		}
+		return nil
+	})
+}
+
+// scanSettled scans the directories that haven't changed for long enough.
+func (w *libraryWatcher) scanSettled() {
+	for directory, lastChanged := range w.pending {
+		if time.Since(lastChanged) < w.settle {
+			continue
+		}
+		delete(w.pending, directory)
+
+		// The files of the directory are done changing, so it doesn't need watching
+		// anymore, which also keeps its .scummvm file from counting as a change
+		walkDirectories(filepath.Join(w.library, directory), w.watcher.Remove)
+
+		if err := w.scan(directory); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// scan scans a directory of the library that has stopped changing, adds it to the
+// results, and writes its .scummvm file. Changes that aren't new game directories, such
+// as .scummvm files and games that were already found, are left alone.
+func (w *libraryWatcher) scan(directory string) error {
+	// Make sure it is a game directory, going by the same rules as a scan
+	gameDirectories, err := detect.GameDirectories(w.library, detect.ListingOptions{})
+	if err != nil {
+		return err
+	}
+	isGameDirectory := false
+	for _, gameDirectory := range gameDirectories {
+		if gameDirectory == directory {
+			isGameDirectory = true
+		}
+	}
+	if !isGameDirectory {
+		return nil
+	}
+
+	// Leave the games that were already found alone
+	scummvmJoinedDataFilePath := filepath.Join(w.library, directory)
+	scummvmOutputSlice, err := output.ReadResults(w.successFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for _, scummGameMatch := range scummvmOutputSlice {
+		if scummGameMatch.Directory == scummvmJoinedDataFilePath {
+			return nil
+		}
+	}
+
+	// Scan it
+	fmt.Printf("%s... ", scummvmJoinedDataFilePath)
+	scummGameMatch, err := w.scanner.ScanDirectory(context.Background(), scummvmJoinedDataFilePath)
+	if err != nil {
+		return err
+	}
+	if scummGameMatch.ErrorKind != "" {
+		fmt.Printf("❌ %s\n", scummGameMatch.Description)
+		return replaceDirectoryResult(w.successFile, w.errorFile, scummvmJoinedDataFilePath, scummGameMatch)
+	}
+
+	// Work out where its .scummvm file goes, renaming the directory if the layout asks
+	// for it, and save it before writing the file, the way a scan does
+	scummGameMatches := []match.ScummGameMatch{scummGameMatch}
+	if w.layout != output.DefaultMarkerLayout {
+		if err := output.PlanMarkerFiles(scummGameMatches, w.layout); err != nil {
+			return err
+		}
+	}
+	if _, err := output.RenameGameDirectories(scummGameMatches, w.changeJournal); err != nil {
+		return err
+	}
+	if err := replaceDirectoryResult(w.successFile, w.errorFile, scummvmJoinedDataFilePath, scummGameMatches[0]); err != nil {
+		return err
+	}
+	if err := output.WriteMarkerFile(scummGameMatches[0], w.changeJournal); err != nil {
+		return err
+	}
+	fmt.Printf("✅ %s\n", scummGameMatches[0].GameID)
+	return nil
+}
+
+// replaceDirectoryResult saves the result of scanning a directory in place of its old
+// one, in success.json or error.json depending on whether a game was found. Missing
+// results files are treated as empty.
+func replaceDirectoryResult(successFile string, errorFile string, directory string, scummGameMatch match.ScummGameMatch) error {
+	scummvmOutputSlice, err := output.ReadResults(successFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		scummvmOutputSlice = make([]match.ScummGameMatch, 0)
+	} else if err != nil {
+		return err
+	}
+	scummvmOutputErrorSlice, err := output.ReadResults(errorFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		scummvmOutputErrorSlice = make([]match.ScummGameMatch, 0)
+	} else if err != nil {
+		return err
+	}
+
+	scummvmOutputSlice = withoutDirectory(scummvmOutputSlice, directory)
+	scummvmOutputErrorSlice = withoutDirectory(scummvmOutputErrorSlice, directory)
+	if scummGameMatch.ErrorKind != "" {
+		scummvmOutputErrorSlice = append(scummvmOutputErrorSlice, scummGameMatch)
+	} else {
+		scummvmOutputSlice = append(scummvmOutputSlice, scummGameMatch)
+	}
+
+	if err := output.WriteResults(successFile, scummvmOutputSlice); err != nil {
+		return err
+	}
+	return output.WriteResults(errorFile, scummvmOutputErrorSlice)
+}
+
+// withoutDirectory returns the matches other than the one for a directory.
+func withoutDirectory(scummGameMatches []match.ScummGameMatch, directory string) []match.ScummGameMatch {
+	remaining := make([]match.ScummGameMatch, 0, len(scummGameMatches))
+	for _, scummGameMatch := range scummGameMatches {
+		if scummGameMatch.Directory != directory {
+			remaining = append(remaining, scummGameMatch)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/furui/scummer/match"
+)
+
+// These are the events a webhook can be told about.
+const (
+	// webhookEventScanFinished fires at the end of every scan.
+	webhookEventScanFinished = "scan-finished"
+
+	// webhookEventNewFailures fires at the end of a scan that has failures that weren't
+	// in error.json before.
+	webhookEventNewFailures = "new-failures"
+)
+
+// webhookEvents are the events a webhook can be told about.
+var webhookEvents = []string{webhookEventScanFinished, webhookEventNewFailures}
+
+// webhookTimeout is how long a webhook has to answer.
+const webhookTimeout = 10 * time.Second
+
+// scummerWebhook is a URL the results of a scan are posted to as JSON.
+type scummerWebhook struct {
+	URL string `yaml:"url"`
+
+	// Events are the events the webhook fires on; all of them if empty.
+	Events []string `yaml:"events"`
+}
+
+// firesOn returns whether the webhook wants to be told about an event.
+func (w scummerWebhook) firesOn(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, wantedEvent := range w.Events {
+		if wantedEvent == event {
+			return true
+		}
+	}
+	return false
+}
+
+// checkScummerWebhooks makes sure every webhook has a URL that can be posted to, and only
+// fires on events we know about.
+func checkScummerWebhooks(webhooks []scummerWebhook) error {
+	for _, webhook := range webhooks {
+		webhookURL, err := url.Parse(webhook.URL)
+		if err != nil {
+			return fmt.Errorf("webhook %q: %w", webhook.URL, err)
+		}
+		if webhookURL.Scheme != "http" && webhookURL.Scheme != "https" {
+			return fmt.Errorf("webhook %q must be an http or https URL", webhook.URL)
+		}
+		for _, event := range webhook.Events {
+			if event != webhookEventScanFinished && event != webhookEventNewFailures {
+				return fmt.Errorf("webhook %q: unknown event %q, must be one of %s", webhook.URL, event, strings.Join(webhookEvents, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// webhookFailure is a directory that failed, as it is sent to a webhook.
+type webhookFailure struct {
+	Directory   string
+	ErrorKind   string
+	Description string
+}
+
+// webhookPayload is what is posted to a webhook.
+type webhookPayload struct {
+	Event          string
+	Library        string
+	Detected       int
+	Ambiguous      int
+	Failed         int
+	Skipped        int
+	RuntimeSeconds float64
+	NewFailures    []webhookFailure
+	ReportURL      string `json:",omitempty"`
+}
+
+// newFailures returns the directories that failed this time but not the time before.
+func newFailures(errorSlice []match.ScummGameMatch, previousErrorSlice []match.ScummGameMatch) []webhookFailure {
+	previouslyFailed := make(map[string]bool)
+	for _, scummGameMatch := range previousErrorSlice {
+		if scummGameMatch.ErrorKind != match.ErrorKindSkipped {
+			previouslyFailed[scummGameMatch.Directory] = true
+		}
+	}
+
+	failures := make([]webhookFailure, 0)
+	for _, scummGameMatch := range errorSlice {
+		if scummGameMatch.ErrorKind == match.ErrorKindSkipped || previouslyFailed[scummGameMatch.Directory] {
+			continue
+		}
+		failures = append(failures, webhookFailure{Directory: scummGameMatch.Directory, ErrorKind: scummGameMatch.ErrorKind, Description: scummGameMatch.Description})
+	}
+	return failures
+}
+
+// notifyWebhooks tells the webhooks that a scan is over. A webhook that can't be reached
+// or doesn't answer with a 2xx status is reported, but doesn't fail the scan.
+func notifyWebhooks(webhooks []scummerWebhook, library string, summary scanSummary, failures []webhookFailure, reportURL string) {
+	client := &http.Client{Timeout: webhookTimeout}
+	for _, webhook := range webhooks {
+		// Pick the event, preferring the one about new failures if the webhook wants it
+		event := ""
+		if len(failures) > 0 && webhook.firesOn(webhookEventNewFailures) {
+			event = webhookEventNewFailures
+		} else if webhook.firesOn(webhookEventScanFinished) {
+			event = webhookEventScanFinished
+		} else {
+			continue
+		}
+
+		// Post the payload
+		payload := webhookPayload{
+			Event:          event,
+			Library:        library,
+			Detected:       summary.Detected,
+			Ambiguous:      summary.Ambiguous,
+			Failed:         summary.Failed,
+			Skipped:        summary.Skipped,
+			RuntimeSeconds: summary.RuntimeSeconds,
+			NewFailures:    failures,
+			ReportURL:      reportURL,
+		}
+		payloadJSON, err := json.Marshal(payload)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		response, err := client.Post(webhook.URL, "application/json", bytes.NewReader(payloadJSON))
+		if err != nil {
+			fmt.Printf("Webhook %s failed: %v\n", webhook.URL, err)
+			continue
+		}
+		response.Body.Close()
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			fmt.Printf("Webhook %s failed: %s\n", webhook.URL, response.Status)
+		}
+	}
+}
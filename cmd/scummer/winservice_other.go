@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+)
+
+// runWindowsService returns false, since only Windows has the Windows service manager.
+func runWindowsService(run func(ctx context.Context) error) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// windowsServiceName is the name scummer is installed as a Windows service under.
+const windowsServiceName = "scummer"
+
+// windowsService runs the server as a Windows service, stopping it when the service
+// manager asks.
+type windowsService struct {
+	run func(ctx context.Context) error
+}
+
+// Execute is called by the service manager to run the service.
+func (w windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+
+	// Run the server until it is told to stop
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- w.run(ctx)
+	}()
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case err := <-done:
+			// The server stopped by itself
+			if err != nil {
+				fmt.Println(err)
+				return false, 1
+			}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				changes <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				// Stop the server, which waits for the running scans to stop
+				changes <- svc.Status{State: svc.StopPending}
+				cancel()
+				if err := <-done; err != nil {
+					fmt.Println(err)
+					return false, 1
+				}
+				return false, 0
+			}
+		}
+	}
+}
+
+// runWindowsService runs the server as a Windows service if the service manager started
+// us, and returns whether it did.
+func runWindowsService(run func(ctx context.Context) error) (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false, err
+	}
+	return true, svc.Run(windowsServiceName, windowsService{run: run})
+}
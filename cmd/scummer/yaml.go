@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/furui/scummer/match"
+)
+
+// yamlExport is what the yaml format writes: the games that were found, and the
+// directories that weren't matched.
+type yamlExport struct {
+	Games  []match.ScummGameMatch
+	Errors []match.ScummGameMatch `json:",omitempty"`
+}
+
+// blockStyle clears the styles a YAML node was decoded with, so that it is written out
+// in YAML's usual block style rather than looking like the JSON it came from.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// exportYaml writes the results as YAML. The fields are named and ordered exactly as
+// they are in success.json, which is done by converting the JSON, since JSON is also
+// YAML.
+func exportYaml(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputFile := options.OutputFile
+	if outputFile == "" {
+		outputFile = "results.yaml"
+	}
+
+	// Convert the JSON into YAML
+	resultsJSON, err := json.Marshal(yamlExport{Games: scummGameMatches, Errors: options.Errors})
+	if err != nil {
+		return err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(resultsJSON, &document); err != nil {
+		return err
+	}
+	blockStyle(&document)
+
+	// Write the YAML, indented the way most YAML is
+	var results bytes.Buffer
+	encoder := yaml.NewEncoder(&results)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	if err := options.Journal.WriteFile(outputFile, results.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d games and %d errors to %s\n", len(scummGameMatches), len(options.Errors), outputFile)
+	return nil
+}
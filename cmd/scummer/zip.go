@@ -0,0 +1,202 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/output"
+)
+
+// addFileToZip copies a file into a zip archive under the given name.
+func addFileToZip(zipWriter *zip.Writer, fileName string, name string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	writer, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, file)
+	return err
+}
+
+// zipScummGame writes a zip archive holding a game's files along with its .scummvm
+// file, named after the game, all at the top of the archive.
+func zipScummGame(scummGameMatch match.ScummGameMatch, zipFile string, markerName string) error {
+	contents, err := output.MarkerContents(scummGameMatch)
+	if err != nil {
+		return err
+	}
+
+	// Create the file
+	file, err := os.Create(zipFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	zipWriter := zip.NewWriter(file)
+
+	// Add the game's files
+	err = filepath.WalkDir(scummGameMatch.Directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(scummGameMatch.Directory, path)
+		if err != nil {
+			return err
+		}
+		return addFileToZip(zipWriter, path, filepath.ToSlash(relativePath))
+	})
+	if err != nil {
+		return err
+	}
+
+	// Add the .scummvm file
+	markerWriter, err := zipWriter.CreateHeader(&zip.FileHeader{Name: markerName, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(markerWriter, contents); err != nil {
+		return err
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// exportZips writes a zip archive for each game, holding its files and its .scummvm
+// file, for frontends and sync tools that would rather have one file per game than a
+// directory. The archives are named after the games' titles and go in the directory
+// given with --output.
+func exportZips(scummGameMatches []match.ScummGameMatch, options exportOptions) error {
+	outputDirectory := options.OutputFile
+	if outputDirectory == "" {
+		outputDirectory = "zips"
+	}
+
+	// Create the directory
+	if err := options.Journal.MkdirAll(outputDirectory, 0755); err != nil {
+		return err
+	}
+
+	usedNames := make(map[string]bool)
+	for _, scummGameMatch := range scummGameMatches {
+		// Name the archive after the title, numbering the ones that would clash, such
+		// as two variants of the same game
+		title := scummGameMatch.Title
+		if title == "" {
+			title = match.ParseDescriptionVariant(scummGameMatch.Description).Title
+		}
+		name := output.SanitizeMarkerName(title)
+		for n := 2; usedNames[name]; n++ {
+			name = output.SanitizeMarkerName(title) + " (" + strconv.Itoa(n) + ")"
+		}
+		usedNames[name] = true
+
+		markerName := name + filepath.Ext(output.MarkerFileNames(scummGameMatch)[0])
+		zipFile := filepath.Join(outputDirectory, name+".zip")
+		if err := options.Journal.ChangeFile(zipFile, func() error { return zipScummGame(scummGameMatch, zipFile, markerName) }); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Wrote %d zip files to %s\n", len(scummGameMatches), outputDirectory)
+	return nil
+}
+
+// zipTopDirectory returns the directory every file of a zip archive is in, such as
+// "bass-cd-1.2/", or "" if they aren't all in one.
+func zipTopDirectory(files []*zip.File) string {
+	topDirectory := ""
+	for _, file := range files {
+		first, _, found := strings.Cut(file.Name, "/")
+		if !found || !filepath.IsLocal(first) || (topDirectory != "" && topDirectory != first+"/") {
+			return ""
+		}
+		topDirectory = first + "/"
+	}
+	return topDirectory
+}
+
+// extractZip unpacks a zip archive into destination, leaving out the directory the files
+// are in if they are all in one, so that the game's files end up at the top of it.
+// Files that would end up outside destination are refused.
+func extractZip(zipFile string, destination string) error {
+	zipReader, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return err
+	}
+	defer zipReader.Close()
+
+	topDirectory := zipTopDirectory(zipReader.File)
+	for _, file := range zipReader.File {
+		name := strings.TrimPrefix(file.Name, topDirectory)
+		if name == "" {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("%s would be unpacked outside of %s", file.Name, destination)
+		}
+		path := filepath.Join(destination, filepath.FromSlash(name))
+
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := extractZipFile(file, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipFile writes a file of a zip archive to path, creating the directory it is in.
+func extractZipFile(file *zip.File, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	writer, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(writer, reader); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
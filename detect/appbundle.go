@@ -0,0 +1,32 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// bundleExecutablePattern finds the name of the executable in a bundle's Info.plist.
+var bundleExecutablePattern = regexp.MustCompile(`<key>CFBundleExecutable</key>\s*<string>([^<]+)</string>`)
+
+// ResolveAppBundle returns the executable inside a macOS .app bundle, such as
+// Contents/MacOS/scummvm inside /Applications/ScummVM.app, so that the bundle can be
+// given in place of the binary. Anything that isn't a bundle is returned as it is.
+func ResolveAppBundle(path string) string {
+	if !strings.HasSuffix(strings.TrimRight(path, `/\`), ".app") {
+		return path
+	}
+	if f, err := os.Stat(path); err != nil || !f.IsDir() {
+		return path
+	}
+
+	// The bundle says what its executable is called, which is scummvm for ScummVM's own
+	executableName := "scummvm"
+	if infoPlist, err := os.ReadFile(filepath.Join(path, "Contents", "Info.plist")); err == nil {
+		if match := bundleExecutablePattern.FindSubmatch(infoPlist); match != nil {
+			executableName = strings.TrimSpace(string(match[1]))
+		}
+	}
+	return filepath.Join(path, "Contents", "MacOS", executableName)
+}
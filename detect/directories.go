@@ -0,0 +1,117 @@
+package detect
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ListingOptions controls which entries GameDirectories and GameArchives return.
+type ListingOptions struct {
+	// FollowSymlinks includes symlinks that point at directories.
+	FollowSymlinks bool
+
+	// IncludeHidden includes hidden and system directories.
+	IncludeHidden bool
+}
+
+// GameDirectories takes in a directory path and returns a list of all the
+// directories that are in the directory path. Hidden and system directories are left
+// out unless options.IncludeHidden is set. If options.FollowSymlinks is set, then
+// symlinks that point at directories are included too, as long as they don't loop back
+// to the directory path or point at a directory that is already in the list.
+func GameDirectories(scummvmDataFileDirectory string, options ListingOptions) ([]string, error) {
+	// Get a list of all the files in the directory
+	files, err := os.ReadDir(scummvmDataFileDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a slice to store the scummvm data file directories
+	scummvmDataFileDirectories := make([]string, 0)
+
+	// Keep track of the real paths of the directories we've added so that a symlink to
+	// a directory that is already being scanned isn't scanned twice
+	seenRealPaths := make(map[string]string)
+
+	// Loop through each file and check if it is a directory
+	for _, file := range files {
+		// Leave out hidden and system directories
+		if !options.IncludeHidden && isHiddenDirectory(file.Name()) {
+			continue
+		}
+
+		// Check if the file is a directory
+		if file.IsDir() {
+			// Add the file to the list of scummvm data file directories
+			scummvmDataFileDirectories = append(scummvmDataFileDirectories, file.Name())
+
+			// Remember where it really lives in case a symlink points at it
+			if options.FollowSymlinks {
+				if realPath, err := filepath.EvalSymlinks(filepath.Join(scummvmDataFileDirectory, file.Name())); err == nil {
+					seenRealPaths[realPath] = file.Name()
+				}
+			}
+		}
+	}
+
+	// Loop through each file again and add the symlinks that point at directories
+	for _, file := range files {
+		// Check if the file is a symlink that we should follow
+		if !options.FollowSymlinks || file.Type()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		// Leave out hidden and system directories
+		if !options.IncludeHidden && isHiddenDirectory(file.Name()) {
+			continue
+		}
+
+		// Resolve the symlink and make sure it is safe to scan
+		realPath, err := resolveSymlinkedDirectory(scummvmDataFileDirectory, filepath.Join(scummvmDataFileDirectory, file.Name()))
+		if err != nil {
+			fmt.Printf("Skipping symlink %s: %s\n", file.Name(), err)
+			continue
+		}
+		if seenName, ok := seenRealPaths[realPath]; ok {
+			fmt.Printf("Skipping symlink %s: it points at the same directory as %s\n", file.Name(), seenName)
+			continue
+		}
+		seenRealPaths[realPath] = file.Name()
+
+		// Add the symlink to the list of scummvm data file directories
+		scummvmDataFileDirectories = append(scummvmDataFileDirectories, file.Name())
+	}
+
+	// Return the list of scummvm data file directories
+	return scummvmDataFileDirectories, nil
+}
+
+// GameArchives returns the files at the top of a directory path whose extension is one
+// of the given ones, such as ".zip", compared without regard to case. These are games
+// kept in an archive rather than in a directory of their own. Hidden and system files
+// are left out unless options.IncludeHidden is set.
+func GameArchives(scummvmDataFileDirectory string, extensions []string, options ListingOptions) ([]string, error) {
+	files, err := os.ReadDir(scummvmDataFileDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	archives := make([]string, 0)
+	for _, file := range files {
+		if !options.IncludeHidden && isHiddenDirectory(file.Name()) {
+			continue
+		}
+		if !file.Type().IsRegular() {
+			continue
+		}
+		for _, extension := range extensions {
+			if strings.EqualFold(filepath.Ext(file.Name()), extension) {
+				archives = append(archives, file.Name())
+				break
+			}
+		}
+	}
+	return archives, nil
+}
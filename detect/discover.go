@@ -0,0 +1,108 @@
+package detect
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/furui/scummer/wsl"
+)
+
+// scummvmBinaryCandidates returns every place scummvm might be installed on this
+// platform, in the order they should be tried.
+func scummvmBinaryCandidates() []Command {
+	candidates := make([]Command, 0)
+
+	// Anything on the PATH wins
+	if scummvmPath, err := exec.LookPath("scummvm"); err == nil {
+		candidates = append(candidates, Command{Path: scummvmPath})
+	}
+
+	// Then try the usual install locations
+	var installPaths []string
+	switch runtime.GOOS {
+	case "windows":
+		// Where the installer says it put ScummVM comes first, then the usual places for
+		// everyone and for just this user. The console build is preferred in each, since
+		// scummer can read what it prints
+		installDirectories := registryInstallDirectories()
+		for _, programFiles := range []string{os.Getenv("ProgramFiles"), os.Getenv("ProgramFiles(x86)")} {
+			if programFiles != "" {
+				installDirectories = append(installDirectories, filepath.Join(programFiles, "ScummVM"))
+			}
+		}
+		if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
+			installDirectories = append(installDirectories, filepath.Join(localAppData, "Programs", "ScummVM"))
+		}
+		for _, installDirectory := range installDirectories {
+			installPaths = append(installPaths, filepath.Join(installDirectory, "scummvm-console.exe"), filepath.Join(installDirectory, "scummvm.exe"))
+		}
+	case "darwin":
+		installPaths = append(installPaths, "/Applications/ScummVM.app/Contents/MacOS/scummvm")
+		if homeDirectory, err := os.UserHomeDir(); err == nil {
+			installPaths = append(installPaths, filepath.Join(homeDirectory, "Applications", "ScummVM.app", "Contents", "MacOS", "scummvm"))
+		}
+	default:
+		installPaths = append(installPaths, "/usr/bin/scummvm", "/usr/local/bin/scummvm", "/usr/games/scummvm", "/snap/bin/scummvm")
+
+		// Inside WSL, a ScummVM installed on Windows can be run too
+		if wsl.Running() {
+			installPaths = append(installPaths, "/mnt/c/Program Files/ScummVM/scummvm-console.exe", "/mnt/c/Program Files/ScummVM/scummvm.exe")
+		}
+	}
+	for _, installPath := range installPaths {
+		if f, err := os.Stat(installPath); err == nil && !f.IsDir() {
+			candidates = append(candidates, Command{Path: installPath})
+		}
+	}
+
+	// Finally, try the Flatpak
+	if flatpakCommand, err := FlatpakCommand(); err == nil {
+		candidates = append(candidates, flatpakCommand)
+	}
+
+	return candidates
+}
+
+// Discover looks for an installed scummvm and returns the first one that
+// answers "--version" correctly.
+func Discover() (Command, error) {
+	for _, candidate := range scummvmBinaryCandidates() {
+		if _, err := Verify(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	return Command{}, fmt.Errorf("could not find scummvm; use --scummvm to say where it is")
+}
+
+// PackagedCommand returns the command for a scummvm that is given by how it is packaged
+// rather than by its path: "flatpak" for the ScummVM Flatpak, "docker:<image>" for a
+// container image, "ssh://[user@]host[:port][/path/to/scummvm]" for a scummvm on
+// another machine, or "wsl" for the scummvm inside WSL, from Windows. It returns false
+// if name is just a path.
+func PackagedCommand(name string) (Command, bool, error) {
+	if name == "flatpak" {
+		command, err := FlatpakCommand()
+		return command, true, err
+	}
+	if image, ok := strings.CutPrefix(name, dockerCommandPrefix); ok {
+		command, err := DockerCommand(image)
+		return command, true, err
+	}
+	if name == "wsl" {
+		wslPath, err := exec.LookPath("wsl")
+		if err != nil {
+			return Command{}, true, fmt.Errorf("could not find wsl: %w", err)
+		}
+		return Command{Path: wslPath, Args: []string{"--exec", "scummvm"}}, true, nil
+	}
+	if strings.HasPrefix(name, sshCommandPrefix) {
+		command, err := SSHCommand(name)
+		return command, true, err
+	}
+	return Command{}, false, nil
+}
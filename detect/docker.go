@@ -0,0 +1,32 @@
+package detect
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dockerCommandPrefix is how a container image is given in place of the scummvm binary,
+// as in "docker:ghcr.io/example/scummvm:2.8.1".
+const dockerCommandPrefix = "docker:"
+
+// DockerCommand returns the command that runs scummvm in a container of an image, which
+// needs scummvm on its PATH. The container can't reach the network, and only sees the
+// directories scummvm is asked to look at.
+func DockerCommand(image string) (Command, error) {
+	if image == "" {
+		return Command{}, fmt.Errorf("no container image given after %s", dockerCommandPrefix)
+	}
+	dockerPath, err := exec.LookPath("docker")
+	if err != nil {
+		return Command{}, fmt.Errorf("could not find docker: %w", err)
+	}
+	return Command{Path: dockerPath, Args: []string{"run", "--rm", "--network=none", "--entrypoint=scummvm", image}}, nil
+}
+
+// IsDocker returns whether the command runs scummvm in a container with "docker run".
+func (c Command) IsDocker() bool {
+	name := strings.TrimSuffix(filepath.Base(c.Path), ".exe")
+	return name == "docker" && len(c.Args) > 0 && c.Args[0] == "run"
+}
@@ -0,0 +1,21 @@
+package detect
+
+import (
+	"fmt"
+
+	"github.com/furui/scummer/parse"
+)
+
+// LoadEngines returns the engines compiled into a scummvm binary, as a map of their IDs
+// to their names.
+func LoadEngines(scummvmBinary Command) (map[string]string, error) {
+	scummvmOutput, err := Run(scummvmBinary, []string{"--list-engines"})
+	if err != nil {
+		return nil, err
+	}
+	engines := parse.EngineList(scummvmOutput)
+	if len(engines) == 0 {
+		return nil, fmt.Errorf("%s didn't list any engines", scummvmBinary)
+	}
+	return engines, nil
+}
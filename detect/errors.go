@@ -0,0 +1,33 @@
+package detect
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+
+	"github.com/furui/scummer/match"
+)
+
+// ClassifyFilesystemError returns the error kind that best describes an error returned
+// while accessing a directory.
+func ClassifyFilesystemError(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return match.ErrorKindPermission
+	case errors.Is(err, fs.ErrNotExist):
+		return match.ErrorKindNotFound
+	case errors.Is(err, syscall.EIO):
+		return match.ErrorKindIO
+	default:
+		return match.ErrorKindFilesystem
+	}
+}
+
+// CheckDirectoryReadable makes sure the contents of a directory can be listed before it
+// is handed to scummvm, so that filesystem problems are reported as such instead of
+// showing up as a game that could not be detected.
+func CheckDirectoryReadable(directory string) error {
+	_, err := os.ReadDir(directory)
+	return err
+}
@@ -0,0 +1,56 @@
+package detect
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+
+	"github.com/furui/scummer/checksum"
+)
+
+// DirectoryFingerprint identifies the files in a game directory by their names and
+// sizes, without reading them, so that a later check can tell cheaply whether anything
+// was added, removed or replaced since the directory was scanned. The .scummvm files and
+// checksum manifest scummer writes into the directory are left out, so writing them
+// doesn't change it.
+func DirectoryFingerprint(directory string) (string, error) {
+	// Look inside a game directory that is a symlink, which WalkDir wouldn't
+	directory, err := filepath.EvalSymlinks(directory)
+	if err != nil {
+		return "", err
+	}
+
+	files := make([]string, 0)
+	err = filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || checksum.IsManifestFile(path) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, fmt.Sprintf("%s\x00%d", filepath.ToSlash(relativePath), info.Size()))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The order the files are listed in depends on the filesystem
+	sort.Strings(files)
+	fingerprint := sha256.New()
+	for _, file := range files {
+		fingerprint.Write([]byte(file + "\n"))
+	}
+	return hex.EncodeToString(fingerprint.Sum(nil)), nil
+}
@@ -0,0 +1,42 @@
+package detect
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// flatpakScummvmAppID is the ID ScummVM is published under on Flathub.
+const flatpakScummvmAppID = "org.scummvm.ScummVM"
+
+// flatpakReservedPaths are the host directories Flatpak never lets an app see, not even
+// with a --filesystem override.
+var flatpakReservedPaths = []string{"/app", "/bin", "/dev", "/etc", "/lib", "/lib32", "/lib64", "/proc", "/run/flatpak", "/run/host", "/sbin", "/sys", "/usr"}
+
+// FlatpakCommand returns the command that runs the ScummVM Flatpak, if flatpak is
+// installed.
+func FlatpakCommand() (Command, error) {
+	flatpakPath, err := exec.LookPath("flatpak")
+	if err != nil {
+		return Command{}, fmt.Errorf("could not find flatpak: %w", err)
+	}
+	return Command{Path: flatpakPath, Args: []string{"run", flatpakScummvmAppID}}, nil
+}
+
+// IsFlatpak returns whether the command runs scummvm through "flatpak run".
+func (c Command) IsFlatpak() bool {
+	name := strings.TrimSuffix(filepath.Base(c.Path), ".exe")
+	return name == "flatpak" && len(c.Args) > 0 && c.Args[0] == "run"
+}
+
+// checkFlatpakAccess returns an error if the Flatpak can't be given access to the
+// library.
+func checkFlatpakAccess(library string) error {
+	for _, reservedPath := range flatpakReservedPaths {
+		if isSameOrParentPath(reservedPath, library) {
+			return fmt.Errorf("the ScummVM Flatpak can't see %s, since Flatpak keeps %s to itself; move the games somewhere else, such as your home directory", library, reservedPath)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package detect
+
+import (
+	"github.com/furui/scummer/parse"
+)
+
+// LoadGameTitles returns the bundled game titles, with the titles listed by the given
+// scummvm binary added on top so that games newer than the bundled list are known too.
+func LoadGameTitles(scummvmBinary Command) map[string]string {
+	gameTitles := make(map[string]string)
+	for gameID, title := range parse.BundledGameTitles {
+		gameTitles[gameID] = title
+	}
+
+	scummvmOutput, err := Run(scummvmBinary, []string{"--list-games"})
+	if err != nil {
+		return gameTitles
+	}
+	for gameID, title := range parse.GameList(scummvmOutput) {
+		gameTitles[gameID] = title
+	}
+	return gameTitles
+}
@@ -0,0 +1,35 @@
+package detect
+
+import (
+	"strings"
+)
+
+// systemDirectoryNames are directories that operating systems create on their own at
+// the root of a drive or in any folder they touch. None of them ever contain a game.
+var systemDirectoryNames = []string{
+	"System Volume Information",
+	"$RECYCLE.BIN",
+	"RECYCLER",
+	"__MACOSX",
+}
+
+// isHiddenDirectory reports whether a directory name is a hidden or system directory
+// that should be left out of the scan by default. This covers dot-directories (which
+// includes the .Trash-* folders of Linux desktops and macOS metadata folders such as
+// .Spotlight-V100, .fseventsd and .Trashes) and the system directories Windows and
+// macOS leave behind.
+func isHiddenDirectory(name string) bool {
+	// Dot-directories are hidden on every platform
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+
+	// Check the list of known system directories
+	for _, systemDirectoryName := range systemDirectoryNames {
+		if strings.EqualFold(name, systemDirectoryName) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package detect
+
+// registryInstallDirectories returns nothing, since only Windows has a registry.
+func registryInstallDirectories() []string {
+	return nil
+}
@@ -0,0 +1,67 @@
+package detect
+
+import (
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// uninstallKeyPath is where installers register themselves, so that Windows can list
+// them under installed apps.
+const uninstallKeyPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall`
+
+// registryInstallDirectories returns the directories ScummVM's installer says it was
+// installed to, from the uninstall keys of the machine (64-bit and 32-bit) and of the
+// user.
+func registryInstallDirectories() []string {
+	directories := make([]string, 0)
+	uninstallKeys := []struct {
+		root   registry.Key
+		access uint32
+	}{
+		{registry.LOCAL_MACHINE, registry.WOW64_64KEY},
+		{registry.LOCAL_MACHINE, registry.WOW64_32KEY},
+		{registry.CURRENT_USER, 0},
+	}
+	for _, uninstallKey := range uninstallKeys {
+		key, err := registry.OpenKey(uninstallKey.root, uninstallKeyPath, registry.ENUMERATE_SUB_KEYS|registry.READ|uninstallKey.access)
+		if err != nil {
+			continue
+		}
+		subKeyNames, _ := key.ReadSubKeyNames(-1)
+		key.Close()
+
+		for _, subKeyName := range subKeyNames {
+			if directory := scummvmInstallLocation(uninstallKey.root, uninstallKeyPath+`\`+subKeyName, uninstallKey.access); directory != "" {
+				directories = append(directories, directory)
+			}
+		}
+	}
+	return directories
+}
+
+// scummvmInstallLocation returns where the program of an uninstall key was installed,
+// if it is ScummVM.
+func scummvmInstallLocation(root registry.Key, keyPath string, access uint32) string {
+	key, err := registry.OpenKey(root, keyPath, registry.QUERY_VALUE|access)
+	if err != nil {
+		return ""
+	}
+	defer key.Close()
+
+	displayName, _, err := key.GetStringValue("DisplayName")
+	if err != nil || !strings.HasPrefix(displayName, "ScummVM") {
+		return ""
+	}
+
+	// The install location is usually recorded, otherwise the icon is scummvm.exe itself
+	if installLocation, _, err := key.GetStringValue("InstallLocation"); err == nil && installLocation != "" {
+		return filepath.Clean(installLocation)
+	}
+	if displayIcon, _, err := key.GetStringValue("DisplayIcon"); err == nil && displayIcon != "" {
+		displayIcon, _, _ = strings.Cut(strings.Trim(displayIcon, `"`), ",")
+		return filepath.Dir(displayIcon)
+	}
+	return ""
+}
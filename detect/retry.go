@@ -0,0 +1,41 @@
+package detect
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// Retry says how many more times an operation on the library is tried when it fails
+// with an error that a network filesystem, such as a NAS reached over Wi-Fi, gives now
+// and then, and how long to wait before the first of them. The wait doubles after each
+// one. The zero Retry tries everything once.
+type Retry struct {
+	Attempts int
+	Delay    time.Duration
+}
+
+// IsTransientError returns whether an error is one that a network filesystem gives now
+// and then and that may well go away on its own: an I/O error, a file that is briefly
+// missing, a stale file handle, or a timeout.
+func IsTransientError(err error) bool {
+	return errors.Is(err, syscall.EIO) ||
+		errors.Is(err, fs.ErrNotExist) ||
+		errors.Is(err, syscall.ESTALE) ||
+		errors.Is(err, syscall.ETIMEDOUT) ||
+		errors.Is(err, syscall.EAGAIN)
+}
+
+// Do runs an operation, running it again if it fails with a transient error until it
+// succeeds or the attempts run out. The error of the last attempt is returned.
+func (r Retry) Do(operation func() error) error {
+	delay := r.Delay
+	err := operation()
+	for attempt := 0; attempt < r.Attempts && err != nil && IsTransientError(err); attempt++ {
+		time.Sleep(delay)
+		delay *= 2
+		err = operation()
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package detect
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// withPathAccess returns the command with read-only access to every directory given to
+// scummvm with --path, for a scummvm that can't see them otherwise: a Flatpak gets a
+// --filesystem override, since its sandbox only sees the user's home directory, and a
+// container gets the directory bind-mounted at the same path. The directories are made
+// absolute in the arguments too, as the sandbox may not start in the current directory.
+// Other commands are returned as they are.
+func (c Command) withPathAccess(commandLineArguments []string) (Command, []string) {
+	var pathAccess func(path string) string
+	switch {
+	case c.IsFlatpak():
+		pathAccess = func(path string) string { return "--filesystem=" + path + ":ro" }
+	case c.IsDocker():
+		pathAccess = func(path string) string { return "--volume=" + path + ":" + path + ":ro" }
+	default:
+		return c, commandLineArguments
+	}
+
+	// The access is given with options of "flatpak run" or "docker run", so it goes right
+	// after "run"
+	args := []string{c.Args[0]}
+	arguments := make([]string, 0, len(commandLineArguments))
+	for _, argument := range commandLineArguments {
+		if path, ok := strings.CutPrefix(argument, "--path="); ok {
+			if absolutePath, err := filepath.Abs(path); err == nil {
+				args = append(args, pathAccess(absolutePath))
+				argument = "--path=" + absolutePath
+			}
+		}
+		arguments = append(arguments, argument)
+	}
+	c.Args = append(args, c.Args[1:]...)
+	return c, arguments
+}
+
+// CheckSandboxAccess returns an error if scummvm can't see the library because of the
+// sandbox it runs in, such as a Flatpak's or a Snap's.
+func CheckSandboxAccess(scummvmBinary Command, library string) error {
+	if !scummvmBinary.IsFlatpak() && !scummvmBinary.IsSnap() {
+		return nil
+	}
+
+	// The sandbox goes by where the library really is, not by the symlinks leading to it
+	absoluteLibrary, err := filepath.Abs(library)
+	if err != nil {
+		return err
+	}
+	if realLibrary, err := filepath.EvalSymlinks(absoluteLibrary); err == nil {
+		absoluteLibrary = realLibrary
+	}
+
+	if scummvmBinary.IsFlatpak() {
+		return checkFlatpakAccess(absoluteLibrary)
+	}
+	return checkSnapAccess(scummvmBinary, absoluteLibrary)
+}
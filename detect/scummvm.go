@@ -0,0 +1,79 @@
+// Package detect runs scummvm and finds the games for it to look at: it finds an
+// installed scummvm, checks that it works, lists the directories of a library, and runs
+// scummvm on them with whatever arguments are needed, such as "--detect".
+package detect
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Command describes how to run scummvm. Most of the time this is just the path
+// to the scummvm binary, but some installs (such as Flatpak) have to be started through
+// another program, so any arguments that have to come before scummvm's own arguments
+// are kept in Args.
+type Command struct {
+	Path string
+	Args []string
+
+	// LocalLibrary and RemoteLibrary, for a scummvm on another machine, are where the
+	// library is here and where it is on that machine, so that the directories scummvm
+	// is given can be found there. Directories are given as they are if they aren't set.
+	LocalLibrary  string
+	RemoteLibrary string
+}
+
+// String returns the command the way a user would type it.
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Path}, c.Args...), " ")
+}
+
+// Run takes in the scummvm command, and a slice of strings that are the
+// command line arguments to pass to the scummvm binary. The function executes the
+// scummvm binary with the command line arguments and returns the output of the scummvm
+// binary.
+func Run(scummvmBinary Command, commandLineArguments []string) (string, error) {
+	return RunContext(context.Background(), scummvmBinary, commandLineArguments)
+}
+
+// RunContext is Run, but scummvm is killed if the context is done before it finishes.
+func RunContext(ctx context.Context, scummvmBinary Command, commandLineArguments []string) (string, error) {
+	// Let a sandboxed scummvm see the directories it is given
+	scummvmBinary, commandLineArguments = scummvmBinary.withPathAccess(commandLineArguments)
+	if scummvmBinary.IsSSH() {
+		commandLineArguments = scummvmBinary.remoteArguments(commandLineArguments)
+	}
+	commandLineArguments = scummvmBinary.withWSLPaths(commandLineArguments)
+
+	// Create a new command
+	cmd := exec.CommandContext(ctx, scummvmBinary.Path, append(append([]string{}, scummvmBinary.Args...), commandLineArguments...)...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	// Execute the command
+	err := cmd.Run()
+	if err != nil {
+		return out.String(), err
+	}
+
+	// Return the output
+	return out.String(), nil
+}
+
+// Verify runs scummvm with "--version" as a sanity check to make sure the
+// binary can be used, and returns the version output.
+func Verify(scummvmBinary Command) (string, error) {
+	// Check if the scummvm binary file returns a version
+	scummvmVersion, err := Run(scummvmBinary, []string{"--version"})
+	if err != nil {
+		return scummvmVersion, err
+	}
+	if !strings.Contains(scummvmVersion, "ScummVM") {
+		return scummvmVersion, fmt.Errorf("%s is not a valid scummvm binary", scummvmBinary)
+	}
+
+	return scummvmVersion, nil
+}
@@ -0,0 +1,75 @@
+package detect
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// snapBinDirectory is where snapd puts the commands of the installed snaps.
+const snapBinDirectory = "/snap/bin"
+
+// snapRemovableMediaPaths are the directories a snap can only see once its
+// removable-media interface is connected.
+var snapRemovableMediaPaths = []string{"/media", "/run/media", "/mnt"}
+
+// IsSnap returns whether the command runs a snap, which is confined to the user's home
+// directory and, with the removable-media interface, to /media and /mnt.
+func (c Command) IsSnap() bool {
+	return filepath.Dir(filepath.Clean(c.Path)) == snapBinDirectory
+}
+
+// checkSnapAccess returns an error if the snap can't see the library, saying how to
+// let it if it can be done.
+func checkSnapAccess(scummvmBinary Command, library string) error {
+	snapName := filepath.Base(scummvmBinary.Path)
+
+	// The snap can see everything in the home directory apart from the hidden directories
+	// at the top of it
+	if homeDirectory, err := os.UserHomeDir(); err == nil {
+		if realHomeDirectory, err := filepath.EvalSymlinks(homeDirectory); err == nil {
+			homeDirectory = realHomeDirectory
+		}
+		if isSameOrParentPath(homeDirectory, library) {
+			relativeLibrary, _ := filepath.Rel(homeDirectory, library)
+			if strings.HasPrefix(relativeLibrary, ".") && relativeLibrary != "." {
+				return fmt.Errorf("the ScummVM Snap can't see %s, since snaps can't see the hidden directories in your home directory; move the games somewhere else in it", library)
+			}
+			return nil
+		}
+	}
+
+	// Removable media can be seen once the snap is allowed to
+	for _, removableMediaPath := range snapRemovableMediaPaths {
+		if isSameOrParentPath(removableMediaPath, library) {
+			if !snapRemovableMediaConnected(snapName) {
+				return fmt.Errorf("the ScummVM Snap can't see %s until it is allowed to; run \"sudo snap connect %s:removable-media\"", library, snapName)
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the ScummVM Snap can only see your home directory, /media and /mnt, so it can't see %s; move the games into one of them, or install ScummVM another way", library)
+}
+
+// snapRemovableMediaConnected returns whether a snap's removable-media interface is
+// connected. If snap can't tell us, it is assumed to be, so that scummvm's own errors
+// show up rather than ours.
+func snapRemovableMediaConnected(snapName string) bool {
+	connections, err := exec.Command("snap", "connections", snapName).Output()
+	if err != nil {
+		return true
+	}
+
+	// Each line is the interface, the plug, the slot it is connected to ("-" if it isn't)
+	// and notes
+	for _, line := range strings.Split(string(connections), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "removable-media" {
+			return fields[2] != "-"
+		}
+	}
+	return false
+}
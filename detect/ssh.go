@@ -0,0 +1,101 @@
+package detect
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// sshCommandPrefix is how a scummvm on another machine is given in place of the scummvm
+// binary, as in "ssh://deck@steamdeck/usr/bin/scummvm".
+const sshCommandPrefix = "ssh://"
+
+// SSHCommand returns the command that runs scummvm on another machine over ssh, given
+// as "ssh://[user@]host[:port][/path/to/scummvm]". The scummvm on the PATH of that
+// machine is run if the URL has no path. ssh must be able to log in without asking for
+// a password, such as with a key.
+func SSHCommand(scummvmURL string) (Command, error) {
+	parsedURL, err := url.Parse(scummvmURL)
+	if err != nil {
+		return Command{}, err
+	}
+	if parsedURL.Hostname() == "" {
+		return Command{}, fmt.Errorf("no host given in %s", scummvmURL)
+	}
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		return Command{}, fmt.Errorf("could not find ssh: %w", err)
+	}
+
+	// Never wait for a password, since nobody is there to type it
+	args := []string{"-o", "BatchMode=yes"}
+	if port := parsedURL.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	destination := parsedURL.Hostname()
+	if parsedURL.User != nil {
+		destination = parsedURL.User.Username() + "@" + destination
+	}
+	remoteScummvm := "scummvm"
+	if parsedURL.Path != "" && parsedURL.Path != "/" {
+		remoteScummvm = parsedURL.Path
+	}
+	return Command{Path: sshPath, Args: append(args, destination, "--", shellQuote(remoteScummvm))}, nil
+}
+
+// IsSSH returns whether the command runs scummvm on another machine over ssh.
+func (c Command) IsSSH() bool {
+	return strings.TrimSuffix(filepath.Base(c.Path), ".exe") == "ssh"
+}
+
+// WithRemoteLibrary returns the command with the library at localLibrary here being at
+// remoteLibrary on the machine scummvm runs on.
+func (c Command) WithRemoteLibrary(localLibrary string, remoteLibrary string) (Command, error) {
+	absoluteLibrary, err := filepath.Abs(localLibrary)
+	if err != nil {
+		return c, err
+	}
+	c.LocalLibrary = absoluteLibrary
+	c.RemoteLibrary = remoteLibrary
+	return c, nil
+}
+
+// remoteArguments returns the arguments for a scummvm on another machine: the
+// directories given with --path are moved from the local library to the remote one, and
+// everything is quoted, since ssh hands the arguments to a shell on the other machine.
+func (c Command) remoteArguments(commandLineArguments []string) []string {
+	arguments := make([]string, 0, len(commandLineArguments))
+	for _, argument := range commandLineArguments {
+		if directory, ok := strings.CutPrefix(argument, "--path="); ok {
+			argument = "--path=" + c.remotePath(directory)
+		}
+		arguments = append(arguments, shellQuote(argument))
+	}
+	return arguments
+}
+
+// remotePath returns where a local directory is on the machine scummvm runs on. The
+// other machine may not be running the same operating system, so the remote path always
+// uses forward slashes.
+func (c Command) remotePath(directory string) string {
+	if c.LocalLibrary == "" {
+		return directory
+	}
+	absoluteDirectory, err := filepath.Abs(directory)
+	if err != nil {
+		return directory
+	}
+	relativeDirectory, err := filepath.Rel(c.LocalLibrary, absoluteDirectory)
+	if err != nil || !isSameOrParentPath(c.LocalLibrary, absoluteDirectory) {
+		return directory
+	}
+	return path.Join(c.RemoteLibrary, filepath.ToSlash(relativeDirectory))
+}
+
+// shellQuote quotes an argument for a POSIX shell.
+func shellQuote(argument string) string {
+	return "'" + strings.ReplaceAll(argument, "'", `'\''`) + "'"
+}
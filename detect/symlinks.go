@@ -0,0 +1,51 @@
+package detect
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveSymlinkedDirectory follows a symlink found in the scummvm data file directory
+// and returns the real path of the directory it points at. An error is returned if the
+// link is broken, doesn't point at a directory, or points back at the library itself
+// (or one of its parents), which would otherwise make scummer scan the library as if
+// it were a game.
+func resolveSymlinkedDirectory(scummvmDataFileDirectory string, linkPath string) (string, error) {
+	// Resolve the link all the way to the real path
+	realPath, err := filepath.EvalSymlinks(linkPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Make sure the link points at a directory
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s does not point at a directory", linkPath)
+	}
+
+	// Make sure the link doesn't point back at the library or one of its parents
+	realLibraryPath, err := filepath.EvalSymlinks(scummvmDataFileDirectory)
+	if err != nil {
+		return "", err
+	}
+	if isSameOrParentPath(realPath, realLibraryPath) {
+		return "", fmt.Errorf("%s loops back to %s", linkPath, realPath)
+	}
+
+	return realPath, nil
+}
+
+// isSameOrParentPath reports whether parent is the same path as child, or one of its
+// parent directories.
+func isSameOrParentPath(parent string, child string) bool {
+	relativePath, err := filepath.Rel(parent, child)
+	if err != nil {
+		return false
+	}
+	return relativePath == "." || (relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator)))
+}
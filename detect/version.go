@@ -0,0 +1,93 @@
+package detect
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// versionPattern finds the version in what "scummvm --version" prints, such as
+// "ScummVM 2.8.1 (Feb 18 2024 12:00:00)" or "ScummVM 2.9.0git1234-gabcdef (...)".
+var versionPattern = regexp.MustCompile(`ScummVM\s+(\d+)\.(\d+)(?:\.(\d+))?([0-9A-Za-z.+~-]*)`)
+
+// minimumVersionPattern is how a minimum version is written, such as "2.7" or "2.8.1".
+var minimumVersionPattern = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?([0-9A-Za-z.+~-]*)$`)
+
+// Version is a ScummVM version. Suffix is anything after the patch number, such as "git"
+// for a development build or "pre" for a pre-release, which come before the release
+// with the same numbers.
+type Version struct {
+	Major  int
+	Minor  int
+	Patch  int
+	Suffix string
+}
+
+// ParseVersion finds the version in what "scummvm --version" prints.
+func ParseVersion(scummvmVersion string) (Version, error) {
+	parts := versionPattern.FindStringSubmatch(scummvmVersion)
+	if parts == nil {
+		return Version{}, fmt.Errorf("could not find the version of scummvm in %q", scummvmVersion)
+	}
+	return versionFromParts(parts), nil
+}
+
+// ParseMinimumVersion reads a version written as a user would, such as "2.7" or "2.8.1".
+func ParseMinimumVersion(version string) (Version, error) {
+	parts := minimumVersionPattern.FindStringSubmatch(version)
+	if parts == nil {
+		return Version{}, fmt.Errorf("%q is not a version such as 2.8.1", version)
+	}
+	return versionFromParts(parts), nil
+}
+
+// versionFromParts turns the parts a version pattern matched into a Version.
+func versionFromParts(parts []string) Version {
+	version := Version{Suffix: parts[4]}
+	version.Major, _ = strconv.Atoi(parts[1])
+	version.Minor, _ = strconv.Atoi(parts[2])
+	if parts[3] != "" {
+		version.Patch, _ = strconv.Atoi(parts[3])
+	}
+	return version
+}
+
+// String returns the version the way ScummVM writes it.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d%s", v.Major, v.Minor, v.Patch, v.Suffix)
+}
+
+// Compare returns -1 if v comes before other, 1 if it comes after it, and 0 if they are
+// the same version.
+func (v Version) Compare(other Version) int {
+	for _, difference := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if difference < 0 {
+			return -1
+		} else if difference > 0 {
+			return 1
+		}
+	}
+
+	// A build with a suffix comes before the release, and the suffixes of two builds
+	// can't be told apart any better than alphabetically
+	switch {
+	case v.Suffix == other.Suffix:
+		return 0
+	case v.Suffix == "":
+		return 1
+	case other.Suffix == "":
+		return -1
+	case v.Suffix < other.Suffix:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// CheckMinimumVersion returns an error if the version of scummvm comes before minimum.
+func CheckMinimumVersion(version Version, minimum Version) error {
+	if version.Compare(minimum) < 0 {
+		return fmt.Errorf("scummvm %s is older than the minimum version, %s", version, minimum)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package detect
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/furui/scummer/wsl"
+)
+
+// withWSLPaths returns the arguments with the directories given with --path translated
+// for a scummvm on the other side of WSL: a Windows scummvm.exe run from inside WSL gets
+// C:\Games for /mnt/c/Games, and a scummvm inside WSL run with "wsl" from Windows gets
+// /mnt/c/Games for C:\Games. Other commands get the arguments as they are.
+func (c Command) withWSLPaths(commandLineArguments []string) []string {
+	var translate func(path string) string
+	name := strings.ToLower(filepath.Base(c.Path))
+	switch {
+	case wsl.Running() && strings.HasSuffix(name, ".exe"):
+		translate = wsl.WindowsPath
+	case runtime.GOOS == "windows" && strings.TrimSuffix(name, ".exe") == "wsl":
+		translate = wsl.LinuxPath
+	default:
+		return commandLineArguments
+	}
+
+	arguments := make([]string, 0, len(commandLineArguments))
+	for _, argument := range commandLineArguments {
+		if path, ok := strings.CutPrefix(argument, "--path="); ok {
+			if absolutePath, err := filepath.Abs(path); err == nil {
+				argument = "--path=" + translate(absolutePath)
+			}
+		}
+		arguments = append(arguments, argument)
+	}
+	return arguments
+}
@@ -0,0 +1,192 @@
+package discimage
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// framesPerSecond is how many sectors a CD plays in a second, which the times in a cue
+// sheet count in.
+const framesPerSecond = 75
+
+// cueTrack is a track of a cue sheet, before the sizes of the files it is in are known.
+type cueTrack struct {
+	number int
+	mode   string
+	file   string
+
+	// pregap and start are the INDEX 00 and INDEX 01 of the track, in sectors from the
+	// start of its file. pregap is -1 if the track doesn't have an INDEX 00.
+	pregap int64
+	start  int64
+}
+
+// trackModes are the sector layouts of the tracks of a cue sheet: how big a sector is,
+// and where the 2048 bytes of data are in it. Audio tracks have no data in this sense.
+var trackModes = map[string]struct {
+	sectorSize int
+	dataOffset int
+}{
+	"MODE1/2048": {2048, 0},
+	"MODE1/2352": {2352, 16},
+	"MODE2/2336": {2336, 8},
+	"MODE2/2352": {2352, 24},
+	"AUDIO":      {2352, 0},
+}
+
+// parseCueTime parses a time of a cue sheet, mm:ss:ff, into sectors.
+func parseCueTime(text string) (int64, error) {
+	parts := strings.Split(text, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("%q isn't a cue sheet time", text)
+	}
+	values := make([]int64, 3)
+	for i, part := range parts {
+		value, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q isn't a cue sheet time", text)
+		}
+		values[i] = value
+	}
+	return (values[0]*60+values[1])*framesPerSecond + values[2], nil
+}
+
+// parseCueFields splits a line of a cue sheet into its fields, keeping quoted file
+// names together.
+func parseCueFields(line string) []string {
+	fields := make([]string, 0)
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		if strings.HasPrefix(line, `"`) {
+			if end := strings.Index(line[1:], `"`); end >= 0 {
+				fields = append(fields, line[1:end+1])
+				line = line[end+2:]
+				continue
+			}
+		}
+		field, rest, _ := strings.Cut(line, " ")
+		fields = append(fields, field)
+		line = rest
+	}
+	return fields
+}
+
+// readCueSheet reads the tracks of a cue sheet. The files the tracks are in are looked
+// up next to the cue sheet, without regard to case if they aren't there as written,
+// since cue sheets made on Windows often don't match the case of the files.
+func readCueSheet(path string) ([]Track, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cueTracks := make([]cueTrack, 0)
+	currentFile := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := parseCueFields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "FILE":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("%s: a FILE without a file name", path)
+			}
+			currentFile, err = findCueFile(filepath.Dir(path), fields[1])
+			if err != nil {
+				return nil, err
+			}
+		case "TRACK":
+			if len(fields) < 3 || currentFile == "" {
+				return nil, fmt.Errorf("%s: a TRACK that isn't in a FILE", path)
+			}
+			number, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q isn't a track number", path, fields[1])
+			}
+			mode := strings.ToUpper(fields[2])
+			if _, ok := trackModes[mode]; !ok {
+				return nil, fmt.Errorf("%s: track %d is in the %s mode, which isn't supported", path, number, mode)
+			}
+			cueTracks = append(cueTracks, cueTrack{number: number, mode: mode, file: currentFile, pregap: -1})
+		case "INDEX":
+			if len(fields) < 3 || len(cueTracks) == 0 {
+				continue
+			}
+			sectors, err := parseCueTime(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			switch fields[1] {
+			case "00", "0":
+				cueTracks[len(cueTracks)-1].pregap = sectors
+			case "01", "1":
+				cueTracks[len(cueTracks)-1].start = sectors
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cueTracks) == 0 {
+		return nil, fmt.Errorf("%s has no tracks", path)
+	}
+
+	// Each track ends where the next one in the same file starts, counting its pregap,
+	// or at the end of the file
+	tracks := make([]Track, 0, len(cueTracks))
+	for i, cueTrack := range cueTracks {
+		mode := trackModes[cueTrack.mode]
+		track := Track{
+			Number:     cueTrack.number,
+			Audio:      cueTrack.mode == "AUDIO",
+			file:       cueTrack.file,
+			offset:     cueTrack.start * int64(mode.sectorSize),
+			sectorSize: mode.sectorSize,
+			dataOffset: mode.dataOffset,
+		}
+		end := int64(-1)
+		if i+1 < len(cueTracks) && cueTracks[i+1].file == cueTrack.file {
+			end = cueTracks[i+1].start
+			if cueTracks[i+1].pregap >= 0 {
+				end = cueTracks[i+1].pregap
+			}
+		}
+		if end < 0 {
+			info, err := os.Stat(cueTrack.file)
+			if err != nil {
+				return nil, err
+			}
+			end = info.Size() / int64(mode.sectorSize)
+		}
+		track.Sectors = end - cueTrack.start
+		if track.Sectors < 0 {
+			return nil, fmt.Errorf("%s: track %d ends before it starts", path, cueTrack.number)
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks, nil
+}
+
+// findCueFile finds a file a cue sheet refers to in the directory it is in.
+func findCueFile(directory string, name string) (string, error) {
+	path := filepath.Join(directory, filepath.Base(filepath.FromSlash(strings.ReplaceAll(name, `\`, "/"))))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Name(), filepath.Base(path)) {
+			return filepath.Join(directory, entry.Name()), nil
+		}
+	}
+	return "", fmt.Errorf("the cue sheet refers to %s, which isn't in %s", name, directory)
+}
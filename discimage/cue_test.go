@@ -0,0 +1,62 @@
+package discimage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCueSheet(t *testing.T) {
+	// A data track and two audio tracks with pregaps, all in one .bin file whose name
+	// doesn't match the case the cue sheet uses
+	directory := t.TempDir()
+	binFile := filepath.Join(directory, "GAME.BIN")
+	if err := os.WriteFile(binFile, make([]byte, 30*2352), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cueSheet := `FILE "game.bin" BINARY
+  TRACK 01 MODE1/2352
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 00 00:00:10
+    INDEX 01 00:00:12
+  TRACK 03 AUDIO
+    INDEX 00 00:00:20
+    INDEX 01 00:00:22
+`
+	cueFile := filepath.Join(directory, "game.cue")
+	if err := os.WriteFile(cueFile, []byte(cueSheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tracks, err := readCueSheet(cueFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each track ends where the pregap of the next one starts
+	want := []Track{
+		{Number: 1, Sectors: 10, file: binFile, offset: 0, sectorSize: 2352, dataOffset: 16},
+		{Number: 2, Audio: true, Sectors: 8, file: binFile, offset: 12 * 2352, sectorSize: 2352},
+		{Number: 3, Audio: true, Sectors: 8, file: binFile, offset: 22 * 2352, sectorSize: 2352},
+	}
+	if len(tracks) != len(want) {
+		t.Fatalf("readCueSheet returned %d tracks, want %d", len(tracks), len(want))
+	}
+	for i := range want {
+		if tracks[i] != want[i] {
+			t.Errorf("track %d = %+v, want %+v", i+1, tracks[i], want[i])
+		}
+	}
+}
+
+func TestReadCueSheetMissingFile(t *testing.T) {
+	directory := t.TempDir()
+	cueFile := filepath.Join(directory, "game.cue")
+	if err := os.WriteFile(cueFile, []byte("FILE \"game.bin\" BINARY\n  TRACK 01 MODE1/2048\n    INDEX 01 00:00:00\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readCueSheet(cueFile); err == nil {
+		t.Error("readCueSheet returned no error for a cue sheet whose file is missing")
+	}
+}
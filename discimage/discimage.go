@@ -0,0 +1,352 @@
+// Package discimage reads the CD images games are often kept as, .iso files and .cue
+// sheets with their .bin files, without mounting them. The files of the data track are
+// read from its ISO 9660 filesystem, preferring the long names of a Joliet filesystem
+// when there is one, and the audio tracks can be written out as WAV files, which is how
+// ScummVM plays the CD audio of the games that have it.
+package discimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// sectorSize is the size of the data in a sector of a data track.
+const sectorSize = 2048
+
+// Extensions are the extensions of the images Open reads.
+var Extensions = []string{".cue", ".iso"}
+
+// Track is a track of a CD image.
+type Track struct {
+	Number int
+	Audio  bool
+
+	// Sectors is how long the track is.
+	Sectors int64
+
+	// file is the file the track is in, and offset is where in it the track starts.
+	// Each sector is sectorSize bytes long, with the data at dataOffset in it.
+	file       string
+	offset     int64
+	sectorSize int
+	dataOffset int
+}
+
+// File is a file on the data track of a CD image.
+type File struct {
+	// Path is where the file is on the disc, with forward slashes.
+	Path string
+	Size int64
+
+	extent int64
+}
+
+// Image is an opened CD image.
+type Image struct {
+	// Path is the .iso file or the .cue sheet.
+	Path   string
+	Tracks []Track
+
+	files    map[string]*os.File
+	data     *Track
+	dataFile *os.File
+}
+
+// Open opens an .iso file, or a .cue sheet and the files its tracks are in.
+func Open(imagePath string) (*Image, error) {
+	var tracks []Track
+	switch strings.ToLower(filepath.Ext(imagePath)) {
+	case ".iso":
+		info, err := os.Stat(imagePath)
+		if err != nil {
+			return nil, err
+		}
+		tracks = []Track{{Number: 1, Sectors: info.Size() / sectorSize, file: imagePath, sectorSize: sectorSize}}
+	case ".cue":
+		var err error
+		if tracks, err = readCueSheet(imagePath); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s isn't a CD image, must be a .cue or .iso file", imagePath)
+	}
+
+	image := &Image{Path: imagePath, Tracks: tracks, files: make(map[string]*os.File)}
+	for i := range image.Tracks {
+		if !image.Tracks[i].Audio {
+			image.data = &image.Tracks[i]
+			break
+		}
+	}
+	if image.data != nil {
+		file, err := image.open(image.data.file)
+		if err != nil {
+			image.Close()
+			return nil, err
+		}
+		image.dataFile = file
+	}
+	return image, nil
+}
+
+// Sources returns the files the image is made of: the .iso file, or the .cue sheet and
+// the files its tracks are in.
+func (image *Image) Sources() []string {
+	sources := []string{image.Path}
+	seen := map[string]bool{image.Path: true}
+	for _, track := range image.Tracks {
+		if !seen[track.file] {
+			sources = append(sources, track.file)
+			seen[track.file] = true
+		}
+	}
+	return sources
+}
+
+// AudioTracks returns the audio tracks of the image.
+func (image *Image) AudioTracks() []Track {
+	audioTracks := make([]Track, 0)
+	for _, track := range image.Tracks {
+		if track.Audio {
+			audioTracks = append(audioTracks, track)
+		}
+	}
+	return audioTracks
+}
+
+// open opens a file the image is made of, once.
+func (image *Image) open(name string) (*os.File, error) {
+	if file, ok := image.files[name]; ok {
+		return file, nil
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	image.files[name] = file
+	return file, nil
+}
+
+// Close closes the files the image is made of.
+func (image *Image) Close() error {
+	var closeErr error
+	for _, file := range image.files {
+		if err := file.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
+
+// readSector reads the data of a sector of the data track.
+func (image *Image) readSector(sector int64, buffer []byte) error {
+	if image.data == nil {
+		return errors.New("the image has no data track")
+	}
+	if sector < 0 || sector >= image.data.Sectors {
+		return fmt.Errorf("sector %d is past the end of the data track", sector)
+	}
+	_, err := image.dataFile.ReadAt(buffer[:sectorSize], image.data.offset+sector*int64(image.data.sectorSize)+int64(image.data.dataOffset))
+	return err
+}
+
+// directoryRecord is an entry of a directory of an ISO 9660 filesystem.
+type directoryRecord struct {
+	name        string
+	extent      int64
+	size        int64
+	isDirectory bool
+}
+
+// parseDirectoryRecord parses the directory record at the start of data. It returns how
+// long the record is, which is 0 at the end of the records in a sector.
+func parseDirectoryRecord(data []byte, joliet bool) (directoryRecord, int, error) {
+	length := int(data[0])
+	if length == 0 {
+		return directoryRecord{}, 0, nil
+	}
+	if length < 34 || length > len(data) || 33+int(data[32]) > length {
+		return directoryRecord{}, 0, errors.New("the image has a broken directory record")
+	}
+	record := directoryRecord{
+		extent:      int64(binary.LittleEndian.Uint32(data[2:6])),
+		size:        int64(binary.LittleEndian.Uint32(data[10:14])),
+		isDirectory: data[25]&0x02 != 0,
+	}
+	rawName := data[33 : 33+int(data[32])]
+	if len(rawName) == 1 && rawName[0] <= 1 {
+		// The directory itself and its parent
+		return record, length, nil
+	}
+	if joliet {
+		units := make([]uint16, len(rawName)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(rawName[i*2:])
+		}
+		record.name = string(utf16.Decode(units))
+	} else {
+		record.name = string(rawName)
+	}
+
+	// File names end in a version, such as ";1", and a dot if they have no extension
+	if !record.isDirectory {
+		if name, _, found := strings.Cut(record.name, ";"); found {
+			record.name = name
+		}
+		record.name = strings.TrimSuffix(record.name, ".")
+	}
+	return record, length, nil
+}
+
+// rootDirectory finds the root directory of the data track, from the Joliet volume
+// descriptor if there is one and the primary one if there isn't.
+func (image *Image) rootDirectory() (directoryRecord, bool, error) {
+	buffer := make([]byte, sectorSize)
+	var primary *directoryRecord
+	for sector := int64(16); ; sector++ {
+		if err := image.readSector(sector, buffer); err != nil {
+			return directoryRecord{}, false, fmt.Errorf("%s has no ISO 9660 filesystem: %w", image.Path, err)
+		}
+		if string(buffer[1:6]) != "CD001" {
+			return directoryRecord{}, false, fmt.Errorf("%s has no ISO 9660 filesystem", image.Path)
+		}
+		switch buffer[0] {
+		case 1:
+			record, _, err := parseDirectoryRecord(buffer[156:190], false)
+			if err != nil {
+				return directoryRecord{}, false, err
+			}
+			primary = &record
+		case 2:
+			escapes := buffer[88:91]
+			if bytes.Equal(escapes, []byte("%/@")) || bytes.Equal(escapes, []byte("%/C")) || bytes.Equal(escapes, []byte("%/E")) {
+				record, _, err := parseDirectoryRecord(buffer[156:190], true)
+				return record, true, err
+			}
+		case 255:
+			if primary == nil {
+				return directoryRecord{}, false, fmt.Errorf("%s has no primary volume descriptor", image.Path)
+			}
+			return *primary, false, nil
+		}
+	}
+}
+
+// Files lists the files on the data track, sorted by path.
+func (image *Image) Files() ([]File, error) {
+	root, joliet, err := image.rootDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]File, 0)
+	visited := make(map[int64]bool)
+	var walk func(directory directoryRecord, directoryPath string) error
+	walk = func(directory directoryRecord, directoryPath string) error {
+		// A broken image could have a directory that contains itself
+		if visited[directory.extent] {
+			return nil
+		}
+		visited[directory.extent] = true
+
+		buffer := make([]byte, sectorSize)
+		sectors := (directory.size + sectorSize - 1) / sectorSize
+		for sector := int64(0); sector < sectors; sector++ {
+			if err := image.readSector(directory.extent+sector, buffer); err != nil {
+				return err
+			}
+			for offset := 0; offset < sectorSize; {
+				record, length, err := parseDirectoryRecord(buffer[offset:], joliet)
+				if err != nil {
+					return err
+				}
+				if length == 0 {
+					break
+				}
+				offset += length
+				if record.name == "" {
+					continue
+				}
+				recordPath := path.Join(directoryPath, record.name)
+				if record.isDirectory {
+					if err := walk(record, recordPath); err != nil {
+						return err
+					}
+					continue
+				}
+				files = append(files, File{Path: recordPath, Size: record.size, extent: record.extent})
+			}
+		}
+		return nil
+	}
+	if err := walk(root, ""); err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// WriteFile copies a file on the data track to w.
+func (image *Image) WriteFile(file File, w io.Writer) error {
+	buffer := make([]byte, sectorSize)
+	remaining := file.Size
+	for sector := file.extent; remaining > 0; sector++ {
+		if err := image.readSector(sector, buffer); err != nil {
+			return fmt.Errorf("reading %s from %s: %w", file.Path, image.Path, err)
+		}
+		n := int64(sectorSize)
+		if remaining < n {
+			n = remaining
+		}
+		if _, err := w.Write(buffer[:n]); err != nil {
+			return err
+		}
+		remaining -= n
+	}
+	return nil
+}
+
+// WriteWAV writes an audio track to w as a WAV file, which is how it is on the CD:
+// 16-bit stereo at 44.1 kHz.
+func (image *Image) WriteWAV(track Track, w io.Writer) error {
+	if !track.Audio {
+		return fmt.Errorf("track %d isn't an audio track", track.Number)
+	}
+	file, err := image.open(track.file)
+	if err != nil {
+		return err
+	}
+	size := track.Sectors * int64(track.sectorSize)
+
+	// The header of a WAV file of CD audio
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+size))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], 2)
+	binary.LittleEndian.PutUint32(header[24:], 44100)
+	binary.LittleEndian.PutUint32(header[28:], 44100*4)
+	binary.LittleEndian.PutUint16(header[32:], 4)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(size))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, io.NewSectionReader(file, track.offset, size))
+	return err
+}
@@ -0,0 +1,154 @@
+package discimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+// directoryRecordBytes makes an ISO 9660 directory record.
+func directoryRecordBytes(name []byte, extent uint32, size uint32, isDirectory bool) []byte {
+	length := 33 + len(name)
+	if length%2 != 0 {
+		length++
+	}
+	record := make([]byte, length)
+	record[0] = byte(length)
+	binary.LittleEndian.PutUint32(record[2:6], extent)
+	binary.BigEndian.PutUint32(record[6:10], extent)
+	binary.LittleEndian.PutUint32(record[10:14], size)
+	binary.BigEndian.PutUint32(record[14:18], size)
+	if isDirectory {
+		record[25] = 0x02
+	}
+	record[32] = byte(len(name))
+	copy(record[33:], name)
+	return record
+}
+
+// writeISO writes an .iso file with a primary volume descriptor whose root directory is
+// the given records, in sector 18, and the given data in sector 19.
+func writeISO(t *testing.T, rootRecords [][]byte, data []byte) string {
+	t.Helper()
+	image := make([]byte, 20*sectorSize)
+
+	// The primary volume descriptor, and the one that ends the descriptors
+	primary := image[16*sectorSize:]
+	primary[0] = 1
+	copy(primary[1:6], "CD001")
+	copy(primary[156:190], directoryRecordBytes([]byte{0}, 18, sectorSize, true))
+	terminator := image[17*sectorSize:]
+	terminator[0] = 255
+	copy(terminator[1:6], "CD001")
+
+	// The root directory, and the data of its files
+	offset := 18 * sectorSize
+	for _, record := range rootRecords {
+		offset += copy(image[offset:], record)
+	}
+	copy(image[19*sectorSize:], data)
+
+	imagePath := filepath.Join(t.TempDir(), "game.iso")
+	if err := os.WriteFile(imagePath, image, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return imagePath
+}
+
+func TestParseDirectoryRecord(t *testing.T) {
+	jolietName := make([]byte, 0)
+	for _, unit := range utf16.Encode([]rune("Loom.exe;1")) {
+		jolietName = binary.BigEndian.AppendUint16(jolietName, unit)
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		joliet     bool
+		want       directoryRecord
+		wantLength int
+	}{
+		{"file", directoryRecordBytes([]byte("LOOM.EXE;1"), 20, 1234, false), false, directoryRecord{name: "LOOM.EXE", extent: 20, size: 1234}, 44},
+		{"file without an extension", directoryRecordBytes([]byte("README.;1"), 21, 5, false), false, directoryRecord{name: "README", extent: 21, size: 5}, 42},
+		{"directory", directoryRecordBytes([]byte("DATA"), 22, 2048, true), false, directoryRecord{name: "DATA", extent: 22, size: 2048, isDirectory: true}, 38},
+		{"itself", directoryRecordBytes([]byte{0}, 18, 2048, true), false, directoryRecord{extent: 18, size: 2048, isDirectory: true}, 34},
+		{"parent", directoryRecordBytes([]byte{1}, 18, 2048, true), false, directoryRecord{extent: 18, size: 2048, isDirectory: true}, 34},
+		{"joliet", directoryRecordBytes(jolietName, 20, 1234, false), true, directoryRecord{name: "Loom.exe", extent: 20, size: 1234}, 54},
+		{"end of the sector", make([]byte, 34), false, directoryRecord{}, 0},
+	}
+	for _, test := range tests {
+		record, length, err := parseDirectoryRecord(test.data, test.joliet)
+		if err != nil {
+			t.Errorf("%s: parseDirectoryRecord returned %v", test.name, err)
+			continue
+		}
+		if record != test.want || length != test.wantLength {
+			t.Errorf("%s: parseDirectoryRecord = %+v, %d, want %+v, %d", test.name, record, length, test.want, test.wantLength)
+		}
+	}
+}
+
+func TestParseDirectoryRecordBroken(t *testing.T) {
+	tooShort := directoryRecordBytes([]byte("A"), 20, 1, false)
+	tooShort[0] = 20
+	pastTheData := directoryRecordBytes([]byte("LOOM.EXE;1"), 20, 1, false)[:40]
+	nameTooLong := directoryRecordBytes([]byte("LOOM.EXE;1"), 20, 1, false)
+	nameTooLong[32] = 40
+
+	for name, data := range map[string][]byte{"too short": tooShort, "past the data": pastTheData, "name too long": nameTooLong} {
+		if _, _, err := parseDirectoryRecord(data, false); err == nil {
+			t.Errorf("%s: parseDirectoryRecord returned no error", name)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	// The root directory has a file, and a directory that is the root directory again
+	imagePath := writeISO(t, [][]byte{
+		directoryRecordBytes([]byte{0}, 18, sectorSize, true),
+		directoryRecordBytes([]byte{1}, 18, sectorSize, true),
+		directoryRecordBytes([]byte("GAME.DAT;1"), 19, 5, false),
+		directoryRecordBytes([]byte("LOOP"), 18, sectorSize, true),
+	}, []byte("hello"))
+	image, err := Open(imagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer image.Close()
+
+	files, err := image.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "GAME.DAT" || files[0].Size != 5 {
+		t.Fatalf("Files() = %+v, want just GAME.DAT", files)
+	}
+	var contents bytes.Buffer
+	if err := image.WriteFile(files[0], &contents); err != nil {
+		t.Fatal(err)
+	}
+	if contents.String() != "hello" {
+		t.Errorf("WriteFile wrote %q, want %q", contents.String(), "hello")
+	}
+}
+
+func TestFilesBrokenDirectoryRecord(t *testing.T) {
+	broken := directoryRecordBytes([]byte("GAME.DAT;1"), 19, 5, false)
+	broken[32] = 200
+	imagePath := writeISO(t, [][]byte{
+		directoryRecordBytes([]byte{0}, 18, sectorSize, true),
+		broken,
+	}, nil)
+	image, err := Open(imagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer image.Close()
+
+	if _, err := image.Files(); err == nil {
+		t.Error("Files() returned no error for a broken directory record")
+	}
+}
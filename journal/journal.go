@@ -0,0 +1,503 @@
+// Package journal records the changes a run of scummer makes to the filesystem, such as
+// the .scummvm files it writes and the directories it renames or moves, so that "scummer
+// undo" can put everything back the way it was. Each run has a journal of its own,
+// named after when it started, with one JSON object per line that is written as soon as
+// the change is made, so a run that is stopped part of the way through can be undone
+// too. Files that are overwritten are backed up next to the journal.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The changes a journal records.
+const (
+	// ActionRun starts every journal, and says which command made the changes.
+	ActionRun = "run"
+
+	// ActionCreated is a file that didn't exist before.
+	ActionCreated = "created"
+
+	// ActionReplaced is a file that was overwritten. Its old contents are in Backup.
+	ActionReplaced = "replaced"
+
+	// ActionRemoved is a file that was removed. Its contents are in Backup.
+	ActionRemoved = "removed"
+
+	// ActionRenamed is a file or directory that was renamed or moved From somewhere else.
+	ActionRenamed = "renamed"
+
+	// ActionLinked is a symlink that was made, pointing at From.
+	ActionLinked = "linked"
+
+	// ActionMadeDirectory is an empty directory that was made to put things in.
+	ActionMadeDirectory = "made-directory"
+
+	// ActionAddedDirectory is a directory that was added with everything in it, such as
+	// a game copied into the library. Size and Files say how much was in it.
+	ActionAddedDirectory = "added-directory"
+)
+
+// Entry is a change that was made.
+type Entry struct {
+	Action string
+	Time   time.Time
+
+	// Path is the absolute path of what was changed.
+	Path string `json:",omitempty"`
+
+	// From is where a renamed file or directory was before, or what a symlink points at.
+	From string `json:",omitempty"`
+
+	// Backup is the file in the journal's backup directory that the old contents of a
+	// replaced or removed file were saved to, and Mode is the file's permissions.
+	Backup string      `json:",omitempty"`
+	Mode   fs.FileMode `json:",omitempty"`
+
+	// Size and ModTime are what a file was like once it was written, so that undo can
+	// tell if it has been changed since. Size and Files are the same for an added
+	// directory.
+	Size    int64 `json:",omitempty"`
+	ModTime time.Time
+	Files   int `json:",omitempty"`
+
+	// Command is the command line of the run, for ActionRun.
+	Command string `json:",omitempty"`
+}
+
+// Journal records the changes of a run. Nothing is written until the first change, so
+// a run that changes nothing leaves no journal behind. A nil Journal records nothing, so
+// that the commands that don't keep one, such as a scan of a library on remote storage,
+// don't have to check.
+type Journal struct {
+	// RunID names the run, and is what "scummer undo" is given.
+	RunID string
+
+	directory string
+	command   string
+	file      *os.File
+	backups   int
+	mutex     sync.Mutex
+}
+
+// New starts the journal of a run in directory, which is created when the first change
+// is recorded. The command is the command line of the run.
+func New(directory string, command string) *Journal {
+	return &Journal{RunID: time.Now().Format("20060102-150405"), directory: directory, command: command}
+}
+
+// Changed returns whether any changes were recorded.
+func (j *Journal) Changed() bool {
+	if j == nil {
+		return false
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.file != nil
+}
+
+// Close closes the journal's file.
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+// open creates the journal's file on the first change. Another run that started in the
+// same second gets a number added to its RunID.
+func (j *Journal) open() error {
+	if j.file != nil {
+		return nil
+	}
+	if err := os.MkdirAll(j.directory, 0755); err != nil {
+		return err
+	}
+	runID := j.RunID
+	for n := 2; ; n++ {
+		file, err := os.OpenFile(filepath.Join(j.directory, runID+".jsonl"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			runID = j.RunID + "-" + strconv.Itoa(n)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		j.RunID = runID
+		j.file = file
+		break
+	}
+	return j.write(Entry{Action: ActionRun, Time: time.Now(), Command: j.command})
+}
+
+// write adds an entry to the journal's file, which must be open.
+func (j *Journal) write(entry Entry) error {
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(entryJSON, '\n')); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// record adds a change to the journal, creating it if it is the first.
+func (j *Journal) record(entry Entry) error {
+	if err := j.open(); err != nil {
+		return fmt.Errorf("recording the change to %s in the journal: %w", entry.Path, err)
+	}
+	entry.Time = time.Now()
+	return j.write(entry)
+}
+
+// WriteFile writes a file the way os.WriteFile does, and records it.
+func (j *Journal) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	return j.ChangeFile(path, func() error {
+		return os.WriteFile(path, data, perm)
+	})
+}
+
+// ChangeFile runs write, which creates or overwrites the file at path, and records it.
+// A file that was already there is backed up first, so that undo can bring it back.
+func (j *Journal) ChangeFile(path string, write func() error) error {
+	if j == nil {
+		return write()
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	// Back up what is there now
+	entry := Entry{Action: ActionCreated, Path: absolutePath}
+	if info, err := os.Lstat(absolutePath); err == nil && info.Mode().IsRegular() {
+		backup, err := j.backup(absolutePath)
+		if err != nil {
+			return err
+		}
+		entry = Entry{Action: ActionReplaced, Path: absolutePath, Backup: backup, Mode: info.Mode().Perm()}
+	}
+
+	if err := write(); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(absolutePath); err == nil {
+		entry.Size = info.Size()
+		entry.ModTime = info.ModTime()
+	}
+	return j.record(entry)
+}
+
+// RemoveFile removes a file, and records it. The file is backed up first, so that undo
+// can bring it back.
+func (j *Journal) RemoveFile(path string) error {
+	if j == nil {
+		return os.Remove(path)
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(absolutePath)
+	if err != nil {
+		return err
+	}
+	backup, err := j.backup(absolutePath)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(absolutePath); err != nil {
+		return err
+	}
+	return j.record(Entry{Action: ActionRemoved, Path: absolutePath, Backup: backup, Mode: info.Mode().Perm()})
+}
+
+// backup copies a file into the journal's backup directory, returning where it went.
+func (j *Journal) backup(path string) (string, error) {
+	if err := j.open(); err != nil {
+		return "", err
+	}
+	j.backups++
+	backup := filepath.Join(j.directory, j.RunID, strconv.Itoa(j.backups))
+	if err := copyFile(path, backup); err != nil {
+		return "", fmt.Errorf("backing up %s: %w", path, err)
+	}
+	return backup, nil
+}
+
+// Renamed records that a file or directory was renamed or moved from one path to
+// another.
+func (j *Journal) Renamed(from string, to string) error {
+	if j == nil {
+		return nil
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	absoluteFrom, err := filepath.Abs(from)
+	if err != nil {
+		return err
+	}
+	absoluteTo, err := filepath.Abs(to)
+	if err != nil {
+		return err
+	}
+	return j.record(Entry{Action: ActionRenamed, Path: absoluteTo, From: absoluteFrom})
+}
+
+// Linked records that a symlink was made at path, pointing at target.
+func (j *Journal) Linked(path string, target string) error {
+	if j == nil {
+		return nil
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return j.record(Entry{Action: ActionLinked, Path: absolutePath, From: target})
+}
+
+// MkdirAll creates a directory and the ones it is in that don't exist yet, the way
+// os.MkdirAll does, and records the ones it made.
+func (j *Journal) MkdirAll(path string, perm fs.FileMode) error {
+	if j == nil {
+		return os.MkdirAll(path, perm)
+	}
+
+	// Find the directories that are missing, the innermost first
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	missingDirectories := make([]string, 0)
+	for directory := absolutePath; ; directory = filepath.Dir(directory) {
+		if _, err := os.Lstat(directory); err == nil || filepath.Dir(directory) == directory {
+			break
+		}
+		missingDirectories = append(missingDirectories, directory)
+	}
+
+	if err := os.MkdirAll(absolutePath, perm); err != nil {
+		return err
+	}
+	for i := len(missingDirectories) - 1; i >= 0; i-- {
+		if err := j.MadeDirectory(missingDirectories[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MadeDirectory records that an empty directory was made to put things in, which undo
+// removes again once it is empty.
+func (j *Journal) MadeDirectory(path string) error {
+	if j == nil {
+		return nil
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return j.record(Entry{Action: ActionMadeDirectory, Path: absolutePath})
+}
+
+// AddedDirectory records that a directory was added with everything in it, which undo
+// removes again as long as nothing in it has been added, removed or changed in size.
+func (j *Journal) AddedDirectory(path string) error {
+	if j == nil {
+		return nil
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	files, size, err := DirectoryContents(absolutePath)
+	if err != nil {
+		return err
+	}
+	return j.record(Entry{Action: ActionAddedDirectory, Path: absolutePath, Files: files, Size: size})
+}
+
+// DirectoryContents returns how many files are in a directory and everything below it,
+// and how big they are altogether.
+func DirectoryContents(directory string) (int, int64, error) {
+	files := 0
+	size := int64(0)
+	err := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files++
+		size += info.Size()
+		return nil
+	})
+	return files, size, err
+}
+
+// copyFile copies a file, creating the directory it goes in.
+func copyFile(source string, destination string) error {
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destinationFile, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(destinationFile, sourceFile); err != nil {
+		destinationFile.Close()
+		return err
+	}
+	return destinationFile.Close()
+}
+
+// Run is a run whose changes are in a journal.
+type Run struct {
+	RunID   string
+	Started time.Time
+	Command string
+	Entries []Entry
+
+	// DamagedLines are the lines of the journal that couldn't be read and were skipped,
+	// such as the last one of a run that was stopped while writing it.
+	DamagedLines []int
+}
+
+// Read returns the run with the given RunID from the journals in directory. Lines that
+// can't be read are skipped, and listed in the run's DamagedLines.
+func Read(directory string, runID string) (Run, error) {
+	if !filepath.IsLocal(runID) || strings.ContainsAny(runID, `/\`) {
+		return Run{}, fmt.Errorf("%s isn't a run ID", runID)
+	}
+	file, err := os.Open(filepath.Join(directory, runID+".jsonl"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Run{}, fmt.Errorf("there is no run %s in %s", runID, directory)
+	}
+	if err != nil {
+		return Run{}, err
+	}
+	defer file.Close()
+
+	run := Run{RunID: runID, Entries: make([]Entry, 0)}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// The last line is cut short if the run was stopped while writing it
+			run.DamagedLines = append(run.DamagedLines, line)
+			continue
+		}
+		if entry.Action == ActionRun {
+			run.Started = entry.Time
+			run.Command = entry.Command
+			continue
+		}
+		run.Entries = append(run.Entries, entry)
+	}
+	return run, scanner.Err()
+}
+
+// List returns the runs in the journals in directory, oldest first.
+func List(directory string) ([]Run, error) {
+	files, err := os.ReadDir(directory)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Run{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]Run, 0)
+	for _, file := range files {
+		runID, ok := strings.CutSuffix(file.Name(), ".jsonl")
+		if !ok || file.IsDir() {
+			continue
+		}
+		run, err := Read(directory, runID)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(a, b int) bool {
+		return runs[a].Started.Before(runs[b].Started)
+	})
+	return runs, nil
+}
+
+// Rewrite replaces the journal of a run with the given entries, which are what is left
+// of it once some of its changes were undone. A run with nothing left has its journal
+// and backups removed.
+func Rewrite(directory string, run Run, entries []Entry) error {
+	journalFile := filepath.Join(directory, run.RunID+".jsonl")
+	if len(entries) == 0 {
+		if err := os.RemoveAll(filepath.Join(directory, run.RunID)); err != nil {
+			return err
+		}
+		return os.Remove(journalFile)
+	}
+
+	var contents strings.Builder
+	for _, entry := range append([]Entry{{Action: ActionRun, Time: run.Started, Command: run.Command}}, entries...) {
+		entryJSON, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		contents.Write(entryJSON)
+		contents.WriteByte('\n')
+	}
+	temporaryFile := journalFile + ".tmp"
+	if err := os.WriteFile(temporaryFile, []byte(contents.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temporaryFile, journalFile)
+}
@@ -0,0 +1,132 @@
+package match
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// Alias is what a community abbreviation such as "DOTT" stands for. GameID is the
+// GameID of the game without the engine prefix, and is left empty when the
+// abbreviation covers games with several GameIDs.
+type Alias struct {
+	Title  string `json:"Title"`
+	GameID string `json:"GameID,omitempty"`
+}
+
+// BuiltinAliases are the abbreviations that are commonly used to name game directories.
+// The keys are lower case.
+var BuiltinAliases = map[string]Alias{
+	"mm":         {Title: "Maniac Mansion", GameID: "maniac"},
+	"zak":        {Title: "Zak McKracken and the Alien Mindbenders", GameID: "zak"},
+	"ijlc":       {Title: "Indiana Jones and the Last Crusade", GameID: "indy3"},
+	"indy3":      {Title: "Indiana Jones and the Last Crusade", GameID: "indy3"},
+	"mi":         {Title: "The Secret of Monkey Island", GameID: "monkey"},
+	"mi1":        {Title: "The Secret of Monkey Island", GameID: "monkey"},
+	"somi":       {Title: "The Secret of Monkey Island", GameID: "monkey"},
+	"mi2":        {Title: "Monkey Island 2: LeChuck's Revenge", GameID: "monkey2"},
+	"comi":       {Title: "The Curse of Monkey Island", GameID: "comi"},
+	"mi3":        {Title: "The Curse of Monkey Island", GameID: "comi"},
+	"foa":        {Title: "Indiana Jones and the Fate of Atlantis", GameID: "atlantis"},
+	"ijfoa":      {Title: "Indiana Jones and the Fate of Atlantis", GameID: "atlantis"},
+	"indy4":      {Title: "Indiana Jones and the Fate of Atlantis", GameID: "atlantis"},
+	"dott":       {Title: "Day of the Tentacle", GameID: "tentacle"},
+	"samnmax":    {Title: "Sam & Max Hit the Road", GameID: "samnmax"},
+	"snm":        {Title: "Sam & Max Hit the Road", GameID: "samnmax"},
+	"ft":         {Title: "Full Throttle", GameID: "ft"},
+	"bass":       {Title: "Beneath a Steel Sky", GameID: "sky"},
+	"fotaq":      {Title: "Flight of the Amazon Queen", GameID: "queen"},
+	"bs1":        {Title: "Broken Sword: The Shadow of the Templars", GameID: "sword1"},
+	"bs2":        {Title: "Broken Sword II: The Smoking Mirror", GameID: "sword2"},
+	"ite":        {Title: "Inherit the Earth: Quest for the Orb", GameID: "ite"},
+	"ihnm":       {Title: "I Have No Mouth and I Must Scream", GameID: "ihnm"},
+	"gk1":        {Title: "Gabriel Knight: Sins of the Fathers", GameID: "gk1"},
+	"gk2":        {Title: "The Beast Within: A Gabriel Knight Mystery", GameID: "gk2"},
+	"kq1":        {Title: "King's Quest I: Quest for the Crown"},
+	"kq2":        {Title: "King's Quest II: Romancing the Throne"},
+	"kq3":        {Title: "King's Quest III: To Heir Is Human"},
+	"kq4":        {Title: "King's Quest IV: The Perils of Rosella"},
+	"kq5":        {Title: "King's Quest V: Absence Makes the Heart Go Yonder!", GameID: "kq5"},
+	"kq6":        {Title: "King's Quest VI: Heir Today, Gone Tomorrow", GameID: "kq6"},
+	"kq7":        {Title: "King's Quest VII: The Princeless Bride", GameID: "kq7"},
+	"sq1":        {Title: "Space Quest I: The Sarien Encounter"},
+	"sq2":        {Title: "Space Quest II: Vohaul's Revenge", GameID: "sq2"},
+	"sq3":        {Title: "Space Quest III: The Pirates of Pestulon", GameID: "sq3"},
+	"sq4":        {Title: "Space Quest IV: Roger Wilco and the Time Rippers", GameID: "sq4"},
+	"sq5":        {Title: "Space Quest V: The Next Mutation", GameID: "sq5"},
+	"sq6":        {Title: "Space Quest 6: Roger Wilco in the Spinal Frontier", GameID: "sq6"},
+	"qfg1":       {Title: "Quest for Glory I: So You Want to Be a Hero"},
+	"qfg2":       {Title: "Quest for Glory II: Trial by Fire", GameID: "qfg2"},
+	"qfg3":       {Title: "Quest for Glory III: Wages of War", GameID: "qfg3"},
+	"qfg4":       {Title: "Quest for Glory IV: Shadows of Darkness", GameID: "qfg4"},
+	"pq1":        {Title: "Police Quest: In Pursuit of the Death Angel"},
+	"pq2":        {Title: "Police Quest II: The Vengeance", GameID: "pq2"},
+	"pq3":        {Title: "Police Quest III: The Kindred", GameID: "pq3"},
+	"pq4":        {Title: "Police Quest: Open Season", GameID: "pq4"},
+	"lsl1":       {Title: "Leisure Suit Larry in the Land of the Lounge Lizards"},
+	"lsl2":       {Title: "Leisure Suit Larry 2: Goes Looking for Love", GameID: "lsl2"},
+	"lsl3":       {Title: "Leisure Suit Larry 3: Passionate Patti in Pursuit of the Pulsating Pectorals", GameID: "lsl3"},
+	"lsl5":       {Title: "Leisure Suit Larry 5: Passionate Patti Does a Little Undercover Work", GameID: "lsl5"},
+	"lsl6":       {Title: "Leisure Suit Larry 6: Shape Up or Slip Out!", GameID: "lsl6"},
+	"lsl7":       {Title: "Leisure Suit Larry: Love for Sail!", GameID: "lsl7"},
+	"tlj":        {Title: "The Longest Journey", GameID: "tlj"},
+	"toonstruck": {Title: "Toonstruck", GameID: "toon"},
+}
+
+// ReadAliases loads extra aliases from a JSON file that maps abbreviations to an
+// Alias, for example {"MI2SE": {"Title": "Monkey Island 2 Special Edition"}}.
+func ReadAliases(aliasesFile string) (map[string]Alias, error) {
+	aliasesJSON, err := os.ReadFile(aliasesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := make(map[string]Alias)
+	if err := json.Unmarshal(aliasesJSON, &aliases); err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// MergeAliases returns the built-in aliases with the given extra aliases added on top.
+// Extra aliases replace built-in aliases with the same abbreviation.
+func MergeAliases(extraAliases map[string]Alias) map[string]Alias {
+	aliases := make(map[string]Alias, len(BuiltinAliases)+len(extraAliases))
+	for abbreviation, entry := range BuiltinAliases {
+		aliases[abbreviation] = entry
+	}
+	for abbreviation, entry := range extraAliases {
+		aliases[strings.ToLower(abbreviation)] = entry
+	}
+	return aliases
+}
+
+// expandAliases replaces every abbreviation in a directory name with the title it stands
+// for, and returns the expanded name along with the GameIDs of the abbreviations that
+// were found.
+func expandAliases(directoryName string, aliases map[string]Alias) (string, []string) {
+	words := strings.FieldsFunc(directoryName, func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-' || r == '.'
+	})
+	gameIDs := make([]string, 0)
+	for i, word := range words {
+		entry, ok := aliases[strings.ToLower(word)]
+		if !ok {
+			continue
+		}
+		words[i] = entry.Title
+		if entry.GameID != "" {
+			gameIDs = append(gameIDs, entry.GameID)
+		}
+	}
+	return strings.Join(words, " "), gameIDs
+}
+
+// BareGameID returns a GameID without its engine prefix, so "scumm:monkey2" becomes
+// "monkey2".
+func BareGameID(gameID string) string {
+	if _, bare, found := strings.Cut(gameID, ":"); found {
+		return bare
+	}
+	return gameID
+}
@@ -0,0 +1,16 @@
+package match
+
+// These are the kinds of errors that can be recorded against a directory in error.json.
+// They let the user tell a directory that scummvm didn't recognize apart from one that
+// couldn't even be read.
+const (
+	ErrorKindPermission = "permission"
+	ErrorKindNotFound   = "not-found"
+	ErrorKindIO         = "io"
+	ErrorKindFilesystem = "filesystem"
+	ErrorKindScummvm    = "scummvm"
+	ErrorKindDetection  = "detection"
+	ErrorKindSkipped    = "skipped"
+
+	ErrorKindLowConfidence = "low-confidence"
+)
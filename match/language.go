@@ -0,0 +1,154 @@
+package match
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/kljensen/snowball"
+)
+
+// snowballLanguages maps the scummvm language names to the languages the snowball
+// stemmer supports. Titles in any other language are compared without stemming, since
+// stemming them as English only mangles them.
+var snowballLanguages = map[string]string{
+	"English":   "english",
+	"Spanish":   "spanish",
+	"French":    "french",
+	"Russian":   "russian",
+	"Swedish":   "swedish",
+	"Norwegian": "norwegian",
+	"Hungarian": "hungarian",
+}
+
+// languageStopWords are short, common words that give away the language of a directory
+// name when it doesn't say so itself. When two languages have as many words in a name,
+// the one that comes first wins.
+var languageStopWords = []struct {
+	Language  string
+	StopWords []string
+}{
+	{"English", []string{"the", "of", "and", "in", "to"}},
+	{"German", []string{"der", "die", "das", "und", "des", "im", "von", "dem", "den"}},
+	{"French", []string{"le", "la", "les", "et", "du", "des", "de", "aux"}},
+	{"Spanish", []string{"el", "los", "las", "y", "del", "de"}},
+	{"Italian", []string{"il", "lo", "gli", "della", "di", "e"}},
+	{"Dutch", []string{"het", "een", "van", "en", "de"}},
+}
+
+// languageMarkerAdjustment is added to the similarity of a candidate whose language
+// matches the language marker in the directory name, and taken off one whose language
+// doesn't.
+const languageMarkerAdjustment = 0.15
+
+// directoryLanguageMatcher matches language markers in a directory name, such as
+// "(German)", "[FR]" or "(de)".
+var directoryLanguageMatcher = regexp.MustCompile(`[(\[{]([^)\]}]+)[)\]}]`)
+
+// descriptionLanguage returns the language of a scummvm Description, which scummvm
+// includes as one of the variant tags. English is assumed if it doesn't say.
+func descriptionLanguage(description string) string {
+	if language := ParseDescriptionVariant(description).Language; language != "" {
+		if canonical, ok := canonicalLanguage(language); ok {
+			return canonical
+		}
+	}
+	return "English"
+}
+
+// regionCodes are the language codes that are more often region tags in a directory
+// name, such as "(EU)" for a European release, "(CA)" for Canada, "(PT)" for Portugal
+// and "(AR)" for Argentina, so they aren't taken for language markers.
+var regionCodes = map[string]bool{"eu": true, "ca": true, "pt": true, "ar": true}
+
+// markedDirectoryLanguage returns the language a directory name is explicitly marked
+// with, such as "(German)" or "[FR]", or an empty string if it isn't marked. The language
+// codes that are also region tags only count when the language is spelled out.
+func markedDirectoryLanguage(directoryName string) string {
+	for _, match := range directoryLanguageMatcher.FindAllStringSubmatch(directoryName, -1) {
+		for _, tag := range strings.FieldsFunc(match[1], func(r rune) bool { return r == ' ' || r == ',' || r == '/' || r == '-' }) {
+			if language, ok := canonicalLanguage(tag); ok {
+				return language
+			}
+			if regionCodes[strings.ToLower(tag)] {
+				continue
+			}
+			if language, ok := scummvmLanguages[strings.ToLower(tag)]; ok {
+				return language
+			}
+		}
+	}
+	return ""
+}
+
+// languageMarkerSimilarity adjusts the similarity of a candidate when the directory name
+// is marked with a language, such as "(German)". A candidate in that language is
+// boosted, and one in another language is penalized. Candidates whose Description
+// doesn't say what language they are in are left alone.
+func languageMarkerSimilarity(similarity float64, description string, directoryName string) float64 {
+	markedLanguage := markedDirectoryLanguage(directoryName)
+	candidateLanguage := ParseDescriptionVariant(description).Language
+	if markedLanguage == "" || candidateLanguage == "" {
+		return similarity
+	}
+
+	if strings.EqualFold(candidateLanguage, markedLanguage) {
+		similarity += languageMarkerAdjustment
+	} else {
+		similarity -= languageMarkerAdjustment
+	}
+
+	// Keep it in range
+	if similarity > 1 {
+		similarity = 1
+	}
+	if similarity < 0 {
+		similarity = 0
+	}
+	return similarity
+}
+
+// directoryLanguage guesses the language of a directory name. A language marker in the
+// name wins, otherwise the language whose common words appear most often is used.
+// English is assumed if there is nothing to go on.
+func directoryLanguage(directoryName string) string {
+	if language := markedDirectoryLanguage(directoryName); language != "" {
+		return language
+	}
+
+	// Count the stop words of each language
+	bestLanguage := "English"
+	bestCount := 0
+	words := titleTokens(directoryName)
+	for _, languageStopWord := range languageStopWords {
+		count := 0
+		for _, word := range words {
+			for _, stopWord := range languageStopWord.StopWords {
+				if word == stopWord {
+					count++
+				}
+			}
+		}
+		if count > bestCount {
+			bestLanguage = languageStopWord.Language
+			bestCount = count
+		}
+	}
+	return bestLanguage
+}
+
+// stemTitle stems each word of a title in the given scummvm language. If the snowball
+// stemmer doesn't support the language, then the title is returned in lower case
+// without stemming.
+func stemTitle(title string, language string) string {
+	words := strings.Fields(strings.ToLower(title))
+	snowballLanguage, ok := snowballLanguages[language]
+	if !ok {
+		return strings.Join(words, " ")
+	}
+	for i, word := range words {
+		if stemmed, err := snowball.Stem(word, snowballLanguage, false); err == nil && stemmed != "" {
+			words[i] = stemmed
+		}
+	}
+	return strings.Join(words, " ")
+}
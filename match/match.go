@@ -0,0 +1,310 @@
+// Package match decides which game is in a directory when scummvm found several
+// candidates, by comparing their descriptions and titles with the directory name, and
+// holds ScummGameMatch, the result of a scan that the other packages share.
+package match
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adrg/strutil"
+	"github.com/adrg/strutil/metrics"
+)
+
+// These are the policies for a match that no candidate is similar enough to, which are
+// chosen with --on-low-confidence.
+const (
+	// LowConfidenceSkip leaves the directory out, recording it in error.json.
+	LowConfidenceSkip = "skip"
+
+	// LowConfidencePrompt asks the user to choose, if there is a terminal to ask on.
+	// Otherwise it behaves like LowConfidenceBestGuess.
+	LowConfidencePrompt = "prompt"
+
+	// LowConfidenceBestGuess uses the closest candidate anyway.
+	LowConfidenceBestGuess = "best-guess"
+
+	// LowConfidenceError records the directory in error.json and fails the run.
+	LowConfidenceError = "error"
+)
+
+// Options controls how the candidates scummvm found are compared with the
+// directory name.
+type Options struct {
+	// Metric is the string metric used to compare the Description with the directory name.
+	Metric strutil.StringMetric
+
+	// Aliases maps lower case abbreviations used in directory names to what they stand for.
+	Aliases map[string]Alias
+
+	// PreferredLanguages are scummvm language names, most preferred first. They decide
+	// between candidates that only differ by language.
+	PreferredLanguages []string
+
+	// PreferredPlatforms are scummvm platform names, most preferred first. They decide
+	// between candidates that are equally similar to the directory name.
+	PreferredPlatforms []string
+
+	// GameTitles maps GameIDs to the full titles of the games. The directory name is
+	// compared with the full title as well as the Description.
+	GameTitles map[string]string
+
+	// Ranking, when set, picks the candidate with the highest weighted score instead of
+	// the most similar one.
+	Ranking *RankingWeights
+}
+
+// stringMetrics are the string metrics that can be chosen with --metric.
+var stringMetrics = map[string]func() strutil.StringMetric{
+	"levenshtein": func() strutil.StringMetric {
+		lev := metrics.NewLevenshtein()
+		lev.CaseSensitive = false
+		return lev
+	},
+	"jaro": func() strutil.StringMetric {
+		jaro := metrics.NewJaro()
+		jaro.CaseSensitive = false
+		return jaro
+	},
+	"jaro-winkler": func() strutil.StringMetric {
+		jaroWinkler := metrics.NewJaroWinkler()
+		jaroWinkler.CaseSensitive = false
+		return jaroWinkler
+	},
+	"sorensen-dice": func() strutil.StringMetric {
+		sorensenDice := metrics.NewSorensenDice()
+		sorensenDice.CaseSensitive = false
+		return sorensenDice
+	},
+	"jaccard": func() strutil.StringMetric {
+		jaccard := metrics.NewJaccard()
+		jaccard.CaseSensitive = false
+		return jaccard
+	},
+	"overlap": func() strutil.StringMetric {
+		overlap := metrics.NewOverlapCoefficient()
+		overlap.CaseSensitive = false
+		return overlap
+	},
+	"smith-waterman-gotoh": func() strutil.StringMetric {
+		smithWatermanGotoh := metrics.NewSmithWatermanGotoh()
+		smithWatermanGotoh.CaseSensitive = false
+		return smithWatermanGotoh
+	},
+	"hamming": func() strutil.StringMetric {
+		hamming := metrics.NewHamming()
+		hamming.CaseSensitive = false
+		return hamming
+	},
+}
+
+// StringMetricNames returns the names of the string metrics that can be chosen with
+// --metric, in alphabetical order.
+func StringMetricNames() []string {
+	names := make([]string, 0, len(stringMetrics))
+	for name := range stringMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewStringMetric creates the string metric with the given name. levenshteinCosts is
+// only used by the Levenshtein metric, and holds the insert, delete and replace costs
+// separated by commas.
+func NewStringMetric(name string, levenshteinCosts string) (strutil.StringMetric, error) {
+	newMetric, ok := stringMetrics[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q, must be one of %s", name, strings.Join(StringMetricNames(), ", "))
+	}
+	metric := newMetric()
+
+	// Apply the Levenshtein costs
+	if lev, ok := metric.(*metrics.Levenshtein); ok {
+		costs := strings.Split(levenshteinCosts, ",")
+		if len(costs) != 3 {
+			return nil, fmt.Errorf("the Levenshtein costs must be three numbers: insert,delete,replace")
+		}
+		for i, cost := range []*int{&lev.InsertCost, &lev.DeleteCost, &lev.ReplaceCost} {
+			value, err := strconv.Atoi(strings.TrimSpace(costs[i]))
+			if err != nil || value < 0 {
+				return nil, fmt.Errorf("the Levenshtein cost %q is not a positive number", costs[i])
+			}
+			*cost = value
+		}
+	}
+
+	return metric, nil
+}
+
+// titleSimilarity compares a Description with a directory name and returns how similar
+// they are, from 0 to 1. Both are normalized and compared twice: once as a whole after
+// stemming each in its own language, and once word by word regardless of word order and
+// articles. The better of the two comparisons is used.
+func titleSimilarity(description string, directoryName string, options Options) float64 {
+	normalizedDescription := normalizeTitle(description)
+	normalizedDirectoryName := normalizeTitle(directoryName)
+
+	// Compare the stemmed titles as a whole
+	stemmedDescription := stemTitle(normalizedDescription, descriptionLanguage(description))
+	stemmedDirectoryName := stemTitle(normalizedDirectoryName, directoryLanguage(directoryName))
+	similarity := strutil.Similarity(stemmedDescription, stemmedDirectoryName, options.Metric)
+
+	// Compare the titles word by word, ignoring word order and articles
+	sortedDescription := tokenSortTitle(normalizedDescription)
+	sortedDirectoryName := tokenSortTitle(normalizedDirectoryName)
+	if sortedDescription != "" && sortedDirectoryName != "" {
+		if tokenSimilarity := strutil.Similarity(sortedDescription, sortedDirectoryName, options.Metric); tokenSimilarity > similarity {
+			similarity = tokenSimilarity
+		}
+	}
+
+	// Some metrics can go below zero when the costs are high, so keep it in range
+	if similarity < 0 {
+		similarity = 0
+	}
+
+	return similarity
+}
+
+// Closest takes in the candidates scummvm found for a directory and
+// returns the index of the one whose Description is most similar to the directory name,
+// along with that similarity (between 0 and 1) and the reason it was chosen. The full
+// title of each candidate's game is compared too, and the better of the two is used.
+// The Similarity of every candidate is filled in along the way. Abbreviations in the
+// directory name are looked up in the aliases first: if one of them names the GameID of
+// a candidate, then that candidate counts as an exact match. If the directory name is
+// marked with a language, candidates in that language are favoured over the others.
+// When options.Ranking is set, the candidate with the highest weighted score is
+// returned instead.
+func Closest(candidates []ScummGameMatch, options Options) (int, float64, Reason) {
+	// Interate through each candidate and compare its Description with the Directory to
+	// find the closest match
+	closestMatchIndex := 0
+	closestMatchDistance := 0.0
+	similarities := make([]float64, len(candidates))
+	aliasHits := make([]bool, len(candidates))
+	languageMarkerApplied := false
+	for i := 0; i < len(candidates); i++ {
+		// Calculate the similarity between the Description and Directory, after expanding
+		// any abbreviations in the Directory
+		expandedDirectoryName, aliasGameIDs := expandAliases(filepath.Base(candidates[i].Directory), options.Aliases)
+		similarity := titleSimilarity(candidates[i].Description, expandedDirectoryName, options)
+
+		// The Description of a variant doesn't always share any words with the directory
+		// name, so compare the full title of the game too
+		if title, ok := GameTitle(options.GameTitles, candidates[i].GameID); ok {
+			if titleSimilarity := titleSimilarity(title, expandedDirectoryName, options); titleSimilarity > similarity {
+				similarity = titleSimilarity
+			}
+		}
+		for _, aliasGameID := range aliasGameIDs {
+			if BareGameID(candidates[i].GameID) == aliasGameID {
+				similarity = 1
+				aliasHits[i] = true
+			}
+		}
+
+		// Favour the candidates in the language the directory name is marked with
+		if markedSimilarity := languageMarkerSimilarity(similarity, candidates[i].Description, filepath.Base(candidates[i].Directory)); markedSimilarity != similarity {
+			similarity = markedSimilarity
+			languageMarkerApplied = true
+		}
+		similarities[i] = similarity
+		candidates[i].Similarity = similarity
+
+		// Check if the similarity is greater than the closestMatchDistance
+		if similarity > closestMatchDistance {
+			// Update the closestMatchIndex and closestMatchDistance
+			closestMatchIndex = i
+			closestMatchDistance = similarity
+		}
+	}
+
+	// Start explaining the choice
+	reason := Reason{Rule: ReasonSimilarity}
+	if languageMarkerApplied {
+		reason.Preferences = append(reason.Preferences, PreferenceLanguageMarker)
+	}
+
+	// Rank the candidates by their weighted score if we were given weights, which take
+	// the place of the preferences below
+	if options.Ranking != nil {
+		closestMatchIndex = rankScummGameCandidates(candidates, *options.Ranking)
+		reason.Rule = ReasonRanking
+		return closestMatchIndex, similarities[closestMatchIndex], explainScummGameMatch(candidates, closestMatchIndex, aliasHits, reason)
+	}
+
+	// If other candidates are just as close, then use the one for the preferred platform
+	if len(options.PreferredPlatforms) > 0 {
+		preferredIndex := PreferPlatform(candidates, similarities, closestMatchIndex, options.PreferredPlatforms)
+		if preferredIndex != closestMatchIndex {
+			reason.Preferences = append(reason.Preferences, PreferencePlatform)
+		}
+		closestMatchIndex = preferredIndex
+	}
+
+	// If other candidates only differ from the closest match by language, then use the
+	// one in the preferred language, unless the directory name says which language it is
+	if len(options.PreferredLanguages) > 0 && markedDirectoryLanguage(filepath.Base(candidates[closestMatchIndex].Directory)) == "" {
+		preferredIndex := PreferLanguage(candidates, closestMatchIndex, options.PreferredLanguages)
+		if preferredIndex != closestMatchIndex {
+			reason.Preferences = append(reason.Preferences, PreferenceLanguage)
+		}
+		closestMatchIndex = preferredIndex
+	}
+
+	// Return the closest match
+	return closestMatchIndex, similarities[closestMatchIndex], explainScummGameMatch(candidates, closestMatchIndex, aliasHits, reason)
+}
+
+// explainScummGameMatch finishes explaining why the candidate at chosenIndex was chosen.
+func explainScummGameMatch(candidates []ScummGameMatch, chosenIndex int, aliasHits []bool, reason Reason) Reason {
+	switch {
+	case len(candidates) == 1:
+		return Reason{Rule: ReasonSingleMatch}
+	case aliasHits[chosenIndex] && reason.Rule != ReasonRanking:
+		reason.Rule = ReasonAlias
+	}
+	reason.ExplainRunnerUp(Candidates(candidates), chosenIndex)
+	return reason
+}
+
+// Confidence works out how sure scummer is that a match is right, from 0 to 1. A
+// GameID that scummvm was sure about, or that the user picked (now or in an answers
+// file), has a confidence of 1. Otherwise scummer had to pick between several
+// candidates, and the confidence is how similar the chosen candidate is to the
+// directory name.
+func Confidence(candidateCount int, similarity float64, chosenBy string) float64 {
+	if candidateCount <= 1 || chosenBy != "" {
+		return 1
+	}
+	return similarity
+}
+
+// Chosen turns the candidate at chosenIndex into the match for its directory, with its
+// full title, how confident scummer is in it and why it was chosen. chosenBy is "user"
+// or "answers" when the choice was made for scummer, and empty otherwise. Every
+// candidate is kept when scummvm wasn't sure which game it was.
+func Chosen(candidates []ScummGameMatch, chosenIndex int, reason Reason, chosenBy string, options Options) ScummGameMatch {
+	scummGameMatch := ScummGameMatch{GameID: candidates[chosenIndex].GameID, Description: candidates[chosenIndex].Description, Directory: candidates[chosenIndex].Directory, ChosenBy: chosenBy}
+	scummGameMatch.Title, _ = GameTitle(options.GameTitles, scummGameMatch.GameID)
+	scummGameMatch.Similarity = candidates[chosenIndex].Similarity
+	scummGameMatch.Confidence = Confidence(len(candidates), scummGameMatch.Similarity, chosenBy)
+
+	// Explain the choice, which was out of scummer's hands if it was made for it
+	if chosenBy != "" {
+		reason = Reason{Rule: chosenBy}
+		reason.ExplainRunnerUp(Candidates(candidates), chosenIndex)
+	}
+	scummGameMatch.Reason = &reason
+	if len(candidates) > 1 {
+		scummGameMatch.Candidates = Candidates(candidates)
+		scummGameMatch.Score = candidates[chosenIndex].Score
+		scummGameMatch.Ranking = options.Ranking
+	}
+	return scummGameMatch
+}
@@ -0,0 +1,174 @@
+package match
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// releaseTagMatcher matches parenthetical and bracketed release tags such as
+	// "(CD DOS VGA)", "[GOG]" or "{Floppy}".
+	releaseTagMatcher = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]|\{[^}]*\}`)
+
+	// versionMatcher matches version numbers such as "v1.2", "1.0.3" or "v2".
+	versionMatcher = regexp.MustCompile(`(?i)\bv\d+(\.\d+)*[a-z]?\b|\b\d+(\.\d+)+[a-z]?\b`)
+
+	// RegionCodeMatcher matches region and video standard codes that are sometimes
+	// left outside of brackets, such as "Loom USA" or "Zak PAL".
+	RegionCodeMatcher = regexp.MustCompile(`\b(USA|US|EUR|EU|UK|JPN|JP|PAL|NTSC)\b`)
+
+	// separatorMatcher matches the characters people use instead of spaces in
+	// directory names.
+	separatorMatcher = regexp.MustCompile(`[_.\-]+`)
+)
+
+// stripReleaseTags removes the release tags, version numbers and region codes from a
+// Description or directory name, so that only the title itself is compared. These tags
+// otherwise dominate the distance between two strings. If nothing is left once the
+// tags are gone, then the original string is returned.
+func stripReleaseTags(title string) string {
+	strippedTitle := releaseTagMatcher.ReplaceAllString(title, " ")
+	strippedTitle = versionMatcher.ReplaceAllString(strippedTitle, " ")
+	strippedTitle = RegionCodeMatcher.ReplaceAllString(strippedTitle, " ")
+	strippedTitle = separatorMatcher.ReplaceAllString(strippedTitle, " ")
+	strippedTitle = strings.Join(strings.Fields(strippedTitle), " ")
+
+	if strippedTitle == "" {
+		return title
+	}
+	return strippedTitle
+}
+
+// romanNumeralMatcher matches the roman numerals from I to XXXIX, which covers every
+// sequel number a game is likely to have without mistaking words like "CD" or "MIX"
+// for numbers.
+var romanNumeralMatcher = regexp.MustCompile(`^X{0,3}(IX|IV|V?I{0,3})$`)
+
+// romanNumeralValues are the values of the letters used in romanNumeralMatcher.
+var romanNumeralValues = map[byte]int{'I': 1, 'V': 5, 'X': 10}
+
+// numberWords maps spelled out numbers to their digits.
+var numberWords = map[string]string{
+	"one": "1", "two": "2", "three": "3", "four": "4", "five": "5",
+	"six": "6", "seven": "7", "eight": "8", "nine": "9", "ten": "10",
+	"eleven": "11", "twelve": "12", "thirteen": "13", "fourteen": "14", "fifteen": "15",
+	"sixteen": "16", "seventeen": "17", "eighteen": "18", "nineteen": "19", "twenty": "20",
+}
+
+// romanNumeralToNumber converts a roman numeral matched by romanNumeralMatcher to a
+// number.
+func romanNumeralToNumber(numeral string) int {
+	number := 0
+	for i := 0; i < len(numeral); i++ {
+		value := romanNumeralValues[numeral[i]]
+		if i+1 < len(numeral) && value < romanNumeralValues[numeral[i+1]] {
+			number -= value
+		} else {
+			number += value
+		}
+	}
+	return number
+}
+
+// normalizeNumbers replaces roman numerals and spelled out numbers with digits, so that
+// "Monkey Island II", "Monkey Island Two" and "Monkey Island 2" are all compared as the
+// same title. Single letter numerals are only converted at the end of a title, or of the
+// part before a subtitle, so that words such as the "I" in "I Have No Mouth" are left
+// alone. Punctuation around a number, such as the colon in "Quest for Glory II: Trial by
+// Fire", is kept around its digits.
+func normalizeNumbers(title string) string {
+	words := strings.Fields(title)
+	for i, word := range words {
+		// Set aside the punctuation around the word
+		start := strings.IndexFunc(word, isLetterOrDigit)
+		if start < 0 {
+			continue
+		}
+		end := strings.LastIndexFunc(word, isLetterOrDigit) + 1
+		prefix, bare, suffix := word[:start], word[start:end], word[end:]
+
+		// Spelled out numbers
+		if digits, ok := numberWords[strings.ToLower(bare)]; ok {
+			words[i] = prefix + digits + suffix
+			continue
+		}
+
+		// Roman numerals
+		numeral := strings.ToUpper(bare)
+		if !romanNumeralMatcher.MatchString(numeral) {
+			continue
+		}
+		endsTitle := i == len(words)-1 || strings.HasPrefix(suffix, ":")
+		if len(numeral) == 1 && (!endsTitle || i == 0) {
+			continue
+		}
+		words[i] = prefix + strconv.Itoa(romanNumeralToNumber(numeral)) + suffix
+	}
+	return strings.Join(words, " ")
+}
+
+// isLetterOrDigit returns whether a rune is part of a word rather than punctuation.
+func isLetterOrDigit(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// normalizeTitle prepares a Description or directory name to be compared with another.
+func normalizeTitle(title string) string {
+	return normalizeNumbers(stripReleaseTags(FoldDiacritics(title)))
+}
+
+// articles are dropped from titles when comparing them token by token, so that "The
+// Dig", "Dig, The" and "Dig" are all compared as the same title.
+var articles = map[string]bool{"the": true, "a": true, "an": true}
+
+// titleTokens splits a normalized title into lower case words, dropping punctuation
+// and articles.
+func titleTokens(title string) []string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.Trim(word, "'")
+		if word == "" || articles[word] {
+			continue
+		}
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// tokenSortTitle returns the words of a normalized title in alphabetical order without
+// any articles, so that titles can be compared regardless of word order. "Secret of
+// Monkey Island, The" and "The Secret of Monkey Island" both become "island monkey of
+// secret".
+func tokenSortTitle(title string) string {
+	tokens := titleTokens(title)
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+// foldedLetters are letters that don't decompose into a base letter and a diacritic,
+// but are still commonly written without it.
+var foldedLetters = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE",
+	"ø", "o", "Ø", "O", "ł", "l", "Ł", "L", "đ", "d", "Đ", "D", "þ", "th", "Þ", "TH",
+)
+
+// FoldDiacritics brings a title into a single Unicode normal form and removes any
+// diacritics, so that "Flüch" and "Fluch" compare as equal, and so that the decomposed
+// (NFD) file names macOS hands out compare equal to the composed ones scummvm prints.
+func FoldDiacritics(title string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), title)
+	if err != nil {
+		folded = norm.NFC.String(title)
+	}
+	return foldedLetters.Replace(folded)
+}
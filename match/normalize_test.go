@@ -0,0 +1,25 @@
+package match
+
+import "testing"
+
+func TestNormalizeNumbers(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Monkey Island II", "Monkey Island 2"},
+		{"Monkey Island Two", "Monkey Island 2"},
+		{"I Have No Mouth", "I Have No Mouth"},
+		{"Quest for Glory II: Trial by Fire", "Quest for Glory 2: Trial by Fire"},
+		{"Space Quest III:", "Space Quest 3:"},
+		{"Space Quest III, The Pirates of Pestulon", "Space Quest 3, The Pirates of Pestulon"},
+		{"Quest for Glory I: So You Want to Be a Hero", "Quest for Glory 1: So You Want to Be a Hero"},
+		{"King's Quest (V)", "King's Quest (5)"},
+		{"Mixed-Up Mother Goose", "Mixed-Up Mother Goose"},
+	}
+	for _, test := range tests {
+		if got := normalizeNumbers(test.title); got != test.want {
+			t.Errorf("normalizeNumbers(%q) = %q, want %q", test.title, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package match
+
+import (
+	"strings"
+)
+
+// RankingWeights are the weights used to combine everything known about a candidate
+// into a single score. They are set in the "ranking" section of the config file and
+// saved with every ranked match, so that the decision can be reproduced.
+type RankingWeights struct {
+	// Similarity is the weight of how similar the candidate is to the directory name.
+	Similarity float64 `yaml:"similarity" json:"Similarity"`
+
+	// Language is the weight of the candidate's language being preferred.
+	Language float64 `yaml:"language" json:"Language"`
+
+	// Platform is the weight of the candidate's platform being preferred.
+	Platform float64 `yaml:"platform" json:"Platform"`
+
+	// Engine is the weight of the candidate's engine being preferred.
+	Engine float64 `yaml:"engine" json:"Engine"`
+
+	// Variant is the weight of the candidate's variant tags (such as CD or VGA) being
+	// preferred.
+	Variant float64 `yaml:"variant" json:"Variant"`
+
+	// Languages and Platforms are the preferred languages and platforms, most preferred
+	// first. --prefer-language and --prefer-platform take precedence over them.
+	Languages []string `yaml:"languages" json:"Languages,omitempty"`
+	Platforms []string `yaml:"platforms" json:"Platforms,omitempty"`
+
+	// Engines are the preferred engines, such as scumm or sci, most preferred first.
+	Engines []string `yaml:"engines" json:"Engines,omitempty"`
+
+	// Variants are the preferred variant tags, such as CD, Talkie or VGA, most preferred
+	// first.
+	Variants []string `yaml:"variants" json:"Variants,omitempty"`
+}
+
+// preferenceScore turns the position of value in a list of preferences into a score
+// from 0 to 1. The most preferred value scores 1, and a value that isn't in the list
+// scores 0.
+func preferenceScore(preferences []string, value string) float64 {
+	rank := preferenceRank(preferences, value)
+	if rank >= len(preferences) {
+		return 0
+	}
+	return 1 - float64(rank)/float64(len(preferences))
+}
+
+// CandidateEngine returns the engine part of a GameID, such as "scumm" for "scumm:loom".
+func CandidateEngine(gameID string) string {
+	engine, _, found := strings.Cut(gameID, ":")
+	if !found {
+		return ""
+	}
+	return engine
+}
+
+// candidateScore combines the similarity of a candidate and how well it fits the
+// preferences into a single score from 0 to 1, using the weights.
+func candidateScore(candidate ScummGameMatch, weights RankingWeights) float64 {
+	variant := ParseDescriptionVariant(candidate.Description)
+
+	// The variant scores as well as its most preferred tag
+	variantScore := 0.0
+	for _, tag := range variant.Tags {
+		if tagScore := preferenceScore(weights.Variants, tag); tagScore > variantScore {
+			variantScore = tagScore
+		}
+	}
+
+	score := weights.Similarity*candidate.Similarity +
+		weights.Language*preferenceScore(weights.Languages, variant.Language) +
+		weights.Platform*preferenceScore(weights.Platforms, variant.Platform) +
+		weights.Engine*preferenceScore(weights.Engines, CandidateEngine(candidate.GameID)) +
+		weights.Variant*variantScore
+
+	// Scale the score back to between 0 and 1
+	totalWeight := weights.Similarity + weights.Language + weights.Platform + weights.Engine + weights.Variant
+	if totalWeight <= 0 {
+		return 0
+	}
+	return score / totalWeight
+}
+
+// rankScummGameCandidates fills in the Score of every candidate and returns the index of
+// the one with the highest score. The Similarity of the candidates must already be
+// filled in.
+func rankScummGameCandidates(candidates []ScummGameMatch, weights RankingWeights) int {
+	bestIndex := 0
+	for i := range candidates {
+		candidates[i].Score = candidateScore(candidates[i], weights)
+		if candidates[i].Score > candidates[bestIndex].Score {
+			bestIndex = i
+		}
+	}
+	return bestIndex
+}
@@ -0,0 +1,81 @@
+package match
+
+// These are the rules that can decide which candidate is used for a directory.
+const (
+	// ReasonSingleMatch means scummvm only found one game.
+	ReasonSingleMatch = "single-match"
+
+	// ReasonAlias means an abbreviation in the directory name named the GameID.
+	ReasonAlias = "alias"
+
+	// ReasonSimilarity means the candidate was the most similar to the directory name.
+	ReasonSimilarity = "similarity"
+
+	// ReasonRanking means the candidate had the highest weighted score.
+	ReasonRanking = "ranking"
+
+	// ReasonAnswers means the GameID came from the answers file.
+	ReasonAnswers = "answers"
+
+	// ReasonUser means the user picked the GameID.
+	ReasonUser = "user"
+)
+
+// These are the preferences that can change which candidate is used.
+const (
+	// PreferenceLanguageMarker means the language marker in the directory name favoured
+	// the candidates in that language.
+	PreferenceLanguageMarker = "language-marker"
+
+	// PreferencePlatform means --prefer-platform picked between equally close candidates.
+	PreferencePlatform = "platform"
+
+	// PreferenceLanguage means --prefer-language picked between candidates that only
+	// differ by language.
+	PreferenceLanguage = "language"
+)
+
+// Reason explains why a GameID was chosen for a directory.
+type Reason struct {
+	// Rule is the rule that decided the match, such as "similarity" or "alias".
+	Rule string `json:"Rule"`
+
+	// Preferences are the preferences that changed the outcome, in the order they were
+	// applied.
+	Preferences []string `json:"Preferences,omitempty"`
+
+	// RunnerUp is the GameID of the next best candidate, and RunnerUpSimilarity its
+	// similarity to the directory name. Margin is how much better the chosen candidate
+	// did, either by similarity or by score when the candidates were ranked.
+	RunnerUp           string  `json:"RunnerUp,omitempty"`
+	RunnerUpSimilarity float64 `json:"RunnerUpSimilarity,omitempty"`
+	Margin             float64 `json:"Margin,omitempty"`
+}
+
+// ExplainRunnerUp fills in the next best candidate after the chosen one. Candidates are
+// compared by score when they were ranked, and by similarity otherwise.
+func (reason *Reason) ExplainRunnerUp(candidates []ScummGameCandidate, chosenIndex int) {
+	value := func(candidate ScummGameCandidate) float64 {
+		if reason.Rule == ReasonRanking {
+			return candidate.Score
+		}
+		return candidate.Similarity
+	}
+
+	runnerUpIndex := -1
+	for i, candidate := range candidates {
+		if i == chosenIndex {
+			continue
+		}
+		if runnerUpIndex < 0 || value(candidate) > value(candidates[runnerUpIndex]) {
+			runnerUpIndex = i
+		}
+	}
+	if runnerUpIndex < 0 {
+		return
+	}
+
+	reason.RunnerUp = candidates[runnerUpIndex].GameID
+	reason.RunnerUpSimilarity = candidates[runnerUpIndex].Similarity
+	reason.Margin = value(candidates[chosenIndex]) - value(candidates[runnerUpIndex])
+}
@@ -0,0 +1,154 @@
+package match
+
+// ScummGameMatch is a game scummvm found in a directory, along with how and why it was
+// chosen.
+type ScummGameMatch struct {
+	GameID      string `json:"GameID"`
+	Description string `json:"Description"`
+	Directory   string `json:"Directory"`
+
+	// Title is the full title of the game, when it is known.
+	Title string `json:"Title,omitempty"`
+
+	// ScummvmVersion is the version of the scummvm that scanned the directory.
+	ScummvmVersion string `json:"ScummvmVersion,omitempty"`
+
+	// RegisteredTarget is the scummvm.ini target that already uses this directory, if any.
+	RegisteredTarget string `json:"RegisteredTarget,omitempty"`
+
+	// Fingerprint identifies the files that were in the directory when it was scanned,
+	// so that "scummer verify --quick" can tell whether it has changed without running
+	// scummvm again.
+	Fingerprint string `json:"Fingerprint,omitempty"`
+
+	// Archive is the archive the game was detected in, such as a .zip, when it was left
+	// packed. Directory is then where it would be unpacked to, next to the archive.
+	Archive string `json:"Archive,omitempty"`
+
+	// DiscImage is the CD image the game was detected in, when its directory has nothing
+	// else in it, and DiscImageFiles are the files of the image that have to be kept to
+	// play it, with its audio tracks as trackNN.wav.
+	DiscImage      string   `json:"DiscImage,omitempty"`
+	DiscImageFiles []string `json:"DiscImageFiles,omitempty"`
+
+	// Warnings are the warnings scummvm printed when it detected the game, and Incomplete
+	// is set when they say files of the game are missing, such as its speech files or a
+	// data track, which means the directory holds an incomplete dump of it.
+	Warnings   []string `json:"Warnings,omitempty"`
+	Incomplete bool     `json:"Incomplete,omitempty"`
+
+	// UnknownVariants is what scummvm asked to be reported to the ScummVM team when it
+	// took the game for a variant it doesn't know. See "scummer scan --unknown-variants".
+	UnknownVariants []UnknownVariant `json:"UnknownVariants,omitempty"`
+
+	// ErrorKind says what went wrong for entries in error.json.
+	ErrorKind string `json:"ErrorKind,omitempty"`
+
+	// NestedIn are the directories the files of the game are in, relative to Directory,
+	// for entries in error.json whose directory has nothing but them in it, which is
+	// most likely why scummvm found no game there. See "scummer scan --nested".
+	NestedIn []string `json:"NestedIn,omitempty"`
+
+	// ChosenBy is "user" when the GameID was picked at the interactive prompt or on the
+	// review screen, and "answers" when it came from the answers file.
+	ChosenBy string `json:"ChosenBy,omitempty"`
+
+	// Similarity is how close the Description is to the directory name, from 0 to 1.
+	Similarity float64 `json:"Similarity,omitempty"`
+
+	// Reason explains why the GameID was chosen.
+	Reason *Reason `json:"Reason,omitempty"`
+
+	// Score is the weighted score of the match, when the candidates were ranked with the
+	// weights from the config file. Ranking holds those weights.
+	Score   float64         `json:"Score,omitempty"`
+	Ranking *RankingWeights `json:"Ranking,omitempty"`
+
+	// Confidence is how sure scummer is that the GameID is right, from 0 to 1. See
+	// Confidence for how it is worked out.
+	Confidence float64 `json:"Confidence"`
+
+	// SuspectedGameID and SuspectedTitle are the closest known game to the directory name,
+	// suggested for entries in error.json when scummvm couldn't detect anything. They are
+	// never written to a .scummvm file.
+	SuspectedGameID     string  `json:"SuspectedGameID,omitempty"`
+	SuspectedTitle      string  `json:"SuspectedTitle,omitempty"`
+	SuspectedSimilarity float64 `json:"SuspectedSimilarity,omitempty"`
+
+	// GamesInside are the games scummvm found further down in a directory it found no
+	// game in, such as a collection copied from a compilation CD. See "scummer scan
+	// --split".
+	GamesInside []GameInside `json:"GamesInside,omitempty"`
+
+	// QuarantinedFrom is where a directory scummvm couldn't identify was in the library
+	// before --quarantine moved it out of the way, to Directory.
+	QuarantinedFrom string `json:"QuarantinedFrom,omitempty"`
+
+	// DuplicateRole is set when the same game was found in more than one directory. It
+	// is "canonical" for the copy to keep, and "duplicate" or "variant" for the others,
+	// whose CanonicalDirectory is the directory of the copy to keep.
+	DuplicateRole      string `json:"DuplicateRole,omitempty"`
+	CanonicalDirectory string `json:"CanonicalDirectory,omitempty"`
+
+	// DiscGroup and DiscNumber are set when the game is on more than one disc, each in a
+	// directory of its own. The first disc lists the directories of all the Discs, and
+	// the others point at the FirstDisc. DiscsGrouped is set when only the first disc
+	// is written out.
+	DiscGroup    string   `json:"DiscGroup,omitempty"`
+	DiscNumber   int      `json:"DiscNumber,omitempty"`
+	Discs        []string `json:"Discs,omitempty"`
+	FirstDisc    string   `json:"FirstDisc,omitempty"`
+	DiscsGrouped bool     `json:"DiscsGrouped,omitempty"`
+
+	// MarkerFiles are where the .scummvm files of the game go, and MarkerFormat is how
+	// the GameID is written in them, unless MarkerTemplate gives their contents instead.
+	// RenameTo is set when the directory has to be renamed before they are written.
+	MarkerFiles    []string `json:"MarkerFiles,omitempty"`
+	MarkerFormat   string   `json:"MarkerFormat,omitempty"`
+	MarkerTemplate string   `json:"MarkerTemplate,omitempty"`
+	RenameTo       string   `json:"RenameTo,omitempty"`
+
+	// Candidates are all the games scummvm found when it wasn't sure which one it was.
+	Candidates []ScummGameCandidate `json:"Candidates,omitempty"`
+}
+
+// ScummGameCandidate is one of the games scummvm found in a directory when it wasn't
+// sure which one it was.
+type ScummGameCandidate struct {
+	GameID      string  `json:"GameID"`
+	Description string  `json:"Description"`
+	Similarity  float64 `json:"Similarity"`
+	Score       float64 `json:"Score,omitempty"`
+}
+
+// GameInside is a game scummvm found further down in a directory it found no game in,
+// with Directory relative to that directory.
+type GameInside struct {
+	Directory   string `json:"Directory"`
+	GameID      string `json:"GameID"`
+	Description string `json:"Description"`
+	Title       string `json:"Title,omitempty"`
+}
+
+// UnknownVariant is the data scummvm printed about a game variant it doesn't know, for
+// one of the engines whose games it resembles.
+type UnknownVariant struct {
+	// Engine is the engine scummvm matched the files against, and MatchedGameIDs are the
+	// games of that engine they resemble, when scummvm says so.
+	Engine         string   `json:"Engine,omitempty"`
+	MatchedGameIDs []string `json:"MatchedGameIDs,omitempty"`
+
+	// Files are the lines with the names, MD5 checksums and sizes of the files, as
+	// scummvm printed them, to be pasted into the report as they are.
+	Files []string `json:"Files,omitempty"`
+}
+
+// Candidates turns the matches parsed from the scummvm output into the
+// candidates that are saved with the chosen match.
+func Candidates(scummGameMatches []ScummGameMatch) []ScummGameCandidate {
+	candidates := make([]ScummGameCandidate, 0, len(scummGameMatches))
+	for _, scummGameMatch := range scummGameMatches {
+		candidates = append(candidates, ScummGameCandidate{GameID: scummGameMatch.GameID, Description: scummGameMatch.Description, Similarity: scummGameMatch.Similarity, Score: scummGameMatch.Score})
+	}
+	return candidates
+}
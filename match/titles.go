@@ -0,0 +1,50 @@
+package match
+
+import "sort"
+
+// GameTitle returns the full title of a game. Older scummvm versions print GameIDs
+// without the engine prefix, so the title is also looked up by the bare GameID.
+func GameTitle(gameTitles map[string]string, gameID string) (string, bool) {
+	if title, ok := gameTitles[gameID]; ok {
+		return title, true
+	}
+	for listedGameID, title := range gameTitles {
+		if BareGameID(listedGameID) == BareGameID(gameID) {
+			return title, true
+		}
+	}
+	return "", false
+}
+
+// SuspectedGame compares a directory name with the full title of every known game,
+// for directories scummvm couldn't detect anything in. It returns the GameID and title
+// of the closest game and how similar it is, from 0 to 1. Abbreviations in the directory
+// name are expanded first, and one that names a GameID counts as an exact match.
+func SuspectedGame(directoryName string, options Options) (string, string, float64) {
+	expandedDirectoryName, aliasGameIDs := expandAliases(directoryName, options.Aliases)
+
+	// Go through the GameIDs in order, so that the same game is suggested every time when
+	// several share a title
+	gameIDs := make([]string, 0, len(options.GameTitles))
+	for gameID := range options.GameTitles {
+		gameIDs = append(gameIDs, gameID)
+	}
+	sort.Strings(gameIDs)
+
+	suspectedGameID := ""
+	closestSimilarity := 0.0
+	for _, gameID := range gameIDs {
+		similarity := titleSimilarity(options.GameTitles[gameID], expandedDirectoryName, options)
+		for _, aliasGameID := range aliasGameIDs {
+			if BareGameID(gameID) == aliasGameID {
+				similarity = 1
+			}
+		}
+		if similarity > closestSimilarity {
+			suspectedGameID = gameID
+			closestSimilarity = similarity
+		}
+	}
+
+	return suspectedGameID, options.GameTitles[suspectedGameID], closestSimilarity
+}
@@ -0,0 +1,234 @@
+package match
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// similarityEpsilon is how close two similarities have to be to count as equal.
+const similarityEpsilon = 1e-9
+
+// scummvmLanguages are the language names scummvm uses in the Description of a game,
+// keyed by the language codes that can be given with --prefer-language.
+var scummvmLanguages = map[string]string{
+	"en": "English",
+	"de": "German",
+	"fr": "French",
+	"it": "Italian",
+	"es": "Spanish",
+	"pt": "Portuguese",
+	"nl": "Dutch",
+	"sv": "Swedish",
+	"se": "Swedish",
+	"da": "Danish",
+	"no": "Norwegian",
+	"nb": "Norwegian",
+	"fi": "Finnish",
+	"pl": "Polish",
+	"cs": "Czech",
+	"cz": "Czech",
+	"hu": "Hungarian",
+	"ru": "Russian",
+	"el": "Greek",
+	"gr": "Greek",
+	"tr": "Turkish",
+	"ca": "Catalan",
+	"eu": "Basque",
+	"he": "Hebrew",
+	"ar": "Arabic",
+	"ja": "Japanese",
+	"jp": "Japanese",
+	"ko": "Korean",
+	"zh": "Chinese",
+}
+
+// scummvmPlatforms are the platform names scummvm uses in the Description of a game.
+var scummvmPlatforms = []string{
+	"DOS", "Windows", "Macintosh", "Amiga", "Atari ST", "FM-TOWNS", "PC-98", "PC-Engine",
+	"Sega CD", "NES", "3DO", "Apple II", "Apple IIgs", "C64", "Acorn", "Linux", "CD-i",
+	"Amstrad CPC", "ZX Spectrum", "MSX", "Atari 8-bit", "PlayStation", "Saturn", "Wii",
+	"Nintendo DS", "Pippin", "Android", "iOS",
+}
+
+// platformAliases are shorter names that can be given with --prefer-platform.
+var platformAliases = map[string]string{
+	"pc":      "DOS",
+	"win":     "Windows",
+	"mac":     "Macintosh",
+	"atari":   "Atari ST",
+	"fmtowns": "FM-TOWNS",
+	"towns":   "FM-TOWNS",
+	"pce":     "PC-Engine",
+	"segacd":  "Sega CD",
+	"psx":     "PlayStation",
+}
+
+// DescriptionVariant is a scummvm Description split into the title and the variant tags
+// that follow it, for example "Loom (VGA/DOS/English)" is the title "Loom" with the
+// tags "VGA", "DOS" and "English".
+type DescriptionVariant struct {
+	Title    string
+	Tags     []string
+	Language string
+	Platform string
+}
+
+// ParseDescriptionVariant splits a scummvm Description into its title and variant tags.
+func ParseDescriptionVariant(description string) DescriptionVariant {
+	variant := DescriptionVariant{Title: strings.TrimSpace(description)}
+
+	// The tags are in the last set of parentheses at the end of the Description
+	trimmedDescription := strings.TrimSpace(description)
+	if !strings.HasSuffix(trimmedDescription, ")") {
+		return variant
+	}
+	openIndex := strings.LastIndex(trimmedDescription, "(")
+	if openIndex < 0 {
+		return variant
+	}
+	variant.Title = strings.TrimSpace(trimmedDescription[:openIndex])
+	for _, tag := range strings.Split(trimmedDescription[openIndex+1:len(trimmedDescription)-1], "/") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		variant.Tags = append(variant.Tags, tag)
+
+		// Remember the language and platform if this tag is one
+		if variant.Language == "" && isScummvmLanguage(tag) {
+			variant.Language = tag
+		}
+		if variant.Platform == "" && isScummvmPlatform(tag) {
+			variant.Platform = tag
+		}
+	}
+
+	return variant
+}
+
+// isScummvmLanguage reports whether a tag is one of the language names scummvm uses.
+func isScummvmLanguage(tag string) bool {
+	_, ok := canonicalLanguage(tag)
+	return ok
+}
+
+// canonicalLanguage returns the language name the way scummvm spells it.
+func canonicalLanguage(name string) (string, bool) {
+	for _, language := range scummvmLanguages {
+		if strings.EqualFold(language, name) {
+			return language, true
+		}
+	}
+	return "", false
+}
+
+// isScummvmPlatform reports whether a tag is one of the platform names scummvm uses.
+func isScummvmPlatform(tag string) bool {
+	for _, platform := range scummvmPlatforms {
+		if strings.EqualFold(platform, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutLanguage returns the Description of the variant with its language left out,
+// so that two variants that only differ by language look the same.
+func (v DescriptionVariant) withoutLanguage() string {
+	tags := make([]string, 0, len(v.Tags))
+	for _, tag := range v.Tags {
+		if tag != v.Language {
+			tags = append(tags, tag)
+		}
+	}
+	return v.Title + " (" + strings.Join(tags, "/") + ")"
+}
+
+// ParseLanguagePreference turns the comma separated list given with --prefer-language
+// into scummvm language names. Both codes ("de") and names ("German") are accepted.
+func ParseLanguagePreference(preference string) ([]string, error) {
+	languages := make([]string, 0)
+	for _, code := range strings.Split(preference, ",") {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		if language, ok := scummvmLanguages[strings.ToLower(code)]; ok {
+			languages = append(languages, language)
+			continue
+		}
+		if language, ok := canonicalLanguage(code); ok {
+			languages = append(languages, language)
+			continue
+		}
+		return nil, fmt.Errorf("unknown language %q", code)
+	}
+	return languages, nil
+}
+
+// ParsePlatformPreference turns the comma separated list given with --prefer-platform
+// into scummvm platform names.
+func ParsePlatformPreference(preference string) ([]string, error) {
+	platforms := make([]string, 0)
+	for _, name := range strings.Split(preference, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if platform, ok := platformAliases[strings.ToLower(name)]; ok {
+			name = platform
+		}
+		if !isScummvmPlatform(name) {
+			return nil, fmt.Errorf("unknown platform %q", name)
+		}
+		platforms = append(platforms, name)
+	}
+	return platforms, nil
+}
+
+// preferenceRank returns the position of value in the list of preferences, or the
+// length of the list if it isn't in it, so that a lower rank is better.
+func preferenceRank(preferences []string, value string) int {
+	for i, preference := range preferences {
+		if strings.EqualFold(preference, value) {
+			return i
+		}
+	}
+	return len(preferences)
+}
+
+// PreferLanguage looks for candidates that only differ from the chosen candidate by
+// language, and returns the index of the one in the most preferred language.
+func PreferLanguage(candidates []ScummGameMatch, chosenIndex int, preferredLanguages []string) int {
+	chosenVariant := ParseDescriptionVariant(candidates[chosenIndex].Description)
+	bestRank := preferenceRank(preferredLanguages, chosenVariant.Language)
+	for i, candidate := range candidates {
+		variant := ParseDescriptionVariant(candidate.Description)
+		if variant.withoutLanguage() != chosenVariant.withoutLanguage() {
+			continue
+		}
+		if rank := preferenceRank(preferredLanguages, variant.Language); rank < bestRank {
+			chosenIndex = i
+			bestRank = rank
+		}
+	}
+	return chosenIndex
+}
+
+// PreferPlatform looks for candidates that are just as similar to the directory name as
+// the chosen candidate, and returns the index of the one for the most preferred
+// platform.
+func PreferPlatform(candidates []ScummGameMatch, similarities []float64, chosenIndex int, preferredPlatforms []string) int {
+	bestRank := preferenceRank(preferredPlatforms, ParseDescriptionVariant(candidates[chosenIndex].Description).Platform)
+	for i, candidate := range candidates {
+		if math.Abs(similarities[i]-similarities[chosenIndex]) > similarityEpsilon {
+			continue
+		}
+		if rank := preferenceRank(preferredPlatforms, ParseDescriptionVariant(candidate.Description).Platform); rank < bestRank {
+			chosenIndex = i
+			bestRank = rank
+		}
+	}
+	return chosenIndex
+}
@@ -0,0 +1,121 @@
+package output
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/furui/scummer/match"
+)
+
+// unsafeDirectoryNameReplacer takes out of a title the characters that can't be in a
+// directory name on one platform or another, keeping the title readable, so that
+// "Indiana Jones and the Fate of Atlantis: The Action Game" isn't cut short at the colon.
+var unsafeDirectoryNameReplacer = strings.NewReplacer(
+	": ", " - ",
+	":", "-",
+	"/", "-",
+	`\`, "-",
+	"|", "-",
+	"<", "",
+	">", "",
+	`"`, "",
+	"?", "",
+	"*", "",
+)
+
+// CanonicalDirectoryName returns the name a game's directory gets when it is renamed to
+// the game's title: the full title from scummvm's list of games, or the title in the
+// Description if the GameID isn't in it, made safe to use as a directory name on every
+// platform. It returns "" if the game has no title.
+func CanonicalDirectoryName(scummGameMatch match.ScummGameMatch) string {
+	title := scummGameMatch.Title
+	if title == "" {
+		title = match.ParseDescriptionVariant(scummGameMatch.Description).Title
+	}
+
+	// Leave out the control characters as well, and the dots and spaces Windows doesn't
+	// allow at the end of a name
+	name := strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, unsafeDirectoryNameReplacer.Replace(title))
+	return strings.TrimRight(strings.Join(strings.Fields(name), " "), ". ")
+}
+
+// PlanCanonicalDirectories decides to rename the directory of each game to its
+// CanonicalDirectoryName, such as "mi2cd" to "Monkey Island 2 - LeChuck's Revenge",
+// and records it in RenameTo, so that RenameGameDirectories renames it along with the
+// directories PlanMarkerFiles renames. Directories that already have their name are
+// left alone, and so are the discs of multi-disc games, whose names say which disc they
+// are, and the games left in their archives, which have no directory to rename.
+//
+// When two games would get the same name, the tags of their Descriptions are added to
+// it, such as "Loom (CD DOS English)", and a number after that if it isn't enough, such
+// as "Loom (2)". A name that is already taken by something else in the library is
+// treated the same way.
+func PlanCanonicalDirectories(scummGameMatches []match.ScummGameMatch) {
+	// Work out the name of each game that is renamed
+	names := make(map[int]string)
+	nameCounts := make(map[string]int)
+	for i, scummGameMatch := range scummGameMatches {
+		if scummGameMatch.DiscGroup != "" || scummGameMatch.Archive != "" {
+			continue
+		}
+		name := CanonicalDirectoryName(scummGameMatch)
+		if name == "" {
+			continue
+		}
+		names[i] = name
+		nameCounts[strings.ToLower(name)]++
+	}
+
+	// Tell apart the games that would get the same name by their tags
+	for i, name := range names {
+		if nameCounts[strings.ToLower(name)] < 2 {
+			continue
+		}
+		if tags := match.ParseDescriptionVariant(scummGameMatches[i].Description).Tags; len(tags) > 0 {
+			names[i] = CanonicalDirectoryName(match.ScummGameMatch{Title: name + " (" + strings.Join(tags, " ") + ")"})
+		}
+	}
+
+	// Number the names that are still taken, going through the games in order so that
+	// the same library is always renamed the same way
+	takenNames := make(map[string]bool)
+	for i := range scummGameMatches {
+		name, ok := names[i]
+		if !ok {
+			continue
+		}
+		directory := scummGameMatches[i].Directory
+		renameTo := filepath.Join(filepath.Dir(directory), name)
+		for n := 2; takenNames[strings.ToLower(renameTo)] || isOtherDirectory(renameTo, directory); n++ {
+			renameTo = filepath.Join(filepath.Dir(directory), name+" ("+strconv.Itoa(n)+")")
+		}
+		takenNames[strings.ToLower(renameTo)] = true
+
+		if renameTo != directory {
+			scummGameMatches[i].RenameTo = renameTo
+		}
+	}
+}
+
+// isOtherDirectory reports whether something other than directory is already at path.
+// On a filesystem that ignores case, a name that only differs from the directory's in
+// case is the directory itself.
+func isOtherDirectory(path string, directory string) bool {
+	pathInfo, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	directoryInfo, err := os.Lstat(directory)
+	return err != nil || !os.SameFile(pathInfo, directoryInfo)
+}
@@ -0,0 +1,125 @@
+package output
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+)
+
+// discNumberMatcher matches the disc number at the end of a directory name, such as
+// "(Disc 1)", "[CD2]" or "- Disk 1 of 2", and captures the name before it and the
+// number.
+var discNumberMatcher = regexp.MustCompile(`(?i)^(.*?)[\s._-]*[(\[]?\b(?:disc|disk|cd)[\s._-]*(\d+)(?:\s*of\s*\d+)?[)\]]?$`)
+
+// parseDiscDirectoryName splits a directory name such as "Broken Sword (Disc 2)" into
+// the name of the game and the number of the disc. It returns false if the name doesn't
+// end in a disc number.
+func parseDiscDirectoryName(directoryName string) (string, int, bool) {
+	nameMatch := discNumberMatcher.FindStringSubmatch(strings.TrimSpace(directoryName))
+	if nameMatch == nil || strings.TrimSpace(nameMatch[1]) == "" {
+		return "", 0, false
+	}
+	discNumber, err := strconv.Atoi(nameMatch[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.TrimSpace(nameMatch[1]), discNumber, true
+}
+
+// isLaterDisc reports whether a match is a disc of a multi-disc game other than the
+// first, whose discs were grouped so that only the first one is written out.
+func isLaterDisc(scummGameMatch match.ScummGameMatch) bool {
+	return scummGameMatch.DiscsGrouped && scummGameMatch.FirstDisc != ""
+}
+
+// WithoutLaterDiscs returns the matches that are written out, leaving out the later
+// discs of grouped multi-disc games.
+func WithoutLaterDiscs(scummGameMatches []match.ScummGameMatch) []match.ScummGameMatch {
+	writtenMatches := make([]match.ScummGameMatch, 0, len(scummGameMatches))
+	for _, scummGameMatch := range scummGameMatches {
+		if !isLaterDisc(scummGameMatch) {
+			writtenMatches = append(writtenMatches, scummGameMatch)
+		}
+	}
+	return writtenMatches
+}
+
+// MarkMultiDiscGames finds the directories next to each other that are discs of
+// the same game, such as "Game (Disc 1)" and "Game (Disc 2)" with the same GameID, and
+// annotates them with their disc numbers. The first disc lists all the discs, and the
+// others point at it. When group is set, the discs are grouped into one game, so only
+// the first disc gets a .scummvm file, along with an .m3u listing every disc.
+func MarkMultiDiscGames(scummGameMatches []match.ScummGameMatch, group bool) {
+	// Gather the discs of each game
+	discGroups := make(map[string][]int)
+	groupKeys := make([]string, 0)
+	for i, scummGameMatch := range scummGameMatches {
+		name, discNumber, ok := parseDiscDirectoryName(filepath.Base(scummGameMatch.Directory))
+		if !ok {
+			continue
+		}
+		scummGameMatches[i].DiscGroup = name
+		scummGameMatches[i].DiscNumber = discNumber
+
+		key := filepath.Dir(scummGameMatch.Directory) + "\x00" + strings.ToLower(name) + "\x00" + scummGameMatch.GameID
+		if _, ok := discGroups[key]; !ok {
+			groupKeys = append(groupKeys, key)
+		}
+		discGroups[key] = append(discGroups[key], i)
+	}
+
+	for _, key := range groupKeys {
+		discs := discGroups[key]
+
+		// A single disc on its own isn't a multi-disc game
+		if len(discs) < 2 {
+			scummGameMatches[discs[0]].DiscGroup = ""
+			scummGameMatches[discs[0]].DiscNumber = 0
+			continue
+		}
+
+		// Put the discs in order, and annotate them
+		sort.SliceStable(discs, func(i, j int) bool {
+			return scummGameMatches[discs[i]].DiscNumber < scummGameMatches[discs[j]].DiscNumber
+		})
+		first := discs[0]
+		scummGameMatches[first].Discs = nil
+		for _, i := range discs {
+			scummGameMatches[first].Discs = append(scummGameMatches[first].Discs, scummGameMatches[i].Directory)
+			scummGameMatches[i].DiscsGrouped = group
+			if i != first {
+				scummGameMatches[i].FirstDisc = scummGameMatches[first].Directory
+			}
+		}
+
+		fmt.Printf("%s is on %d discs\n", scummGameMatches[first].DiscGroup, len(discs))
+	}
+}
+
+// writeDiscPlaylist writes an .m3u listing the discs of a grouped multi-disc game, next
+// to the .scummvm file of its first disc. The discs are listed relative to the .m3u.
+func writeDiscPlaylist(scummGameMatch match.ScummGameMatch, changeJournal *journal.Journal) error {
+	if !scummGameMatch.DiscsGrouped || len(scummGameMatch.Discs) < 2 {
+		return nil
+	}
+
+	playlistDirectory := filepath.Dir(MarkerFileNames(scummGameMatch)[0])
+	var playlist strings.Builder
+	for _, disc := range scummGameMatch.Discs {
+		relativePath, err := filepath.Rel(playlistDirectory, disc)
+		if err != nil {
+			relativePath = disc
+		}
+		playlist.WriteString(filepath.ToSlash(relativePath) + "\n")
+	}
+	playlistFile := filepath.Join(playlistDirectory, scummGameMatch.DiscGroup+".m3u")
+	return changeJournal.ChangeFile(playlistFile, func() error {
+		return writeFileAtomically(playlistFile, []byte(playlist.String()))
+	})
+}
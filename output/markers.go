@@ -0,0 +1,454 @@
+// Package output writes the results of a scan: success.json and error.json, and the
+// .scummvm marker files that frontends launch games from.
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/journal"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/wsl"
+)
+
+// These are the places a .scummvm file can be written.
+const (
+	// MarkerPlacementSibling writes the .scummvm file next to the game's directory,
+	// named after the directory.
+	MarkerPlacementSibling = "sibling"
+
+	// MarkerPlacementInside writes the .scummvm file inside the game's directory, named
+	// after the directory.
+	MarkerPlacementInside = "inside"
+
+	// MarkerPlacementBoth writes a .scummvm file in both places.
+	MarkerPlacementBoth = "both"
+)
+
+// MarkerPlacements are the placements that can be chosen with --marker-placement.
+var MarkerPlacements = []string{MarkerPlacementSibling, MarkerPlacementInside, MarkerPlacementBoth}
+
+// MarkerExtension is the usual extension of the marker files.
+const MarkerExtension = ".scummvm"
+
+// These are the ways the GameID can be written in the marker files.
+const (
+	// MarkerFormatQualified writes the GameID with its engine prefix, such as
+	// "scumm:loom", which is what scummvm 2.x expects. It is the default, so layouts
+	// leave their Format empty for it.
+	MarkerFormatQualified = "qualified"
+
+	// MarkerFormatBare writes the GameID without its engine prefix, such as "loom"
+	// rather than "scumm:loom", for frontends that pass the marker to an older scummvm.
+	MarkerFormatBare = "bare"
+)
+
+// MarkerLayout controls where the .scummvm files are written.
+type MarkerLayout struct {
+	// Placement is where the .scummvm file goes, relative to the game's directory.
+	Placement string
+
+	// DirectoryAsGame renames the game's directory to end in .scummvm, so that frontends
+	// such as ES-DE show the directory as a single game rather than as a folder.
+	DirectoryAsGame bool
+
+	// Extension is the extension of the marker files, .scummvm unless set.
+	Extension string
+
+	// Format is how the GameID is written: in full, or MarkerFormatBare.
+	Format string
+
+	// SanitizeNames leaves everything but letters, digits, spaces and a few safe
+	// punctuation marks out of the names of the marker files, for frontends that can't
+	// launch files with other characters in their names.
+	SanitizeNames bool
+
+	// NameTemplate, when set, is a text/template that gives the name of the marker
+	// files, extension and all, in place of the directory name.
+	NameTemplate string
+
+	// ContentsTemplate, when set, is a text/template that gives the contents of the
+	// marker files in place of the GameID.
+	ContentsTemplate string
+}
+
+// markerTemplateData is what the marker name and contents templates can use.
+type markerTemplateData struct {
+	GameID      string
+	BareGameID  string
+	Engine      string
+	Description string
+	Title       string
+	Directory   string
+	Language    string
+	Platform    string
+}
+
+// newMarkerTemplateData gathers what the templates can use about a game in the given
+// directory.
+func newMarkerTemplateData(scummGameMatch match.ScummGameMatch, directory string) markerTemplateData {
+	variant := match.ParseDescriptionVariant(scummGameMatch.Description)
+	data := markerTemplateData{
+		GameID:      scummGameMatch.GameID,
+		BareGameID:  match.BareGameID(scummGameMatch.GameID),
+		Engine:      match.CandidateEngine(scummGameMatch.GameID),
+		Description: scummGameMatch.Description,
+		Title:       scummGameMatch.Title,
+		Directory:   filepath.Base(directory),
+		Language:    variant.Language,
+		Platform:    variant.Platform,
+	}
+	if data.Title == "" {
+		data.Title = variant.Title
+	}
+	return data
+}
+
+// renderMarkerTemplate fills in a marker name or contents template.
+func renderMarkerTemplate(text string, data markerTemplateData) (string, error) {
+	markerTemplate, err := template.New("marker").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := markerTemplate.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// CheckTemplates makes sure the layout's templates work, so that a mistake in one is
+// reported before the scan starts rather than after it.
+func (layout MarkerLayout) CheckTemplates() error {
+	example := newMarkerTemplateData(match.ScummGameMatch{GameID: "scumm:loom", Description: "Loom (VGA/DOS/English)", Title: "Loom"}, "Loom")
+	for _, text := range []string{layout.NameTemplate, layout.ContentsTemplate} {
+		if text == "" {
+			continue
+		}
+		if _, err := renderMarkerTemplate(text, example); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unsafeMarkerNameMatcher matches the characters SanitizeMarkerName leaves out.
+var unsafeMarkerNameMatcher = regexp.MustCompile(`[^A-Za-z0-9 ._()\[\]-]+`)
+
+// SanitizeMarkerName makes a name safe to launch: accents are removed, other unsafe
+// characters are left out, and runs of spaces are collapsed.
+func SanitizeMarkerName(name string) string {
+	sanitizedName := unsafeMarkerNameMatcher.ReplaceAllString(match.FoldDiacritics(name), "")
+	return strings.Join(strings.Fields(sanitizedName), " ")
+}
+
+// DefaultMarkerLayout is the layout scummer has always used.
+var DefaultMarkerLayout = MarkerLayout{Placement: MarkerPlacementSibling}
+
+// extension returns the extension of the marker files with this layout.
+func (layout MarkerLayout) extension() string {
+	if layout.Extension == "" {
+		return MarkerExtension
+	}
+	return layout.Extension
+}
+
+// PutsMarkerInside reports whether this layout writes a .scummvm file inside the game's
+// directory.
+func (layout MarkerLayout) PutsMarkerInside() bool {
+	return layout.Placement == MarkerPlacementInside || layout.Placement == MarkerPlacementBoth
+}
+
+// markerName returns the name of the .scummvm file of a game in the given directory,
+// when it is placed inside the directory or next to it.
+func (layout MarkerLayout) markerName(scummGameMatch match.ScummGameMatch, directory string, inside bool) (string, error) {
+	// The name template gives the whole name
+	if layout.NameTemplate != "" {
+		name, err := renderMarkerTemplate(layout.NameTemplate, newMarkerTemplateData(scummGameMatch, directory))
+		if err != nil {
+			return "", err
+		}
+		if layout.SanitizeNames {
+			name = SanitizeMarkerName(name)
+		}
+		if name == "" || strings.ContainsAny(name, `/\`) {
+			return "", fmt.Errorf("the marker name template gave %q for %s, which isn't a file name", name, directory)
+		}
+		return name, nil
+	}
+
+	// Inside the directory, a directory that already ends in .scummvm doesn't need it twice
+	name := filepath.Base(directory)
+	if inside {
+		name = strings.TrimSuffix(name, MarkerExtension)
+	}
+	if layout.SanitizeNames {
+		name = SanitizeMarkerName(name)
+	}
+	return name + layout.extension(), nil
+}
+
+// markerFileNames returns where the .scummvm files of a game in the given directory go
+// with this layout.
+func (layout MarkerLayout) markerFileNames(scummGameMatch match.ScummGameMatch, directory string) ([]string, error) {
+	markerFiles := make([]string, 0, 2)
+
+	// Next to the directory
+	if layout.Placement != MarkerPlacementInside {
+		name, err := layout.markerName(scummGameMatch, directory, false)
+		if err != nil {
+			return nil, err
+		}
+		markerFiles = append(markerFiles, filepath.Join(filepath.Dir(directory), name))
+	}
+
+	// Inside the directory
+	if layout.PutsMarkerInside() {
+		name, err := layout.markerName(scummGameMatch, directory, true)
+		if err != nil {
+			return nil, err
+		}
+		markerFiles = append(markerFiles, filepath.Join(directory, name))
+	}
+
+	return markerFiles, nil
+}
+
+// PlanMarkerFiles works out where the .scummvm file of each game goes, and
+// which directories need to be renamed first, and records it in the matches. Nothing
+// is changed on disk until RenameGameDirectories and WriteMarkerFiles are called.
+// The names of the marker files are worked out now, so a name template sees the game
+// as it is at this point. A directory that is already going to be renamed, such as by
+// PlanCanonicalDirectories, has its marker files planned with its new name. A game left
+// in its archive only gets the .scummvm file next to it, since it has no directory to
+// put one in or rename.
+func PlanMarkerFiles(scummGameMatches []match.ScummGameMatch, layout MarkerLayout) error {
+	for i := range scummGameMatches {
+		gameLayout := layout
+		if scummGameMatches[i].Archive != "" {
+			gameLayout.Placement = MarkerPlacementSibling
+			gameLayout.DirectoryAsGame = false
+		}
+
+		directory := scummGameMatches[i].Directory
+		if scummGameMatches[i].RenameTo != "" {
+			directory = scummGameMatches[i].RenameTo
+		}
+		if gameLayout.DirectoryAsGame && !strings.HasSuffix(directory, MarkerExtension) {
+			directory += MarkerExtension
+			scummGameMatches[i].RenameTo = directory
+		}
+		markerFiles, err := gameLayout.markerFileNames(scummGameMatches[i], directory)
+		if err != nil {
+			return err
+		}
+		scummGameMatches[i].MarkerFiles = markerFiles
+		scummGameMatches[i].MarkerFormat = layout.Format
+		scummGameMatches[i].MarkerTemplate = layout.ContentsTemplate
+	}
+	return nil
+}
+
+// RenameGameDirectories renames the directories that PlanMarkerFiles decided to
+// rename, records the renames in the journal, and updates the matches to point at
+// their new names. It returns whether any directories were renamed.
+func RenameGameDirectories(scummGameMatches []match.ScummGameMatch, changeJournal *journal.Journal) (bool, error) {
+	renamed := false
+	for i := range scummGameMatches {
+		if scummGameMatches[i].RenameTo == "" {
+			continue
+		}
+
+		// Don't overwrite anything that is already there, other than the directory itself
+		// on a filesystem that ignores case, when only the case of its name changes
+		if isOtherDirectory(scummGameMatches[i].RenameTo, scummGameMatches[i].Directory) {
+			return renamed, fmt.Errorf("can't rename %s to %s: it already exists", scummGameMatches[i].Directory, scummGameMatches[i].RenameTo)
+		}
+		if err := os.Rename(scummGameMatches[i].Directory, scummGameMatches[i].RenameTo); err != nil {
+			return renamed, err
+		}
+		if err := changeJournal.Renamed(scummGameMatches[i].Directory, scummGameMatches[i].RenameTo); err != nil {
+			return true, err
+		}
+
+		// The other copies of a game found in more than one directory point at this one
+		for j := range scummGameMatches {
+			if scummGameMatches[j].CanonicalDirectory == scummGameMatches[i].Directory {
+				scummGameMatches[j].CanonicalDirectory = scummGameMatches[i].RenameTo
+			}
+		}
+
+		scummGameMatches[i].Directory = scummGameMatches[i].RenameTo
+		scummGameMatches[i].RenameTo = ""
+		renamed = true
+	}
+	return renamed, nil
+}
+
+// MarkerFileNames returns the names of the .scummvm files for a game. Results
+// from before marker layouts existed don't record them, and use the default layout.
+// Results from the other side of WSL, such as a scan on Windows applied inside WSL, have
+// their paths translated to this side's.
+func MarkerFileNames(scummGameMatch match.ScummGameMatch) []string {
+	if len(scummGameMatch.MarkerFiles) > 0 {
+		markerFiles := make([]string, 0, len(scummGameMatch.MarkerFiles))
+		for _, markerFile := range scummGameMatch.MarkerFiles {
+			markerFiles = append(markerFiles, wsl.LocalPath(markerFile))
+		}
+		return markerFiles
+	}
+
+	// The default layout has no templates, so it can't fail
+	markerFiles, _ := DefaultMarkerLayout.markerFileNames(scummGameMatch, wsl.LocalPath(scummGameMatch.Directory))
+	return markerFiles
+}
+
+// MarkerContents returns what goes in the .scummvm files of a game, which is its
+// GameID in the format the layout asked for, or whatever the contents template gives.
+// The template is filled in when the file is written, so it sees any change made to the
+// game in a review.
+func MarkerContents(scummGameMatch match.ScummGameMatch) (string, error) {
+	if scummGameMatch.MarkerTemplate != "" {
+		return renderMarkerTemplate(scummGameMatch.MarkerTemplate, newMarkerTemplateData(scummGameMatch, scummGameMatch.Directory))
+	}
+	if scummGameMatch.MarkerFormat == MarkerFormatBare {
+		return match.BareGameID(scummGameMatch.GameID), nil
+	}
+	return scummGameMatch.GameID, nil
+}
+
+// WriteMarkerFile writes the .scummvm files of a game, which contain its GameID, and
+// records them in the journal. Each file is written in full before it takes the place
+// of the one that is already there, so a .scummvm file is never left cut short.
+func WriteMarkerFile(scummGameMatch match.ScummGameMatch, changeJournal *journal.Journal) error {
+	contents, err := MarkerContents(scummGameMatch)
+	if err != nil {
+		return err
+	}
+
+	for _, markerFileName := range MarkerFileNames(scummGameMatch) {
+		err := changeJournal.ChangeFile(markerFileName, func() error {
+			return writeFileAtomically(markerFileName, []byte(contents))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFileAtomically writes a file by writing a temporary file next to it, flushing it
+// to the disk and renaming it into place, so that a file that is already there is
+// either left as it was or replaced as a whole, even if scummer is stopped or the disk
+// fills up part of the way through. A file that is replaced keeps its permissions.
+func writeFileAtomically(fileName string, contents []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fileName); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", fileName)
+		}
+		mode = info.Mode().Perm()
+	}
+
+	temporaryFile, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporaryFile.Name())
+
+	if _, err := temporaryFile.Write(contents); err != nil {
+		temporaryFile.Close()
+		return err
+	}
+	if err := temporaryFile.Sync(); err != nil {
+		temporaryFile.Close()
+		return err
+	}
+	if err := temporaryFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temporaryFile.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(temporaryFile.Name(), fileName)
+}
+
+// MarkerWriteError is what WriteMarkerFiles returns when some of the games' .scummvm
+// files couldn't be written. The other games' files were written anyway.
+type MarkerWriteError struct {
+	// Written is how many games had their .scummvm files written.
+	Written int
+
+	// Failed are the games whose .scummvm files couldn't be written, and Errors say why,
+	// in the same order.
+	Failed []match.ScummGameMatch
+	Errors []error
+}
+
+// Error lists the games whose .scummvm files couldn't be written, and why.
+func (e *MarkerWriteError) Error() string {
+	failures := make([]string, 0, len(e.Failed))
+	for i, scummGameMatch := range e.Failed {
+		failures = append(failures, fmt.Sprintf("%s: %s", scummGameMatch.Directory, e.Errors[i]))
+	}
+	return fmt.Sprintf("couldn't write the .scummvm files of %d of the %d games:\n  %s", len(e.Failed), e.Written+len(e.Failed), strings.Join(failures, "\n  "))
+}
+
+// Unwrap returns why each game's .scummvm files couldn't be written, so that errors.Is
+// can look at them.
+func (e *MarkerWriteError) Unwrap() []error {
+	return e.Errors
+}
+
+// WriteMarkerFiles writes a .scummvm file for each game, recording them in the journal.
+// The later discs of a grouped multi-disc game are left out, and an .m3u is written for
+// the first disc. Writing a game's files is tried again the way retry says if it fails
+// with an error that may go away, and a game whose files still can't be written doesn't
+// stop the others from being written. A game whose .scummvm file would be the same file
+// as another game's doesn't overwrite it. The games that failed are returned in a
+// *MarkerWriteError.
+func WriteMarkerFiles(scummGameMatches []match.ScummGameMatch, changeJournal *journal.Journal, retry detect.Retry) error {
+	writeErr := &MarkerWriteError{}
+	markerOwners := make(map[string]string)
+	for _, scummGameMatch := range WithoutLaterDiscs(scummGameMatches) {
+		err := retry.Do(func() error {
+			return writeGameMarkerFiles(scummGameMatch, markerOwners, changeJournal)
+		})
+		if err != nil {
+			writeErr.Failed = append(writeErr.Failed, scummGameMatch)
+			writeErr.Errors = append(writeErr.Errors, err)
+			continue
+		}
+		writeErr.Written++
+	}
+	if len(writeErr.Failed) > 0 {
+		return writeErr
+	}
+	return nil
+}
+
+// writeGameMarkerFiles writes the .scummvm files and .m3u of a game, unless another
+// game's .scummvm file has already been written to the same file. markerOwners are
+// the directories of the games whose files were written, by their lowercased names, so
+// that names only differing in case collide the way they do on Windows and macOS.
+func writeGameMarkerFiles(scummGameMatch match.ScummGameMatch, markerOwners map[string]string, changeJournal *journal.Journal) error {
+	markerFileNames := MarkerFileNames(scummGameMatch)
+	for _, markerFileName := range markerFileNames {
+		if owner, ok := markerOwners[strings.ToLower(markerFileName)]; ok {
+			return fmt.Errorf("%s is already the .scummvm file of %s", markerFileName, owner)
+		}
+	}
+
+	if err := WriteMarkerFile(scummGameMatch, changeJournal); err != nil {
+		return err
+	}
+	for _, markerFileName := range markerFileNames {
+		markerOwners[strings.ToLower(markerFileName)] = scummGameMatch.Directory
+	}
+	return writeDiscPlaylist(scummGameMatch, changeJournal)
+}
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/furui/scummer/match"
+)
+
+// SchemaVersion is the version of the format of success.json and error.json. It goes up
+// whenever a field is taken away or changes meaning, but not when one is added. Files
+// from before it was introduced are a bare list of games, which is version 1.
+const SchemaVersion = 2
+
+// resultsFile is what success.json and error.json hold.
+type resultsFile struct {
+	SchemaVersion int                    `json:"schema_version"`
+	Games         []match.ScummGameMatch `json:"Games"`
+}
+
+// WriteResults saves a list of ScummGameMatch structs to a JSON file, such as
+// success.json or error.json.
+func WriteResults(fileName string, scummGameMatches []match.ScummGameMatch) error {
+	if scummGameMatches == nil {
+		scummGameMatches = make([]match.ScummGameMatch, 0)
+	}
+	scummGameMatchesJSON, err := json.MarshalIndent(resultsFile{SchemaVersion: SchemaVersion, Games: scummGameMatches}, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, scummGameMatchesJSON, 0644)
+}
+
+// ReadResults loads a list of ScummGameMatch structs from a JSON file that was
+// written by WriteResults, or by a scummer from before the files had a schema version.
+func ReadResults(fileName string) ([]match.ScummGameMatch, error) {
+	scummGameMatchesJSON, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files from before the schema version are just the list of games
+	scummGameMatches := make([]match.ScummGameMatch, 0)
+	if bytes.HasPrefix(bytes.TrimSpace(scummGameMatchesJSON), []byte("[")) {
+		if err := json.Unmarshal(scummGameMatchesJSON, &scummGameMatches); err != nil {
+			return nil, err
+		}
+		return scummGameMatches, nil
+	}
+
+	results := resultsFile{Games: scummGameMatches}
+	if err := json.Unmarshal(scummGameMatchesJSON, &results); err != nil {
+		return nil, err
+	}
+	if results.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("%s has schema version %d, but this scummer only understands up to version %d", fileName, results.SchemaVersion, SchemaVersion)
+	}
+	return results.Games, nil
+}
@@ -0,0 +1,85 @@
+package output
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// jsonSchemaURL is the JSON Schema draft the schema is written in.
+const jsonSchemaURL = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema returns the JSON Schema of success.json and error.json. It is worked out from
+// the structs the files are written from, so it can't drift away from them.
+func Schema() ([]byte, error) {
+	definitions := make(map[string]interface{})
+	schema := jsonSchemaStruct(reflect.TypeOf(resultsFile{}), definitions)
+	schema["$schema"] = jsonSchemaURL
+	schema["title"] = "scummer results"
+	schema["description"] = "success.json or error.json, as written by scummer"
+	schema["$defs"] = definitions
+
+	// Only this version of the files is described
+	schema["properties"].(map[string]interface{})["schema_version"] = map[string]interface{}{"const": SchemaVersion}
+
+	return json.MarshalIndent(schema, "", "    ")
+}
+
+// jsonSchemaType returns the schema of a Go type the way encoding/json writes it.
+// Structs are added to definitions and referred to by name.
+func jsonSchemaType(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonSchemaType(t.Elem(), definitions)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchemaType(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchemaType(t.Elem(), definitions)}
+	case reflect.Struct:
+		if _, ok := definitions[t.Name()]; !ok {
+			// Add a placeholder first, in case the struct refers to itself
+			definitions[t.Name()] = nil
+			definitions[t.Name()] = jsonSchemaStruct(t, definitions)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// jsonSchemaStruct returns the schema of a struct. Fields that are left out when they
+// are empty are optional, and the rest are required.
+func jsonSchemaStruct(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		// Use the name and options from the json tag
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, tagOptions, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = jsonSchemaType(field.Type, definitions)
+		if !strings.Contains(tagOptions, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+}
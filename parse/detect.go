@@ -0,0 +1,218 @@
+// Package parse reads what scummvm prints: the games "--detect" found in a directory,
+// and the full titles of every game from "--list-games".
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/furui/scummer/match"
+)
+
+// Some sample outputs from scummvm are as follows.
+
+// When the game cannot be found, scummvm returns:
+// C:\Program Files\ScummVM>scummvm --detect --path="G:\example\SCUMMVM"
+// WARNING: ScummVM could not find any game in G:\example\SCUMMVM\
+// WARNING: Consider using --recursive to search inside subdirectories
+
+// When the game can be found, scummvm returns:
+// GameID                         Description                                                Full Path
+// ------------------------------ ---------------------------------------------------------- ---------------------------------------------------------
+// scumm:loom                     Loom (VGA/DOS/English)                                     G:\example\scummvm\Loom (CD DOS VGA)\
+
+// When the game can be found, but scummvm is not sure of what it is, then scummvm returns:
+// The game in 'Astro Chicken (Floppy DOS)\' seems to be an unknown game variant.
+//
+// Please report the following data to the ScummVM team at
+// https://bugs.scummvm.org/ along with the name of the game you tried to add and
+// its version, language, etc.:
+//
+// Matched game IDs for the director engine: iwave-mac
+//
+//   {"!", 0, "d:52807765c2438df92ebf1ab1fdbe6dfc", 1792},
+//
+// GameID                         Description                                                Full Path
+// ------------------------------ ---------------------------------------------------------- ---------------------------------------------------------
+// director:iwave                 Interactive Wave (Issue 1/Macintosh/English)               G:\example\SCUMMVM\Astro Chicken (Floppy DOS)\
+// sci:astrochicken               Astro Chicken (DOS/English)                                G:\example\SCUMMVM\Astro Chicken (Floppy DOS)\
+
+// When the game can be found, but some of its files are missing, scummvm warns about
+// them before the list of games, for instance:
+// WARNING: Could not find the speech file MONSTER.SOU in G:\example\SCUMMVM\Sam & Max\
+// GameID                         Description                                                Full Path
+// ------------------------------ ---------------------------------------------------------- ---------------------------------------------------------
+// scumm:samnmax                  Sam & Max Hit the Road (CD/DOS/English)                    G:\example\SCUMMVM\Sam & Max\
+
+// When running scummvm with just the "--version" command line option, scummvm returns:
+// ScummVM 2.7.0 (Feb 14 2023 14:26:43)
+// Features compiled in: Vorbis FLAC MP3 RGB zLib MPEG2 FluidSynth Theora AAC A/52 FreeType2 FriBiDi JPEG PNG GIF taskbar TTS cloud (servers, local) TinyGL OpenGL (with shaders)
+
+// DetectOutput takes in the output of the scummvm binary and returns every
+// candidate GameID and Description it lists. There is more than one candidate when
+// scummvm isn't sure which game it found.
+func DetectOutput(scummvmOutput string) ([]match.ScummGameMatch, error) {
+	// Check if the scummvm output contains the string "WARNING: ScummVM could not find any game in"
+	if strings.Contains(scummvmOutput, "WARNING: ScummVM could not find any game in") {
+		// Return an error
+		return nil, fmt.Errorf("scummvm could not find any game")
+	}
+
+	// Make sure the scummvm output contains a match for regex "GameID\s+Description\s+Full Path"
+	if !regexp.MustCompile(`GameID\s+Description\s+Full Path`).MatchString(scummvmOutput) {
+		// Return an error
+		return nil, fmt.Errorf("scummvm output does not contain a match for regex \"GameID\\s+Description\\s+Full Path\"")
+	}
+
+	// Define newlines for the scummvm output in case we're running on Windows
+	eol := "\n"
+	if strings.Contains(scummvmOutput, "\r\n") {
+		eol = "\r\n"
+	}
+
+	// Split the scummvm output by newlines
+	scummvmOutputSplit := strings.Split(scummvmOutput, eol)
+
+	// Create a slice that contains a possible set of matches
+	var scummvmOutputSlice []match.ScummGameMatch
+
+	// Generate regex for matching the line that contains the GameID, Description, and Directory
+	matcher := regexp.MustCompile(`^(.+?)\s{2,}(.+?)\s{2,}(.+?)$`)
+	lineMatcher := regexp.MustCompile(`^-+\s-+\s-+$`)
+
+	// Loop through each line of the scummvm output
+	// and then find the first line that matches the regex "^-+\s-+\s-+$"
+	// and then loop through each line after that line until the end of the
+	// scummvm output and then parse each line into a ScummGameMatch struct
+	// and then append the ScummGameMatch struct to the scummvmOutputSlice
+	for i := 0; i < len(scummvmOutputSplit); i++ {
+		// Check if the line matches the regex "^-+\s-+\s-+$"
+		if lineMatcher.MatchString(scummvmOutputSplit[i]) {
+			// Loop through each line after the line that matches the regex "^-+\s-+\s-+$"
+			// until the end of the scummvm output
+			for j := i + 1; j < len(scummvmOutputSplit); j++ {
+				// Using the regex "^(.+)\s{2,}(.+)\s{2,}(.+)$", parse the line into
+				// three groups: GameID, Description, and Directory and save them into
+				// a ScummGameMatch struct
+				scummGameMatch := match.ScummGameMatch{}
+				scummGameMatch.GameID = matcher.ReplaceAllString(scummvmOutputSplit[j], "$1")
+				scummGameMatch.Description = matcher.ReplaceAllString(scummvmOutputSplit[j], "$2")
+				scummGameMatch.Directory = matcher.ReplaceAllString(scummvmOutputSplit[j], "$3")
+
+				// If any of the fields in the ScummGameMatch struct are empty, then
+				// continue to the next line
+				if scummGameMatch.GameID == "" || scummGameMatch.Description == "" || scummGameMatch.Directory == "" {
+					continue
+				}
+
+				// Append the ScummGameMatch struct to the scummvmOutputSlice
+				scummvmOutputSlice = append(scummvmOutputSlice, scummGameMatch)
+			}
+
+			// Break out of the loop
+			break
+		}
+	}
+
+	// Check if the scummvmOutputSlice is empty
+	if len(scummvmOutputSlice) == 0 {
+		// Return an error
+		return nil, fmt.Errorf("scummvm output slice is empty")
+	}
+
+	// Return every candidate that scummvm found
+	return scummvmOutputSlice, nil
+}
+
+// noGameWarnings are the warnings scummvm prints when it found no game at all, which
+// DetectOutput already turns into an error.
+var noGameWarnings = []string{"ScummVM could not find any game in", "Consider using --recursive"}
+
+// missingFilesMatcher matches the warnings that say files of the game are missing.
+var missingFilesMatcher = regexp.MustCompile(`(?i)\b(?:missing|not found|(?:could|can)(?:n't|not| not) (?:find|open|locate))\b`)
+
+// DetectWarnings returns the warnings scummvm printed along with the games it found,
+// such as about speech files or data tracks it couldn't find, without the "WARNING:" in
+// front of them.
+func DetectWarnings(scummvmOutput string) []string {
+	var warnings []string
+	for _, line := range strings.Split(scummvmOutput, "\n") {
+		line = strings.TrimSpace(line)
+		warning, ok := strings.CutPrefix(line, "WARNING:")
+		if !ok {
+			continue
+		}
+		warning = strings.TrimSpace(warning)
+
+		// Leave out the ones about not finding a game at all
+		noGame := false
+		for _, noGameWarning := range noGameWarnings {
+			if strings.HasPrefix(warning, noGameWarning) {
+				noGame = true
+			}
+		}
+		if !noGame && warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings
+}
+
+// MissingFiles returns whether any of the warnings say files of the game are missing,
+// which means the game was dumped incompletely, even though scummvm found it.
+func MissingFiles(warnings []string) bool {
+	for _, warning := range warnings {
+		if missingFilesMatcher.MatchString(warning) {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownVariantMatcher matches the line scummvm starts its data about a game variant it
+// doesn't know with.
+var unknownVariantMatcher = regexp.MustCompile(`^The game in '.*' seems to be an unknown`)
+
+// gameListHeaderMatcher matches the header of the list of games scummvm found.
+var gameListHeaderMatcher = regexp.MustCompile(`GameID\s+Description\s+Full Path`)
+
+// matchedGameIDsMatcher matches the line that names the engine the data is for, and the
+// games of that engine it resembles.
+var matchedGameIDsMatcher = regexp.MustCompile(`^Matched game IDs for the (.+?) engine:(.*)$`)
+
+// UnknownVariants returns the data scummvm asks to be reported to the ScummVM team when
+// it finds a variant of a game it doesn't know, one for each engine it printed data
+// for. The data are the indented lines after the request to report them, which are the
+// names, MD5 checksums and sizes of the files in whatever form the engine prints them.
+func UnknownVariants(scummvmOutput string) []match.UnknownVariant {
+	var unknownVariants []match.UnknownVariant
+	inUnknownVariant := false
+	for _, line := range strings.Split(strings.ReplaceAll(scummvmOutput, "\r\n", "\n"), "\n") {
+		switch {
+		case unknownVariantMatcher.MatchString(line):
+			// The data follow, for whichever engine is named first
+			inUnknownVariant = true
+			unknownVariants = append(unknownVariants, match.UnknownVariant{})
+		case !inUnknownVariant:
+		case gameListHeaderMatcher.MatchString(line):
+			// The list of games comes after the data
+			inUnknownVariant = false
+		case matchedGameIDsMatcher.MatchString(line):
+			// Start on the data for another engine, unless none has been given yet
+			submatches := matchedGameIDsMatcher.FindStringSubmatch(line)
+			last := &unknownVariants[len(unknownVariants)-1]
+			if last.Engine != "" || len(last.Files) > 0 {
+				unknownVariants = append(unknownVariants, match.UnknownVariant{})
+				last = &unknownVariants[len(unknownVariants)-1]
+			}
+			last.Engine = submatches[1]
+			last.MatchedGameIDs = strings.Fields(strings.ReplaceAll(submatches[2], ",", " "))
+		case strings.TrimSpace(line) != "" && strings.TrimLeft(line, " \t") != line:
+			// The data are indented, unlike the request to report them
+			last := &unknownVariants[len(unknownVariants)-1]
+			last.Files = append(last.Files, strings.TrimSpace(line))
+		}
+	}
+	return unknownVariants
+}
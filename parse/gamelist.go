@@ -0,0 +1,60 @@
+package parse
+
+import (
+	_ "embed"
+	"regexp"
+	"strings"
+)
+
+// The bundled game titles are the output of "scummvm --list-games". To update them,
+// run the command below with the newest scummvm.
+//
+//go:generate sh -c "scummvm --list-games > gametitles.txt"
+
+//go:embed gametitles.txt
+var bundledGameList string
+
+// BundledGameTitles are the titles from the bundled game list.
+var BundledGameTitles = GameList(bundledGameList)
+
+// gameListMatcher matches a line of "scummvm --list-games" output, which is the GameID
+// followed by the full title of the game, or of "scummvm --list-engines" output, which is
+// the engine ID followed by its name.
+var gameListMatcher = regexp.MustCompile(`^(\S+)\s+(.+?)\s*$`)
+
+// gameListSeparatorMatcher matches the line of dashes under the two column header.
+var gameListSeparatorMatcher = regexp.MustCompile(`^-+\s-+\s*$`)
+
+// GameList takes in the output of "scummvm --list-games" and returns a map
+// of GameIDs to the full titles of the games.
+func GameList(scummvmOutput string) map[string]string {
+	return twoColumnList(scummvmOutput)
+}
+
+// EngineList takes in the output of "scummvm --list-engines" and returns a map of the
+// IDs of the engines compiled into scummvm to their names.
+func EngineList(scummvmOutput string) map[string]string {
+	return twoColumnList(scummvmOutput)
+}
+
+// twoColumnList reads the two column table scummvm prints for --list-games and
+// --list-engines, and returns a map of the first column to the second.
+func twoColumnList(scummvmOutput string) map[string]string {
+	list := make(map[string]string)
+
+	// Skip everything up to and including the line of dashes under the header
+	lines := strings.Split(strings.ReplaceAll(scummvmOutput, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if !gameListSeparatorMatcher.MatchString(line) {
+			continue
+		}
+		for _, listLine := range lines[i+1:] {
+			if match := gameListMatcher.FindStringSubmatch(listLine); match != nil {
+				list[match[1]] = match[2]
+			}
+		}
+		break
+	}
+
+	return list
+}
@@ -0,0 +1,188 @@
+// Package remote reaches libraries on remote storage, such as an SMB share, an SFTP
+// server or an S3 bucket, through rclone. scummvm can only look at local files, so the
+// game directories are copied to a local directory to be scanned, and what is written
+// next to them, such as the .scummvm files, is copied back.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Library is a library on remote storage.
+type Library struct {
+	// URL is the library as it was given, such as smb://nas/games/scummvm.
+	URL string
+
+	// location is the library as rclone names it, such as :smb,host=nas:games/scummvm.
+	location string
+}
+
+// Parse returns the library on remote storage that a library argument names, or false
+// if it is a local directory. These are understood, and any query parameters are passed
+// on to rclone as options of its backend, such as ?endpoint=https://minio:9000 for S3:
+//
+//	smb://[user@]host[:port]/share/path
+//	sftp://[user@]host[:port]/path
+//	s3://bucket/path
+//	rclone:remote:path, for a remote set up with "rclone config"
+//
+// Passwords are left to rclone, which reads them from its environment variables, such
+// as RCLONE_SMB_PASS, or from its config file for a remote set up there.
+func Parse(library string) (Library, bool, error) {
+	// A remote set up with "rclone config" is used as it is
+	if location, ok := strings.CutPrefix(library, "rclone:"); ok {
+		if !strings.Contains(location, ":") {
+			return Library{}, true, fmt.Errorf("%s should be rclone:<remote>:<path>", library)
+		}
+		return Library{URL: library, location: location}, true, nil
+	}
+
+	scheme, _, found := strings.Cut(library, "://")
+	if !found {
+		return Library{}, false, nil
+	}
+	libraryURL, err := url.Parse(library)
+	if err != nil {
+		return Library{}, true, err
+	}
+
+	// Work out the rclone backend and its options
+	options := make(map[string]string)
+	path := strings.Trim(libraryURL.Path, "/")
+	switch strings.ToLower(scheme) {
+	case "smb", "sftp":
+		options["host"] = libraryURL.Hostname()
+		if libraryURL.Port() != "" {
+			options["port"] = libraryURL.Port()
+		}
+		if libraryURL.User != nil {
+			options["user"] = libraryURL.User.Username()
+		}
+		if scheme == "smb" && path == "" {
+			return Library{}, true, fmt.Errorf("%s should name a share, such as smb://%s/games", library, libraryURL.Host)
+		}
+	case "s3":
+		// The bucket is the host, and the credentials come from the environment the way
+		// the AWS tools take them
+		options["provider"] = "AWS"
+		options["env_auth"] = "true"
+		path = strings.TrimSuffix(libraryURL.Host+"/"+path, "/")
+	default:
+		return Library{}, true, fmt.Errorf("unknown remote storage %s://, must be smb://, sftp://, s3:// or rclone:", scheme)
+	}
+	if options["host"] == "" && scheme != "s3" {
+		return Library{}, true, fmt.Errorf("%s doesn't say which server the library is on", library)
+	}
+	for name, values := range libraryURL.Query() {
+		options[name] = values[len(values)-1]
+	}
+
+	// Build an rclone connection string, such as :smb,host=nas,user=me:games/scummvm,
+	// quoting the options that would otherwise be taken for part of it
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	location := ":" + strings.ToLower(scheme)
+	for _, name := range names {
+		value := options[name]
+		if strings.ContainsAny(value, `,:"'=`) {
+			value = `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+		}
+		location += "," + name + "=" + value
+	}
+	return Library{URL: strings.TrimSuffix(library, "/"), location: location + ":" + path}, true, nil
+}
+
+// String returns the library as it was given.
+func (l Library) String() string {
+	return l.URL
+}
+
+// join returns where a directory of the library is, as rclone names it.
+func (l Library) join(directory string) string {
+	if directory == "" {
+		return l.location
+	}
+	if strings.HasSuffix(l.location, ":") || strings.HasSuffix(l.location, "/") {
+		return l.location + directory
+	}
+	return l.location + "/" + directory
+}
+
+// Path returns the URL of a path in the library, given relative to its top with
+// forward slashes.
+func (l Library) Path(relativePath string) string {
+	if relativePath == "" || relativePath == "." {
+		return l.URL
+	}
+	if strings.HasSuffix(l.URL, ":") {
+		return l.URL + relativePath
+	}
+	return l.URL + "/" + relativePath
+}
+
+// runRclone runs rclone, returning what it printed on its standard output. What it
+// printed on its standard error is returned as the error if it fails.
+func runRclone(ctx context.Context, args ...string) (string, error) {
+	rclonePath, err := exec.LookPath("rclone")
+	if err != nil {
+		return "", fmt.Errorf("could not find rclone, which reaches libraries on remote storage; install it from https://rclone.org")
+	}
+
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, rclonePath, args...)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("rclone %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("rclone %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// Directories returns the names of the directories at the top of the library.
+func (l Library) Directories(ctx context.Context) ([]string, error) {
+	listing, err := runRclone(ctx, "lsf", "--dirs-only", "--max-depth", "1", l.location)
+	if err != nil {
+		return nil, err
+	}
+	directories := make([]string, 0)
+	for _, line := range strings.Split(listing, "\n") {
+		if directory := strings.TrimSuffix(strings.TrimSpace(line), "/"); directory != "" {
+			directories = append(directories, directory)
+		}
+	}
+	return directories, nil
+}
+
+// DownloadFiles copies the files at the top of the library, but none of its
+// directories, to a local directory. They are the files scummer may add to, such as
+// .scummvm files next to the games, or an EmulationStation gamelist.xml.
+func (l Library) DownloadFiles(ctx context.Context, localDirectory string) error {
+	_, err := runRclone(ctx, "copy", "--max-depth", "1", l.location, localDirectory)
+	return err
+}
+
+// Download copies a directory of the library, and everything in it, to a local
+// directory.
+func (l Library) Download(ctx context.Context, directory string, localDirectory string) error {
+	_, err := runRclone(ctx, "copy", l.join(directory), localDirectory)
+	return err
+}
+
+// Upload copies everything in a local directory to the top of the library, leaving
+// alone the files that are already there and haven't changed.
+func (l Library) Upload(ctx context.Context, localDirectory string) error {
+	_, err := runRclone(ctx, "copy", localDirectory, l.location)
+	return err
+}
@@ -0,0 +1,5 @@
+// Package rpc is the gRPC API of "scummer serve", generated from scummer.proto. Clients
+// in other languages can generate their own code from the same file.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative scummer.proto
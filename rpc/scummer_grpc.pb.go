@@ -0,0 +1,290 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: scummer.proto
+
+// The gRPC API of "scummer serve", for clients that aren't written in Go or that would
+// rather keep a connection open than poll the web API.
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Scummer_Scan_FullMethodName    = "/scummer.v1.Scummer/Scan"
+	Scummer_Watch_FullMethodName   = "/scummer.v1.Scummer/Watch"
+	Scummer_Resolve_FullMethodName = "/scummer.v1.Scummer/Resolve"
+	Scummer_Export_FullMethodName  = "/scummer.v1.Scummer/Export"
+)
+
+// ScummerClient is the client API for Scummer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ScummerClient interface {
+	// Scan scans a library, sending the result of each directory as soon as it is known.
+	// The results replace the ones the server had when the scan is done.
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (Scummer_ScanClient, error)
+	// Watch sends the current results, and then sends them again every time they change.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Scummer_WatchClient, error)
+	// Resolve picks the game in a directory that scummvm wasn't sure about, or skips the
+	// directory, the same way as the review page.
+	Resolve(ctx context.Context, in *ResolveRequest, opts ...grpc.CallOption) (*ResolveResponse, error)
+	// Export exports the results to one of the formats of "scummer export".
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ExportResponse, error)
+}
+
+type scummerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewScummerClient(cc grpc.ClientConnInterface) ScummerClient {
+	return &scummerClient{cc}
+}
+
+func (c *scummerClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (Scummer_ScanClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Scummer_ServiceDesc.Streams[0], Scummer_Scan_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &scummerScanClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Scummer_ScanClient interface {
+	Recv() (*ScanProgress, error)
+	grpc.ClientStream
+}
+
+type scummerScanClient struct {
+	grpc.ClientStream
+}
+
+func (x *scummerScanClient) Recv() (*ScanProgress, error) {
+	m := new(ScanProgress)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *scummerClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Scummer_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Scummer_ServiceDesc.Streams[1], Scummer_Watch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &scummerWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Scummer_WatchClient interface {
+	Recv() (*Results, error)
+	grpc.ClientStream
+}
+
+type scummerWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *scummerWatchClient) Recv() (*Results, error) {
+	m := new(Results)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *scummerClient) Resolve(ctx context.Context, in *ResolveRequest, opts ...grpc.CallOption) (*ResolveResponse, error) {
+	out := new(ResolveResponse)
+	err := c.cc.Invoke(ctx, Scummer_Resolve_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scummerClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ExportResponse, error) {
+	out := new(ExportResponse)
+	err := c.cc.Invoke(ctx, Scummer_Export_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ScummerServer is the server API for Scummer service.
+// All implementations must embed UnimplementedScummerServer
+// for forward compatibility
+type ScummerServer interface {
+	// Scan scans a library, sending the result of each directory as soon as it is known.
+	// The results replace the ones the server had when the scan is done.
+	Scan(*ScanRequest, Scummer_ScanServer) error
+	// Watch sends the current results, and then sends them again every time they change.
+	Watch(*WatchRequest, Scummer_WatchServer) error
+	// Resolve picks the game in a directory that scummvm wasn't sure about, or skips the
+	// directory, the same way as the review page.
+	Resolve(context.Context, *ResolveRequest) (*ResolveResponse, error)
+	// Export exports the results to one of the formats of "scummer export".
+	Export(context.Context, *ExportRequest) (*ExportResponse, error)
+	mustEmbedUnimplementedScummerServer()
+}
+
+// UnimplementedScummerServer must be embedded to have forward compatible implementations.
+type UnimplementedScummerServer struct {
+}
+
+func (UnimplementedScummerServer) Scan(*ScanRequest, Scummer_ScanServer) error {
+	return status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedScummerServer) Watch(*WatchRequest, Scummer_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedScummerServer) Resolve(context.Context, *ResolveRequest) (*ResolveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resolve not implemented")
+}
+func (UnimplementedScummerServer) Export(context.Context, *ExportRequest) (*ExportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedScummerServer) mustEmbedUnimplementedScummerServer() {}
+
+// UnsafeScummerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ScummerServer will
+// result in compilation errors.
+type UnsafeScummerServer interface {
+	mustEmbedUnimplementedScummerServer()
+}
+
+func RegisterScummerServer(s grpc.ServiceRegistrar, srv ScummerServer) {
+	s.RegisterService(&Scummer_ServiceDesc, srv)
+}
+
+func _Scummer_Scan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ScummerServer).Scan(m, &scummerScanServer{stream})
+}
+
+type Scummer_ScanServer interface {
+	Send(*ScanProgress) error
+	grpc.ServerStream
+}
+
+type scummerScanServer struct {
+	grpc.ServerStream
+}
+
+func (x *scummerScanServer) Send(m *ScanProgress) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Scummer_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ScummerServer).Watch(m, &scummerWatchServer{stream})
+}
+
+type Scummer_WatchServer interface {
+	Send(*Results) error
+	grpc.ServerStream
+}
+
+type scummerWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *scummerWatchServer) Send(m *Results) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Scummer_Resolve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScummerServer).Resolve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Scummer_Resolve_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScummerServer).Resolve(ctx, req.(*ResolveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scummer_Export_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScummerServer).Export(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Scummer_Export_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScummerServer).Export(ctx, req.(*ExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Scummer_ServiceDesc is the grpc.ServiceDesc for Scummer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Scummer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "scummer.v1.Scummer",
+	HandlerType: (*ScummerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Resolve",
+			Handler:    _Scummer_Resolve_Handler,
+		},
+		{
+			MethodName: "Export",
+			Handler:    _Scummer_Export_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Scan",
+			Handler:       _Scummer_Scan_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _Scummer_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "scummer.proto",
+}
@@ -0,0 +1,301 @@
+// Package scummer works out which scummvm game is in each directory of a library, the
+// way the scummer command does, for Go programs that would rather not run scummer and
+// read its JSON files.
+//
+//	scanner, err := scummer.NewScanner(scummer.Options{Threshold: 0.5})
+//	if err != nil {
+//		return err
+//	}
+//	result, err := scanner.Scan(ctx, "/roms/scummvm")
+//
+// The types and functions in this package are scummer's public API, and they follow
+// semantic versioning: nothing here is removed or changed in a way that breaks callers
+// without a new major version. The detect, parse, match and output packages they are
+// built on can be imported too, but they are allowed to change between minor versions.
+package scummer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/furui/scummer/detect"
+	"github.com/furui/scummer/match"
+	"github.com/furui/scummer/parse"
+)
+
+// Match is a game found in a directory, as saved in success.json. A directory no game
+// was found in is a Match too, with an ErrorKind saying what went wrong, as saved in
+// error.json.
+type Match = match.ScummGameMatch
+
+// Candidate is one of the games scummvm found in a directory when it wasn't sure which
+// one it was.
+type Candidate = match.ScummGameCandidate
+
+// Alias is what an abbreviation used in directory names, such as "DOTT", stands for.
+type Alias = match.Alias
+
+// RankingWeights are the weights that combine everything known about a candidate into a
+// single score.
+type RankingWeights = match.RankingWeights
+
+// These are the things a Scanner can do with a low confidence match, which is one where
+// scummvm found several games and none of them is similar enough to the directory name.
+const (
+	// LowConfidenceBestGuess uses the closest candidate anyway.
+	LowConfidenceBestGuess = match.LowConfidenceBestGuess
+
+	// LowConfidenceSkip leaves the directory out, adding it to the errors with the
+	// ErrorKind "skipped".
+	LowConfidenceSkip = match.LowConfidenceSkip
+
+	// LowConfidenceError adds the directory to the errors with the ErrorKind
+	// "low-confidence".
+	LowConfidenceError = match.LowConfidenceError
+)
+
+// Options controls how a Scanner finds and matches games. The zero value finds an
+// installed scummvm and always uses the closest candidate.
+type Options struct {
+	// Scummvm is the path to the scummvm binary, and ScummvmArgs are any arguments that
+	// have to come before scummvm's own, such as for "flatpak run". An installed scummvm
+	// is looked for if Scummvm is empty, and a macOS .app bundle stands for the scummvm
+	// inside it. Without any ScummvmArgs, Scummvm can also be "flatpak" for the ScummVM
+	// Flatpak, or "docker:<image>" to run scummvm in a container of that image.
+	Scummvm     string
+	ScummvmArgs []string
+
+	// MinScummvmVersion, such as "2.7", is the oldest scummvm NewScanner accepts. Any
+	// version is accepted if it is empty.
+	MinScummvmVersion string
+
+	// Metric is the string metric the candidates are compared with the directory name
+	// with, such as "jaro-winkler". It is "levenshtein" if empty.
+	Metric string
+
+	// Threshold is the similarity, from 0 to 1, below which a match is low confidence.
+	// OnLowConfidence says what to do with those; it is LowConfidenceBestGuess if empty.
+	Threshold       float64
+	OnLowConfidence string
+
+	// PreferredLanguages and PreferredPlatforms are scummvm language and platform names
+	// or codes, such as "de" or "DOS", most preferred first. They decide between
+	// candidates that only differ by language, or are equally close to the directory name.
+	PreferredLanguages []string
+	PreferredPlatforms []string
+
+	// Aliases are abbreviations used in directory names, keyed in lower case, on top of
+	// the ones scummer knows about.
+	Aliases map[string]Alias
+
+	// Ranking, when set, picks the candidate with the highest weighted score instead of
+	// the most similar one.
+	Ranking *RankingWeights
+
+	// Suggest adds the closest known game to the directories scummvm found nothing in.
+	Suggest bool
+
+	// FollowSymlinks scans symlinks to directories, and IncludeHidden scans hidden and
+	// system directories.
+	FollowSymlinks bool
+	IncludeHidden  bool
+}
+
+// Result is what a scan found.
+type Result struct {
+	// Matches are the directories a game was found in.
+	Matches []Match
+
+	// Errors are the directories no game was found in, or that were left out because
+	// of OnLowConfidence.
+	Errors []Match
+}
+
+// Scanner finds the games in a library with scummvm. It is safe to use from several
+// goroutines at once.
+type Scanner struct {
+	scummvm         detect.Command
+	scummvmVersion  string
+	parsedVersion   string
+	matchOptions    match.Options
+	threshold       float64
+	onLowConfidence string
+	suggest         bool
+	listingOptions  detect.ListingOptions
+}
+
+// NewScanner checks the options, makes sure scummvm can be run, and loads the titles of
+// the games it knows about.
+func NewScanner(options Options) (*Scanner, error) {
+	scanner := &Scanner{threshold: options.Threshold, onLowConfidence: options.OnLowConfidence, suggest: options.Suggest}
+	scanner.listingOptions = detect.ListingOptions{FollowSymlinks: options.FollowSymlinks, IncludeHidden: options.IncludeHidden}
+
+	// Check that the low confidence policy is one we know about
+	switch scanner.onLowConfidence {
+	case "":
+		scanner.onLowConfidence = LowConfidenceBestGuess
+	case LowConfidenceBestGuess, LowConfidenceSkip, LowConfidenceError:
+	default:
+		return nil, fmt.Errorf("unknown low confidence policy %q, must be one of %s, %s or %s", options.OnLowConfidence, LowConfidenceBestGuess, LowConfidenceSkip, LowConfidenceError)
+	}
+
+	// Set up the matching
+	metricName := options.Metric
+	if metricName == "" {
+		metricName = "levenshtein"
+	}
+	metric, err := match.NewStringMetric(metricName, "1,1,2")
+	if err != nil {
+		return nil, err
+	}
+	scanner.matchOptions = match.Options{Metric: metric, Aliases: match.MergeAliases(options.Aliases), Ranking: options.Ranking}
+	if scanner.matchOptions.PreferredLanguages, err = match.ParseLanguagePreference(strings.Join(options.PreferredLanguages, ",")); err != nil {
+		return nil, err
+	}
+	if scanner.matchOptions.PreferredPlatforms, err = match.ParsePlatformPreference(strings.Join(options.PreferredPlatforms, ",")); err != nil {
+		return nil, err
+	}
+
+	// Find scummvm if we weren't told where it is, and make sure it works
+	if options.Scummvm == "" {
+		if scanner.scummvm, err = detect.Discover(); err != nil {
+			return nil, err
+		}
+	} else if packagedCommand, ok, err := detect.PackagedCommand(options.Scummvm); ok && len(options.ScummvmArgs) == 0 {
+		if err != nil {
+			return nil, err
+		}
+		scanner.scummvm = packagedCommand
+	} else {
+		scanner.scummvm = detect.Command{Path: detect.ResolveAppBundle(options.Scummvm), Args: options.ScummvmArgs}
+	}
+	if scanner.scummvmVersion, err = detect.Verify(scanner.scummvm); err != nil {
+		return nil, err
+	}
+	scanner.scummvmVersion = strings.TrimSpace(scanner.scummvmVersion)
+
+	// Work out the version every match records, and check that it is new enough
+	version, versionErr := detect.ParseVersion(scanner.scummvmVersion)
+	if versionErr == nil {
+		scanner.parsedVersion = version.String()
+	}
+	if options.MinScummvmVersion != "" {
+		minimumVersion, err := detect.ParseMinimumVersion(options.MinScummvmVersion)
+		if err != nil {
+			return nil, err
+		}
+		if versionErr != nil {
+			return nil, versionErr
+		}
+		if err := detect.CheckMinimumVersion(version, minimumVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	// Load the full titles of the games scummvm knows about
+	scanner.matchOptions.GameTitles = detect.LoadGameTitles(scanner.scummvm)
+
+	return scanner, nil
+}
+
+// ScummvmVersion returns what the scummvm the Scanner runs says its version is.
+func (s *Scanner) ScummvmVersion() string {
+	return s.scummvmVersion
+}
+
+// GameTitles returns the full titles of the games the Scanner knows about, by GameID.
+func (s *Scanner) GameTitles() map[string]string {
+	return s.matchOptions.GameTitles
+}
+
+// Scan finds the game in each directory of a library. If the context is done before
+// the scan finishes, Scan returns what it found so far along with the context's error.
+// An error is returned straight away if scummvm runs in a sandbox that can't see the
+// library.
+func (s *Scanner) Scan(ctx context.Context, library string) (*Result, error) {
+	if err := detect.CheckSandboxAccess(s.scummvm, library); err != nil {
+		return nil, err
+	}
+
+	directories, err := detect.GameDirectories(library, s.listingOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Matches: make([]Match, 0), Errors: make([]Match, 0)}
+	for _, directory := range directories {
+		scummGameMatch, err := s.ScanDirectory(ctx, filepath.Join(library, directory))
+		if err != nil {
+			return result, err
+		}
+		if scummGameMatch.ErrorKind != "" {
+			result.Errors = append(result.Errors, scummGameMatch)
+		} else {
+			result.Matches = append(result.Matches, scummGameMatch)
+		}
+	}
+	return result, nil
+}
+
+// ScanDirectory finds the game in a single directory. A directory with no game in it,
+// or one that is left out because of OnLowConfidence, gives a Match with its ErrorKind
+// set. An error is only returned if the context is done before scummvm finishes.
+func (s *Scanner) ScanDirectory(ctx context.Context, directory string) (Match, error) {
+	scummGameMatch, err := s.scanDirectory(ctx, directory)
+	if err != nil {
+		return scummGameMatch, err
+	}
+	scummGameMatch.ScummvmVersion = s.parsedVersion
+	if scummGameMatch.ErrorKind == "" {
+		scummGameMatch.Fingerprint, _ = detect.DirectoryFingerprint(directory)
+	}
+	return scummGameMatch, nil
+}
+
+// scanDirectory is ScanDirectory, apart from recording the version of scummvm.
+func (s *Scanner) scanDirectory(ctx context.Context, directory string) (Match, error) {
+	// Make sure the directory can actually be read
+	if err := detect.CheckDirectoryReadable(directory); err != nil {
+		return Match{GameID: "unknown", Description: err.Error(), Directory: directory, ErrorKind: detect.ClassifyFilesystemError(err)}, nil
+	}
+
+	// Ask scummvm what is in the directory
+	scummvmOutput, err := detect.RunContext(ctx, s.scummvm, []string{"--detect", "--path=" + directory})
+	if ctx.Err() != nil {
+		return Match{}, ctx.Err()
+	}
+	if err != nil {
+		return Match{GameID: "unknown", Description: err.Error(), Directory: directory, ErrorKind: match.ErrorKindScummvm}, nil
+	}
+	candidates, err := parse.DetectOutput(scummvmOutput)
+	if err != nil {
+		scummGameMatch := Match{GameID: "unknown", Description: err.Error(), Directory: directory, ErrorKind: match.ErrorKindDetection}
+		if s.suggest {
+			suspectedGameID, suspectedTitle, suspectedSimilarity := match.SuspectedGame(filepath.Base(directory), s.matchOptions)
+			if suspectedSimilarity >= s.threshold {
+				scummGameMatch.SuspectedGameID = suspectedGameID
+				scummGameMatch.SuspectedTitle = suspectedTitle
+				scummGameMatch.SuspectedSimilarity = suspectedSimilarity
+			}
+		}
+		return scummGameMatch, nil
+	}
+
+	// Pick the candidate that is closest to the directory name, following the low
+	// confidence policy if none of them is close enough
+	chosenIndex, similarity, reason := match.Closest(candidates, s.matchOptions)
+	if len(candidates) > 1 && similarity < s.threshold {
+		switch s.onLowConfidence {
+		case LowConfidenceSkip:
+			return Match{GameID: "unknown", Description: "skipped because of low confidence", Directory: directory, ErrorKind: match.ErrorKindSkipped, Candidates: match.Candidates(candidates)}, nil
+		case LowConfidenceError:
+			return Match{GameID: "unknown", Description: fmt.Sprintf("no candidate is similar enough to the directory name (best similarity %.2f)", similarity), Directory: directory, ErrorKind: match.ErrorKindLowConfidence, Candidates: match.Candidates(candidates)}, nil
+		}
+	}
+
+	scummGameMatch := match.Chosen(candidates, chosenIndex, reason, "", s.matchOptions)
+	scummGameMatch.Directory = directory
+	return scummGameMatch, nil
+}
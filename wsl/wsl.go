@@ -0,0 +1,74 @@
+// Package wsl translates paths between Windows and the Windows Subsystem for Linux, so
+// that scummer running on one side can work with a scummvm, or with results, from the
+// other side: /mnt/c/Games on Linux is C:\Games on Windows, and /home/me in a WSL
+// distribution is \\wsl.localhost\<distribution>\home\me.
+package wsl
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// windowsDrivePathPattern matches a path on a Windows drive, such as C:\Games or C:/Games.
+var windowsDrivePathPattern = regexp.MustCompile(`^([A-Za-z]):([\\/].*)?$`)
+
+// windowsSharePathPattern matches a path into a WSL distribution from Windows, such as
+// \\wsl.localhost\Ubuntu\home\me or \\wsl$\Ubuntu\home\me.
+var windowsSharePathPattern = regexp.MustCompile(`^[\\/]{2}(?i:wsl\.localhost|wsl\$)[\\/][^\\/]+([\\/].*)?$`)
+
+// mountedDrivePathPattern matches a Windows drive as WSL mounts it, such as /mnt/c/Games.
+var mountedDrivePathPattern = regexp.MustCompile(`^/mnt/([A-Za-z])(/.*)?$`)
+
+// Running returns whether scummer is running inside WSL.
+func Running() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	_, err := os.Stat("/proc/sys/fs/binfmt_misc/WSLInterop")
+	return err == nil
+}
+
+// WindowsPath returns what an absolute Linux path inside WSL is called on Windows. A
+// path that isn't on a Windows drive is reached through the distribution's share, which
+// needs WSL_DISTRO_NAME to be set, as it is in WSL; otherwise it is returned as it is.
+func WindowsPath(linuxPath string) string {
+	if parts := mountedDrivePathPattern.FindStringSubmatch(linuxPath); parts != nil {
+		return strings.ToUpper(parts[1]) + `:\` + strings.ReplaceAll(strings.TrimPrefix(parts[2], "/"), "/", `\`)
+	}
+	distribution := os.Getenv("WSL_DISTRO_NAME")
+	if !strings.HasPrefix(linuxPath, "/") || distribution == "" {
+		return linuxPath
+	}
+	return `\\wsl.localhost\` + distribution + strings.ReplaceAll(linuxPath, "/", `\`)
+}
+
+// LinuxPath returns what a Windows path is called inside WSL. A path that isn't on a
+// drive or in a WSL distribution's share is returned as it is.
+func LinuxPath(windowsPath string) string {
+	if parts := windowsDrivePathPattern.FindStringSubmatch(windowsPath); parts != nil {
+		return path.Join("/mnt", strings.ToLower(parts[1]), strings.ReplaceAll(parts[2], `\`, "/"))
+	}
+	if parts := windowsSharePathPattern.FindStringSubmatch(windowsPath); parts != nil {
+		return path.Join("/", strings.ReplaceAll(parts[1], `\`, "/"))
+	}
+	return windowsPath
+}
+
+// LocalPath returns a path written on the other side of WSL the way this side calls it,
+// such as the paths in results from a scan on Windows when they are used inside WSL.
+// Paths that are already this side's are returned as they are.
+func LocalPath(otherPath string) string {
+	switch {
+	case Running() && (windowsDrivePathPattern.MatchString(otherPath) || windowsSharePathPattern.MatchString(otherPath)):
+		return LinuxPath(otherPath)
+	case runtime.GOOS == "windows" && mountedDrivePathPattern.MatchString(otherPath):
+		return WindowsPath(otherPath)
+	}
+	return otherPath
+}